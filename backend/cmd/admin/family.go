@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// deleteFamily 彻底删除家庭及其全部用户和数据，不带 -yes 时只显示将要删除的成员
+func deleteFamily(db *gorm.DB, args []string) error {
+	flags := flag.NewFlagSet("delete-family", flag.ContinueOnError)
+	confirmed := flags.Bool("yes", false, "delete without asking again")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: delete-family [-yes] <family_id>")
+	}
+	familyID, err := strconv.ParseUint(flags.Arg(0), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid family ID %q", flags.Arg(0))
+	}
+
+	var family models.Family
+	if err := db.First(&family, familyID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("family %d not found", familyID)
+		}
+		return err
+	}
+	var users []models.User
+	if err := db.Where("family_id = ? OR id IN (?)", family.ID, models.FamilyMemberIDs(db, family.ID)).
+		Order("id ASC").Find(&users).Error; err != nil {
+		return err
+	}
+
+	fmt.Printf("Family %d %s\n\n", family.ID, family.Name)
+	w := newTable()
+	fmt.Fprintln(w, "ID\tROLE\tPHONE\tNICKNAME")
+	for _, user := range users {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", user.ID, user.Role, utils.GetStringValue(user.Phone), user.Nickname)
+	}
+	w.Flush()
+	if !*confirmed {
+		return errors.New("re-run with -yes to permanently delete this family, its users and all their data")
+	}
+
+	deleted, err := models.DeleteFamily(db, family.ID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nDeleted family %d and %d user(s)\n", family.ID, len(deleted))
+	return nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/utils"
+	"child-behavior-app/migrations"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// usage admin 命令用法，选项需写在参数之前
+const usage = `usage: admin <command> [options] [arguments]
+
+Commands:
+  users [-phone p] [-family id] [-role r] [-limit n]  list users, -phone matches part of the phone number
+  user <phone|id>                                     show a user with family, points and lockouts
+  reset-password [-password p] <phone|id>             set a parent's password, read from stdin without -password
+  unlock <phone|id>                                   clear every login lockout of a user
+  recompute-points <phone|id>...                      rebuild user points from the point ledger
+  merge-users [-yes] <keep phone|id> <duplicate phone|id>
+                                                      move a duplicate account's data into another and delete it
+  delete-family [-yes] <family_id>                    permanently delete a family with all its users and data
+  status                                              show the database and migration status
+
+The database is configured the same way as the server (configs/config.yaml and environment variables).`
+
+// command 子命令，args 为命令名之后的参数
+type command func(db *gorm.DB, args []string) error
+
+var commands = map[string]command{
+	"users":            listUsers,
+	"user":             showUser,
+	"reset-password":   resetPassword,
+	"unlock":           unlockUser,
+	"recompute-points": recomputePoints,
+	"merge-users":      mergeUsers,
+	"delete-family":    deleteFamily,
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "help" || os.Args[1] == "-h" || os.Args[1] == "--help" {
+		fmt.Println(usage)
+		return
+	}
+
+	name := os.Args[1]
+	run, ok := commands[name]
+	if !ok && name != "status" {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s\n", name, usage)
+		os.Exit(2)
+	}
+
+	db, appConfig, err := openDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "admin: %v\n", err)
+		os.Exit(1)
+	}
+
+	if name == "status" {
+		err = showStatus(db, appConfig)
+	} else if err = requireSchema(db); err == nil {
+		err = run(db, os.Args[2:])
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "admin %s: %v\n", name, err)
+		os.Exit(1)
+	}
+}
+
+// openDB 按服务端相同的配置连接数据库，不输出 SQL 日志
+func openDB() (*gorm.DB, *utils.Config, error) {
+	appConfig, err := utils.LoadConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	db, err := models.InitDBWithConfig(appConfig.Database)
+	if err != nil {
+		return nil, nil, err
+	}
+	return db.Session(&gorm.Session{Logger: logger.Default.LogMode(logger.Silent)}), appConfig, nil
+}
+
+// requireSchema 表结构不是最新时拒绝执行，避免按旧表结构修改数据
+func requireSchema(db *gorm.DB) error {
+	pending, err := migrations.Pending(db)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d pending migration(s), run `go run ./cmd/server migrate up` first",
+			migrations.ErrSchemaOutdated, len(pending))
+	}
+	return nil
+}
+
+// showStatus 输出数据库连接和每个迁移版本的执行状态
+func showStatus(db *gorm.DB, appConfig *utils.Config) error {
+	database := appConfig.Database
+	if database.Driver == utils.DatabaseDriverSQLite {
+		fmt.Printf("Database:  sqlite %s\n", database.Path)
+	} else {
+		fmt.Printf("Database:  mysql %s@%s:%d/%s\n", database.Username, database.Host, database.Port, database.DBName)
+	}
+
+	current, err := migrations.CurrentVersion(db)
+	if err != nil {
+		return err
+	}
+	latest, err := migrations.LatestVersion(db.Dialector.Name())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Schema:    version %d, latest %d\n\n", current, latest)
+
+	statuses, err := migrations.GetStatus(db)
+	w := newTable()
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS")
+	pending := 0
+	for _, status := range statuses {
+		state := "pending"
+		if status.Applied {
+			state = "applied at " + status.AppliedAt.Format("2006-01-02 15:04:05")
+		} else {
+			pending++
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, state)
+	}
+	w.Flush()
+	if err != nil {
+		return err
+	}
+
+	if pending > 0 {
+		fmt.Printf("\n%d pending migration(s), run `go run ./cmd/server migrate up`\n", pending)
+	} else {
+		fmt.Println("\nSchema is up to date")
+	}
+	return nil
+}
+
+// findUser 按手机号查找用户，找不到且参数为数字时按用户ID查找
+func findUser(db *gorm.DB, ref string) (*models.User, error) {
+	var user models.User
+	err := db.Where("phone = ?", ref).First(&user).Error
+	if err == nil {
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if id, parseErr := strconv.ParseUint(ref, 10, 64); parseErr == nil {
+		err = db.First(&user, id).Error
+		if err == nil {
+			return &user, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("user %q not found", ref)
+}
+
+// newTable 创建按列对齐输出的表格
+func newTable() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+}
+
+// optionalID 格式化可为空的ID
+func optionalID(id *uint) string {
+	if id == nil {
+		return "-"
+	}
+	return strconv.FormatUint(uint64(*id), 10)
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// minPasswordLength 与注册时的密码长度要求一致
+const minPasswordLength = 6
+
+// listUsers 列出用户，可按手机号片段、家庭和角色筛选
+func listUsers(db *gorm.DB, args []string) error {
+	flags := flag.NewFlagSet("users", flag.ContinueOnError)
+	phone := flags.String("phone", "", "part of the phone number")
+	familyID := flags.Uint("family", 0, "family ID")
+	role := flags.String("role", "", "parent or child")
+	limit := flags.Int("limit", 50, "maximum number of users, 0 for all")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	query := db.Model(&models.User{})
+	if *phone != "" {
+		query = query.Where("phone LIKE ?", "%"+*phone+"%")
+	}
+	if *familyID != 0 {
+		query = query.Where("family_id = ?", *familyID)
+	}
+	if *role != "" {
+		query = query.Where("role = ?", *role)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return err
+	}
+	if *limit > 0 {
+		query = query.Limit(*limit)
+	}
+	var users []models.User
+	if err := query.Order("id ASC").Find(&users).Error; err != nil {
+		return err
+	}
+
+	w := newTable()
+	fmt.Fprintln(w, "ID\tROLE\tPHONE\tNICKNAME\tFAMILY\tCREATED")
+	for _, user := range users {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", user.ID, user.Role, utils.GetStringValue(user.Phone),
+			user.Nickname, optionalID(user.FamilyID), user.CreatedAt.Format("2006-01-02 15:04"))
+	}
+	w.Flush()
+	fmt.Printf("\n%d of %d user(s)\n", len(users), total)
+	return nil
+}
+
+// showUser 输出用户详情：家庭、积分、会话、登录方式和登录锁定
+func showUser(db *gorm.DB, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: user <phone|id>")
+	}
+	user, err := findUser(db, args[0])
+	if err != nil {
+		return err
+	}
+
+	w := newTable()
+	fmt.Fprintf(w, "ID:\t%d\n", user.ID)
+	fmt.Fprintf(w, "Role:\t%s\n", user.Role)
+	fmt.Fprintf(w, "Phone:\t%s\n", utils.GetStringValue(user.Phone))
+	fmt.Fprintf(w, "Nickname:\t%s\n", user.Nickname)
+	fmt.Fprintf(w, "Created:\t%s\n", user.CreatedAt.Format("2006-01-02 15:04:05"))
+
+	if user.FamilyID != nil {
+		var family models.Family
+		if err := db.First(&family, *user.FamilyID).Error; err == nil {
+			fmt.Fprintf(w, "Family:\t%d %s (owner %d)\n", family.ID, family.Name, family.OwnerID)
+		} else {
+			fmt.Fprintf(w, "Family:\t%d (missing)\n", *user.FamilyID)
+		}
+		if member, err := models.GetFamilyMember(db, user.ID); err == nil {
+			fmt.Fprintf(w, "Family role:\t%s\n", member.Role)
+		}
+	} else {
+		fmt.Fprintf(w, "Family:\t-\n")
+	}
+
+	var userPoints models.UserPoints
+	if err := db.Where("user_id = ?", user.ID).Limit(1).Find(&userPoints).Error; err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Points:\t%d available, %d total\n", userPoints.AvailablePoints, userPoints.TotalPoints)
+
+	now := time.Now()
+	var sessions int64
+	if err := db.Model(&models.Session{}).Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", user.ID, now).
+		Count(&sessions).Error; err != nil {
+		return err
+	}
+	var passkeys int64
+	if err := db.Model(&models.WebAuthnCredential{}).Where("user_id = ?", user.ID).Count(&passkeys).Error; err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Sessions:\t%d active\n", sessions)
+	fmt.Fprintf(w, "Sign-in:\tpassword %t, PIN %t, two-factor %t, %d passkey(s)\n",
+		user.Password != nil, user.Pin != nil, user.TOTPEnabled, passkeys)
+
+	locks, err := lockouts(db, user, now)
+	if err != nil {
+		return err
+	}
+	if len(locks) == 0 {
+		fmt.Fprintf(w, "Lockouts:\tnone\n")
+	}
+	for _, lock := range locks {
+		fmt.Fprintf(w, "Lockouts:\t%s\n", lock)
+	}
+	return w.Flush()
+}
+
+// lockouts 获取用户当前被锁定的登录方式
+func lockouts(db *gorm.DB, user *models.User, now time.Time) ([]string, error) {
+	subjects := map[string]string{
+		models.LockoutScopeVerifyPassword: fmt.Sprint(user.ID),
+		models.LockoutScopeChildPin:       fmt.Sprint(user.ID),
+		models.LockoutScopeTwoFactor:      fmt.Sprint(user.ID),
+	}
+	if user.Phone != nil {
+		subjects[models.LockoutScopeLogin] = *user.Phone
+	}
+
+	var locks []string
+	for _, scope := range []string{models.LockoutScopeLogin, models.LockoutScopeVerifyPassword, models.LockoutScopeChildPin, models.LockoutScopeTwoFactor} {
+		subject, ok := subjects[scope]
+		if !ok {
+			continue
+		}
+		status, err := models.GetLockStatus(db, scope, subject, now)
+		if err != nil {
+			return nil, err
+		}
+		if status.Locked {
+			locks = append(locks, fmt.Sprintf("%s until %s", scope, status.LockedUntil.Format("2006-01-02 15:04:05")))
+		}
+	}
+	return locks, nil
+}
+
+// resetPassword 为家长设置新密码，撤销所有会话并清除登录锁定
+func resetPassword(db *gorm.DB, args []string) error {
+	flags := flag.NewFlagSet("reset-password", flag.ContinueOnError)
+	password := flags.String("password", "", "new password, read from stdin when omitted")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: reset-password [-password p] <phone|id>")
+	}
+
+	user, err := findUser(db, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if user.Role != "parent" {
+		return fmt.Errorf("user %d is a %s, only parents have passwords (set a child's PIN in the app)", user.ID, user.Role)
+	}
+
+	if *password == "" {
+		fmt.Fprintf(os.Stderr, "New password for %s (%s): ", user.Nickname, utils.GetStringValue(user.Phone))
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("failed to read password: %w", err)
+		}
+		*password = strings.TrimRight(line, "\r\n")
+	}
+	if len(*password) < minPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+
+	hashedPassword, err := utils.HashPassword(*password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	if err := models.ResetUserPassword(db, user, hashedPassword); err != nil {
+		return err
+	}
+	fmt.Printf("Password of user %d reset, all sessions signed out\n", user.ID)
+	return nil
+}
+
+// unlockUser 清除用户的所有登录锁定
+func unlockUser(db *gorm.DB, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: unlock <phone|id>")
+	}
+	user, err := findUser(db, args[0])
+	if err != nil {
+		return err
+	}
+	if err := models.UnlockUser(db, user); err != nil {
+		return err
+	}
+	fmt.Printf("Cleared login lockouts of user %d\n", user.ID)
+	return nil
+}
+
+// recomputePoints 按积分流水重新计算用户积分
+func recomputePoints(db *gorm.DB, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: recompute-points <phone|id>...")
+	}
+
+	w := newTable()
+	defer w.Flush()
+	fmt.Fprintln(w, "ID\tNICKNAME\tAVAILABLE\tTOTAL")
+	for _, ref := range args {
+		user, err := findUser(db, ref)
+		if err != nil {
+			return err
+		}
+		var before models.UserPoints
+		if err := db.Where("user_id = ?", user.ID).Limit(1).Find(&before).Error; err != nil {
+			return err
+		}
+		after, err := models.RebuildUserPoints(db, user.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", user.ID, user.Nickname,
+			pointsChange(before.AvailablePoints, after.AvailablePoints), pointsChange(before.TotalPoints, after.TotalPoints))
+	}
+	return nil
+}
+
+// pointsChange 格式化重新计算前后的积分
+func pointsChange(before, after int) string {
+	if before == after {
+		return fmt.Sprint(after)
+	}
+	return fmt.Sprintf("%d -> %d", before, after)
+}
+
+// mergeUsers 将重复账户并入保留账户，不带 -yes 时只显示将要合并的账户
+func mergeUsers(db *gorm.DB, args []string) error {
+	flags := flag.NewFlagSet("merge-users", flag.ContinueOnError)
+	confirmed := flags.Bool("yes", false, "merge without asking again")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("usage: merge-users [-yes] <keep phone|id> <duplicate phone|id>")
+	}
+
+	keep, err := findUser(db, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	duplicate, err := findUser(db, flags.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	w := newTable()
+	fmt.Fprintln(w, "\tID\tROLE\tPHONE\tNICKNAME\tFAMILY")
+	fmt.Fprintf(w, "keep\t%d\t%s\t%s\t%s\t%s\n", keep.ID, keep.Role, utils.GetStringValue(keep.Phone), keep.Nickname, optionalID(keep.FamilyID))
+	fmt.Fprintf(w, "delete\t%d\t%s\t%s\t%s\t%s\n", duplicate.ID, duplicate.Role, utils.GetStringValue(duplicate.Phone), duplicate.Nickname, optionalID(duplicate.FamilyID))
+	w.Flush()
+	if !*confirmed {
+		return errors.New("re-run with -yes to merge these accounts")
+	}
+
+	merged, err := models.MergeUsers(db, keep.ID, duplicate.ID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Merged user %d into user %d and deleted user %d\n", duplicate.ID, merged.ID, duplicate.ID)
+	return nil
+}
@@ -3,10 +3,15 @@ package main
 import (
 	"fmt"
 	"log"
+	"os"
+	"time"
 
+	"child-behavior-app/internal/api/middleware"
 	"child-behavior-app/internal/api/routes"
+	"child-behavior-app/internal/jobs"
 	"child-behavior-app/internal/models"
 	"child-behavior-app/internal/utils"
+	"child-behavior-app/migrations"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -19,9 +24,38 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	// 初始化MariaDB数据库（使用配置文件）
+	// 按配置文件中的驱动初始化 MySQL/MariaDB 或 SQLite 数据库
 	db := models.InitDB()
-	models.AutoMigrate(db)
+
+	// migrate 子命令只管理数据库迁移，执行后退出
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(db, os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	// 表结构不是最新时，按当前环境的 auto_migrate 配置自动执行迁移或拒绝启动
+	applied, err := migrations.EnsureSchema(db, appConfig.AutoMigrate())
+	if err != nil {
+		log.Fatalf("Database schema check failed: %v", err)
+	}
+	for _, m := range applied {
+		log.Printf("Applied migration %04d_%s", m.Version, m.Name)
+	}
+
+	// seed 子命令生成演示家庭，执行后退出
+	if len(os.Args) > 1 && os.Args[1] == "seed" {
+		if err := runSeed(db, appConfig, os.Args[2:]); err != nil {
+			log.Fatalf("Seed failed: %v", err)
+		}
+		return
+	}
+
+	// 开启 seed_data 时，演示家庭不存在则写入演示数据
+	if appConfig.SeedData() {
+		seedOnStartup(db)
+	}
 
 	// 初始化JWT
 	if err := utils.InitJWT(); err != nil {
@@ -31,6 +65,9 @@ func main() {
 	// 初始化缓存
 	utils.InitCache()
 
+	// 启动周期任务后台作业
+	jobs.StartScheduler(db, 10*time.Minute)
+
 	// 设置Gin模式
 	gin.SetMode(gin.ReleaseMode)
 
@@ -41,12 +78,12 @@ func main() {
 	config := cors.DefaultConfig()
 	config.AllowOrigins = []string{"http://localhost:5173", "http://localhost:3000"}
 	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization", middleware.IdempotencyHeader, middleware.DeviceIDHeader, middleware.ElevatedTokenHeader}
 	config.AllowCredentials = true
 	r.Use(cors.New(config))
 
 	// 设置路由
-	routes.SetupRoutes(r, db)
+	routes.SetupRoutes(r, db, appConfig)
 
 	// 启动服务器
 	port := fmt.Sprintf(":%d", appConfig.App.Port)
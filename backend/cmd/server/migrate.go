@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"child-behavior-app/migrations"
+
+	"gorm.io/gorm"
+)
+
+// migrateUsage migrate 子命令用法
+const migrateUsage = "usage: migrate [up | down [n] | status | to <version>]"
+
+// runMigrate 执行 migrate 子命令
+//
+//	migrate up            执行所有未执行的迁移（默认）
+//	migrate down [n]      回滚最近执行的 n 个迁移，默认 1 个
+//	migrate status        查看每个版本的执行状态
+//	migrate to <version>  升级或回滚到指定版本，0 表示回滚全部
+func runMigrate(db *gorm.DB, args []string) error {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrations.Up(db)
+		printMigrations("Applied", applied)
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of migrations %q, %s", args[1], migrateUsage)
+			}
+			steps = n
+		}
+		reverted, err := migrations.Down(db, steps)
+		printMigrations("Reverted", reverted)
+		return err
+	case "to":
+		if len(args) < 2 {
+			return errors.New(migrateUsage)
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil || version < 0 {
+			return fmt.Errorf("invalid version %q, %s", args[1], migrateUsage)
+		}
+		changed, err := migrations.To(db, version)
+		printMigrations("Migrated", changed)
+		return err
+	case "status":
+		statuses, err := migrations.GetStatus(db)
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.Applied {
+				appliedAt = "applied at " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-32s %s\n", status.Version, status.Name, appliedAt)
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown migrate command %q, %s", command, migrateUsage)
+	}
+}
+
+// printMigrations 输出本次执行或回滚的迁移
+func printMigrations(action string, changed []migrations.Migration) {
+	if len(changed) == 0 {
+		fmt.Println("No migrations to run")
+		return
+	}
+	for _, m := range changed {
+		fmt.Printf("%s %04d_%s\n", action, m.Version, m.Name)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+
+	"child-behavior-app/internal/seed"
+	"child-behavior-app/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// runSeed 执行 seed 子命令，生成演示家庭
+//
+//	seed [-seed n] [-days n] [-until YYYY-MM-DD]
+//
+// 生产环境只有在 production.seed_data 开启时才允许执行
+func runSeed(db *gorm.DB, appConfig *utils.Config, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	seedValue := flags.Int64("seed", seed.DefaultSeed, "random seed, the same seed generates the same data")
+	days := flags.Int("days", seed.DefaultDays, "days of behavior history")
+	until := flags.String("until", seed.DefaultUntil, "last day of history (YYYY-MM-DD)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if appConfig.IsProduction() && !appConfig.SeedData() {
+		return errors.New("refusing to seed demo data in production, enable production.seed_data to allow it")
+	}
+	if *days < 1 {
+		return fmt.Errorf("invalid number of days %d", *days)
+	}
+
+	untilTime, err := seed.ParseUntil(*until)
+	if err != nil {
+		return err
+	}
+
+	opts := seed.Options{Seed: *seedValue, Days: *days, Until: untilTime}
+
+	summary, err := seed.Run(db, opts)
+	if errors.Is(err, seed.ErrAlreadySeeded) {
+		fmt.Printf("Demo family already exists (login %s), nothing to do\n", seed.DemoOwnerPhone)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	printSeedSummary(summary)
+	return nil
+}
+
+// seedOnStartup 启动时按 seed_data 配置写入演示数据，演示家庭已存在时跳过
+func seedOnStartup(db *gorm.DB) {
+	summary, err := seed.Run(db, seed.Options{Seed: seed.DefaultSeed, Days: seed.DefaultDays})
+	if errors.Is(err, seed.ErrAlreadySeeded) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Failed to seed demo data: %v", err)
+	}
+	printSeedSummary(summary)
+}
+
+// printSeedSummary 输出生成的数据量和登录信息
+func printSeedSummary(summary *seed.Summary) {
+	fmt.Printf("Created demo family %d: %d parents, %d children, %d behavior templates, %d rewards\n",
+		summary.FamilyID, summary.Parents, summary.Children, summary.Templates, summary.Rewards)
+	fmt.Printf("Generated %d behavior records (%d claims) and %d exchanges\n",
+		summary.Behaviors, summary.Claims, summary.Exchanges)
+	fmt.Printf("Log in as %s or %s with password %s, child PIN %s\n",
+		seed.DemoOwnerPhone, seed.DemoGuardianPhone, seed.DemoPassword, seed.DemoChildPin)
+}
@@ -0,0 +1,399 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AchievementHandler struct {
+	db *gorm.DB
+}
+
+func NewAchievementHandler(db *gorm.DB) *AchievementHandler {
+	return &AchievementHandler{db: db}
+}
+
+// CreateAchievementRequest 创建成就规则请求
+type CreateAchievementRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description" binding:"max=255"`
+	Icon        string `json:"icon" binding:"max=50"`
+	RuleType    string `json:"rule_type" binding:"required"`
+	Category    string `json:"category"`
+	Threshold   int    `json:"threshold" binding:"required,min=1"`
+}
+
+// UpdateAchievementRequest 更新成就规则请求
+type UpdateAchievementRequest struct {
+	Name        string `json:"name" binding:"max=100"`
+	Description string `json:"description" binding:"max=255"`
+	Icon        string `json:"icon" binding:"max=50"`
+	Threshold   *int   `json:"threshold" binding:"omitempty,min=1"`
+	IsActive    *bool  `json:"is_active"`
+}
+
+// LevelRequest 等级配置项
+type LevelRequest struct {
+	Name      string `json:"name" binding:"required,max=50"`
+	MinPoints int    `json:"min_points" binding:"min=0"`
+}
+
+// UpdateLevelsRequest 整体替换等级表请求
+type UpdateLevelsRequest struct {
+	Levels []LevelRequest `json:"levels" binding:"required,min=1,dive"`
+}
+
+// achievementResponse 构建成就规则返回数据
+func achievementResponse(achievement models.Achievement) gin.H {
+	return gin.H{
+		"id":          achievement.ID,
+		"name":        achievement.Name,
+		"description": achievement.Description,
+		"icon":        achievement.Icon,
+		"rule_type":   achievement.RuleType,
+		"category":    achievement.Category,
+		"threshold":   achievement.Threshold,
+		"is_active":   achievement.IsActive,
+	}
+}
+
+// checkChildAccess 检查当前用户能否查看儿童的数据
+// 儿童只能查看自己的，家长只能查看家庭中孩子的
+func checkChildAccess(db *gorm.DB, childID, currentUserID uint, currentUserRole interface{}, familyID uint) (int, string) {
+	if currentUserRole == "child" {
+		if childID != currentUserID {
+			return http.StatusForbidden, "Permission denied"
+		}
+		return http.StatusOK, ""
+	}
+
+	if _, err := models.FindFamilyChild(db, familyID, childID); err != nil {
+		return http.StatusNotFound, "Child not found or permission denied"
+	}
+	return http.StatusOK, ""
+}
+
+// GetChildAchievements 获取儿童的等级和成就（含未解锁成就的进度）
+func (h *AchievementHandler) GetChildAchievements(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("user_role")
+
+	childID, err := strconv.ParseUint(c.Param("child_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid child ID"))
+		return
+	}
+
+	familyID := c.GetUint("family_id")
+	if status, message := checkChildAccess(h.db, uint(childID), userID.(uint), userRole, familyID); status != http.StatusOK {
+		c.JSON(status, utils.ErrorResponse(status, message))
+		return
+	}
+
+	// 补齐规则变更前已满足条件的成就
+	if _, err := models.EvaluateAchievements(h.db, uint(childID)); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to evaluate achievements"))
+		return
+	}
+
+	var unlocked []models.ChildAchievement
+	if err := h.db.Preload("Achievement").Where("child_id = ?", childID).Order("unlocked_at DESC").Find(&unlocked).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to get achievements"))
+		return
+	}
+
+	unlockedIDs := make(map[uint]bool)
+	unlockedResult := []gin.H{}
+	for _, item := range unlocked {
+		unlockedIDs[item.AchievementID] = true
+		result := achievementResponse(item.Achievement)
+		result["unlocked_at"] = item.UnlockedAt
+		unlockedResult = append(unlockedResult, result)
+	}
+
+	var achievements []models.Achievement
+	if err := h.db.Where("created_by IN (?) AND is_active = ?", models.FamilyMemberIDs(h.db, familyID), true).Order("id ASC").Find(&achievements).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to get achievements"))
+		return
+	}
+
+	lockedResult := []gin.H{}
+	for i := range achievements {
+		if unlockedIDs[achievements[i].ID] {
+			continue
+		}
+		progress, err := models.AchievementProgress(h.db, uint(childID), &achievements[i])
+		if err != nil {
+			continue
+		}
+		result := achievementResponse(achievements[i])
+		result["progress"] = progress
+		lockedResult = append(lockedResult, result)
+	}
+
+	// 等级信息
+	var userPoints models.UserPoints
+	h.db.Where("user_id = ?", childID).First(&userPoints)
+	levels, err := models.LoadLevels(h.db, familyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to get levels"))
+		return
+	}
+	level := models.LevelForPoints(levels, userPoints.TotalPoints)
+	levelResult := gin.H{
+		"level":        level.Level,
+		"name":         level.Name,
+		"total_points": userPoints.TotalPoints,
+	}
+	for _, next := range levels {
+		if next.MinPoints > userPoints.TotalPoints {
+			levelResult["next_level"] = next.Level
+			levelResult["next_level_points"] = next.MinPoints
+			break
+		}
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
+		"level":    levelResult,
+		"unlocked": unlockedResult,
+		"locked":   lockedResult,
+	}))
+}
+
+// GetAchievements 获取家庭配置的成就规则
+func (h *AchievementHandler) GetAchievements(c *gin.Context) {
+	familyID := c.GetUint("family_id")
+
+	if err := models.SeedAchievements(h.db, familyID); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to seed achievements"))
+		return
+	}
+
+	var achievements []models.Achievement
+	if err := h.db.Where("created_by IN (?)", models.FamilyMemberIDs(h.db, familyID)).Order("id ASC").Find(&achievements).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to get achievements"))
+		return
+	}
+
+	result := []gin.H{}
+	for _, achievement := range achievements {
+		result = append(result, achievementResponse(achievement))
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(result))
+}
+
+// CreateAchievement 创建成就规则
+func (h *AchievementHandler) CreateAchievement(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req CreateAchievementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	if !models.AchievementRuleTypes[req.RuleType] {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid rule type"))
+		return
+	}
+
+	achievement := models.Achievement{
+		Name:        req.Name,
+		Description: req.Description,
+		Icon:        req.Icon,
+		RuleType:    req.RuleType,
+		Threshold:   req.Threshold,
+		IsActive:    true,
+		CreatedBy:   userID.(uint),
+	}
+	if req.RuleType == models.AchievementRuleCategoryCount {
+		achievement.Category = models.NormalizeBehaviorCategory(req.Category)
+	}
+
+	if err := h.db.Create(&achievement).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to create achievement"))
+		return
+	}
+	setAuditChange(c, "achievement", achievement.ID, nil, achievement)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(achievementResponse(achievement)))
+}
+
+// UpdateAchievement 更新成就规则，已解锁的记录不受影响
+func (h *AchievementHandler) UpdateAchievement(c *gin.Context) {
+	familyID := c.GetUint("family_id")
+
+	achievementID, err := strconv.ParseUint(c.Param("achievement_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid achievement ID"))
+		return
+	}
+
+	var req UpdateAchievementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	var achievement models.Achievement
+	if err := h.db.Where("id = ? AND created_by IN (?)", achievementID, models.FamilyMemberIDs(h.db, familyID)).First(&achievement).Error; err != nil {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "Achievement not found"))
+		return
+	}
+
+	before := achievement
+
+	updates := make(map[string]interface{})
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Description != "" {
+		updates["description"] = req.Description
+	}
+	if req.Icon != "" {
+		updates["icon"] = req.Icon
+	}
+	if req.Threshold != nil {
+		updates["threshold"] = *req.Threshold
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+
+	if len(updates) > 0 {
+		if err := h.db.Model(&achievement).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to update achievement"))
+			return
+		}
+	}
+	setAuditChange(c, "achievement", achievement.ID, before, achievement)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(achievementResponse(achievement)))
+}
+
+// DeleteAchievement 停用成就规则（已解锁的记录仍然保留）
+func (h *AchievementHandler) DeleteAchievement(c *gin.Context) {
+	familyID := c.GetUint("family_id")
+
+	achievementID, err := strconv.ParseUint(c.Param("achievement_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid achievement ID"))
+		return
+	}
+
+	var achievement models.Achievement
+	if err := h.db.Where("id = ? AND created_by IN (?)", achievementID, models.FamilyMemberIDs(h.db, familyID)).First(&achievement).Error; err != nil {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "Achievement not found"))
+		return
+	}
+	before := achievement
+
+	if err := h.db.Model(&achievement).Update("is_active", false).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to delete achievement"))
+		return
+	}
+	setAuditChange(c, "achievement", achievement.ID, before, achievement)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{"message": "Achievement deactivated successfully"}))
+}
+
+// levelsAuditFields 等级表在审计日志中记为“等级 名称”到门槛的对应关系
+func levelsAuditFields(levels []models.Level) gin.H {
+	fields := gin.H{}
+	for _, level := range levels {
+		fields[fmt.Sprintf("%d %s", level.Level, level.Name)] = level.MinPoints
+	}
+	return fields
+}
+
+// GetLevels 获取家庭配置的等级表
+func (h *AchievementHandler) GetLevels(c *gin.Context) {
+	levels, err := models.LoadLevels(h.db, c.GetUint("family_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to get levels"))
+		return
+	}
+
+	result := []gin.H{}
+	for _, level := range levels {
+		result = append(result, gin.H{
+			"level":      level.Level,
+			"name":       level.Name,
+			"min_points": level.MinPoints,
+		})
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(result))
+}
+
+// UpdateLevels 整体替换家庭的等级表，等级按门槛从低到高依次编号
+func (h *AchievementHandler) UpdateLevels(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	parentID := userID.(uint)
+	familyID := c.GetUint("family_id")
+
+	var req UpdateLevelsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	sort.SliceStable(req.Levels, func(i, j int) bool {
+		return req.Levels[i].MinPoints < req.Levels[j].MinPoints
+	})
+	if req.Levels[0].MinPoints != 0 {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "The first level must start at 0 points"))
+		return
+	}
+
+	levels := make([]models.Level, len(req.Levels))
+	for i, item := range req.Levels {
+		if i > 0 && item.MinPoints == req.Levels[i-1].MinPoints {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Level thresholds must be distinct"))
+			return
+		}
+		levels[i] = models.Level{
+			Level:     i + 1,
+			Name:      item.Name,
+			MinPoints: item.MinPoints,
+			CreatedBy: parentID,
+		}
+	}
+
+	before, err := models.LoadLevels(h.db, familyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to update levels"))
+		return
+	}
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("created_by IN (?)", models.FamilyMemberIDs(tx, familyID)).Delete(&models.Level{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&levels).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to update levels"))
+		return
+	}
+	setAuditChange(c, "levels", familyID, levelsAuditFields(before), levelsAuditFields(levels))
+
+	result := []gin.H{}
+	for _, level := range levels {
+		result = append(result, gin.H{
+			"level":      level.Level,
+			"name":       level.Name,
+			"min_points": level.MinPoints,
+		})
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(result))
+}
@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"child-behavior-app/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentActor 从上下文获取当前用户及其家庭，需在 AuthMiddleware 和 FamilyMiddleware 之后使用
+func currentActor(c *gin.Context) service.Actor {
+	userID, _ := c.Get("user_id")
+	id, _ := userID.(uint)
+	return service.Actor{
+		UserID:   id,
+		Role:     c.GetString("user_role"),
+		FamilyID: c.GetUint("family_id"),
+	}
+}
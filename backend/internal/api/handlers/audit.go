@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AuditHandler struct {
+	db *gorm.DB
+}
+
+func NewAuditHandler(db *gorm.DB) *AuditHandler {
+	return &AuditHandler{db: db}
+}
+
+// setAuditChange 记录本次请求修改的对象及修改前后的内容，由审计中间件写入日志
+// before 为 nil 表示创建，after 为 nil 表示删除
+func setAuditChange(c *gin.Context, targetType string, targetID uint, before, after interface{}) {
+	c.Set(models.AuditChangeKey, models.AuditChange{
+		TargetType: targetType,
+		TargetID:   targetID,
+		Before:     before,
+		After:      after,
+	})
+}
+
+// setAuditUser 记录未登录接口涉及的账户，使登录、找回密码等事件出现在该账户所在家庭的审计日志中
+func setAuditUser(c *gin.Context, user *models.User) {
+	c.Set(models.AuditUserKey, user)
+}
+
+// accountSecurityAudit 账户安全设置在审计日志中记录的内容，不包括密码和密钥本身
+// token_version 递增表示其他设备上的会话已被撤销
+func accountSecurityAudit(user *models.User) gin.H {
+	return gin.H{
+		"token_version": user.TokenVersion,
+		"totp_enabled":  user.TOTPEnabled,
+		"totp_pending":  !user.TOTPEnabled && user.TOTPSecret != nil,
+	}
+}
+
+// setAccountSecurityChange 重新读取账户，记录修改前后的安全设置，before 应为修改前的副本
+func setAccountSecurityChange(c *gin.Context, db *gorm.DB, before models.User) {
+	var after models.User
+	if err := db.First(&after, before.ID).Error; err != nil {
+		return
+	}
+	setAuditChange(c, "user", before.ID, accountSecurityAudit(&before), accountSecurityAudit(&after))
+}
+
+// auditJSON 审计日志中保存的 JSON 内容原样返回，为空时返回 null
+func auditJSON(value string) json.RawMessage {
+	if value == "" {
+		return nil
+	}
+	return json.RawMessage(value)
+}
+
+// GetAuditEvents 获取当前家庭的审计日志
+func (h *AuditHandler) GetAuditEvents(c *gin.Context) {
+	pageStr := c.DefaultQuery("page", "1")
+	limitStr := c.DefaultQuery("limit", "20")
+
+	page, _ := strconv.Atoi(pageStr)
+	limit, _ := strconv.Atoi(limitStr)
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	query := h.db.Model(&models.AuditEvent{}).Where("family_id = ?", c.GetUint("family_id"))
+	if actorID := c.Query("actor_id"); actorID != "" {
+		query = query.Where("actor_id = ?", actorID)
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if targetType := c.Query("target_type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if targetID := c.Query("target_id"); targetID != "" {
+		query = query.Where("target_id = ?", targetID)
+	}
+
+	// 获取总数
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to count audit events"))
+		return
+	}
+
+	var events []models.AuditEvent
+	if err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to get audit events"))
+		return
+	}
+
+	result := []gin.H{}
+	for _, event := range events {
+		result = append(result, gin.H{
+			"id":          event.ID,
+			"actor_id":    event.ActorID,
+			"actor_role":  event.ActorRole,
+			"action":      event.Action,
+			"target_type": event.TargetType,
+			"target_id":   event.TargetID,
+			"status_code": event.StatusCode,
+			"ip":          event.IP,
+			"user_agent":  event.UserAgent,
+			"before":      auditJSON(event.Before),
+			"after":       auditJSON(event.After),
+			"created_at":  event.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
+		"events": result,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	}))
+}
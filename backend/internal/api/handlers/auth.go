@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
 
 	"child-behavior-app/internal/models"
 	"child-behavior-app/internal/utils"
@@ -73,7 +74,15 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		Role:     "parent", // 注册时强制为家长角色
 	}
 
-	if err := h.db.Create(&user).Error; err != nil {
+	// 注册时同时创建以该家长为所有者的家庭
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		_, err := models.CreateFamily(tx, &user)
+		return err
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to create user"))
 		return
 	}
@@ -86,24 +95,23 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 	h.db.Create(&userPoints)
 
-	// 生成JWT令牌
-	token, err := utils.GenerateToken(user.ID, user.Role)
+	// 创建会话并签发令牌
+	result, err := issueTokens(h.db, c, &user, "")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to generate token"))
 		return
 	}
 
-	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
-		"user_id": user.ID,
-		"token":   token,
-		"user": gin.H{
-			"id":       user.ID,
-			"phone":    utils.GetStringValue(user.Phone),
-			"nickname": user.Nickname,
-			"role":     user.Role,
-			"avatar":   user.Avatar,
-		},
-	}))
+	result["user_id"] = user.ID
+	result["user"] = gin.H{
+		"id":        user.ID,
+		"phone":     utils.GetStringValue(user.Phone),
+		"nickname":  user.Nickname,
+		"role":      user.Role,
+		"avatar":    user.Avatar,
+		"family_id": user.FamilyID,
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(result))
 }
 
 // Login 用户登录
@@ -114,21 +122,48 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// 查找用户
+	// 按手机号计数失败次数，不存在的手机号同样计数，避免暴露账号是否存在
+	if !checkLockout(h.db, c, models.LockoutScopeLogin, req.Phone) {
+		return
+	}
+
+	// 查找用户并验证密码
 	var user models.User
-	if err := h.db.Where("phone = ?", req.Phone).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(401, "Invalid phone or password"))
+	err := h.db.Where("phone = ?", req.Phone).First(&user).Error
+	if err == nil {
+		setAuditUser(c, &user)
+	}
+	if err != nil || user.Password == nil || !utils.CheckPasswordHash(req.Password, *user.Password) {
+		respondLoginFailure(h.db, c, models.LockoutScopeLogin, req.Phone, http.StatusUnauthorized, "Invalid phone or password")
+		return
+	}
+	if err := models.ClearLoginFailures(h.db, models.LockoutScopeLogin, req.Phone); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to reset failed attempts"))
 		return
 	}
 
-	// 验证密码
-	if user.Password == nil || !utils.CheckPasswordHash(req.Password, *user.Password) {
-		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(401, "Invalid phone or password"))
+	// 开启两步验证的账户只返回两步验证令牌，提交验证码后才签发访问令牌
+	if user.TOTPEnabled {
+		preAuthToken, err := utils.GeneratePreAuthToken(user.ID, user.Role)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to generate token"))
+			return
+		}
+		c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
+			"two_factor_required": true,
+			"pre_auth_token":      preAuthToken,
+			"expires_in":          int(utils.PreAuthTokenTTL.Seconds()),
+		}))
 		return
 	}
 
-	// 生成JWT令牌
-	token, err := utils.GenerateToken(user.ID, user.Role)
+	respondLogin(h.db, c, &user)
+}
+
+// respondLogin 创建会话、签发令牌并返回登录结果
+func respondLogin(db *gorm.DB, c *gin.Context, user *models.User) {
+	// 创建会话并签发令牌
+	result, err := issueTokens(db, c, user, "")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to generate token"))
 		return
@@ -136,22 +171,21 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	// 获取用户积分信息
 	var userPoints models.UserPoints
-	h.db.Where("user_id = ?", user.ID).First(&userPoints)
-
-	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
-		"user_id": user.ID,
-		"token":   token,
-		"user": gin.H{
-			"id":               user.ID,
-			"phone":            utils.GetStringValue(user.Phone),
-			"nickname":         user.Nickname,
-			"role":             user.Role,
-			"avatar":           user.Avatar,
-			"parent_id":        user.ParentID,
-			"total_points":     userPoints.TotalPoints,
-			"available_points": userPoints.AvailablePoints,
-		},
-	}))
+	db.Where("user_id = ?", user.ID).First(&userPoints)
+
+	result["user_id"] = user.ID
+	result["user"] = gin.H{
+		"id":               user.ID,
+		"phone":            utils.GetStringValue(user.Phone),
+		"nickname":         user.Nickname,
+		"role":             user.Role,
+		"avatar":           user.Avatar,
+		"parent_id":        user.ParentID,
+		"family_id":        user.FamilyID,
+		"total_points":     userPoints.TotalPoints,
+		"available_points": userPoints.AvailablePoints,
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(result))
 }
 
 // VerifyToken 验证JWT令牌
@@ -169,7 +203,7 @@ func (h *AuthHandler) VerifyToken(c *gin.Context) {
 	}))
 }
 
-// VerifyPassword 验证用户密码
+// VerifyPassword 验证用户密码，成功后返回家长模式令牌
 func (h *AuthHandler) VerifyPassword(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -190,14 +224,37 @@ func (h *AuthHandler) VerifyPassword(c *gin.Context) {
 		return
 	}
 
+	// 儿童拿到家长的设备时可能反复猜测密码，按用户计数失败次数
+	subject := strconv.FormatUint(uint64(user.ID), 10)
+	if !checkLockout(h.db, c, models.LockoutScopeVerifyPassword, subject) {
+		return
+	}
+
 	// 验证密码
 	if user.Password == nil || !utils.CheckPasswordHash(req.Password, *user.Password) {
-		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(401, "Invalid password"))
+		respondLoginFailure(h.db, c, models.LockoutScopeVerifyPassword, subject, http.StatusUnauthorized, "Invalid password")
+		return
+	}
+	if err := models.ClearLoginFailures(h.db, models.LockoutScopeVerifyPassword, subject); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to reset failed attempts"))
+		return
+	}
+
+	respondElevated(c, user.ID)
+}
+
+// respondElevated 家长重新验证身份后签发绑定当前会话的家长模式令牌
+func respondElevated(c *gin.Context, userID uint) {
+	elevatedToken, err := utils.GenerateElevatedToken(userID, c.GetUint("session_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to generate token"))
 		return
 	}
 
 	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
-		"valid": true,
+		"valid":          true,
+		"elevated_token": elevatedToken,
+		"expires_in":     int(utils.ElevatedTokenTTL.Seconds()),
 	}))
 }
 
@@ -217,10 +274,21 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "User not found"))
 		return
 	}
+	before := user
+
+	// 旧密码与家长模式验证共用失败计数，不能绕过验证密码的锁定反复猜测
+	subject := strconv.FormatUint(uint64(user.ID), 10)
+	if !checkLockout(h.db, c, models.LockoutScopeVerifyPassword, subject) {
+		return
+	}
 
 	// 验证旧密码
 	if user.Password == nil || !utils.CheckPasswordHash(req.OldPassword, *user.Password) {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "旧密码不正确"))
+		respondLoginFailure(h.db, c, models.LockoutScopeVerifyPassword, subject, http.StatusBadRequest, "旧密码不正确")
+		return
+	}
+	if err := models.ClearLoginFailures(h.db, models.LockoutScopeVerifyPassword, subject); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to reset failed attempts"))
 		return
 	}
 
@@ -237,7 +305,24 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
-		"message": "密码修改成功",
-	}))
+	// 撤销所有会话，其他设备需要重新登录
+	if err := models.RevokeUserSessions(h.db, user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to revoke sessions"))
+		return
+	}
+
+	// 为当前设备签发新令牌
+	if err := h.db.First(&user, user.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to reload user"))
+		return
+	}
+	setAuditChange(c, "user", user.ID, accountSecurityAudit(&before), accountSecurityAudit(&user))
+	result, err := issueTokens(h.db, c, &user, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to generate token"))
+		return
+	}
+
+	result["message"] = "密码修改成功"
+	c.JSON(http.StatusOK, utils.SuccessResponse(result))
 }
@@ -1,132 +1,219 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/repository"
+	"child-behavior-app/internal/service"
 	"child-behavior-app/internal/utils"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
 type BehaviorHandler struct {
-	db *gorm.DB
+	behaviors *service.BehaviorService
 }
 
-func NewBehaviorHandler(db *gorm.DB) *BehaviorHandler {
-	return &BehaviorHandler{db: db}
+func NewBehaviorHandler(behaviors *service.BehaviorService) *BehaviorHandler {
+	return &BehaviorHandler{behaviors: behaviors}
 }
 
 // RecordBehaviorRequest 记录行为请求
+// 使用行为模板时分类、描述和积分可以省略，默认取模板的值
 type RecordBehaviorRequest struct {
 	ChildID      uint   `json:"child_id" binding:"required"`
-	BehaviorType string `json:"behavior_type" binding:"required"` // 前端发送的分类，如learning, life等
-	BehaviorDesc string `json:"behavior_desc" binding:"required"`
-	ScoreChange  int    `json:"score_change" binding:"required"`
+	TemplateID   *uint  `json:"template_id"`
+	BehaviorType string `json:"behavior_type"` // 前端发送的分类，如learning, life等
+	BehaviorDesc string `json:"behavior_desc"`
+	ScoreChange  int    `json:"score_change"`
 	ImageURL     string `json:"image_url"`
 }
 
+// behaviorInputErrorResponse 记录或申报行为时内容校验失败的响应，返回是否已处理
+func behaviorInputErrorResponse(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrChildNotFound):
+		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Child not found or permission denied"))
+	case errors.Is(err, service.ErrTemplateNotFound):
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Behavior template not found or inactive"))
+	case errors.Is(err, service.ErrBehaviorIncomplete):
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, service.ErrBehaviorIncomplete.Error()))
+	default:
+		return false
+	}
+	return true
+}
+
 // RecordBehavior 记录行为
 func (h *BehaviorHandler) RecordBehavior(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	userRole, _ := c.Get("user_role")
+	var req RecordBehaviorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
 
-	// 只有家长可以记录行为
-	if userRole != "parent" {
-		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Only parents can record behaviors"))
+	// 分类来自前端，极性由积分正负确定，不良行为扣除积分（ScoreChange应该是负数）
+	record, err := h.behaviors.Record(currentActor(c), service.BehaviorInput{
+		ChildID:     req.ChildID,
+		TemplateID:  req.TemplateID,
+		Category:    req.BehaviorType,
+		Description: req.BehaviorDesc,
+		ScoreChange: req.ScoreChange,
+		ImageURL:    req.ImageURL,
+	})
+	if behaviorInputErrorResponse(c, err) {
 		return
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to record behavior"))
+		return
+	}
+	setAuditChange(c, "behavior", record.ID, nil, record)
 
-	var req RecordBehaviorRequest
+	c.JSON(http.StatusOK, utils.SuccessResponse(behaviorResponse(*record)))
+}
+
+// behaviorResponse 构建行为记录返回数据
+func behaviorResponse(record models.BehaviorRecord) gin.H {
+	return gin.H{
+		"id":            record.ID,
+		"child_id":      record.ChildID,
+		"recorder_id":   record.RecorderID,
+		"behavior_type": record.BehaviorType,
+		"category":      record.Category,
+		"behavior_desc": record.BehaviorDesc,
+		"score_change":  record.ScoreChange,
+		"image_url":     record.ImageURL,
+		"template_id":   record.TemplateID,
+		"status":        record.Status,
+		"review_note":   record.ReviewNote,
+		"reviewed_at":   record.ReviewedAt,
+		"recorded_at":   record.RecordedAt,
+	}
+}
+
+// UpdateBehaviorRequest 更新行为记录请求
+type UpdateBehaviorRequest struct {
+	Category     string `json:"category"`
+	BehaviorDesc string `json:"behavior_desc"`
+	ScoreChange  *int   `json:"score_change"`
+	ImageURL     string `json:"image_url"`
+}
+
+// UpdateBehavior 修改行为记录，积分差额自动写入流水
+func (h *BehaviorHandler) UpdateBehavior(c *gin.Context) {
+	behaviorID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid behavior ID"))
+		return
+	}
+
+	var req UpdateBehaviorRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
 		return
 	}
 
-	parentID := userID.(uint)
-
-	// 验证儿童是否属于当前家长
-	var child models.User
-	if err := h.db.Where("id = ? AND parent_id = ?", req.ChildID, parentID).First(&child).Error; err != nil {
-		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Child not found or permission denied"))
+	before, record, userPoints, err := h.behaviors.Update(currentActor(c), uint(behaviorID), service.BehaviorChanges{
+		Category:    req.Category,
+		Description: req.BehaviorDesc,
+		ScoreChange: req.ScoreChange,
+		ImageURL:    req.ImageURL,
+	})
+	if errors.Is(err, service.ErrZeroScoreChange) {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Score change cannot be zero"))
 		return
 	}
-
-	// 根据积分正负确定行为类型
-	var behaviorType string
-	if req.ScoreChange > 0 {
-		behaviorType = "good"
-	} else {
-		behaviorType = "bad"
+	if errors.Is(err, service.ErrBehaviorNotFound) {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "Behavior not found or permission denied"))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to update behavior"))
+		return
 	}
+	setAuditChange(c, "behavior", record.ID, before, record)
 
-	// 创建行为记录
-	behaviorRecord := models.BehaviorRecord{
-		ChildID:      req.ChildID,
-		RecorderID:   parentID,
-		BehaviorType: behaviorType,
-		BehaviorDesc: req.BehaviorDesc,
-		ScoreChange:  req.ScoreChange,
-		ImageURL:     req.ImageURL,
-		RecordedAt:   time.Now(),
+	result := behaviorResponse(*record)
+	if userPoints != nil {
+		result["available_points"] = userPoints.AvailablePoints
+		result["total_points"] = userPoints.TotalPoints
 	}
 
-	if err := h.db.Create(&behaviorRecord).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to record behavior"))
+	c.JSON(http.StatusOK, utils.SuccessResponse(result))
+}
+
+// DeleteBehavior 删除行为记录并冲销积分
+func (h *BehaviorHandler) DeleteBehavior(c *gin.Context) {
+	behaviorID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid behavior ID"))
 		return
 	}
 
-	// 更新用户积分
-	var userPoints models.UserPoints
-	if err := h.db.Where("user_id = ?", req.ChildID).First(&userPoints).Error; err != nil {
-		// 如果积分记录不存在，创建一个
-		userPoints = models.UserPoints{
-			UserID:          req.ChildID,
-			TotalPoints:     0,
-			AvailablePoints: 0,
-		}
-		h.db.Create(&userPoints)
+	record, userPoints, err := h.behaviors.Delete(currentActor(c), uint(behaviorID))
+	if errors.Is(err, service.ErrBehaviorNotFound) {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "Behavior not found or permission denied"))
+		return
 	}
-
-	// 根据行为类型更新积分
-	if behaviorType == "good" {
-		userPoints.TotalPoints += req.ScoreChange
-		userPoints.AvailablePoints += req.ScoreChange
-	} else {
-		// 不良行为扣除积分（ScoreChange应该是负数）
-		userPoints.TotalPoints += req.ScoreChange // 总积分记录所有变化
-		userPoints.AvailablePoints += req.ScoreChange
-		if userPoints.AvailablePoints < 0 {
-			userPoints.AvailablePoints = 0
-		}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to delete behavior"))
+		return
 	}
+	setAuditChange(c, "behavior", record.ID, record, nil)
 
-	h.db.Save(&userPoints)
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
+		"message":          "Behavior deleted successfully",
+		"available_points": userPoints.AvailablePoints,
+		"total_points":     userPoints.TotalPoints,
+	}))
+}
+
+// UndoLastBehavior 撤销当前家长在撤销窗口内创建的最后一条行为记录
+func (h *BehaviorHandler) UndoLastBehavior(c *gin.Context) {
+	record, userPoints, err := h.behaviors.Undo(currentActor(c))
+	if errors.Is(err, service.ErrNothingToUndo) {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "No behavior to undo"))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to undo behavior"))
+		return
+	}
+	setAuditChange(c, "behavior", record.ID, record, nil)
 
 	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
-		"id":            behaviorRecord.ID,
-		"child_id":      behaviorRecord.ChildID,
-		"recorder_id":   behaviorRecord.RecorderID,
-		"behavior_type": behaviorRecord.BehaviorType,
-		"behavior_desc": behaviorRecord.BehaviorDesc,
-		"score_change":  behaviorRecord.ScoreChange,
-		"image_url":     behaviorRecord.ImageURL,
-		"recorded_at":   behaviorRecord.RecordedAt,
+		"undone_id":        record.ID,
+		"child_id":         record.ChildID,
+		"behavior_desc":    record.BehaviorDesc,
+		"score_change":     record.ScoreChange,
+		"available_points": userPoints.AvailablePoints,
+		"total_points":     userPoints.TotalPoints,
 	}))
 }
 
+// parseOptionalID 解析可选的ID查询参数，未提供时返回 nil
+func parseOptionalID(value string) (*uint, error) {
+	if value == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	result := uint(id)
+	return &result, nil
+}
+
 // GetBehaviors 获取行为记录
+// 家长可以查看家庭中孩子的行为记录，儿童只能查看自己的行为记录
 func (h *BehaviorHandler) GetBehaviors(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	userRole, _ := c.Get("user_role")
-
 	// 获取查询参数
-	childIDParam := c.Query("child_id")
-	behaviorType := c.Query("behavior_type")
 	startDate := c.Query("start_date")
 	endDate := c.Query("end_date")
 	pageStr := c.DefaultQuery("page", "1")
@@ -136,149 +223,59 @@ func (h *BehaviorHandler) GetBehaviors(c *gin.Context) {
 	limit, _ := strconv.Atoi(limitStr)
 	offset := (page - 1) * limit
 
-	// 构建查询条件
-	query := h.db.Model(&models.BehaviorRecord{})
-
-	if userRole == "parent" {
-		// 家长可以查看自己孩子的行为记录
-		parentID := userID.(uint)
-		if childIDParam != "" {
-			childID, err := strconv.ParseUint(childIDParam, 10, 32)
-			if err != nil {
-				c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid child ID"))
-				return
-			}
-			// 验证儿童是否属于当前家长
-			var child models.User
-			if err := h.db.Where("id = ? AND parent_id = ?", childID, parentID).First(&child).Error; err != nil {
-				c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Child not found or permission denied"))
-				return
-			}
-			query = query.Where("user_id = ?", childID)
-		} else {
-			// 查询所有孩子的行为记录
-			var childIDs []uint
-			if err := h.db.Model(&models.User{}).Where("parent_id = ?", parentID).Pluck("id", &childIDs).Error; err != nil {
-				fmt.Printf("Error getting child IDs: %v\n", err)
-				c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to get children list"))
-				return
-			}
-
-			// 如果家长没有儿童，返回空结果
-			if len(childIDs) == 0 {
-				c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
-					"behaviors": []gin.H{},
-					"pagination": gin.H{
-						"page":  page,
-						"limit": limit,
-						"total": 0,
-					},
-				}))
-				return
-			}
-
-			query = query.Where("user_id IN ?", childIDs)
-		}
-	} else {
-		// 儿童只能查看自己的行为记录
-		query = query.Where("user_id = ?", userID)
+	query := service.BehaviorQuery{
+		BehaviorType: c.Query("behavior_type"),
+		Category:     c.Query("category"),
+		// 默认只返回已生效的记录，status=all 返回全部
+		Status: c.DefaultQuery("status", models.BehaviorStatusApproved),
 	}
 
-	// 添加其他过滤条件
-	if behaviorType != "" {
-		query = query.Where("behavior_type = ?", behaviorType)
+	var err error
+	if c.GetString("user_role") == "parent" {
+		if query.ChildID, err = parseOptionalID(c.Query("child_id")); err != nil {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid child ID"))
+			return
+		}
+	}
+	if query.TemplateID, err = parseOptionalID(c.Query("template_id")); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid template ID"))
+		return
 	}
 
 	if startDate != "" {
 		if start, err := time.Parse("2006-01-02", startDate); err == nil {
-			query = query.Where("recorded_at >= ?", start)
+			query.RecordedFrom = &start
 		}
 	}
-
 	if endDate != "" {
 		if end, err := time.Parse("2006-01-02", endDate); err == nil {
-			query = query.Where("recorded_at <= ?", end.Add(24*time.Hour))
+			end = end.Add(24 * time.Hour)
+			query.RecordedTo = &end
 		}
 	}
 
-	// 获取总数
-	var total int64
-	if err := query.Count(&total).Error; err != nil {
-		fmt.Printf("Error counting behavior records: %v\n", err)
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to count behavior records"))
+	list, err := h.behaviors.List(currentActor(c), query, repository.Page{Offset: offset, Limit: limit})
+	if errors.Is(err, service.ErrChildNotFound) {
+		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Child not found or permission denied"))
 		return
 	}
-
-	// 获取行为记录
-	var behaviors []models.BehaviorRecord
-	if err := query.Order("recorded_at DESC").Offset(offset).Limit(limit).Find(&behaviors).Error; err != nil {
+	if err != nil {
 		fmt.Printf("Error getting behavior records: %v\n", err)
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to get behavior records"))
 		return
 	}
 
-	// 如果没有行为记录，直接返回空结果
-	if len(behaviors) == 0 {
-		c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
-			"behaviors": []gin.H{},
-			"pagination": gin.H{
-				"page":  page,
-				"limit": limit,
-				"total": total,
-			},
-		}))
-		return
-	}
-
-	// 批量获取用户信息以提高性能
-	var userIDs []uint
-	var recorderIDs []uint
-	for _, behavior := range behaviors {
-		userIDs = append(userIDs, behavior.ChildID)
-		recorderIDs = append(recorderIDs, behavior.RecorderID)
-	}
-
-	// 去重
-	userIDMap := make(map[uint]bool)
-	var allUserIDs []uint
-	for _, id := range userIDs {
-		if !userIDMap[id] {
-			userIDMap[id] = true
-			allUserIDs = append(allUserIDs, id)
-		}
-	}
-	for _, id := range recorderIDs {
-		if !userIDMap[id] {
-			userIDMap[id] = true
-			allUserIDs = append(allUserIDs, id)
-		}
-	}
-
-	// 批量查询用户信息
-	var users []models.User
-	if err := h.db.Where("id IN ?", allUserIDs).Find(&users).Error; err != nil {
-		fmt.Printf("Error getting user information: %v\n", err)
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to get user information"))
-		return
-	}
-
-	// 创建用户信息映射
-	userMap := make(map[uint]models.User)
-	for _, user := range users {
-		userMap[user.ID] = user
-	}
-
 	// 构建结果
-	var result []gin.H
-	for _, behavior := range behaviors {
+	result := []gin.H{}
+	for _, behavior := range list.Records {
 		childName := "未知用户"
 		recorderName := "未知用户"
 
-		if user, exists := userMap[behavior.ChildID]; exists {
-			childName = user.Nickname
+		if name, exists := list.Names[behavior.ChildID]; exists {
+			childName = name
 		}
-		if recorder, exists := userMap[behavior.RecorderID]; exists {
-			recorderName = recorder.Nickname
+		if name, exists := list.Names[behavior.RecorderID]; exists {
+			recorderName = name
 		}
 
 		result = append(result, gin.H{
@@ -288,9 +285,13 @@ func (h *BehaviorHandler) GetBehaviors(c *gin.Context) {
 			"recorder_id":   behavior.RecorderID,
 			"recorder_name": recorderName,
 			"behavior_type": behavior.BehaviorType,
+			"category":      behavior.Category,
 			"behavior_desc": behavior.BehaviorDesc,
 			"score_change":  behavior.ScoreChange,
 			"image_url":     behavior.ImageURL,
+			"template_id":   behavior.TemplateID,
+			"status":        behavior.Status,
+			"review_note":   behavior.ReviewNote,
 			"recorded_at":   behavior.RecordedAt,
 		})
 	}
@@ -300,98 +301,34 @@ func (h *BehaviorHandler) GetBehaviors(c *gin.Context) {
 		"pagination": gin.H{
 			"page":  page,
 			"limit": limit,
-			"total": total,
+			"total": list.Total,
 		},
 	}))
 }
 
 // GetBehaviorTrend 获取行为趋势统计
 func (h *BehaviorHandler) GetBehaviorTrend(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	userRole, _ := c.Get("user_role")
-
-	childIDParam := c.Query("child_id")
 	days := c.DefaultQuery("days", "7")
-
 	daysInt, _ := strconv.Atoi(days)
-	startDate := time.Now().AddDate(0, 0, -daysInt)
-
-	// 构建查询条件
-	query := h.db.Model(&models.BehaviorRecord{}).Where("recorded_at >= ?", startDate)
-
-	if userRole == "parent" {
-		parentID := userID.(uint)
-		if childIDParam != "" {
-			childID, err := strconv.ParseUint(childIDParam, 10, 32)
-			if err != nil {
-				c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid child ID"))
-				return
-			}
-			// 验证儿童是否属于当前家长
-			var child models.User
-			if err := h.db.Where("id = ? AND parent_id = ?", childID, parentID).First(&child).Error; err != nil {
-				c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Child not found or permission denied"))
-				return
-			}
-			query = query.Where("user_id = ?", childID)
-		} else {
-			// 查询所有孩子的行为记录
-			var childIDs []uint
-			if err := h.db.Model(&models.User{}).Where("parent_id = ?", parentID).Pluck("id", &childIDs).Error; err != nil {
-				fmt.Printf("Error getting child IDs for trend: %v\n", err)
-				c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to get children list"))
-				return
-			}
-
-			// 如果家长没有儿童，返回空趋势数据
-			if len(childIDs) == 0 {
-				var emptyTrendData []gin.H
-				for i := 0; i < daysInt; i++ {
-					date := time.Now().AddDate(0, 0, -i)
-					dateStr := date.Format("2006-01-02")
-					emptyTrendData = append([]gin.H{{
-						"date":       dateStr,
-						"good_count": 0,
-						"bad_count":  0,
-					}}, emptyTrendData...)
-				}
-				c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
-					"trend_data": emptyTrendData,
-					"period":     fmt.Sprintf("%d days", daysInt),
-				}))
-				return
-			}
-
-			query = query.Where("user_id IN ?", childIDs)
+
+	var childID *uint
+	if c.GetString("user_role") == "parent" {
+		var err error
+		if childID, err = parseOptionalID(c.Query("child_id")); err != nil {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid child ID"))
+			return
 		}
-	} else {
-		// 儿童只能查看自己的行为记录
-		query = query.Where("user_id = ?", userID)
 	}
 
-	// 统计好行为和坏行为的数量
-	type TrendData struct {
-		Date      string `json:"date"`
-		GoodCount int    `json:"good_count"`
-		BadCount  int    `json:"bad_count"`
+	trendData, err := h.behaviors.Trend(currentActor(c), childID, daysInt)
+	if errors.Is(err, service.ErrChildNotFound) {
+		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Child not found or permission denied"))
+		return
 	}
-
-	var trendData []TrendData
-	for i := 0; i < daysInt; i++ {
-		date := time.Now().AddDate(0, 0, -i)
-		dateStr := date.Format("2006-01-02")
-
-		var goodCount int64
-		var badCount int64
-
-		query.Where("DATE(recorded_at) = ? AND behavior_type = ?", dateStr, "good").Count(&goodCount)
-		query.Where("DATE(recorded_at) = ? AND behavior_type = ?", dateStr, "bad").Count(&badCount)
-
-		trendData = append([]TrendData{{
-			Date:      dateStr,
-			GoodCount: int(goodCount),
-			BadCount:  int(badCount),
-		}}, trendData...)
+	if err != nil {
+		fmt.Printf("Error getting behavior trend: %v\n", err)
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to get behavior trend"))
+		return
 	}
 
 	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
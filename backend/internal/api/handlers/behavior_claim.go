@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/service"
+	"child-behavior-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SubmitClaimRequest 儿童自主申报行为请求
+type SubmitClaimRequest struct {
+	TemplateID   *uint  `json:"template_id"`
+	BehaviorType string `json:"behavior_type"`
+	BehaviorDesc string `json:"behavior_desc"`
+	ScoreChange  int    `json:"score_change"`
+	ImageURL     string `json:"image_url"`
+}
+
+// ReviewClaimRequest 家长审核申报请求
+type ReviewClaimRequest struct {
+	Comment string `json:"comment" binding:"max=255"`
+}
+
+// SubmitClaim 儿童申报完成的行为，等待家长审核，审核通过前不影响积分
+func (h *BehaviorHandler) SubmitClaim(c *gin.Context) {
+	var claim SubmitClaimRequest
+	if err := c.ShouldBindJSON(&claim); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	record, err := h.behaviors.Submit(currentActor(c), service.BehaviorInput{
+		TemplateID:  claim.TemplateID,
+		Category:    claim.BehaviorType,
+		Description: claim.BehaviorDesc,
+		ScoreChange: claim.ScoreChange,
+		ImageURL:    claim.ImageURL,
+	})
+	if errors.Is(err, service.ErrNotInFamily) {
+		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Child account is not linked to a family"))
+		return
+	}
+	if behaviorInputErrorResponse(c, err) {
+		return
+	}
+	// 申报只能加分，扣分行为由家长记录
+	if errors.Is(err, service.ErrClaimDeducts) {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Claims can only add points"))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to submit claim"))
+		return
+	}
+	setAuditChange(c, "behavior", record.ID, nil, record)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(behaviorResponse(*record)))
+}
+
+// GetPendingClaims 获取待家长审核的申报列表
+func (h *BehaviorHandler) GetPendingClaims(c *gin.Context) {
+	childID, err := parseOptionalID(c.Query("child_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid child ID"))
+		return
+	}
+
+	list, err := h.behaviors.PendingClaims(c.GetUint("family_id"), childID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to get pending claims"))
+		return
+	}
+
+	result := []gin.H{}
+	for _, claim := range list.Records {
+		item := behaviorResponse(claim)
+		item["child_name"] = list.Names[claim.ChildID]
+		result = append(result, item)
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
+		"claims": result,
+		"total":  len(result),
+	}))
+}
+
+// ApproveClaim 审核通过申报并发放积分
+func (h *BehaviorHandler) ApproveClaim(c *gin.Context) {
+	h.reviewClaim(c, models.BehaviorStatusApproved)
+}
+
+// RejectClaim 拒绝申报，不影响积分
+func (h *BehaviorHandler) RejectClaim(c *gin.Context) {
+	h.reviewClaim(c, models.BehaviorStatusRejected)
+}
+
+// reviewClaim 审核申报，status 为审核后的状态
+func (h *BehaviorHandler) reviewClaim(c *gin.Context, status string) {
+	behaviorID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid behavior ID"))
+		return
+	}
+
+	var req ReviewClaimRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	before, record, userPoints, err := h.behaviors.Review(currentActor(c), uint(behaviorID), status, req.Comment)
+	if errors.Is(err, service.ErrBehaviorNotFound) {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "Claim not found or permission denied"))
+		return
+	}
+	if errors.Is(err, service.ErrClaimAlreadyReviewed) {
+		c.JSON(http.StatusConflict, utils.ErrorResponse(409, "Claim has already been reviewed"))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to review claim"))
+		return
+	}
+	setAuditChange(c, "behavior", record.ID, before, record)
+
+	result := behaviorResponse(*record)
+	if userPoints != nil {
+		result["available_points"] = userPoints.AvailablePoints
+		result["total_points"] = userPoints.TotalPoints
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(result))
+}
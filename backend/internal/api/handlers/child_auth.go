@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// pairingQRPrefix 配对二维码内容前缀，客户端扫码后取出配对码调用儿童登录
+const pairingQRPrefix = "child-behavior://pair?code="
+
+// pinPattern 儿童PIN为4-6位数字
+var pinPattern = regexp.MustCompile(`^[0-9]{4,6}$`)
+
+// SetChildPinRequest 设置儿童PIN请求
+type SetChildPinRequest struct {
+	Pin string `json:"pin" binding:"required"`
+}
+
+// ChildLoginRequest 儿童登录请求，使用配对码或 child_id + PIN 二选一
+type ChildLoginRequest struct {
+	PairingCode string `json:"pairing_code"`
+	ChildID     uint   `json:"child_id"`
+	Pin         string `json:"pin"`
+	DeviceID    string `json:"device_id" binding:"required,max=64"`
+	DeviceName  string `json:"device_name" binding:"max=100"`
+}
+
+// loadOwnChild 获取属于当前家庭的儿童
+func loadOwnChild(db *gorm.DB, c *gin.Context) (*models.User, bool) {
+	childID, err := strconv.ParseUint(c.Param("child_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid child ID"))
+		return nil, false
+	}
+
+	child, err := models.FindFamilyChild(db, c.GetUint("family_id"), uint(childID))
+	if err != nil {
+		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Child not found or permission denied"))
+		return nil, false
+	}
+	return child, true
+}
+
+// CreatePairingCode 家长为儿童生成一次性配对码（可展示为二维码）
+func (h *AuthHandler) CreatePairingCode(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	child, ok := loadOwnChild(h.db, c)
+	if !ok {
+		return
+	}
+
+	pairing, err := models.CreatePairingCode(h.db, child.ID, userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to create pairing code"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
+		"child_id":   child.ID,
+		"code":       pairing.Code,
+		"qr_content": pairingQRPrefix + pairing.Code,
+		"expires_at": pairing.ExpiresAt,
+	}))
+}
+
+// SetChildPin 家长设置或修改儿童的登录PIN
+func (h *AuthHandler) SetChildPin(c *gin.Context) {
+	child, ok := loadOwnChild(h.db, c)
+	if !ok {
+		return
+	}
+
+	var req SetChildPinRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+	if !pinPattern.MatchString(req.Pin) {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "PIN must be 4-6 digits"))
+		return
+	}
+
+	hashedPin, err := utils.HashPassword(req.Pin)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to hash PIN"))
+		return
+	}
+
+	if err := h.db.Model(child).Update("pin", hashedPin).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to update PIN"))
+		return
+	}
+
+	// 家长重设PIN后解除PIN登录的锁定
+	if err := models.ClearLoginFailures(h.db, models.LockoutScopeChildPin, strconv.FormatUint(uint64(child.ID), 10)); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to reset failed attempts"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{"message": "PIN updated successfully"}))
+}
+
+// GetChildDevices 获取儿童已配对的设备
+func (h *AuthHandler) GetChildDevices(c *gin.Context) {
+	child, ok := loadOwnChild(h.db, c)
+	if !ok {
+		return
+	}
+
+	var devices []models.ChildDevice
+	if err := h.db.Where("child_id = ?", child.ID).Order("last_login_at DESC").Find(&devices).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to get devices"))
+		return
+	}
+
+	result := []gin.H{}
+	for _, device := range devices {
+		result = append(result, gin.H{
+			"device_id":     device.DeviceID,
+			"device_name":   device.DeviceName,
+			"last_login_at": device.LastLoginAt,
+			"created_at":    device.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(result))
+}
+
+// DeleteChildDevice 解除儿童设备配对，该设备上的会话立即失效，之后也不能再用PIN登录
+func (h *AuthHandler) DeleteChildDevice(c *gin.Context) {
+	child, ok := loadOwnChild(h.db, c)
+	if !ok {
+		return
+	}
+
+	deviceID := c.Param("device_id")
+	result := h.db.Where("child_id = ? AND device_id = ?", child.ID, deviceID).Delete(&models.ChildDevice{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to delete device"))
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "Device not found"))
+		return
+	}
+
+	if err := models.RevokeDeviceSessions(h.db, child.ID, deviceID); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to revoke device sessions"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{"message": "Device unpaired successfully"}))
+}
+
+// ChildLogin 儿童登录
+// 使用家长生成的配对码在新设备上登录并完成配对；已配对的家庭设备可以使用 child_id + PIN 登录
+// 返回的令牌绑定设备，请求时需要携带 X-Device-ID 请求头
+func (h *AuthHandler) ChildLogin(c *gin.Context) {
+	var req ChildLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	req.PairingCode = strings.ToUpper(strings.TrimSpace(req.PairingCode))
+	if req.PairingCode == "" && (req.ChildID == 0 || req.Pin == "") {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Pairing code or child ID and PIN is required"))
+		return
+	}
+
+	// PIN只有4-6位，按儿童计数失败次数防止穷举
+	pinSubject := strconv.FormatUint(uint64(req.ChildID), 10)
+	if req.PairingCode == "" && !checkLockout(h.db, c, models.LockoutScopeChildPin, pinSubject) {
+		return
+	}
+
+	var child models.User
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if req.PairingCode != "" {
+			pairing, err := models.RedeemPairingCode(tx, req.PairingCode)
+			if err != nil {
+				return err
+			}
+			if err := tx.First(&child, pairing.ChildID).Error; err != nil {
+				return err
+			}
+			// 配对码已确定儿童身份，后续步骤失败时审计日志同样记录到该儿童
+			setAuditUser(c, &child)
+		} else {
+			if err := tx.Where("id = ? AND role = ?", req.ChildID, "child").First(&child).Error; err != nil {
+				return errInvalidChildCredentials
+			}
+			if child.Pin == nil || child.FamilyID == nil || !utils.CheckPasswordHash(req.Pin, *child.Pin) {
+				return errInvalidChildCredentials
+			}
+			paired, err := models.IsFamilyDevice(tx, *child.FamilyID, req.DeviceID)
+			if err != nil {
+				return err
+			}
+			if !paired {
+				return errDeviceNotPaired
+			}
+		}
+		return models.RegisterChildDevice(tx, child.ID, req.DeviceID, req.DeviceName)
+	})
+	switch {
+	case errors.Is(err, models.ErrPairingCodeInvalid):
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(401, "Pairing code is invalid or expired"))
+		return
+	case errors.Is(err, errInvalidChildCredentials):
+		if child.ID != 0 {
+			setAuditUser(c, &child)
+		}
+		respondLoginFailure(h.db, c, models.LockoutScopeChildPin, pinSubject, http.StatusUnauthorized, "Invalid child ID or PIN")
+		return
+	case errors.Is(err, errDeviceNotPaired):
+		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Device is not paired, please use a pairing code first"))
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to log in"))
+		return
+	}
+	if req.PairingCode == "" {
+		if err := models.ClearLoginFailures(h.db, models.LockoutScopeChildPin, pinSubject); err != nil {
+			c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to reset failed attempts"))
+			return
+		}
+	}
+
+	// 创建绑定设备的会话并签发令牌
+	result, err := issueTokens(h.db, c, &child, req.DeviceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to generate token"))
+		return
+	}
+
+	// 获取用户积分信息
+	var userPoints models.UserPoints
+	h.db.Where("user_id = ?", child.ID).First(&userPoints)
+
+	result["user_id"] = child.ID
+	result["device_id"] = req.DeviceID
+	result["user"] = gin.H{
+		"id":               child.ID,
+		"nickname":         child.Nickname,
+		"role":             child.Role,
+		"avatar":           child.Avatar,
+		"parent_id":        child.ParentID,
+		"family_id":        child.FamilyID,
+		"total_points":     userPoints.TotalPoints,
+		"available_points": userPoints.AvailablePoints,
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(result))
+}
+
+// 儿童登录错误
+var (
+	errInvalidChildCredentials = errors.New("invalid child credentials")
+	errDeviceNotPaired         = errors.New("device is not paired")
+)
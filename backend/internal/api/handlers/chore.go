@@ -0,0 +1,426 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ChoreHandler struct {
+	db *gorm.DB
+}
+
+func NewChoreHandler(db *gorm.DB) *ChoreHandler {
+	return &ChoreHandler{db: db}
+}
+
+// CreateChoreRequest 创建周期任务请求
+type CreateChoreRequest struct {
+	ChildID       uint   `json:"child_id" binding:"required"`
+	TemplateID    *uint  `json:"template_id"`
+	Name          string `json:"name" binding:"required,max=100"`
+	Category      string `json:"category"`
+	Points        int    `json:"points" binding:"required,min=1"`
+	PenaltyPoints int    `json:"penalty_points" binding:"min=0"`
+	Recurrence    string `json:"recurrence" binding:"required,oneof=daily weekdays weekly"`
+	WeekDays      []int  `json:"week_days"`
+	DueTime       string `json:"due_time"` // 为空表示全天任务
+}
+
+// UpdateChoreRequest 更新周期任务请求
+type UpdateChoreRequest struct {
+	Name          string `json:"name" binding:"max=100"`
+	Category      string `json:"category"`
+	Points        *int   `json:"points"`
+	PenaltyPoints *int   `json:"penalty_points"`
+	Recurrence    string `json:"recurrence" binding:"omitempty,oneof=daily weekdays weekly"`
+	WeekDays      []int  `json:"week_days"`
+	DueTime       string `json:"due_time"`
+	IsActive      *bool  `json:"is_active"`
+}
+
+// validateSchedule 校验重复规则和截止时间，截止时间为空表示全天任务
+func validateSchedule(recurrence string, weekDays []int, dueTime string) string {
+	if _, err := time.Parse("15:04", dueTime); dueTime != "" && err != nil {
+		return "Invalid due time, expected HH:MM"
+	}
+	if recurrence == models.RecurrenceWeekly {
+		if len(weekDays) == 0 {
+			return "Week days are required for weekly chores"
+		}
+		for _, day := range weekDays {
+			if day < 0 || day > 6 {
+				return "Week days must be between 0 (Sunday) and 6 (Saturday)"
+			}
+		}
+	}
+	return ""
+}
+
+// choreResponse 构建周期任务返回数据
+func choreResponse(chore models.Chore) gin.H {
+	return gin.H{
+		"id":             chore.ID,
+		"child_id":       chore.ChildID,
+		"template_id":    chore.TemplateID,
+		"name":           chore.Name,
+		"category":       chore.Category,
+		"points":         chore.Points,
+		"penalty_points": chore.PenaltyPoints,
+		"recurrence":     chore.Recurrence,
+		"week_days":      chore.WeekDays,
+		"due_time":       chore.DueTime,
+		"is_active":      chore.IsActive,
+		"created_at":     chore.CreatedAt,
+	}
+}
+
+// choreInstanceResponse 构建任务实例返回数据
+func choreInstanceResponse(instance models.ChoreInstance) gin.H {
+	return gin.H{
+		"id":                 instance.ID,
+		"chore_id":           instance.ChoreID,
+		"child_id":           instance.ChildID,
+		"name":               instance.Chore.Name,
+		"category":           instance.Chore.Category,
+		"points":             instance.Chore.Points,
+		"due_date":           instance.DueDate,
+		"due_at":             instance.DueAt,
+		"status":             instance.Status,
+		"completed_at":       instance.CompletedAt,
+		"completed_by":       instance.CompletedBy,
+		"behavior_record_id": instance.BehaviorRecordID,
+	}
+}
+
+// CreateChore 创建周期任务
+func (h *ChoreHandler) CreateChore(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req CreateChoreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	if message := validateSchedule(req.Recurrence, req.WeekDays, req.DueTime); message != "" {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, message))
+		return
+	}
+
+	parentID := userID.(uint)
+	familyID := c.GetUint("family_id")
+
+	// 验证儿童是否属于当前家庭
+	if _, err := models.FindFamilyChild(h.db, familyID, req.ChildID); err != nil {
+		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Child not found or permission denied"))
+		return
+	}
+
+	// 使用行为模板时，分类取模板的值
+	category := req.Category
+	if req.TemplateID != nil {
+		var template models.BehaviorTemplate
+		if err := h.db.Where("id = ? AND created_by IN (?)", *req.TemplateID, models.FamilyMemberIDs(h.db, familyID)).First(&template).Error; err != nil {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Behavior template not found"))
+			return
+		}
+		category = template.Category
+	}
+
+	chore := models.Chore{
+		ChildID:       req.ChildID,
+		TemplateID:    req.TemplateID,
+		Name:          req.Name,
+		Category:      models.NormalizeBehaviorCategory(category),
+		Points:        req.Points,
+		PenaltyPoints: req.PenaltyPoints,
+		Recurrence:    req.Recurrence,
+		DueTime:       req.DueTime,
+		IsActive:      true,
+		CreatedBy:     parentID,
+	}
+	if req.Recurrence == models.RecurrenceWeekly {
+		chore.WeekDays = models.FormatWeekDays(req.WeekDays)
+	}
+
+	if err := h.db.Create(&chore).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to create chore"))
+		return
+	}
+	setAuditChange(c, "chore", chore.ID, nil, chore)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(choreResponse(chore)))
+}
+
+// GetChores 获取周期任务列表
+func (h *ChoreHandler) GetChores(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("user_role")
+
+	query := h.db.Model(&models.Chore{})
+
+	if userRole == "parent" {
+		// 家长查看家庭中所有孩子的任务
+		query = query.Where("child_id IN (?)", models.FamilyChildIDs(h.db, c.GetUint("family_id")))
+		if childIDParam := c.Query("child_id"); childIDParam != "" {
+			childID, err := strconv.ParseUint(childIDParam, 10, 32)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid child ID"))
+				return
+			}
+			query = query.Where("child_id = ?", childID)
+		}
+	} else {
+		// 儿童只能查看分配给自己的启用任务
+		query = query.Where("child_id = ? AND is_active = ?", userID, true)
+	}
+
+	var chores []models.Chore
+	if err := query.Order("due_time ASC").Find(&chores).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to get chores"))
+		return
+	}
+
+	result := []gin.H{}
+	for _, chore := range chores {
+		result = append(result, choreResponse(chore))
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(result))
+}
+
+// UpdateChore 更新周期任务
+func (h *ChoreHandler) UpdateChore(c *gin.Context) {
+	familyID := c.GetUint("family_id")
+
+	choreID, err := strconv.ParseUint(c.Param("chore_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid chore ID"))
+		return
+	}
+
+	var req UpdateChoreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	// 验证任务是否属于当前家庭
+	var chore models.Chore
+	if err := h.db.Where("id = ? AND child_id IN (?)", choreID, models.FamilyChildIDs(h.db, familyID)).First(&chore).Error; err != nil {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "Chore not found"))
+		return
+	}
+
+	before := chore
+
+	// 合并后再校验重复规则
+	recurrence := chore.Recurrence
+	if req.Recurrence != "" {
+		recurrence = req.Recurrence
+	}
+	dueTime := chore.DueTime
+	if req.DueTime != "" {
+		dueTime = req.DueTime
+	}
+	weekDays := req.WeekDays
+	if weekDays == nil && recurrence == models.RecurrenceWeekly {
+		days, _ := models.ParseWeekDays(chore.WeekDays)
+		for _, day := range days {
+			weekDays = append(weekDays, int(day))
+		}
+	}
+	if message := validateSchedule(recurrence, weekDays, dueTime); message != "" {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, message))
+		return
+	}
+
+	// 构建更新字段
+	updates := map[string]interface{}{
+		"recurrence": recurrence,
+		"due_time":   dueTime,
+		"week_days":  "",
+	}
+	if recurrence == models.RecurrenceWeekly {
+		updates["week_days"] = models.FormatWeekDays(weekDays)
+	}
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Category != "" {
+		updates["category"] = models.NormalizeBehaviorCategory(req.Category)
+	}
+	if req.Points != nil {
+		if *req.Points <= 0 {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Points must be positive"))
+			return
+		}
+		updates["points"] = *req.Points
+	}
+	if req.PenaltyPoints != nil {
+		if *req.PenaltyPoints < 0 {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Penalty points cannot be negative"))
+			return
+		}
+		updates["penalty_points"] = *req.PenaltyPoints
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+
+	if err := h.db.Model(&chore).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to update chore"))
+		return
+	}
+
+	// 获取更新后的任务信息
+	h.db.First(&chore, chore.ID)
+	setAuditChange(c, "chore", chore.ID, before, chore)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(choreResponse(chore)))
+}
+
+// DeleteChore 停用周期任务，已生成的实例和行为记录保留
+func (h *ChoreHandler) DeleteChore(c *gin.Context) {
+	familyID := c.GetUint("family_id")
+
+	choreID, err := strconv.ParseUint(c.Param("chore_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid chore ID"))
+		return
+	}
+
+	var chore models.Chore
+	if err := h.db.Where("id = ? AND child_id IN (?)", choreID, models.FamilyChildIDs(h.db, familyID)).First(&chore).Error; err != nil {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "Chore not found"))
+		return
+	}
+	before := chore
+
+	if err := h.db.Model(&chore).Update("is_active", false).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to deactivate chore"))
+		return
+	}
+	setAuditChange(c, "chore", chore.ID, before, chore)
+
+	// 删除今天及以后尚未完成的实例
+	h.db.Where("chore_id = ? AND status = ? AND due_date >= ?", choreID, models.ChoreStatusPending, time.Now().Format(models.ChoreDateLayout)).
+		Delete(&models.ChoreInstance{})
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{"message": "Chore deactivated successfully"}))
+}
+
+// GetChoreInstances 获取指定日期的任务实例，默认为今天
+func (h *ChoreHandler) GetChoreInstances(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("user_role")
+
+	dateStr := c.DefaultQuery("date", time.Now().Format(models.ChoreDateLayout))
+	day, err := time.ParseInLocation(models.ChoreDateLayout, dateStr, time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid date, expected YYYY-MM-DD"))
+		return
+	}
+
+	// 查看今天的任务时确保实例已经生成，不必等待后台作业
+	if dateStr == time.Now().Format(models.ChoreDateLayout) {
+		if err := models.MaterializeChoreInstances(h.db, day); err != nil {
+			c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to prepare chores"))
+			return
+		}
+	}
+
+	query := h.db.Model(&models.ChoreInstance{}).Preload("Chore").Where("due_date = ?", dateStr)
+
+	if userRole == "parent" {
+		query = query.Where("child_id IN (?)", models.FamilyChildIDs(h.db, c.GetUint("family_id")))
+		if childIDParam := c.Query("child_id"); childIDParam != "" {
+			childID, err := strconv.ParseUint(childIDParam, 10, 32)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid child ID"))
+				return
+			}
+			query = query.Where("child_id = ?", childID)
+		}
+	} else {
+		// 儿童只能查看自己的任务
+		query = query.Where("child_id = ?", userID)
+	}
+
+	var instances []models.ChoreInstance
+	if err := query.Order("due_at ASC").Find(&instances).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to get chore instances"))
+		return
+	}
+
+	result := []gin.H{}
+	for _, instance := range instances {
+		result = append(result, choreInstanceResponse(instance))
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
+		"date":      dateStr,
+		"instances": result,
+	}))
+}
+
+// CompleteChoreInstance 标记任务实例完成，家长或任务所属儿童均可操作
+func (h *ChoreHandler) CompleteChoreInstance(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("user_role")
+
+	instanceID, err := strconv.ParseUint(c.Param("instance_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid chore instance ID"))
+		return
+	}
+
+	currentUserID := userID.(uint)
+
+	// 权限检查：家长只能操作家庭中孩子的任务，儿童只能操作自己的任务
+	var instance models.ChoreInstance
+	if err := h.db.First(&instance, instanceID).Error; err != nil {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "Chore instance not found"))
+		return
+	}
+	if userRole == "parent" {
+		if _, err := models.FindFamilyChild(h.db, c.GetUint("family_id"), instance.ChildID); err != nil {
+			c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Permission denied"))
+			return
+		}
+	} else if instance.ChildID != currentUserID {
+		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Permission denied"))
+		return
+	}
+
+	var completed *models.ChoreInstance
+	var userPoints *models.UserPoints
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		completed, userPoints, err = models.CompleteChoreInstance(tx, instance.ID, currentUserID)
+		return err
+	})
+	if errors.Is(err, models.ErrChoreNotPending) {
+		c.JSON(http.StatusConflict, utils.ErrorResponse(409, "Chore instance is already completed or missed"))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to complete chore"))
+		return
+	}
+	setAuditChange(c, "chore_instance", completed.ID, instance, completed)
+
+	result := choreInstanceResponse(*completed)
+	result["available_points"] = userPoints.AvailablePoints
+	result["total_points"] = userPoints.TotalPoints
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(result))
+}
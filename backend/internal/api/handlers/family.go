@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type FamilyHandler struct {
+	db *gorm.DB
+}
+
+func NewFamilyHandler(db *gorm.DB) *FamilyHandler {
+	return &FamilyHandler{db: db}
+}
+
+// CreateFamilyInviteRequest 创建家庭邀请请求，角色可以是内置角色或家庭自定义的角色
+type CreateFamilyInviteRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// JoinFamilyRequest 使用邀请码加入家庭请求
+type JoinFamilyRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// UpdateFamilyMemberRequest 修改成员角色请求
+type UpdateFamilyMemberRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// UpdateFamilyRoleRequest 设置角色权限请求
+type UpdateFamilyRoleRequest struct {
+	Permissions []string `json:"permissions" binding:"required,min=1"`
+}
+
+// GetFamily 获取当前家庭信息、家长成员和儿童
+func (h *FamilyHandler) GetFamily(c *gin.Context) {
+	familyID := c.GetUint("family_id")
+
+	var family models.Family
+	if err := h.db.Preload("Members.User").First(&family, familyID).Error; err != nil {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "Family not found"))
+		return
+	}
+
+	members := []gin.H{}
+	for _, member := range family.Members {
+		members = append(members, gin.H{
+			"user_id":   member.UserID,
+			"nickname":  member.User.Nickname,
+			"avatar":    member.User.Avatar,
+			"role":      member.Role,
+			"joined_at": member.CreatedAt,
+		})
+	}
+
+	var children []models.User
+	if err := h.db.Where("family_id = ? AND role = ?", familyID, "child").Find(&children).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to get children list"))
+		return
+	}
+	childResult := []gin.H{}
+	for _, child := range children {
+		childResult = append(childResult, gin.H{
+			"id":       child.ID,
+			"nickname": child.Nickname,
+			"avatar":   child.Avatar,
+		})
+	}
+
+	familyRole, _ := c.Get("family_role")
+	granted, _ := c.Get("permissions")
+	permissions := []string{}
+	for permission := range granted.(map[string]bool) {
+		permissions = append(permissions, permission)
+	}
+	sort.Strings(permissions)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
+		"id":             family.ID,
+		"name":           family.Name,
+		"owner_id":       family.OwnerID,
+		"my_role":        familyRole,
+		"my_permissions": permissions,
+		"members":        members,
+		"children":       childResult,
+	}))
+}
+
+// CreateInvite 生成邀请码，邀请共同监护人、只读成员或自定义角色的成员
+func (h *FamilyHandler) CreateInvite(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	familyID := c.GetUint("family_id")
+
+	var req CreateFamilyInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	if status, message := h.checkAssignableRole(familyID, req.Role); status != http.StatusOK {
+		c.JSON(status, utils.ErrorResponse(status, message))
+		return
+	}
+
+	invite, err := models.CreateFamilyInvite(h.db, familyID, userID.(uint), req.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to create invite"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
+		"code":       invite.Code,
+		"role":       invite.Role,
+		"expires_at": invite.ExpiresAt,
+	}))
+}
+
+// JoinFamily 家长使用邀请码加入家庭
+// 只能从没有儿童和其他成员的家庭（即注册时自动创建的家庭）加入新家庭
+func (h *FamilyHandler) JoinFamily(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("user_role")
+
+	// 儿童账户随创建它的家庭，不能通过邀请码加入其他家庭
+	if userRole != "parent" {
+		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Only parents can join a family"))
+		return
+	}
+
+	var req JoinFamilyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	member, err := models.AcceptFamilyInvite(h.db, userID.(uint), code)
+	if errors.Is(err, models.ErrFamilyInviteInvalid) {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invite code is invalid or expired"))
+		return
+	}
+	if errors.Is(err, models.ErrAlreadyFamilyMember) {
+		c.JSON(http.StatusConflict, utils.ErrorResponse(409, "You are already a member of this family"))
+		return
+	}
+	if errors.Is(err, models.ErrFamilyNotEmpty) {
+		c.JSON(http.StatusConflict, utils.ErrorResponse(409, "Your current family has children or other members, only parents in a family of their own can join another one"))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to join family"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
+		"family_id": member.FamilyID,
+		"role":      member.Role,
+	}))
+}
+
+// UpdateMember 修改成员角色
+func (h *FamilyHandler) UpdateMember(c *gin.Context) {
+	var req UpdateFamilyMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	if status, message := h.checkAssignableRole(c.GetUint("family_id"), req.Role); status != http.StatusOK {
+		c.JSON(status, utils.ErrorResponse(status, message))
+		return
+	}
+
+	member, ok := h.loadManagedMember(c)
+	if !ok {
+		return
+	}
+	before := *member
+
+	if err := h.db.Model(member).Update("role", req.Role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to update member"))
+		return
+	}
+	member.Role = req.Role
+	setAuditChange(c, "family_member", member.UserID, before, member)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
+		"user_id": member.UserID,
+		"role":    member.Role,
+	}))
+}
+
+// RemoveMember 移除成员，成员创建的配置保留在家庭中
+func (h *FamilyHandler) RemoveMember(c *gin.Context) {
+	member, ok := h.loadManagedMember(c)
+	if !ok {
+		return
+	}
+
+	if err := models.RemoveFamilyMember(h.db, member); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to remove member"))
+		return
+	}
+	setAuditChange(c, "family_member", member.UserID, member, nil)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{"message": "Member removed successfully"}))
+}
+
+// loadManagedMember 获取当前家庭中可被管理的成员，所有者本人不能被修改或移除
+func (h *FamilyHandler) loadManagedMember(c *gin.Context) (*models.FamilyMember, bool) {
+	memberUserID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid user ID"))
+		return nil, false
+	}
+
+	var member models.FamilyMember
+	if err := h.db.Where("family_id = ? AND user_id = ?", c.GetUint("family_id"), memberUserID).First(&member).Error; err != nil {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "Member not found"))
+		return nil, false
+	}
+	if member.Role == models.FamilyRoleOwner {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "The family owner cannot be changed or removed"))
+		return nil, false
+	}
+	return &member, true
+}
+
+// checkAssignableRole 检查角色能否分配给家庭成员
+func (h *FamilyHandler) checkAssignableRole(familyID uint, role string) (int, string) {
+	exists, err := models.FamilyRoleExists(h.db, familyID, role)
+	if err != nil {
+		return http.StatusInternalServerError, "Failed to check role"
+	}
+	if !exists {
+		return http.StatusBadRequest, "Invalid role"
+	}
+	return http.StatusOK, ""
+}
+
+// rolePermissionsAudit 角色权限在审计日志中记为角色名到逗号分隔的权限列表，角色不存在时返回 nil
+func rolePermissionsAudit(role string, permissions []string) gin.H {
+	if len(permissions) == 0 {
+		return nil
+	}
+	sorted := append([]string(nil), permissions...)
+	sort.Strings(sorted)
+	return gin.H{role: strings.Join(sorted, ",")}
+}
+
+// GetRoles 获取家庭中的角色及其权限
+func (h *FamilyHandler) GetRoles(c *gin.Context) {
+	roles, err := models.LoadFamilyRoles(h.db, c.GetUint("family_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to get roles"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
+		"roles":       roles,
+		"permissions": models.AllPermissions,
+	}))
+}
+
+// UpdateRole 设置角色的权限，角色不存在时创建自定义角色（如 babysitter、teacher）
+// 所有者和儿童的权限固定，不能修改
+func (h *FamilyHandler) UpdateRole(c *gin.Context) {
+	role := c.Param("role")
+	familyID := c.GetUint("family_id")
+
+	var req UpdateFamilyRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	before, err := models.LoadRolePermissions(h.db, familyID, role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to update role"))
+		return
+	}
+	err = models.SetRolePermissions(h.db, familyID, role, req.Permissions)
+	if errors.Is(err, models.ErrRoleNotEditable) {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Role name is invalid or its permissions cannot be changed"))
+		return
+	}
+	if errors.Is(err, models.ErrInvalidPermissions) {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to update role"))
+		return
+	}
+	after, _ := models.LoadRolePermissions(h.db, familyID, role)
+	setAuditChange(c, "family_role", familyID, rolePermissionsAudit(role, before), rolePermissionsAudit(role, after))
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
+		"role":        role,
+		"permissions": req.Permissions,
+	}))
+}
+
+// DeleteRole 删除自定义角色；内置角色删除后恢复默认权限
+func (h *FamilyHandler) DeleteRole(c *gin.Context) {
+	role := c.Param("role")
+	familyID := c.GetUint("family_id")
+
+	before, err := models.LoadRolePermissions(h.db, familyID, role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to delete role"))
+		return
+	}
+	err = models.DeleteFamilyRole(h.db, familyID, role)
+	if errors.Is(err, models.ErrRoleNotEditable) {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Role cannot be deleted"))
+		return
+	}
+	if errors.Is(err, models.ErrRoleInUse) {
+		c.JSON(http.StatusConflict, utils.ErrorResponse(409, "Role is still assigned to members or pending invites"))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to delete role"))
+		return
+	}
+	after, _ := models.LoadRolePermissions(h.db, familyID, role)
+	setAuditChange(c, "family_role", familyID, rolePermissionsAudit(role, before), rolePermissionsAudit(role, after))
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{"message": "Role deleted successfully"}))
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// respondLocked 返回锁定状态，客户端根据 retry_after 或 Retry-After 请求头提示等待时间
+func respondLocked(c *gin.Context, status models.LockStatus) {
+	c.Header("Retry-After", strconv.Itoa(status.RetryAfter))
+	c.JSON(http.StatusTooManyRequests, utils.Response{
+		Code:    429,
+		Message: "Too many failed attempts, please try again later",
+		Data:    status,
+	})
+}
+
+// checkLockout 检查是否处于锁定状态，锁定时直接返回锁定状态，不再验证密码
+func checkLockout(db *gorm.DB, c *gin.Context, scope, subject string) bool {
+	status, err := models.GetLockStatus(db, scope, subject, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to check lock status"))
+		return false
+	}
+	if status.Locked {
+		respondLocked(c, status)
+		return false
+	}
+	return true
+}
+
+// respondLoginFailure 记录一次失败并返回错误和剩余尝试次数，本次失败触发锁定时返回锁定状态
+func respondLoginFailure(db *gorm.DB, c *gin.Context, scope, subject string, code int, message string) {
+	status, err := models.RecordLoginFailure(db, scope, subject, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to record failed attempt"))
+		return
+	}
+	if status.Locked {
+		respondLocked(c, status)
+		return
+	}
+	c.JSON(code, utils.Response{
+		Code:    code,
+		Message: message,
+		Data:    status,
+	})
+}
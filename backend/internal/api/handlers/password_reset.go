@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/notify"
+	"child-behavior-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PasswordResetHandler struct {
+	db     *gorm.DB
+	sender notify.MessageSender
+}
+
+func NewPasswordResetHandler(db *gorm.DB, sender notify.MessageSender) *PasswordResetHandler {
+	return &PasswordResetHandler{db: db, sender: sender}
+}
+
+// PasswordResetRequest 申请找回密码验证码请求
+type PasswordResetRequest struct {
+	Phone string `json:"phone" binding:"required"`
+}
+
+// PasswordResetConfirmRequest 使用验证码重设密码请求
+type PasswordResetConfirmRequest struct {
+	Phone       string `json:"phone" binding:"required"`
+	Code        string `json:"code" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// RequestReset 向家长的手机号发送找回密码验证码
+// 无论手机号是否注册都返回相同的结果，避免暴露账号是否存在
+func (h *PasswordResetHandler) RequestReset(c *gin.Context) {
+	var req PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	result := gin.H{
+		"message":    "If the phone number is registered, a verification code has been sent",
+		"expires_in": int(models.PasswordResetTTL.Seconds()),
+	}
+
+	var user models.User
+	if err := h.db.Where("phone = ? AND role = ?", req.Phone, "parent").First(&user).Error; err != nil {
+		c.JSON(http.StatusOK, utils.SuccessResponse(result))
+		return
+	}
+
+	code, err := models.CreatePasswordResetCode(h.db, user.ID, time.Now())
+	if errors.Is(err, models.ErrPasswordResetTooFrequent) || errors.Is(err, models.ErrPasswordResetDailyLimit) {
+		// 间隔内重复申请或超过每日上限时不再发送，之前的验证码仍然有效
+		c.JSON(http.StatusOK, utils.SuccessResponse(result))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to create verification code"))
+		return
+	}
+
+	message := fmt.Sprintf("您的找回密码验证码是 %s，%d分钟内有效。如非本人操作请忽略。", code, int(models.PasswordResetTTL.Minutes()))
+	if err := h.sender.Send(req.Phone, message); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to send verification code"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(result))
+}
+
+// ConfirmReset 使用验证码重设密码，成功后所有设备上的会话失效，需要重新登录
+func (h *PasswordResetHandler) ConfirmReset(c *gin.Context) {
+	var req PasswordResetConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	var user models.User
+	if err := h.db.Where("phone = ? AND role = ?", req.Phone, "parent").First(&user).Error; err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Verification code is invalid or expired"))
+		return
+	}
+	setAuditUser(c, &user)
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to hash password"))
+		return
+	}
+
+	err = models.ResetPasswordWithCode(h.db, user.ID, req.Code, hashedPassword, time.Now())
+	if errors.Is(err, models.ErrPasswordResetInvalid) {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Verification code is invalid or expired"))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to reset password"))
+		return
+	}
+	setAccountSecurityChange(c, h.db, user)
+
+	// 重设密码后解除登录锁定，失败不影响重设结果
+	models.ClearLoginFailures(h.db, models.LockoutScopeLogin, req.Phone)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{"message": "密码重置成功，请重新登录"}))
+}
@@ -1,23 +1,23 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
-	"time"
 
-	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/repository"
+	"child-behavior-app/internal/service"
 	"child-behavior-app/internal/utils"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
 type RewardHandler struct {
-	db *gorm.DB
+	rewards *service.RewardService
 }
 
-func NewRewardHandler(db *gorm.DB) *RewardHandler {
-	return &RewardHandler{db: db}
+func NewRewardHandler(rewards *service.RewardService) *RewardHandler {
+	return &RewardHandler{rewards: rewards}
 }
 
 // CreateRewardRequest 创建奖励请求
@@ -27,61 +27,48 @@ type CreateRewardRequest struct {
 	PointsCost  int    `json:"points_cost" binding:"required,min=1"`
 	Image       string `json:"image"`
 	Stock       int    `json:"stock" binding:"required,min=0"`
+	// 儿童兑换是否需要家长确认
+	RequiresApproval bool `json:"requires_approval"`
 }
 
 // CreateReward 创建奖励
 func (h *RewardHandler) CreateReward(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	userRole, _ := c.Get("user_role")
-
-	// 只有家长可以创建奖励
-	if userRole != "parent" {
-		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Only parents can create rewards"))
-		return
-	}
-
 	var req CreateRewardRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
 		return
 	}
 
-	parentID := userID.(uint)
-
-	// 创建奖励
-	reward := models.Reward{
-		Name:        req.Name,
-		Description: req.Description,
-		Points:      req.PointsCost,
-		Image:       req.Image,
-		Stock:       req.Stock,
-		CreatedBy:   parentID,
-		IsActive:    true,
-	}
-
-	if err := h.db.Create(&reward).Error; err != nil {
+	reward, err := h.rewards.Create(currentActor(c), service.RewardInput{
+		Name:             req.Name,
+		Description:      req.Description,
+		Points:           req.PointsCost,
+		Image:            req.Image,
+		Stock:            req.Stock,
+		RequiresApproval: req.RequiresApproval,
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to create reward"))
 		return
 	}
+	setAuditChange(c, "reward", reward.ID, nil, reward)
 
 	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
-		"id":          reward.ID,
-		"name":        reward.Name,
-		"description": reward.Description,
-		"points":      reward.Points,
-		"image":       reward.Image,
-		"stock":       reward.Stock,
-		"is_active":   reward.IsActive,
-		"created_by":  reward.CreatedBy,
-		"created_at":  reward.CreatedAt,
+		"id":                reward.ID,
+		"name":              reward.Name,
+		"description":       reward.Description,
+		"points":            reward.Points,
+		"image":             reward.Image,
+		"stock":             reward.Stock,
+		"is_active":         reward.IsActive,
+		"requires_approval": reward.RequiresApproval,
+		"created_by":        reward.CreatedBy,
+		"created_at":        reward.CreatedAt,
 	}))
 }
 
 // GetRewards 获取奖励列表
 func (h *RewardHandler) GetRewards(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	userRole, _ := c.Get("user_role")
-
 	pageStr := c.DefaultQuery("page", "1")
 	limitStr := c.DefaultQuery("limit", "20")
 	isActiveStr := c.Query("is_active")
@@ -90,34 +77,15 @@ func (h *RewardHandler) GetRewards(c *gin.Context) {
 	limit, _ := strconv.Atoi(limitStr)
 	offset := (page - 1) * limit
 
-	// 构建查询条件
-	query := h.db.Model(&models.Reward{})
-
-	if userRole == "parent" {
-		// 家长只能看到自己创建的奖励
-		query = query.Where("created_by = ?", userID)
-	} else {
-		// 儿童可以看到家长创建的奖励
-		var user models.User
-		h.db.First(&user, userID)
-		if user.ParentID != nil {
-			query = query.Where("created_by = ?", *user.ParentID)
-		}
-	}
-
-	// 添加活跃状态过滤
+	// 家长和儿童都只能看到家庭成员创建的奖励
+	var isActive *bool
 	if isActiveStr != "" {
-		isActive := isActiveStr == "true"
-		query = query.Where("is_active = ?", isActive)
+		active := isActiveStr == "true"
+		isActive = &active
 	}
 
-	// 获取总数
-	var total int64
-	query.Count(&total)
-
-	// 获取奖励列表
-	var rewards []models.Reward
-	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&rewards).Error; err != nil {
+	rewards, total, err := h.rewards.List(c.GetUint("family_id"), isActive, repository.Page{Offset: offset, Limit: limit})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to get rewards"))
 		return
 	}
@@ -126,15 +94,16 @@ func (h *RewardHandler) GetRewards(c *gin.Context) {
 	var result []gin.H
 	for _, reward := range rewards {
 		result = append(result, gin.H{
-			"id":          reward.ID,
-			"name":        reward.Name,
-			"description": reward.Description,
-			"points":      reward.Points,
-			"image":       reward.Image,
-			"stock":       reward.Stock,
-			"is_active":   reward.IsActive,
-			"created_by":  reward.CreatedBy,
-			"created_at":  reward.CreatedAt,
+			"id":                reward.ID,
+			"name":              reward.Name,
+			"description":       reward.Description,
+			"points":            reward.Points,
+			"image":             reward.Image,
+			"stock":             reward.Stock,
+			"is_active":         reward.IsActive,
+			"requires_approval": reward.RequiresApproval,
+			"created_by":        reward.CreatedBy,
+			"created_at":        reward.CreatedAt,
 		})
 	}
 
@@ -157,119 +126,47 @@ type ExchangeRewardRequest struct {
 
 // ExchangeReward 兑换奖励
 func (h *RewardHandler) ExchangeReward(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	userRole, _ := c.Get("user_role")
-
 	var req ExchangeRewardRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
 		return
 	}
 
-	// 确定兑换的用户ID
-	var targetUserID uint
-	if userRole == "parent" {
-		// 家长为孩子兑换
-		if req.ChildID == 0 {
-			c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Child ID is required for parent"))
-			return
-		}
-		// 验证儿童是否属于当前家长
-		var child models.User
-		if err := h.db.Where("id = ? AND parent_id = ?", req.ChildID, userID).First(&child).Error; err != nil {
-			c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Child not found or permission denied"))
-			return
-		}
-		targetUserID = req.ChildID
-	} else {
-		// 儿童为自己兑换
-		targetUserID = userID.(uint)
-	}
-
-	// 获取奖励信息
-	var reward models.Reward
-	if err := h.db.First(&reward, req.RewardID).Error; err != nil {
+	// 儿童为自己兑换，家长为孩子兑换
+	result, err := h.rewards.Exchange(currentActor(c), req.RewardID, req.ChildID)
+	switch {
+	case errors.Is(err, service.ErrChildRequired):
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Child ID is required for parent"))
+	case errors.Is(err, service.ErrChildNotFound):
+		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Child not found or permission denied"))
+	case errors.Is(err, service.ErrRewardNotFound):
 		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "Reward not found"))
-		return
-	}
-
-	// 检查奖励是否可用
-	if !reward.IsActive {
+	case errors.Is(err, service.ErrRewardInactive):
 		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Reward is not active"))
-		return
-	}
-
-	if reward.Stock <= 0 {
+	case errors.Is(err, service.ErrRewardOutOfStock):
 		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Reward is out of stock"))
-		return
-	}
-
-	// 获取用户积分
-	var userPoints models.UserPoints
-	if err := h.db.Where("user_id = ?", targetUserID).First(&userPoints).Error; err != nil {
+	case errors.Is(err, service.ErrPointsNotFound):
 		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "User points not found"))
-		return
-	}
-
-	// 检查积分是否足够
-	if userPoints.AvailablePoints < reward.Points {
+	case errors.Is(err, service.ErrInsufficientPoints):
 		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Insufficient points"))
-		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to exchange reward"))
+	default:
+		c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
+			"exchange_id":      result.Exchange.ID,
+			"reward_name":      result.Reward.Name,
+			"points":           result.Exchange.PointsUsed,
+			"remaining_points": result.Points.AvailablePoints,
+			"exchanged_at":     result.Exchange.ExchangedAt,
+			"status":           result.Exchange.Status,
+		}))
 	}
-
-	// 开始事务
-	tx := h.db.Begin()
-
-	// 扣除积分
-	userPoints.AvailablePoints -= reward.Points
-	if err := tx.Save(&userPoints).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to update points"))
-		return
-	}
-
-	// 减少库存
-	reward.Stock -= 1
-	if err := tx.Save(&reward).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to update stock"))
-		return
-	}
-
-	// 创建兑换记录
-	exchangeRecord := models.ExchangeRecord{
-		UserID:      targetUserID,
-		RewardID:    req.RewardID,
-		PointsUsed:  reward.Points,
-		ExchangedAt: time.Now(),
-		Status:      "completed",
-	}
-
-	if err := tx.Create(&exchangeRecord).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to create exchange record"))
-		return
-	}
-
-	// 提交事务
-	tx.Commit()
-
-	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
-		"exchange_id":      exchangeRecord.ID,
-		"reward_name":      reward.Name,
-		"points":           exchangeRecord.PointsUsed,
-		"remaining_points": userPoints.AvailablePoints,
-		"exchanged_at":     exchangeRecord.ExchangedAt,
-		"status":           exchangeRecord.Status,
-	}))
 }
 
 // GetExchangeRecords 获取兑换记录
+// 家长可以查看家庭中孩子的兑换记录，儿童只能查看自己的兑换记录
 func (h *RewardHandler) GetExchangeRecords(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	userRole, _ := c.Get("user_role")
-
-	childIDParam := c.Query("child_id")
+	status := c.Query("status")
 	pageStr := c.DefaultQuery("page", "1")
 	limitStr := c.DefaultQuery("limit", "20")
 
@@ -277,61 +174,39 @@ func (h *RewardHandler) GetExchangeRecords(c *gin.Context) {
 	limit, _ := strconv.Atoi(limitStr)
 	offset := (page - 1) * limit
 
-	// 构建查询条件
-	query := h.db.Model(&models.ExchangeRecord{}).Preload("Reward")
-
-	if userRole == "parent" {
-		parentID := userID.(uint)
-		if childIDParam != "" {
-			childID, err := strconv.ParseUint(childIDParam, 10, 32)
-			if err != nil {
-				c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid child ID"))
-				return
-			}
-			// 验证儿童是否属于当前家长
-			var child models.User
-			if err := h.db.Where("id = ? AND parent_id = ?", childID, parentID).First(&child).Error; err != nil {
-				c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Child not found or permission denied"))
-				return
-			}
-			query = query.Where("user_id = ?", childID)
-		} else {
-			// 查询所有孩子的兑换记录
-			var childIDs []uint
-			h.db.Model(&models.User{}).Where("parent_id = ?", parentID).Pluck("id", &childIDs)
-			query = query.Where("user_id IN ?", childIDs)
+	var childID *uint
+	if c.GetString("user_role") == "parent" {
+		var err error
+		if childID, err = parseOptionalID(c.Query("child_id")); err != nil {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid child ID"))
+			return
 		}
-	} else {
-		// 儿童只能查看自己的兑换记录
-		query = query.Where("user_id = ?", userID)
 	}
 
-	// 获取总数
-	var total int64
-	query.Count(&total)
-
-	// 获取兑换记录
-	var exchanges []models.ExchangeRecord
-	if err := query.Order("exchanged_at DESC").Offset(offset).Limit(limit).Find(&exchanges).Error; err != nil {
+	list, err := h.rewards.ListExchanges(currentActor(c), childID, status, repository.Page{Offset: offset, Limit: limit})
+	if errors.Is(err, service.ErrChildNotFound) {
+		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Child not found or permission denied"))
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to get exchange records"))
 		return
 	}
 
 	// 构建返回数据
 	var result []gin.H
-	for _, exchange := range exchanges {
-		var user models.User
-		h.db.First(&user, exchange.UserID)
-
+	for _, exchange := range list.Exchanges {
 		result = append(result, gin.H{
 			"id":           exchange.ID,
 			"user_id":      exchange.UserID,
-			"user_name":    user.Nickname,
+			"user_name":    list.Names[exchange.UserID],
 			"reward_id":    exchange.RewardID,
 			"reward_name":  exchange.Reward.Name,
 			"points":       exchange.PointsUsed,
 			"exchanged_at": exchange.ExchangedAt,
 			"status":       exchange.Status,
+			"review_note":  exchange.ReviewNote,
+			"reviewed_at":  exchange.ReviewedAt,
 		})
 	}
 
@@ -340,21 +215,63 @@ func (h *RewardHandler) GetExchangeRecords(c *gin.Context) {
 		"pagination": gin.H{
 			"page":  page,
 			"limit": limit,
-			"total": total,
+			"total": list.Total,
 		},
 	}))
 }
 
-// UpdateReward 更新奖励信息
-func (h *RewardHandler) UpdateReward(c *gin.Context) {
-	userRole, _ := c.Get("user_role")
+// UpdateExchangeRequest 处理兑换记录请求
+type UpdateExchangeRequest struct {
+	Status string `json:"status" binding:"required,oneof=completed cancelled"`
+	Note   string `json:"note" binding:"max=255"`
+}
+
+// UpdateExchange 家长处理待确认的兑换记录：完成兑换，或取消并退回积分、恢复库存
+func (h *RewardHandler) UpdateExchange(c *gin.Context) {
+	exchangeID, err := strconv.ParseUint(c.Param("exchange_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid exchange ID"))
+		return
+	}
 
-	// 只有家长可以更新奖励
-	if userRole != "parent" {
-		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Only parents can update rewards"))
+	var req UpdateExchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	exchange, userPoints, err := h.rewards.ResolveExchange(currentActor(c), uint(exchangeID), req.Status, req.Note)
+	if errors.Is(err, service.ErrExchangeNotFound) {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "Exchange record not found or permission denied"))
+		return
+	}
+	if errors.Is(err, service.ErrExchangeNotPending) {
+		c.JSON(http.StatusConflict, utils.ErrorResponse(409, "Exchange record has already been processed"))
 		return
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to update exchange record"))
+		return
+	}
+
+	result := gin.H{
+		"id":          exchange.ID,
+		"user_id":     exchange.UserID,
+		"reward_id":   exchange.RewardID,
+		"points":      exchange.PointsUsed,
+		"status":      exchange.Status,
+		"review_note": exchange.ReviewNote,
+		"reviewed_at": exchange.ReviewedAt,
+	}
+	if userPoints != nil {
+		result["remaining_points"] = userPoints.AvailablePoints
+	}
 
+	c.JSON(http.StatusOK, utils.SuccessResponse(result))
+}
+
+// UpdateReward 更新奖励信息
+func (h *RewardHandler) UpdateReward(c *gin.Context) {
 	rewardIDParam := c.Param("reward_id")
 	rewardID, err := strconv.ParseUint(rewardIDParam, 10, 32)
 	if err != nil {
@@ -369,6 +286,8 @@ func (h *RewardHandler) UpdateReward(c *gin.Context) {
 		Image       string `json:"image"`
 		Stock       int    `json:"stock"`
 		IsActive    *bool  `json:"is_active"`
+		// 儿童兑换是否需要家长确认
+		RequiresApproval *bool `json:"requires_approval"`
 	}
 
 	var req UpdateRewardRequest
@@ -377,46 +296,32 @@ func (h *RewardHandler) UpdateReward(c *gin.Context) {
 		return
 	}
 
-	// 构建更新字段
-	updates := make(map[string]interface{})
-	if req.Name != "" {
-		updates["name"] = req.Name
-	}
-	if req.Description != "" {
-		updates["description"] = req.Description
-	}
-	if req.Points > 0 {
-		updates["points"] = req.Points
-	}
-	if req.Image != "" {
-		updates["image"] = req.Image
-	}
-	if req.Stock >= 0 {
-		updates["stock"] = req.Stock
-	}
-	if req.IsActive != nil {
-		updates["is_active"] = *req.IsActive
+	// 验证奖励是否属于当前家庭
+	before, reward, err := h.rewards.Update(c.GetUint("family_id"), uint(rewardID), service.RewardChanges{
+		Name:             req.Name,
+		Description:      req.Description,
+		Points:           req.Points,
+		Image:            req.Image,
+		Stock:            req.Stock,
+		IsActive:         req.IsActive,
+		RequiresApproval: req.RequiresApproval,
+	})
+	if errors.Is(err, service.ErrRewardNotFound) {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "Reward not found"))
+		return
 	}
-
-	if err := h.db.Model(&models.Reward{}).Where("id = ?", rewardID).Updates(updates).Error; err != nil {
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to update reward"))
 		return
 	}
+	setAuditChange(c, "reward", reward.ID, before, reward)
 
 	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{"message": "Reward updated successfully"}))
 }
 
 // DeleteReward 删除奖励
+// 有兑换记录的奖励只设为不活跃，没有兑换记录时物理删除
 func (h *RewardHandler) DeleteReward(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	userRole, _ := c.Get("user_role")
-
-	// 只有家长可以删除奖励
-	if userRole != "parent" {
-		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Only parents can delete rewards"))
-		return
-	}
-
 	rewardIDParam := c.Param("reward_id")
 	rewardID, err := strconv.ParseUint(rewardIDParam, 10, 32)
 	if err != nil {
@@ -424,36 +329,20 @@ func (h *RewardHandler) DeleteReward(c *gin.Context) {
 		return
 	}
 
-	// 获取奖励信息并验证权限
-	var reward models.Reward
-	if err := h.db.First(&reward, rewardID).Error; err != nil {
+	before, after, err := h.rewards.Delete(c.GetUint("family_id"), uint(rewardID))
+	if errors.Is(err, service.ErrRewardNotFound) {
 		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "Reward not found"))
 		return
 	}
-
-	// 验证奖励是否属于当前家长
-	if reward.CreatedBy != userID.(uint) {
-		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Permission denied"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to delete reward"))
 		return
 	}
+	setAuditChange(c, "reward", before.ID, before, after)
 
-	// 检查是否有相关的兑换记录
-	var exchangeCount int64
-	h.db.Model(&models.ExchangeRecord{}).Where("reward_id = ?", rewardID).Count(&exchangeCount)
-
-	if exchangeCount > 0 {
-		// 如果有兑换记录，只是将奖励设为不活跃，而不是物理删除
-		if err := h.db.Model(&reward).Update("is_active", false).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to deactivate reward"))
-			return
-		}
+	if after != nil {
 		c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{"message": "Reward deactivated successfully"}))
-	} else {
-		// 如果没有兑换记录，可以物理删除
-		if err := h.db.Delete(&reward).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to delete reward"))
-			return
-		}
-		c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{"message": "Reward deleted successfully"}))
+		return
 	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{"message": "Reward deleted successfully"}))
 }
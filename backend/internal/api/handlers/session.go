@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RefreshTokenRequest 刷新令牌请求
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// issueTokens 为用户创建登录会话并签发访问令牌和刷新令牌
+func issueTokens(db *gorm.DB, c *gin.Context, user *models.User, deviceID string) (gin.H, error) {
+	session, refreshToken, err := models.CreateSession(db, user, deviceID, c.Request.UserAgent(), c.ClientIP(), utils.RefreshTokenTTL())
+	if err != nil {
+		return nil, err
+	}
+	setAuditUser(c, user)
+	return signTokens(user, session, refreshToken)
+}
+
+// signTokens 为会话签发访问令牌，并与刷新令牌一起返回
+func signTokens(user *models.User, session *models.Session, refreshToken string) (gin.H, error) {
+	token, err := utils.GenerateToken(utils.Claims{
+		UserID:       user.ID,
+		Role:         user.Role,
+		DeviceID:     session.DeviceID,
+		SessionID:    session.ID,
+		TokenVersion: session.TokenVersion,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return gin.H{
+		"token":              token,
+		"expires_in":         int(utils.AccessTokenTTL().Seconds()),
+		"refresh_token":      refreshToken,
+		"refresh_expires_at": session.ExpiresAt,
+	}, nil
+}
+
+// RefreshToken 使用刷新令牌换取新的访问令牌，刷新令牌同时轮换，旧令牌作废
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	session, user, refreshToken, err := models.RotateSession(h.db, req.RefreshToken, utils.RefreshTokenTTL())
+	if errors.Is(err, models.ErrSessionInvalid) || errors.Is(err, models.ErrSessionReused) {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(401, "Invalid or expired refresh token"))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to refresh token"))
+		return
+	}
+	setAuditUser(c, user)
+
+	tokens, err := signTokens(user, session, refreshToken)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to generate token"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(tokens))
+}
+
+// Logout 退出当前会话，当前访问令牌和刷新令牌立即失效
+func (h *AuthHandler) Logout(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	sessionID, _ := c.Get("session_id")
+
+	if err := models.RevokeSession(h.db, userID.(uint), sessionID.(uint)); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to log out"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{"message": "Logged out successfully"}))
+}
+
+// LogoutAll 退出所有设备，撤销全部会话并使已签发的访问令牌失效
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	if err := models.RevokeUserSessions(h.db, userID.(uint)); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to log out"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{"message": "Logged out from all devices"}))
+}
@@ -43,15 +43,15 @@ func (h *StatisticsHandler) GetStatistics(c *gin.Context) {
 		startDate = time.Now().AddDate(0, 0, -7)
 	}
 
-	// 构建查询条件
-	query := h.db.Model(&models.BehaviorRecord{}).Where("recorded_at >= ?", startDate)
+	// 构建查询条件，只统计已生效的行为记录，待审核和已拒绝的申报不计入
+	query := h.db.Model(&models.BehaviorRecord{}).Where("recorded_at >= ? AND status = ?", startDate, models.BehaviorStatusApproved)
 
 	// 权限检查和过滤
 	var childIDs []uint
 	if userRole == "parent" {
-		// 获取家长的所有儿童
+		// 获取家庭中的所有儿童
 		var children []models.User
-		if err := h.db.Where("parent_id = ?", userID).Find(&children).Error; err != nil {
+		if err := h.db.Where("family_id = ? AND role = ?", c.GetUint("family_id"), "child").Find(&children).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to get children"))
 			return
 		}
@@ -97,12 +97,18 @@ func (h *StatisticsHandler) GetStatistics(c *gin.Context) {
 		query = query.Where("user_id = ?", userID)
 	}
 
+	// 后续各项统计共用基础查询条件，使用会话避免条件互相污染
+	query = query.Session(&gorm.Session{})
+
 	// 获取每日统计数据
 	dailyStats := h.getDailyStats(query, startDate, period)
 
 	// 获取分类统计数据
 	categoryStats := h.getCategoryStats(query)
 
+	// 获取行为模板统计数据
+	templateStats := h.getTemplateStats(query)
+
 	// 获取儿童统计数据
 	childrenStats := h.getChildrenStats(childIDs, startDate)
 
@@ -112,6 +118,7 @@ func (h *StatisticsHandler) GetStatistics(c *gin.Context) {
 	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
 		"daily_stats":    dailyStats,
 		"category_stats": categoryStats,
+		"template_stats": templateStats,
 		"children_stats": childrenStats,
 		"overall_stats":  overallStats,
 	}))
@@ -148,6 +155,7 @@ func (h *StatisticsHandler) returnEmptyStatistics(c *gin.Context, period string)
 	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
 		"daily_stats":    emptyDailyStats,
 		"category_stats": []gin.H{},
+		"template_stats": []gin.H{},
 		"children_stats": []gin.H{},
 		"overall_stats": gin.H{
 			"total_behaviors": 0,
@@ -184,7 +192,7 @@ func (h *StatisticsHandler) getDailyStats(baseQuery *gorm.DB, startDate time.Tim
 		var totalPoints int64
 
 		// 创建当日查询条件，继承基础查询的条件
-		dayQuery := h.db.Model(&models.BehaviorRecord{}).Where("DATE(recorded_at) = ?", dateStr)
+		dayQuery := h.db.Model(&models.BehaviorRecord{}).Where("DATE(recorded_at) = ? AND status = ?", dateStr, models.BehaviorStatusApproved)
 
 		// 如果有用户过滤条件，需要添加
 		if baseQuery != nil {
@@ -224,97 +232,96 @@ func (h *StatisticsHandler) getDailyStats(baseQuery *gorm.DB, startDate time.Tim
 	return dailyStats
 }
 
-// getCategoryStats 获取分类统计数据
+// categoryColors 行为分类在数据报告中的颜色
+var categoryColors = map[string]string{
+	models.CategoryLearning: "#3B82F6",
+	models.CategoryLife:     "#10B981",
+	models.CategorySocial:   "#8B5CF6",
+	models.CategoryEmotion:  "#EC4899",
+	models.CategoryExercise: "#F59E0B",
+	models.CategoryEating:   "#EF4444",
+	models.CategoryOther:    "#6B7280",
+}
+
+// getCategoryStats 获取分类统计数据，按存储的行为分类分组汇总
 func (h *StatisticsHandler) getCategoryStats(baseQuery *gorm.DB) []gin.H {
-	// 行为分类映射
-	categoryMap := map[string]string{
-		"learning": "学习",
-		"life":     "生活",
-		"social":   "社交",
-		"emotion":  "情感",
-		"exercise": "运动",
-		"eating":   "饮食",
+	type categoryRow struct {
+		Category string
+		Count    int
+		Points   int
 	}
 
-	categoryColors := map[string]string{
-		"learning": "#3B82F6",
-		"life":     "#10B981",
-		"social":   "#8B5CF6",
-		"emotion":  "#EC4899",
-		"exercise": "#F59E0B",
-		"eating":   "#EF4444",
+	var rows []categoryRow
+	if err := baseQuery.Select("category, COUNT(*) AS count, COALESCE(SUM(points), 0) AS points").
+		Group("category").Order("count DESC").Scan(&rows).Error; err != nil {
+		return []gin.H{}
 	}
 
-	var categoryStats []gin.H
-
-	// 获取基础查询的用户ID条件
-	var userIDs []uint
-	if baseQuery != nil {
-		var tempBehaviors []models.BehaviorRecord
-		baseQuery.Select("DISTINCT user_id").Find(&tempBehaviors)
-		for _, b := range tempBehaviors {
-			userIDs = append(userIDs, b.ChildID)
-		}
+	categoryStats := []gin.H{}
+	for _, row := range rows {
+		category := models.NormalizeBehaviorCategory(row.Category)
+		categoryStats = append(categoryStats, gin.H{
+			"category": models.BehaviorCategoryNames[category],
+			"key":      category,
+			"count":    row.Count,
+			"points":   row.Points,
+			"color":    categoryColors[category],
+		})
 	}
 
-	// 由于当前数据库中没有存储行为分类，我们暂时基于行为描述进行简单分类
-	// 这里可以根据实际需求进行更复杂的分类逻辑
-	for category, name := range categoryMap {
-		var behaviors []models.BehaviorRecord
-		var count int64
-		var totalPoints int64
+	return categoryStats
+}
 
-		// 创建分类查询
-		categoryQuery := h.db.Model(&models.BehaviorRecord{})
+// getTemplateStats 获取行为模板统计数据，未使用模板的记录不计入
+func (h *StatisticsHandler) getTemplateStats(baseQuery *gorm.DB) []gin.H {
+	type templateRow struct {
+		TemplateID uint
+		Count      int
+		Points     int
+	}
 
-		// 添加用户过滤条件
-		if len(userIDs) > 0 {
-			categoryQuery = categoryQuery.Where("user_id IN ?", userIDs)
-		}
+	var rows []templateRow
+	if err := baseQuery.Select("template_id, COUNT(*) AS count, COALESCE(SUM(points), 0) AS points").
+		Where("template_id IS NOT NULL").Group("template_id").Order("count DESC").Scan(&rows).Error; err != nil {
+		return []gin.H{}
+	}
 
-		// 简单的关键词匹配分类（实际项目中应该有更好的分类方法）
-		switch category {
-		case "learning":
-			categoryQuery.Where("behavior_desc LIKE ? OR behavior_desc LIKE ? OR behavior_desc LIKE ?",
-				"%学习%", "%作业%", "%读书%").Find(&behaviors)
-		case "life":
-			categoryQuery.Where("behavior_desc LIKE ? OR behavior_desc LIKE ? OR behavior_desc LIKE ?",
-				"%整理%", "%卫生%", "%生活%").Find(&behaviors)
-		case "social":
-			categoryQuery.Where("behavior_desc LIKE ? OR behavior_desc LIKE ? OR behavior_desc LIKE ?",
-				"%朋友%", "%分享%", "%合作%").Find(&behaviors)
-		case "emotion":
-			categoryQuery.Where("behavior_desc LIKE ? OR behavior_desc LIKE ? OR behavior_desc LIKE ?",
-				"%情绪%", "%开心%", "%生气%").Find(&behaviors)
-		case "exercise":
-			categoryQuery.Where("behavior_desc LIKE ? OR behavior_desc LIKE ? OR behavior_desc LIKE ?",
-				"%运动%", "%跑步%", "%锻炼%").Find(&behaviors)
-		case "eating":
-			categoryQuery.Where("behavior_desc LIKE ? OR behavior_desc LIKE ? OR behavior_desc LIKE ?",
-				"%吃饭%", "%饮食%", "%挑食%").Find(&behaviors)
-		}
+	if len(rows) == 0 {
+		return []gin.H{}
+	}
 
-		count = int64(len(behaviors))
-		for _, behavior := range behaviors {
-			totalPoints += int64(behavior.ScoreChange)
-		}
+	// 批量获取模板信息
+	templateIDs := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		templateIDs = append(templateIDs, row.TemplateID)
+	}
+	var templates []models.BehaviorTemplate
+	h.db.Where("id IN ?", templateIDs).Find(&templates)
+	templateMap := make(map[uint]models.BehaviorTemplate)
+	for _, template := range templates {
+		templateMap[template.ID] = template
+	}
 
-		if count > 0 {
-			categoryStats = append(categoryStats, gin.H{
-				"category": name,
-				"count":    int(count),
-				"points":   int(totalPoints),
-				"color":    categoryColors[category],
-			})
-		}
+	templateStats := []gin.H{}
+	for _, row := range rows {
+		template := templateMap[row.TemplateID]
+		templateStats = append(templateStats, gin.H{
+			"template_id": row.TemplateID,
+			"name":        template.Name,
+			"category":    template.Category,
+			"icon":        template.Icon,
+			"count":       row.Count,
+			"points":      row.Points,
+		})
 	}
 
-	return categoryStats
+	return templateStats
 }
 
 // getChildrenStats 获取儿童统计数据
 func (h *StatisticsHandler) getChildrenStats(childIDs []uint, startDate time.Time) []gin.H {
 	var childrenStats []gin.H
+	levelsByFamily := make(map[uint][]models.Level)
 
 	for _, childID := range childIDs {
 		// 获取儿童信息
@@ -330,8 +337,8 @@ func (h *StatisticsHandler) getChildrenStats(childIDs []uint, startDate time.Tim
 		// 统计行为数据
 		var totalBehaviors int64
 		var positiveBehaviors int64
-		h.db.Model(&models.BehaviorRecord{}).Where("user_id = ? AND recorded_at >= ?", childID, startDate).Count(&totalBehaviors)
-		h.db.Model(&models.BehaviorRecord{}).Where("user_id = ? AND recorded_at >= ? AND behavior_type = ?", childID, startDate, "good").Count(&positiveBehaviors)
+		h.db.Model(&models.BehaviorRecord{}).Where("user_id = ? AND recorded_at >= ? AND status = ?", childID, startDate, models.BehaviorStatusApproved).Count(&totalBehaviors)
+		h.db.Model(&models.BehaviorRecord{}).Where("user_id = ? AND recorded_at >= ? AND status = ? AND behavior_type = ?", childID, startDate, models.BehaviorStatusApproved, "good").Count(&positiveBehaviors)
 
 		// 计算积极率
 		var positiveRate float64
@@ -339,16 +346,17 @@ func (h *StatisticsHandler) getChildrenStats(childIDs []uint, startDate time.Tim
 			positiveRate = float64(positiveBehaviors) / float64(totalBehaviors) * 100
 		}
 
-		// 计算等级（基于总积分）
-		level := 1
-		if userPoints.TotalPoints >= 500 {
-			level = 5
-		} else if userPoints.TotalPoints >= 300 {
-			level = 4
-		} else if userPoints.TotalPoints >= 150 {
-			level = 3
-		} else if userPoints.TotalPoints >= 50 {
-			level = 2
+		// 计算等级（基于总积分和家庭配置的等级表）
+		var level models.Level
+		if child.FamilyID != nil {
+			levels, ok := levelsByFamily[*child.FamilyID]
+			if !ok {
+				levels, _ = models.LoadLevels(h.db, *child.FamilyID)
+				levelsByFamily[*child.FamilyID] = levels
+			}
+			level = models.LevelForPoints(levels, userPoints.TotalPoints)
+		} else {
+			level = models.Level{Level: 1}
 		}
 
 		childrenStats = append(childrenStats, gin.H{
@@ -357,7 +365,8 @@ func (h *StatisticsHandler) getChildrenStats(childIDs []uint, startDate time.Tim
 			"total_behaviors": int(totalBehaviors),
 			"positive_rate":   int(positiveRate),
 			"total_points":    userPoints.TotalPoints,
-			"level":           level,
+			"level":           level.Level,
+			"level_name":      level.Name,
 		})
 	}
 
@@ -374,7 +383,7 @@ func (h *StatisticsHandler) getOverallStats(query *gorm.DB, childIDs []uint) gin
 	query.Count(&totalBehaviors)
 
 	// 统计积极行为数量 - 需要使用相同的过滤条件
-	positiveQuery := h.db.Model(&models.BehaviorRecord{}).Where("behavior_type = ?", "good")
+	positiveQuery := h.db.Model(&models.BehaviorRecord{}).Where("behavior_type = ? AND status = ?", "good", models.BehaviorStatusApproved)
 	if len(childIDs) > 0 {
 		positiveQuery = positiveQuery.Where("user_id IN ?", childIDs)
 	}
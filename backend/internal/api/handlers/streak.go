@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type StreakHandler struct {
+	db *gorm.DB
+}
+
+func NewStreakHandler(db *gorm.DB) *StreakHandler {
+	return &StreakHandler{db: db}
+}
+
+// CreateStreakBonusRuleRequest 创建连续奖励规则请求
+type CreateStreakBonusRuleRequest struct {
+	TemplateID  *uint `json:"template_id"`
+	Days        int   `json:"days" binding:"required,min=2"`
+	BonusPoints int   `json:"bonus_points" binding:"required,min=1"`
+}
+
+// UpdateStreakBonusRuleRequest 更新连续奖励规则请求
+type UpdateStreakBonusRuleRequest struct {
+	Days        *int  `json:"days" binding:"omitempty,min=2"`
+	BonusPoints *int  `json:"bonus_points" binding:"omitempty,min=1"`
+	IsActive    *bool `json:"is_active"`
+}
+
+// streakBonusRuleResponse 构建连续奖励规则返回数据
+func streakBonusRuleResponse(rule models.StreakBonusRule) gin.H {
+	return gin.H{
+		"id":           rule.ID,
+		"template_id":  rule.TemplateID,
+		"days":         rule.Days,
+		"bonus_points": rule.BonusPoints,
+		"is_active":    rule.IsActive,
+		"created_at":   rule.CreatedAt,
+	}
+}
+
+// GetChildStreaks 获取儿童各行为模板的连续天数
+func (h *StreakHandler) GetChildStreaks(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("user_role")
+
+	childID, err := strconv.ParseUint(c.Param("child_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid child ID"))
+		return
+	}
+
+	if status, message := checkChildAccess(h.db, uint(childID), userID.(uint), userRole, c.GetUint("family_id")); status != http.StatusOK {
+		c.JSON(status, utils.ErrorResponse(status, message))
+		return
+	}
+
+	var streaks []models.ChildStreak
+	if err := h.db.Preload("Template").Where("child_id = ?", childID).
+		Order("current_streak DESC, best_streak DESC").Find(&streaks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to get streaks"))
+		return
+	}
+
+	now := time.Now()
+	result := []gin.H{}
+	for _, streak := range streaks {
+		// 后台作业清零前，已中断的连续天数在返回时按0处理
+		broken := streak.IsBroken(now)
+		current := streak.CurrentStreak
+		if broken {
+			current = 0
+		}
+		result = append(result, gin.H{
+			"template_id":    streak.TemplateID,
+			"template_name":  streak.Template.Name,
+			"category":       streak.Template.Category,
+			"current_streak": current,
+			"best_streak":    streak.BestStreak,
+			"last_date":      streak.LastDate,
+			"broken":         broken,
+		})
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(result))
+}
+
+// GetBonusRules 获取连续奖励规则列表
+func (h *StreakHandler) GetBonusRules(c *gin.Context) {
+	familyID := c.GetUint("family_id")
+
+	var rules []models.StreakBonusRule
+	if err := h.db.Where("created_by IN (?)", models.FamilyMemberIDs(h.db, familyID)).Order("days ASC, id ASC").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to get streak bonus rules"))
+		return
+	}
+
+	result := []gin.H{}
+	for _, rule := range rules {
+		result = append(result, streakBonusRuleResponse(rule))
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(result))
+}
+
+// CreateBonusRule 创建连续奖励规则
+func (h *StreakHandler) CreateBonusRule(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	familyID := c.GetUint("family_id")
+
+	var req CreateStreakBonusRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	if req.TemplateID != nil {
+		var template models.BehaviorTemplate
+		if err := h.db.Where("id = ? AND created_by IN (?)", *req.TemplateID, models.FamilyMemberIDs(h.db, familyID)).First(&template).Error; err != nil {
+			c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "Behavior template not found"))
+			return
+		}
+	}
+
+	rule := models.StreakBonusRule{
+		TemplateID:  req.TemplateID,
+		Days:        req.Days,
+		BonusPoints: req.BonusPoints,
+		IsActive:    true,
+		CreatedBy:   userID.(uint),
+	}
+
+	if err := h.db.Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to create streak bonus rule"))
+		return
+	}
+	setAuditChange(c, "streak_bonus_rule", rule.ID, nil, rule)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(streakBonusRuleResponse(rule)))
+}
+
+// UpdateBonusRule 更新连续奖励规则
+func (h *StreakHandler) UpdateBonusRule(c *gin.Context) {
+	familyID := c.GetUint("family_id")
+
+	ruleID, err := strconv.ParseUint(c.Param("rule_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid rule ID"))
+		return
+	}
+
+	var req UpdateStreakBonusRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	var rule models.StreakBonusRule
+	if err := h.db.Where("id = ? AND created_by IN (?)", ruleID, models.FamilyMemberIDs(h.db, familyID)).First(&rule).Error; err != nil {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "Streak bonus rule not found"))
+		return
+	}
+
+	before := rule
+
+	updates := make(map[string]interface{})
+	if req.Days != nil {
+		updates["days"] = *req.Days
+	}
+	if req.BonusPoints != nil {
+		updates["bonus_points"] = *req.BonusPoints
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+
+	if len(updates) > 0 {
+		if err := h.db.Model(&rule).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to update streak bonus rule"))
+			return
+		}
+	}
+	setAuditChange(c, "streak_bonus_rule", rule.ID, before, rule)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(streakBonusRuleResponse(rule)))
+}
+
+// DeleteBonusRule 删除连续奖励规则，已发放的奖励不受影响
+func (h *StreakHandler) DeleteBonusRule(c *gin.Context) {
+	familyID := c.GetUint("family_id")
+
+	ruleID, err := strconv.ParseUint(c.Param("rule_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid rule ID"))
+		return
+	}
+
+	var rule models.StreakBonusRule
+	if err := h.db.Where("id = ? AND created_by IN (?)", ruleID, models.FamilyMemberIDs(h.db, familyID)).First(&rule).Error; err != nil {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "Streak bonus rule not found"))
+		return
+	}
+
+	if err := h.db.Delete(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to delete streak bonus rule"))
+		return
+	}
+	setAuditChange(c, "streak_bonus_rule", rule.ID, rule, nil)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{"message": "Streak bonus rule deleted successfully"}))
+}
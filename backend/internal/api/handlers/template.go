@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type BehaviorTemplateHandler struct {
+	db *gorm.DB
+}
+
+func NewBehaviorTemplateHandler(db *gorm.DB) *BehaviorTemplateHandler {
+	return &BehaviorTemplateHandler{db: db}
+}
+
+// CreateTemplateRequest 创建行为模板请求
+type CreateTemplateRequest struct {
+	Name          string `json:"name" binding:"required,max=100"`
+	Category      string `json:"category" binding:"required"`
+	DefaultPoints int    `json:"default_points" binding:"required"`
+	Icon          string `json:"icon" binding:"max=50"`
+}
+
+// UpdateTemplateRequest 更新行为模板请求
+type UpdateTemplateRequest struct {
+	Name          string `json:"name" binding:"max=100"`
+	Category      string `json:"category"`
+	DefaultPoints *int   `json:"default_points"`
+	Icon          string `json:"icon" binding:"max=50"`
+	IsActive      *bool  `json:"is_active"`
+}
+
+// templateResponse 构建行为模板返回数据
+func templateResponse(template models.BehaviorTemplate) gin.H {
+	return gin.H{
+		"id":             template.ID,
+		"name":           template.Name,
+		"category":       template.Category,
+		"default_points": template.DefaultPoints,
+		"icon":           template.Icon,
+		"is_active":      template.IsActive,
+		"created_at":     template.CreatedAt,
+	}
+}
+
+// GetTemplates 获取行为模板列表
+func (h *BehaviorTemplateHandler) GetTemplates(c *gin.Context) {
+	userRole, _ := c.Get("user_role")
+
+	category := c.Query("category")
+	isActiveStr := c.Query("is_active")
+
+	// 行为目录由家庭成员共享，默认目录在创建家庭时写入
+	memberIDs := models.FamilyMemberIDs(h.db, c.GetUint("family_id"))
+	if userRole != "parent" {
+		// 儿童只能查看启用的模板
+		isActiveStr = "true"
+	}
+
+	query := h.db.Model(&models.BehaviorTemplate{}).Where("created_by IN (?)", memberIDs)
+
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
+
+	if isActiveStr != "" {
+		query = query.Where("is_active = ?", isActiveStr == "true")
+	}
+
+	var templates []models.BehaviorTemplate
+	if err := query.Order("category ASC, id ASC").Find(&templates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to get behavior templates"))
+		return
+	}
+
+	result := []gin.H{}
+	for _, template := range templates {
+		result = append(result, templateResponse(template))
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(result))
+}
+
+// CreateTemplate 创建行为模板
+func (h *BehaviorTemplateHandler) CreateTemplate(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req CreateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	template := models.BehaviorTemplate{
+		Name:          req.Name,
+		Category:      models.NormalizeBehaviorCategory(req.Category),
+		DefaultPoints: req.DefaultPoints,
+		Icon:          req.Icon,
+		IsActive:      true,
+		CreatedBy:     userID.(uint),
+	}
+
+	if err := h.db.Create(&template).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to create behavior template"))
+		return
+	}
+	setAuditChange(c, "template", template.ID, nil, template)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(templateResponse(template)))
+}
+
+// UpdateTemplate 更新行为模板
+func (h *BehaviorTemplateHandler) UpdateTemplate(c *gin.Context) {
+	familyID := c.GetUint("family_id")
+
+	templateID, err := strconv.ParseUint(c.Param("template_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid template ID"))
+		return
+	}
+
+	var req UpdateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	// 验证模板是否属于当前家庭
+	var template models.BehaviorTemplate
+	if err := h.db.Where("id = ? AND created_by IN (?)", templateID, models.FamilyMemberIDs(h.db, familyID)).First(&template).Error; err != nil {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "Behavior template not found"))
+		return
+	}
+
+	before := template
+
+	// 构建更新字段
+	updates := make(map[string]interface{})
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Category != "" {
+		updates["category"] = models.NormalizeBehaviorCategory(req.Category)
+	}
+	if req.DefaultPoints != nil {
+		if *req.DefaultPoints == 0 {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Default points cannot be zero"))
+			return
+		}
+		updates["default_points"] = *req.DefaultPoints
+	}
+	if req.Icon != "" {
+		updates["icon"] = req.Icon
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+
+	if err := h.db.Model(&template).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to update behavior template"))
+		return
+	}
+
+	// 获取更新后的模板信息
+	h.db.First(&template, template.ID)
+	setAuditChange(c, "template", template.ID, before, template)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(templateResponse(template)))
+}
+
+// DeleteTemplate 停用行为模板
+// 模板可能已被行为记录和统计引用，因此只做停用而不物理删除
+func (h *BehaviorTemplateHandler) DeleteTemplate(c *gin.Context) {
+	familyID := c.GetUint("family_id")
+
+	templateID, err := strconv.ParseUint(c.Param("template_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid template ID"))
+		return
+	}
+
+	var template models.BehaviorTemplate
+	if err := h.db.Where("id = ? AND created_by IN (?)", templateID, models.FamilyMemberIDs(h.db, familyID)).First(&template).Error; err != nil {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "Behavior template not found"))
+		return
+	}
+	before := template
+
+	if err := h.db.Model(&template).Update("is_active", false).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to deactivate behavior template"))
+		return
+	}
+	setAuditChange(c, "template", template.ID, before, template)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{"message": "Behavior template deactivated successfully"}))
+}
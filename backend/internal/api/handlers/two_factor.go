@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// totpIssuer 身份验证器应用中显示的服务名称
+const totpIssuer = "Child Behavior"
+
+// TwoFactorCodeRequest 提交两步验证码请求，code 可以是验证码或恢复码
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorLoginRequest 登录第二步请求
+type TwoFactorLoginRequest struct {
+	PreAuthToken string `json:"pre_auth_token" binding:"required"`
+	Code         string `json:"code" binding:"required"`
+}
+
+// DisableTwoFactorRequest 关闭两步验证请求，需要同时验证密码和验证码
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// loadTwoFactorUser 获取当前家长账户，儿童账户不支持两步验证
+func (h *AuthHandler) loadTwoFactorUser(c *gin.Context) (*models.User, bool) {
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("user_role")
+
+	if userRole != "parent" {
+		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Two-factor authentication is only available for parents"))
+		return nil, false
+	}
+
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "User not found"))
+		return nil, false
+	}
+	return &user, true
+}
+
+// verifyTwoFactorCode 校验验证码或恢复码，连续输错会被锁定，失败时已写出响应
+func (h *AuthHandler) verifyTwoFactorCode(c *gin.Context, userID uint, code string) bool {
+	subject := strconv.FormatUint(uint64(userID), 10)
+	if !checkLockout(h.db, c, models.LockoutScopeTwoFactor, subject) {
+		return false
+	}
+
+	err := models.VerifyTwoFactor(h.db, userID, code, time.Now())
+	if errors.Is(err, models.ErrTwoFactorNotEnabled) {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Two-factor authentication is not enabled"))
+		return false
+	}
+	if errors.Is(err, models.ErrTwoFactorCodeInvalid) {
+		respondLoginFailure(h.db, c, models.LockoutScopeTwoFactor, subject, http.StatusUnauthorized, "Invalid verification code")
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to verify code"))
+		return false
+	}
+
+	if err := models.ClearLoginFailures(h.db, models.LockoutScopeTwoFactor, subject); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to reset failed attempts"))
+		return false
+	}
+	return true
+}
+
+// GetTwoFactorStatus 获取两步验证状态和剩余恢复码数量
+func (h *AuthHandler) GetTwoFactorStatus(c *gin.Context) {
+	user, ok := h.loadTwoFactorUser(c)
+	if !ok {
+		return
+	}
+
+	remaining, err := models.CountRecoveryCodes(h.db, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to get recovery codes"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
+		"enabled":                  user.TOTPEnabled,
+		"recovery_codes_remaining": remaining,
+	}))
+}
+
+// EnrollTwoFactor 生成两步验证密钥，返回供身份验证器应用扫码的 otpauth URI
+// 需要在家长模式下操作，调用 VerifyTwoFactor 提交验证码后才会开启
+func (h *AuthHandler) EnrollTwoFactor(c *gin.Context) {
+	user, ok := h.loadTwoFactorUser(c)
+	if !ok {
+		return
+	}
+
+	before := *user
+	secret, err := models.BeginTwoFactorEnrollment(h.db, user)
+	if errors.Is(err, models.ErrTwoFactorEnabled) {
+		c.JSON(http.StatusConflict, utils.ErrorResponse(409, "Two-factor authentication is already enabled"))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to start enrollment"))
+		return
+	}
+	setAccountSecurityChange(c, h.db, before)
+
+	account := utils.GetStringValue(user.Phone)
+	if account == "" {
+		account = user.Nickname
+	}
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
+		"secret":           secret,
+		"provisioning_uri": utils.TOTPProvisioningURI(secret, totpIssuer, account),
+		"digits":           utils.TOTPDigits,
+		"period":           utils.TOTPPeriod,
+	}))
+}
+
+// VerifyTwoFactor 提交身份验证器中的验证码开启两步验证，返回只显示一次的恢复码
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	user, ok := h.loadTwoFactorUser(c)
+	if !ok {
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	codes, err := models.EnableTwoFactor(h.db, user.ID, req.Code, time.Now())
+	switch {
+	case errors.Is(err, models.ErrTwoFactorEnabled):
+		c.JSON(http.StatusConflict, utils.ErrorResponse(409, "Two-factor authentication is already enabled"))
+		return
+	case errors.Is(err, models.ErrTwoFactorNotEnabled):
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Please start enrollment first"))
+		return
+	case errors.Is(err, models.ErrTwoFactorCodeInvalid):
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid verification code"))
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to enable two-factor authentication"))
+		return
+	}
+	setAccountSecurityChange(c, h.db, *user)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
+		"enabled":        true,
+		"recovery_codes": codes,
+	}))
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，之前的恢复码全部作废
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	user, ok := h.loadTwoFactorUser(c)
+	if !ok {
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	if !h.verifyTwoFactorCode(c, user.ID, req.Code) {
+		return
+	}
+
+	codes, err := models.ReplaceRecoveryCodes(h.db, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to generate recovery codes"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{"recovery_codes": codes}))
+}
+
+// DisableTwoFactor 关闭两步验证，需要在家长模式下操作
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	user, ok := h.loadTwoFactorUser(c)
+	if !ok {
+		return
+	}
+
+	var req DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	// 密码与家长模式验证共用失败计数
+	subject := strconv.FormatUint(uint64(user.ID), 10)
+	if !checkLockout(h.db, c, models.LockoutScopeVerifyPassword, subject) {
+		return
+	}
+	if user.Password == nil || !utils.CheckPasswordHash(req.Password, *user.Password) {
+		respondLoginFailure(h.db, c, models.LockoutScopeVerifyPassword, subject, http.StatusUnauthorized, "Invalid password")
+		return
+	}
+	if err := models.ClearLoginFailures(h.db, models.LockoutScopeVerifyPassword, subject); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to reset failed attempts"))
+		return
+	}
+	if !h.verifyTwoFactorCode(c, user.ID, req.Code) {
+		return
+	}
+
+	if err := models.DisableTwoFactor(h.db, user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to disable two-factor authentication"))
+		return
+	}
+	setAccountSecurityChange(c, h.db, *user)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{"enabled": false}))
+}
+
+// TwoFactorLogin 登录第二步，使用两步验证令牌和验证码（或恢复码）换取访问令牌
+func (h *AuthHandler) TwoFactorLogin(c *gin.Context) {
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	claims, err := utils.ParseToken(req.PreAuthToken)
+	if err != nil || claims.Scope != utils.TokenScopePreAuth {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(401, "Pre-auth token is invalid or expired, please log in again"))
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, claims.UserID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(401, "Pre-auth token is invalid or expired, please log in again"))
+		return
+	}
+	setAuditUser(c, &user)
+
+	if !h.verifyTwoFactorCode(c, user.ID, req.Code) {
+		return
+	}
+
+	respondLogin(h.db, c, &user)
+}
@@ -84,12 +84,12 @@ func (h *UploadHandler) UploadFile(c *gin.Context) {
 	fileURL := fmt.Sprintf("/uploads/%s", filename)
 
 	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
-		"filename":     filename,
+		"filename":      filename,
 		"original_name": header.Filename,
-		"size":         header.Size,
-		"content_type": contentType,
-		"url":          fileURL,
-		"uploaded_at":  time.Now(),
+		"size":          header.Size,
+		"content_type":  contentType,
+		"url":           fileURL,
+		"uploaded_at":   time.Now(),
 	}))
 }
 
@@ -155,12 +155,12 @@ func (h *UploadHandler) UploadAvatar(c *gin.Context) {
 	avatarURL := fmt.Sprintf("/uploads/avatars/%s", filename)
 
 	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
-		"filename":     filename,
+		"filename":      filename,
 		"original_name": header.Filename,
-		"size":         header.Size,
-		"content_type": contentType,
-		"url":          avatarURL,
-		"uploaded_at":  time.Now(),
+		"size":          header.Size,
+		"content_type":  contentType,
+		"url":           avatarURL,
+		"uploaded_at":   time.Now(),
 	}))
 }
 
@@ -245,4 +245,4 @@ func (h *UploadHandler) ServeAvatar(c *gin.Context) {
 
 	// 提供文件
 	c.File(avatarPath)
-}
\ No newline at end of file
+}
@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/repository"
+	"child-behavior-app/internal/service"
 	"child-behavior-app/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -12,11 +15,13 @@ import (
 )
 
 type UserHandler struct {
-	db *gorm.DB
+	db       *gorm.DB
+	children *service.ChildService
+	points   *service.PointsService
 }
 
-func NewUserHandler(db *gorm.DB) *UserHandler {
-	return &UserHandler{db: db}
+func NewUserHandler(db *gorm.DB, children *service.ChildService, points *service.PointsService) *UserHandler {
+	return &UserHandler{db: db, children: children, points: points}
 }
 
 // CreateChildRequest 创建儿童账户请求
@@ -29,47 +34,24 @@ type CreateChildRequest struct {
 
 // CreateChild 创建儿童账户
 func (h *UserHandler) CreateChild(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	userRole, _ := c.Get("user_role")
-
-	// 只有家长可以创建儿童账户
-	if userRole != "parent" {
-		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Only parents can create child accounts"))
-		return
-	}
-
 	var req CreateChildRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
 		return
 	}
 
-	parentID := userID.(uint)
-
-	// 创建儿童用户
-	child := models.User{
+	// 创建儿童用户，归入当前家长所在家庭
+	child, err := h.children.Create(currentActor(c), service.ChildProfile{
 		Nickname: req.Nickname,
 		Age:      req.Age,
 		Gender:   req.Gender,
 		Avatar:   req.Avatar,
-		Role:     "child",
-		ParentID: &parentID,
-		// 儿童账户不需要手机号和密码，由家长管理
-		// Phone和Password字段不设置，让它们使用数据库默认值NULL
-	}
-
-	if err := h.db.Create(&child).Error; err != nil {
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to create child account"))
 		return
 	}
-
-	// 为儿童创建积分记录
-	userPoints := models.UserPoints{
-		UserID:          child.ID,
-		TotalPoints:     0,
-		AvailablePoints: 0,
-	}
-	h.db.Create(&userPoints)
+	setAuditChange(c, "child", child.ID, nil, child)
 
 	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
 		"id":               child.ID,
@@ -79,46 +61,32 @@ func (h *UserHandler) CreateChild(c *gin.Context) {
 		"avatar":           child.Avatar,
 		"role":             child.Role,
 		"parent_id":        child.ParentID,
+		"family_id":        child.FamilyID,
 		"total_points":     0,
 		"available_points": 0,
 	}))
 }
 
-// GetChildren 获取儿童列表
+// GetChildren 获取家庭中的儿童列表
 func (h *UserHandler) GetChildren(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	userRole, _ := c.Get("user_role")
-
-	// 只有家长可以获取儿童列表
-	if userRole != "parent" {
-		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Only parents can access children list"))
-		return
-	}
-
-	parentID := userID.(uint)
-
 	// 查询儿童列表及其积分信息
-	var children []models.User
-	if err := h.db.Where("parent_id = ?", parentID).Find(&children).Error; err != nil {
+	children, err := h.children.List(c.GetUint("family_id"))
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to get children list"))
 		return
 	}
 
-	// 获取每个儿童的积分信息
 	var result []gin.H
-	for _, child := range children {
-		var userPoints models.UserPoints
-		h.db.Where("user_id = ?", child.ID).First(&userPoints)
-
+	for _, item := range children {
 		result = append(result, gin.H{
-			"id":               child.ID,
-			"nickname":         child.Nickname,
-			"avatar":           child.Avatar,
-			"role":             child.Role,
-			"parent_id":        child.ParentID,
-			"total_points":     userPoints.TotalPoints,
-			"available_points": userPoints.AvailablePoints,
-			"created_at":       child.CreatedAt,
+			"id":               item.Child.ID,
+			"nickname":         item.Child.Nickname,
+			"avatar":           item.Child.Avatar,
+			"role":             item.Child.Role,
+			"parent_id":        item.Child.ParentID,
+			"total_points":     item.Points.TotalPoints,
+			"available_points": item.Points.AvailablePoints,
+			"created_at":       item.Child.CreatedAt,
 		})
 	}
 
@@ -146,6 +114,7 @@ func (h *UserHandler) GetUserProfile(c *gin.Context) {
 		"avatar":           user.Avatar,
 		"role":             user.Role,
 		"parent_id":        user.ParentID,
+		"family_id":        user.FamilyID,
 		"total_points":     userPoints.TotalPoints,
 		"available_points": userPoints.AvailablePoints,
 		"created_at":       user.CreatedAt,
@@ -192,43 +161,35 @@ func (h *UserHandler) UpdateUserProfile(c *gin.Context) {
 	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{"message": "Profile updated successfully"}))
 }
 
+// pointsErrorResponse 积分查询的访问错误转换为响应
+func pointsErrorResponse(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrPermissionDenied):
+		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Permission denied"))
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "User not found"))
+	default:
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, message))
+	}
+}
+
 // GetUserPoints 获取用户积分
+// 用户只能查看自己的积分，或家长查看家庭中孩子的积分
 func (h *UserHandler) GetUserPoints(c *gin.Context) {
-	userIDParam := c.Param("user_id")
-	currentUserID, _ := c.Get("user_id")
-	currentUserRole, _ := c.Get("user_role")
-
 	// 解析用户ID
-	targetUserID, err := strconv.ParseUint(userIDParam, 10, 32)
+	targetUserID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid user ID"))
 		return
 	}
 
-	// 权限检查：用户只能查看自己的积分，或家长查看自己孩子的积分
-	if currentUserRole == "child" && uint(targetUserID) != currentUserID.(uint) {
-		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Permission denied"))
+	userPoints, err := h.points.Get(currentActor(c), uint(targetUserID))
+	if errors.Is(err, service.ErrPointsNotFound) {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "Points record not found"))
 		return
 	}
-
-	if currentUserRole == "parent" {
-		// 检查是否是自己的孩子
-		var targetUser models.User
-		if err := h.db.First(&targetUser, targetUserID).Error; err != nil {
-			c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "User not found"))
-			return
-		}
-
-		if targetUser.ParentID == nil || *targetUser.ParentID != currentUserID.(uint) {
-			c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Permission denied"))
-			return
-		}
-	}
-
-	// 获取积分信息
-	var userPoints models.UserPoints
-	if err := h.db.Where("user_id = ?", targetUserID).First(&userPoints).Error; err != nil {
-		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "Points record not found"))
+	if err != nil {
+		pointsErrorResponse(c, err, "Failed to get points")
 		return
 	}
 
@@ -239,38 +200,106 @@ func (h *UserHandler) GetUserPoints(c *gin.Context) {
 	}))
 }
 
-// UpdateChild 更新儿童信息
-func (h *UserHandler) UpdateChild(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	userRole, _ := c.Get("user_role")
+// GetPointsLedger 分页获取积分流水
+func (h *UserHandler) GetPointsLedger(c *gin.Context) {
+	targetUserID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid user ID"))
+		return
+	}
 
-	// 只有家长可以更新儿童信息
-	if userRole != "parent" {
-		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Only parents can update child information"))
+	sourceType := c.Query("source_type")
+	pageStr := c.DefaultQuery("page", "1")
+	limitStr := c.DefaultQuery("limit", "20")
+
+	page, _ := strconv.Atoi(pageStr)
+	limit, _ := strconv.Atoi(limitStr)
+	offset := (page - 1) * limit
+
+	transactions, total, err := h.points.Ledger(currentActor(c), uint(targetUserID), sourceType, repository.Page{Offset: offset, Limit: limit})
+	if err != nil {
+		pointsErrorResponse(c, err, "Failed to get point transactions")
 		return
 	}
 
-	childIDParam := c.Param("child_id")
-	childID, err := strconv.ParseUint(childIDParam, 10, 32)
+	result := []gin.H{}
+	for _, txn := range transactions {
+		result = append(result, gin.H{
+			"id":            txn.ID,
+			"source_type":   txn.SourceType,
+			"source_id":     txn.SourceID,
+			"delta":         txn.Delta,
+			"balance_after": txn.BalanceAfter,
+			"total_after":   txn.TotalAfter,
+			"actor_id":      txn.ActorID,
+			"note":          txn.Note,
+			"created_at":    txn.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
+		"transactions": result,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	}))
+}
+
+// AdjustPointsRequest 手动调整积分请求
+type AdjustPointsRequest struct {
+	Delta int    `json:"delta" binding:"required"`
+	Note  string `json:"note" binding:"required,max=255"`
+}
+
+// AdjustPoints 家长手动调整儿童积分
+func (h *UserHandler) AdjustPoints(c *gin.Context) {
+	childID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid child ID"))
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid user ID"))
 		return
 	}
 
-	parentID := userID.(uint)
+	var req AdjustPointsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
 
-	// 验证儿童是否属于当前家长
-	var child models.User
-	if err := h.db.Where("id = ? AND parent_id = ?", childID, parentID).First(&child).Error; err != nil {
+	txn, before, userPoints, err := h.points.Adjust(currentActor(c), uint(childID), req.Delta, req.Note)
+	if errors.Is(err, service.ErrChildNotFound) {
 		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Child not found or permission denied"))
 		return
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to adjust points"))
+		return
+	}
+	setAuditChange(c, "points", txn.UserID, before, userPoints)
 
-	type UpdateChildRequest struct {
-		Nickname string `json:"nickname"`
-		Age      int    `json:"age"`
-		Gender   string `json:"gender"`
-		Avatar   string `json:"avatar"`
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
+		"transaction_id":   txn.ID,
+		"delta":            txn.Delta,
+		"total_points":     userPoints.TotalPoints,
+		"available_points": userPoints.AvailablePoints,
+	}))
+}
+
+// UpdateChildRequest 更新儿童信息请求，空值表示不修改
+type UpdateChildRequest struct {
+	Nickname string `json:"nickname"`
+	Age      int    `json:"age"`
+	Gender   string `json:"gender"`
+	Avatar   string `json:"avatar"`
+}
+
+// UpdateChild 更新儿童信息
+func (h *UserHandler) UpdateChild(c *gin.Context) {
+	childID, err := strconv.ParseUint(c.Param("child_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid child ID"))
+		return
 	}
 
 	var req UpdateChildRequest
@@ -279,28 +308,21 @@ func (h *UserHandler) UpdateChild(c *gin.Context) {
 		return
 	}
 
-	// 更新儿童信息
-	updates := make(map[string]any)
-	if req.Nickname != "" {
-		updates["nickname"] = req.Nickname
-	}
-	if req.Age > 0 {
-		updates["age"] = req.Age
-	}
-	if req.Gender != "" {
-		updates["gender"] = req.Gender
-	}
-	if req.Avatar != "" {
-		updates["avatar"] = req.Avatar
+	before, child, err := h.children.Update(c.GetUint("family_id"), uint(childID), service.ChildProfile{
+		Nickname: req.Nickname,
+		Age:      req.Age,
+		Gender:   req.Gender,
+		Avatar:   req.Avatar,
+	})
+	if errors.Is(err, service.ErrChildNotFound) {
+		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Child not found or permission denied"))
+		return
 	}
-
-	if err := h.db.Model(&models.User{}).Where("id = ?", childID).Updates(updates).Error; err != nil {
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to update child information"))
 		return
 	}
-
-	// 获取更新后的儿童信息
-	h.db.First(&child, childID)
+	setAuditChange(c, "child", child.ID, before, child)
 
 	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
 		"id":        child.ID,
@@ -313,42 +335,24 @@ func (h *UserHandler) UpdateChild(c *gin.Context) {
 	}))
 }
 
-// DeleteChild 删除儿童账户
+// DeleteChild 删除儿童账户及其积分、行为、兑换、登录设备、连续天数和成就
 func (h *UserHandler) DeleteChild(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	userRole, _ := c.Get("user_role")
-
-	// 只有家长可以删除儿童账户
-	if userRole != "parent" {
-		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Only parents can delete child accounts"))
-		return
-	}
-
-	childIDParam := c.Param("child_id")
-	childID, err := strconv.ParseUint(childIDParam, 10, 32)
+	childID, err := strconv.ParseUint(c.Param("child_id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid child ID"))
 		return
 	}
 
-	parentID := userID.(uint)
-
-	// 验证儿童是否属于当前家长
-	var child models.User
-	if err := h.db.Where("id = ? AND parent_id = ?", childID, parentID).First(&child).Error; err != nil {
+	child, err := h.children.Delete(c.GetUint("family_id"), uint(childID))
+	if errors.Is(err, service.ErrChildNotFound) {
 		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Child not found or permission denied"))
 		return
 	}
-
-	// 删除相关数据（级联删除）
-	// 删除积分记录
-	h.db.Where("user_id = ?", childID).Delete(&models.UserPoints{})
-	// 删除行为记录
-	h.db.Where("child_id = ?", childID).Delete(&models.BehaviorRecord{})
-	// 删除兑换记录
-	h.db.Where("user_id = ?", childID).Delete(&models.ExchangeRecord{})
-	// 删除用户记录
-	h.db.Delete(&child)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to delete child account"))
+		return
+	}
+	setAuditChange(c, "child", child.ID, child, nil)
 
 	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
 		"message": "Child account deleted successfully",
@@ -0,0 +1,381 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"gorm.io/gorm"
+)
+
+// webAuthnCeremonyTTL 注册或验证流程的有效期，超时需要重新开始
+const webAuthnCeremonyTTL = 5 * time.Minute
+
+// webAuthnCeremony 保存在缓存中的注册或验证流程，UserID 为 0 表示通行密钥登录
+type webAuthnCeremony struct {
+	UserID  uint
+	Session webauthn.SessionData
+}
+
+type WebAuthnHandler struct {
+	db       *gorm.DB
+	webAuthn *webauthn.WebAuthn
+}
+
+// NewWebAuthnHandler 创建通行密钥处理器，配置无效时相关接口返回503
+func NewWebAuthnHandler(db *gorm.DB, config utils.WebAuthnConfig) *WebAuthnHandler {
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPID:          config.RPID,
+		RPDisplayName: config.RPDisplayName,
+		RPOrigins:     config.RPOrigins,
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			AuthenticatorAttachment: protocol.Platform,
+			ResidentKey:             protocol.ResidentKeyRequirementPreferred,
+			UserVerification:        protocol.VerificationRequired,
+		},
+	})
+	if err != nil {
+		log.Printf("WebAuthn is disabled: %v", err)
+	}
+	return &WebAuthnHandler{db: db, webAuthn: webAuthn}
+}
+
+// WebAuthnFinishRequest 完成注册或验证请求，credential 为浏览器 navigator.credentials 返回的凭据
+type WebAuthnFinishRequest struct {
+	SessionID  string          `json:"session_id" binding:"required"`
+	Name       string          `json:"name" binding:"max=50"` // 仅注册时使用，如“我的iPhone”
+	Credential json.RawMessage `json:"credential" binding:"required"`
+}
+
+// ready 检查 WebAuthn 是否已正确配置
+func (h *WebAuthnHandler) ready(c *gin.Context) bool {
+	if h.webAuthn == nil {
+		c.JSON(http.StatusServiceUnavailable, utils.ErrorResponse(503, "WebAuthn is not configured"))
+		return false
+	}
+	return true
+}
+
+// saveCeremony 保存流程数据，返回客户端完成流程时需要提交的 session_id
+func saveCeremony(userID uint, session *webauthn.SessionData) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	sessionID := base64.RawURLEncoding.EncodeToString(buf)
+	utils.SetCache("webauthn:"+sessionID, webAuthnCeremony{UserID: userID, Session: *session}, webAuthnCeremonyTTL)
+	return sessionID, nil
+}
+
+// takeCeremony 取出流程数据，每个流程只能完成一次
+func takeCeremony(sessionID string, userID uint) (*webAuthnCeremony, bool) {
+	key := "webauthn:" + sessionID
+	value, ok := utils.GetCache(key)
+	if !ok {
+		return nil, false
+	}
+	utils.DeleteCache(key)
+
+	ceremony, ok := value.(webAuthnCeremony)
+	if !ok || ceremony.UserID != userID {
+		return nil, false
+	}
+	return &ceremony, true
+}
+
+// loadParent 获取当前家长账户及其凭据，儿童账户不能使用通行密钥
+func (h *WebAuthnHandler) loadParent(c *gin.Context) (*models.WebAuthnUser, bool) {
+	userID, _ := c.Get("user_id")
+	userRole, _ := c.Get("user_role")
+
+	if userRole != "parent" {
+		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Passkeys are only available for parents"))
+		return nil, false
+	}
+
+	user, err := models.LoadWebAuthnUser(h.db, userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "User not found"))
+		return nil, false
+	}
+	return user, true
+}
+
+// BeginRegistration 开始注册通行密钥，返回传给 navigator.credentials.create 的参数
+func (h *WebAuthnHandler) BeginRegistration(c *gin.Context) {
+	if !h.ready(c) {
+		return
+	}
+	user, ok := h.loadParent(c)
+	if !ok {
+		return
+	}
+
+	// 排除已注册的凭据，避免同一个验证器重复注册
+	options, session, err := h.webAuthn.BeginRegistration(user, webauthn.WithExclusions(user.CredentialDescriptors()))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to begin registration"))
+		return
+	}
+
+	sessionID, err := saveCeremony(user.User.ID, session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to begin registration"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
+		"session_id": sessionID,
+		"options":    options,
+	}))
+}
+
+// FinishRegistration 校验验证器返回的凭据并保存
+func (h *WebAuthnHandler) FinishRegistration(c *gin.Context) {
+	if !h.ready(c) {
+		return
+	}
+	user, ok := h.loadParent(c)
+	if !ok {
+		return
+	}
+
+	var req WebAuthnFinishRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	ceremony, ok := takeCeremony(req.SessionID, user.User.ID)
+	if !ok {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Registration session is invalid or expired"))
+		return
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(req.Credential))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid credential"))
+		return
+	}
+	credential, err := h.webAuthn.CreateCredential(user, ceremony.Session, parsed)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Failed to verify credential"))
+		return
+	}
+
+	record, err := models.SaveWebAuthnCredential(h.db, user.User.ID, req.Name, credential)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to save credential"))
+		return
+	}
+	setAuditChange(c, "passkey", record.ID, nil, record)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(record))
+}
+
+// GetCredentials 获取当前家长已注册的通行密钥
+func (h *WebAuthnHandler) GetCredentials(c *gin.Context) {
+	user, ok := h.loadParent(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(user.Credentials))
+}
+
+// DeleteCredential 删除通行密钥
+func (h *WebAuthnHandler) DeleteCredential(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	credentialID, err := strconv.ParseUint(c.Param("credential_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid credential ID"))
+		return
+	}
+
+	var credential models.WebAuthnCredential
+	if err := h.db.Where("id = ? AND user_id = ?", credentialID, userID).First(&credential).Error; err != nil {
+		c.JSON(http.StatusNotFound, utils.ErrorResponse(404, "Credential not found"))
+		return
+	}
+	if err := h.db.Delete(&credential).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to delete credential"))
+		return
+	}
+	setAuditChange(c, "passkey", credential.ID, credential, nil)
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{"message": "Credential deleted successfully"}))
+}
+
+// BeginVerification 开始使用通行密钥进入家长模式，代替 verify-password 验证密码
+func (h *WebAuthnHandler) BeginVerification(c *gin.Context) {
+	if !h.ready(c) {
+		return
+	}
+	user, ok := h.loadParent(c)
+	if !ok {
+		return
+	}
+	if len(user.Credentials) == 0 {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "No passkey registered"))
+		return
+	}
+
+	options, session, err := h.webAuthn.BeginLogin(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to begin verification"))
+		return
+	}
+
+	sessionID, err := saveCeremony(user.User.ID, session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to begin verification"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
+		"session_id": sessionID,
+		"options":    options,
+	}))
+}
+
+// FinishVerification 校验通行密钥的签名，成功后返回家长模式令牌
+func (h *WebAuthnHandler) FinishVerification(c *gin.Context) {
+	if !h.ready(c) {
+		return
+	}
+	user, ok := h.loadParent(c)
+	if !ok {
+		return
+	}
+
+	var req WebAuthnFinishRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	ceremony, ok := takeCeremony(req.SessionID, user.User.ID)
+	if !ok {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Verification session is invalid or expired"))
+		return
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(req.Credential))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid credential"))
+		return
+	}
+	credential, err := h.webAuthn.ValidateLogin(user, ceremony.Session, parsed)
+	if !h.recordAssertion(c, user.User.ID, credential, err) {
+		return
+	}
+
+	respondElevated(c, user.User.ID)
+}
+
+// BeginLogin 开始通行密钥登录，验证器自行选择账户，无需输入手机号
+func (h *WebAuthnHandler) BeginLogin(c *gin.Context) {
+	if !h.ready(c) {
+		return
+	}
+
+	options, session, err := h.webAuthn.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to begin login"))
+		return
+	}
+
+	sessionID, err := saveCeremony(0, session)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to begin login"))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{
+		"session_id": sessionID,
+		"options":    options,
+	}))
+}
+
+// FinishLogin 校验通行密钥并签发令牌
+// 通行密钥本身要求用户验证（指纹、面容或设备密码），不再要求两步验证码
+func (h *WebAuthnHandler) FinishLogin(c *gin.Context) {
+	if !h.ready(c) {
+		return
+	}
+
+	var req WebAuthnFinishRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, err.Error()))
+		return
+	}
+
+	ceremony, ok := takeCeremony(req.SessionID, 0)
+	if !ok {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Login session is invalid or expired"))
+		return
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(req.Credential))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Invalid credential"))
+		return
+	}
+
+	var user *models.WebAuthnUser
+	credential, err := h.webAuthn.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+		found, err := models.FindWebAuthnUser(h.db, rawID, userHandle)
+		if err != nil {
+			return nil, err
+		}
+		user = found
+		return found, nil
+	}, ceremony.Session, parsed)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(401, "Passkey is not registered"))
+		return
+	}
+	if !h.recordAssertion(c, user.User.ID, credential, err) {
+		return
+	}
+
+	respondLogin(h.db, c, user.User)
+}
+
+// recordAssertion 处理签名校验结果，成功时更新签名计数，失败时已写出响应
+func (h *WebAuthnHandler) recordAssertion(c *gin.Context, userID uint, credential *webauthn.Credential, err error) bool {
+	var protocolErr *protocol.Error
+	if errors.As(err, &protocolErr) {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(401, "Passkey verification failed"))
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to verify passkey"))
+		return false
+	}
+
+	// 签名计数没有增长说明凭据可能被复制，拒绝使用
+	if credential.Authenticator.CloneWarning {
+		c.JSON(http.StatusUnauthorized, utils.ErrorResponse(401, "Passkey may have been cloned, please register it again"))
+		return false
+	}
+
+	if err := models.RecordWebAuthnUse(h.db, userID, credential, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to update credential"))
+		return false
+	}
+	return true
+}
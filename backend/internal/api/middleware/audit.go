@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"child-behavior-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxAuditBodySize 超过该大小的请求体不记录内容
+const maxAuditBodySize = 64 << 10
+
+// AuditMiddleware 审计中间件，记录所有修改数据的请求
+// 需放在 AuthMiddleware 之前，登录后的请求从上下文获取操作人和家庭，
+// 处理器可以通过 models.AuditChangeKey 提供修改对象和修改前后的内容，未提供时记录去掉敏感字段的请求体
+func AuditMiddleware(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		var body []byte
+		if c.ContentType() == "application/json" && c.Request.ContentLength > 0 && c.Request.ContentLength <= maxAuditBodySize {
+			var err error
+			if body, err = io.ReadAll(c.Request.Body); err == nil {
+				c.Request.Body = io.NopCloser(bytes.NewReader(body))
+			}
+		}
+
+		c.Next()
+
+		// 未匹配到路由的请求不记录
+		if c.FullPath() == "" {
+			return
+		}
+
+		event := models.AuditEvent{
+			Action:     c.Request.Method + " " + c.FullPath(),
+			StatusCode: c.Writer.Status(),
+			IP:         c.ClientIP(),
+			UserAgent:  c.Request.UserAgent(),
+		}
+
+		// 默认以路由中的最后一个参数作为修改对象，如 /children/:child_id/devices/:device_id 记为 device
+		if n := len(c.Params); n > 0 {
+			param := c.Params[n-1]
+			event.TargetType = strings.TrimSuffix(param.Key, "_id")
+			event.TargetID = param.Value
+		}
+
+		if userID, ok := c.Get("user_id"); ok {
+			actorID := userID.(uint)
+			event.ActorID = &actorID
+			event.ActorRole = c.GetString("user_role")
+			if familyID := c.GetUint("family_id"); familyID != 0 {
+				event.FamilyID = &familyID
+			}
+		} else if value, ok := c.Get(models.AuditUserKey); ok {
+			// 登录等未登录接口记录到涉及的账户所在家庭，成功时该账户即操作人
+			user := value.(*models.User)
+			event.FamilyID = user.FamilyID
+			event.TargetType = "user"
+			event.TargetID = strconv.FormatUint(uint64(user.ID), 10)
+			if event.StatusCode < http.StatusBadRequest {
+				event.ActorID = &user.ID
+				event.ActorRole = user.Role
+			}
+		}
+
+		if value, ok := c.Get(models.AuditChangeKey); ok {
+			change := value.(models.AuditChange)
+			event.TargetType = change.TargetType
+			event.TargetID = strconv.FormatUint(uint64(change.TargetID), 10)
+			before, after, err := change.Diff()
+			if err != nil {
+				log.Printf("Failed to encode audit change: %v", err)
+			}
+			event.Before = before
+			event.After = after
+		} else if len(body) > 0 {
+			event.After = models.RedactAuditBody(body)
+		}
+
+		// 响应已经写出，写入失败只记录日志
+		if err := models.CreateAuditEvent(db, &event); err != nil {
+			log.Printf("Failed to record audit event: %v", err)
+		}
+	}
+}
@@ -5,20 +5,26 @@ import (
 	"net/http"
 	"strings"
 
+	"child-behavior-app/internal/models"
 	"child-behavior-app/internal/utils"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// DeviceIDHeader 客户端设备标识请求头，绑定设备的令牌必须携带
+const DeviceIDHeader = "X-Device-ID"
+
 // AuthMiddleware JWT认证中间件
-func AuthMiddleware() gin.HandlerFunc {
+// 除校验签名和有效期外，还检查令牌所属会话未被撤销且令牌版本与用户当前版本一致
+func AuthMiddleware(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 跳过OPTIONS预检请求
 		if c.Request.Method == "OPTIONS" {
 			c.Next()
 			return
 		}
-		
+
 		// 获取Authorization头
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -43,33 +49,39 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// 将用户信息存储到上下文
-		c.Set("user_id", claims.UserID)
-		c.Set("user_role", claims.Role)
-		c.Next()
-	}
-}
+		// 两步验证令牌等特殊用途的令牌不能访问接口
+		if claims.Scope != "" {
+			c.JSON(http.StatusUnauthorized, utils.ErrorResponse(401, "Invalid token"))
+			c.Abort()
+			return
+		}
 
-// RoleMiddleware 角色权限中间件
-func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		userRole, exists := c.Get("user_role")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, utils.ErrorResponse(401, "User role not found"))
+		// 绑定设备的令牌只能在对应设备上使用
+		if claims.DeviceID != "" && c.GetHeader(DeviceIDHeader) != claims.DeviceID {
+			c.JSON(http.StatusUnauthorized, utils.ErrorResponse(401, "Token is bound to another device"))
 			c.Abort()
 			return
 		}
 
-		role := userRole.(string)
-		for _, allowedRole := range allowedRoles {
-			if role == allowedRole {
-				c.Next()
-				return
-			}
+		// 退出登录、修改密码后旧令牌立即失效
+		active, err := models.IsSessionActive(db, claims.UserID, claims.SessionID, claims.TokenVersion)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to verify session"))
+			c.Abort()
+			return
+		}
+		if !active {
+			c.JSON(http.StatusUnauthorized, utils.ErrorResponse(401, "Session has expired or been revoked"))
+			c.Abort()
+			return
 		}
 
-		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Insufficient permissions"))
-		c.Abort()
+		// 将用户信息存储到上下文
+		c.Set("user_id", claims.UserID)
+		c.Set("user_role", claims.Role)
+		c.Set("device_id", claims.DeviceID)
+		c.Set("session_id", claims.SessionID)
+		c.Next()
 	}
 }
 
@@ -88,4 +100,4 @@ func LoggerMiddleware() gin.HandlerFunc {
 			param.ErrorMessage,
 		)
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+
+	"child-behavior-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ElevatedTokenHeader 家长模式令牌请求头，由验证密码或通行密钥后获得
+const ElevatedTokenHeader = "X-Elevated-Token"
+
+// ElevatedMiddleware 家长模式中间件，需在 AuthMiddleware 之后使用
+// 要求请求携带当前会话签发的、未过期的家长模式令牌，防止儿童在家长已登录的共用设备上执行敏感操作
+func ElevatedMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+		sessionID, _ := c.Get("session_id")
+
+		token := c.GetHeader(ElevatedTokenHeader)
+		if token == "" {
+			c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Parent verification required"))
+			c.Abort()
+			return
+		}
+
+		// 令牌必须属于当前用户的当前会话，访问令牌撤销后家长模式令牌同时失效
+		claims, err := utils.ParseToken(token)
+		if err != nil || claims.Scope != utils.TokenScopeElevated ||
+			claims.UserID != userID || claims.SessionID != sessionID {
+			c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Parent verification is invalid or expired"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// FamilyMiddleware 家庭中间件，需在 AuthMiddleware 之后使用
+// 家长从成员记录获取家庭和角色，儿童使用账户所属家庭，
+// 结果和该角色在家庭中的权限存入上下文的 family_id、family_role 和 permissions
+func FamilyMiddleware(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+		userRole, _ := c.Get("user_role")
+
+		var familyID uint
+		var familyRole string
+		if userRole == "child" {
+			var child models.User
+			if err := db.Select("id", "family_id").First(&child, userID).Error; err != nil || child.FamilyID == nil {
+				c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Family not found"))
+				c.Abort()
+				return
+			}
+			familyID = *child.FamilyID
+			familyRole = models.FamilyRoleChild
+		} else {
+			member, err := models.GetFamilyMember(db, userID.(uint))
+			if err != nil {
+				c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Family not found"))
+				c.Abort()
+				return
+			}
+			familyID = member.FamilyID
+			familyRole = member.Role
+		}
+
+		permissions, err := models.LoadRolePermissions(db, familyID, familyRole)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to load permissions"))
+			c.Abort()
+			return
+		}
+		granted := make(map[string]bool, len(permissions))
+		for _, permission := range permissions {
+			granted[permission] = true
+		}
+
+		c.Set("family_id", familyID)
+		c.Set("family_role", familyRole)
+		c.Set("permissions", granted)
+		c.Next()
+	}
+}
+
+// PermissionMiddleware 权限中间件，需在 FamilyMiddleware 之后使用，当前角色缺少权限时拒绝访问
+func PermissionMiddleware(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted, _ := c.Get("permissions")
+		if permissions, ok := granted.(map[string]bool); ok && permissions[permission] {
+			c.Next()
+			return
+		}
+
+		c.JSON(http.StatusForbidden, utils.ErrorResponse(403, "Insufficient permissions"))
+		c.Abort()
+	}
+}
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// IdempotencyHeader 客户端重试时携带的幂等键请求头
+	IdempotencyHeader = "Idempotency-Key"
+	// idempotencyKeyTTL 幂等键的保留时间，过期后同一个键可以重新使用
+	idempotencyKeyTTL       = 24 * time.Hour
+	maxIdempotencyKeyLength = 64
+)
+
+// responseRecorder 在写出响应的同时保留一份响应体
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyMiddleware 幂等中间件，需放在 AuthMiddleware 之后
+// 带 Idempotency-Key 的重复请求直接返回首次请求的结果，不会再次执行
+func IdempotencyMiddleware(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Idempotency-Key is too long"))
+			c.Abort()
+			return
+		}
+
+		userID, _ := c.Get("user_id")
+
+		// 计算请求指纹，防止同一个键被用于不同的请求
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, utils.ErrorResponse(400, "Failed to read request body"))
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		hash := sha256.Sum256(append([]byte(c.Request.Method+" "+c.Request.URL.Path+"\n"), body...))
+		requestHash := hex.EncodeToString(hash[:])
+
+		record := models.IdempotencyKey{
+			UserID:      userID.(uint),
+			Key:         key,
+			Method:      c.Request.Method,
+			Path:        c.Request.URL.Path,
+			RequestHash: requestHash,
+		}
+
+		// 唯一索引保证同一个键只有一个请求能占位成功
+		if err := db.Create(&record).Error; err != nil {
+			var existing models.IdempotencyKey
+			if err := db.Where(&models.IdempotencyKey{UserID: record.UserID, Key: key}).First(&existing).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Failed to check idempotency key"))
+				c.Abort()
+				return
+			}
+
+			switch {
+			case time.Since(existing.CreatedAt) > idempotencyKeyTTL:
+				// 过期的键重新占位
+				db.Delete(&existing)
+				if err := db.Create(&record).Error; err != nil {
+					c.JSON(http.StatusConflict, utils.ErrorResponse(409, "A request with this Idempotency-Key is in progress"))
+					c.Abort()
+					return
+				}
+			case existing.RequestHash != requestHash:
+				c.JSON(http.StatusUnprocessableEntity, utils.ErrorResponse(422, "Idempotency-Key was already used for a different request"))
+				c.Abort()
+				return
+			case !existing.Completed:
+				c.JSON(http.StatusConflict, utils.ErrorResponse(409, "A request with this Idempotency-Key is in progress"))
+				c.Abort()
+				return
+			default:
+				// 返回首次请求的结果
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(existing.StatusCode, "application/json; charset=utf-8", []byte(existing.ResponseBody))
+				c.Abort()
+				return
+			}
+		}
+
+		// 处理器 panic 时 gin 的 Recovery 在本中间件之外写出响应，占位需要在这里删除，
+		// 否则同一个键在过期前一直返回409
+		defer func() {
+			if err := recover(); err != nil {
+				db.Delete(&record)
+				panic(err)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// 服务器错误不保存结果，允许客户端使用同一个键重试
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			db.Delete(&record)
+			return
+		}
+
+		db.Model(&record).Updates(map[string]interface{}{
+			"completed":     true,
+			"status_code":   status,
+			"response_body": recorder.body.String(),
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"child-behavior-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitIdleTTL 令牌桶闲置超过该时间后被清理
+const rateLimitIdleTTL = 10 * time.Minute
+
+// tokenBucket 令牌桶，tokens 按速率随时间补充，最多 burst 个
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// RateLimiter 基于令牌桶的内存限流器
+type RateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	rate      float64 // 每秒补充的令牌数
+	burst     float64
+	lastSweep time.Time
+}
+
+// NewRateLimiter 根据配置创建限流器，burst 未配置时使用每分钟请求数
+func NewRateLimiter(config utils.RateLimitConfig) *RateLimiter {
+	burst := config.Burst
+	if burst <= 0 {
+		burst = config.RequestsPerMinute
+	}
+	return &RateLimiter{
+		buckets:   make(map[string]*tokenBucket),
+		rate:      float64(config.RequestsPerMinute) / 60,
+		burst:     float64(burst),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow 尝试从 key 对应的令牌桶取一个令牌，返回是否放行、剩余令牌数和需要等待的时间
+func (l *RateLimiter) Allow(key string, now time.Time) (bool, int, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > rateLimitIdleTTL {
+		for k, bucket := range l.buckets {
+			if now.Sub(bucket.lastSeen) > rateLimitIdleTTL {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, lastSeen: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.lastSeen).Seconds()*l.rate)
+	bucket.lastSeen = now
+
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+		return false, 0, wait
+	}
+	bucket.tokens--
+	return true, int(bucket.tokens), 0
+}
+
+// RateLimitMiddleware 限流中间件
+// 放在 AuthMiddleware 之后时按用户限流，否则按客户端IP限流；未启用或未配置速率时不限制
+func RateLimitMiddleware(config utils.RateLimitConfig) gin.HandlerFunc {
+	if !config.Enabled || config.RequestsPerMinute <= 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	limiter := NewRateLimiter(config)
+	return func(c *gin.Context) {
+		key := "ip:" + c.ClientIP()
+		if userID, exists := c.Get("user_id"); exists {
+			key = fmt.Sprintf("user:%v", userID)
+		}
+
+		allowed, remaining, wait := limiter.Allow(key, time.Now())
+		c.Header("X-RateLimit-Limit", strconv.Itoa(config.RequestsPerMinute))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, utils.ErrorResponse(429, "Too many requests, please try again later"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,90 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+// auditEvent 审计日志中测试用到的字段
+type auditEvent struct {
+	ActorID    *uint                  `json:"actor_id"`
+	ActorRole  string                 `json:"actor_role"`
+	Action     string                 `json:"action"`
+	TargetType string                 `json:"target_type"`
+	TargetID   string                 `json:"target_id"`
+	Before     map[string]interface{} `json:"before"`
+	After      map[string]interface{} `json:"after"`
+}
+
+// auditEvents 获取当前家庭指定操作的审计日志，最新的在前
+func (c *testClient) auditEvents(action string) []auditEvent {
+	c.s.t.Helper()
+	var result struct {
+		Events []auditEvent `json:"events"`
+	}
+	c.mustOK(http.MethodGet, "/api/audit?action="+url.QueryEscape(action), nil, &result)
+	return result.Events
+}
+
+func TestChildPairingLoginAudit(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
+	parent := s.register("爸爸")
+	childID := parent.createChild("小明")
+	parent.loginChild(childID)
+
+	// 配对码登录记录到儿童名下，配对码不写入审计日志
+	events := parent.auditEvents("POST /api/auth/child-login")
+	if len(events) != 1 {
+		t.Fatalf("child login events = %+v", events)
+	}
+	event := events[0]
+	if event.ActorID == nil || *event.ActorID != childID || event.ActorRole != "child" {
+		t.Fatalf("child login actor = %v %q, want child %d", event.ActorID, event.ActorRole, childID)
+	}
+	if _, ok := event.After["pairing_code"]; ok {
+		t.Fatalf("pairing code recorded in audit log: %+v", event.After)
+	}
+	if event.After["device_id"] == nil {
+		t.Fatalf("device ID missing from audit log: %+v", event.After)
+	}
+}
+
+func TestBehaviorChangeAudit(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
+	parent := s.register("爸爸")
+	childID := parent.createChild("小明")
+	behaviorID := parent.recordBehavior(childID, 10)
+
+	parent.mustOK(http.MethodPut, fmt.Sprintf("/api/behaviors/%d", behaviorID), map[string]interface{}{
+		"behavior_desc": "整理书桌", "score_change": 6,
+	}, nil)
+
+	// 修改只记录发生变化的字段的前后内容
+	events := parent.auditEvents("PUT /api/behaviors/:id")
+	if len(events) != 1 {
+		t.Fatalf("update events = %+v", events)
+	}
+	event := events[0]
+	if event.TargetType != "behavior" || event.TargetID != strconv.FormatUint(uint64(behaviorID), 10) {
+		t.Fatalf("update target = %s %s, want behavior %d", event.TargetType, event.TargetID, behaviorID)
+	}
+	if len(event.Before) != 2 || event.Before["behavior_desc"] != "测试行为" || event.Before["score_change"] != float64(10) {
+		t.Fatalf("update before = %+v", event.Before)
+	}
+	if len(event.After) != 2 || event.After["behavior_desc"] != "整理书桌" || event.After["score_change"] != float64(6) {
+		t.Fatalf("update after = %+v", event.After)
+	}
+
+	// 删除记录完整的原记录
+	parent.elevate()
+	parent.mustOK(http.MethodDelete, fmt.Sprintf("/api/behaviors/%d", behaviorID), nil, nil)
+	events = parent.auditEvents("DELETE /api/behaviors/:id")
+	if len(events) != 1 || events[0].After != nil || events[0].Before["score_change"] != float64(6) || events[0].Before["child_id"] != float64(childID) {
+		t.Fatalf("delete events = %+v", events)
+	}
+}
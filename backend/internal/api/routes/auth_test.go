@@ -0,0 +1,85 @@
+package routes_test
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRegisterAndLogin(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
+	parent := s.register("爸爸")
+
+	var profile struct {
+		ID       uint   `json:"id"`
+		Nickname string `json:"nickname"`
+		Role     string `json:"role"`
+	}
+	parent.mustOK(http.MethodGet, "/api/users/profile", nil, &profile)
+	if profile.ID != parent.userID || profile.Nickname != "爸爸" || profile.Role != "parent" {
+		t.Fatalf("profile = %+v", profile)
+	}
+
+	// 同一手机号不能重复注册
+	resp := s.do(http.MethodPost, "/api/auth/register", map[string]string{
+		"phone": parent.phone, "password": testPassword, "nickname": "重复", "role": "parent",
+	}, nil)
+	if resp.Status != http.StatusConflict {
+		t.Fatalf("duplicate register: status %d, want %d", resp.Status, http.StatusConflict)
+	}
+
+	// 密码错误时返回剩余尝试次数
+	resp = s.do(http.MethodPost, "/api/auth/login", map[string]string{"phone": parent.phone, "password": "wrong-password"}, nil)
+	if resp.Status != http.StatusUnauthorized {
+		t.Fatalf("wrong password: status %d, want %d", resp.Status, http.StatusUnauthorized)
+	}
+	var failure struct {
+		RemainingAttempts int `json:"remaining_attempts"`
+	}
+	resp.decode(t, &failure)
+	if failure.RemainingAttempts != 4 {
+		t.Fatalf("remaining attempts = %d, want 4", failure.RemainingAttempts)
+	}
+
+	resp = s.do(http.MethodPost, "/api/auth/login", map[string]string{"phone": parent.phone, "password": testPassword}, nil)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("login: status %d: %s", resp.Status, resp.Message)
+	}
+	var login loginResult
+	resp.decode(t, &login)
+	if login.UserID != parent.userID || login.Token == "" {
+		t.Fatalf("login result = %+v", login)
+	}
+
+	// 新令牌可以访问接口，没有令牌时拒绝访问
+	loggedIn := &testClient{s: s, userID: login.UserID, token: login.Token}
+	loggedIn.mustOK(http.MethodGet, "/api/auth/verify", nil, nil)
+	if resp := s.do(http.MethodGet, "/api/auth/verify", nil, nil); resp.Status != http.StatusUnauthorized {
+		t.Fatalf("verify without token: status %d, want %d", resp.Status, http.StatusUnauthorized)
+	}
+}
+
+func TestLogoutRevokesToken(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
+	parent := s.register("妈妈")
+
+	parent.mustOK(http.MethodPost, "/api/auth/logout", nil, nil)
+	parent.expectStatus(http.StatusUnauthorized, http.MethodGet, "/api/auth/verify", nil)
+}
+
+func TestChangePasswordLocksOutWrongOldPassword(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
+	parent := s.register("爸爸")
+
+	body := map[string]string{"old_password": "wrong-password", "new_password": "new-secret"}
+	for i := 0; i < 4; i++ {
+		parent.expectStatus(http.StatusBadRequest, http.MethodPut, "/api/auth/password", body)
+	}
+	// 第5次失败触发锁定，之后正确的旧密码和家长模式验证同样被拒绝
+	parent.expectStatus(http.StatusTooManyRequests, http.MethodPut, "/api/auth/password", body)
+	body["old_password"] = testPassword
+	parent.expectStatus(http.StatusTooManyRequests, http.MethodPut, "/api/auth/password", body)
+	parent.expectStatus(http.StatusTooManyRequests, http.MethodPost, "/api/auth/verify-password", map[string]string{"password": testPassword})
+}
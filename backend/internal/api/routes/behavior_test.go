@@ -0,0 +1,210 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"child-behavior-app/internal/models"
+)
+
+func TestRecordBehavior(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
+	parent := s.register("爸爸")
+	childID := parent.createChild("小明")
+
+	parent.recordBehavior(childID, 10)
+	parent.recordBehavior(childID, -3)
+	parent.expectPoints(childID, 7)
+
+	// 使用家庭创建时写入的默认行为模板记录
+	var templates []struct {
+		ID            uint   `json:"id"`
+		Name          string `json:"name"`
+		DefaultPoints int    `json:"default_points"`
+	}
+	parent.mustOK(http.MethodGet, "/api/behavior-templates/", nil, &templates)
+	if len(templates) == 0 {
+		t.Fatal("family has no default behavior templates")
+	}
+	template := templates[0]
+	var record struct {
+		ScoreChange  int    `json:"score_change"`
+		BehaviorDesc string `json:"behavior_desc"`
+		Status       string `json:"status"`
+	}
+	parent.mustOK(http.MethodPost, "/api/behaviors/", map[string]interface{}{"child_id": childID, "template_id": template.ID}, &record)
+	if record.ScoreChange != template.DefaultPoints || record.BehaviorDesc != template.Name || record.Status != "approved" {
+		t.Fatalf("template record = %+v, template = %+v", record, template)
+	}
+	parent.expectPoints(childID, 7+template.DefaultPoints)
+
+	// 再次读取模板不会重复写入默认目录
+	var again []struct{}
+	parent.mustOK(http.MethodGet, "/api/behavior-templates/", nil, &again)
+	if len(again) != len(templates) {
+		t.Fatalf("templates after second read = %d, want %d", len(again), len(templates))
+	}
+
+	var ledger struct {
+		Transactions []struct {
+			SourceType   string `json:"source_type"`
+			Delta        int    `json:"delta"`
+			BalanceAfter int    `json:"balance_after"`
+		} `json:"transactions"`
+	}
+	parent.mustOK(http.MethodGet, fmt.Sprintf("/api/users/%d/points/ledger", childID), nil, &ledger)
+	if len(ledger.Transactions) != 3 || ledger.Transactions[2].Delta != 10 || ledger.Transactions[1].BalanceAfter != 7 {
+		t.Fatalf("ledger = %+v", ledger.Transactions)
+	}
+}
+
+func TestRecordBehaviorRejectsOtherFamilyChild(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
+	parent := s.register("爸爸")
+	other := s.register("邻居")
+	childID := parent.createChild("小明")
+
+	other.expectStatus(http.StatusForbidden, http.MethodPost, "/api/behaviors/", map[string]interface{}{
+		"child_id": childID, "behavior_type": "life", "behavior_desc": "测试行为", "score_change": 5,
+	})
+	other.expectStatus(http.StatusForbidden, http.MethodGet, fmt.Sprintf("/api/users/%d/points", childID), nil)
+}
+
+func TestReverseClampedDeduction(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
+	parent := s.register("爸爸")
+	childID := parent.createChild("小明")
+
+	parent.recordBehavior(childID, 3)
+	// 可用积分只有3，扣10分只扣到0
+	deduction := parent.recordBehavior(childID, -10)
+	parent.expectPoints(childID, 0)
+
+	// 删除扣分记录需要家长模式，只退回实际扣除的3分
+	parent.expectStatus(http.StatusForbidden, http.MethodDelete, fmt.Sprintf("/api/behaviors/%d", deduction), nil)
+	parent.elevate()
+	parent.mustOK(http.MethodDelete, fmt.Sprintf("/api/behaviors/%d", deduction), nil, nil)
+	parent.expectPoints(childID, 3)
+
+	// 修改扣分记录按实际扣除的积分计算差额
+	deduction = parent.recordBehavior(childID, -10)
+	parent.expectPoints(childID, 0)
+	parent.mustOK(http.MethodPut, fmt.Sprintf("/api/behaviors/%d", deduction), map[string]int{"score_change": -2}, nil)
+	parent.expectPoints(childID, 1)
+
+	// 撤销同样只退回实际扣除的积分
+	parent.recordBehavior(childID, -10)
+	parent.expectPoints(childID, 0)
+	parent.mustOK(http.MethodPost, "/api/behaviors/undo", nil, nil)
+	parent.expectPoints(childID, 1)
+}
+
+func TestUndoReversesStreakBonus(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
+	parent := s.register("爸爸")
+	childID := parent.createChild("小明")
+
+	var templates []struct {
+		ID            uint `json:"id"`
+		DefaultPoints int  `json:"default_points"`
+	}
+	parent.mustOK(http.MethodGet, "/api/behavior-templates/", nil, &templates)
+	var templateID uint
+	for _, template := range templates {
+		if template.DefaultPoints > 0 {
+			templateID = template.ID
+			break
+		}
+	}
+	if templateID == 0 {
+		t.Fatal("family has no positive behavior template")
+	}
+	parent.mustOK(http.MethodPost, "/api/streak-bonus-rules/", map[string]interface{}{
+		"template_id": templateID, "days": 2, "bonus_points": 20,
+	}, nil)
+
+	// 昨天已经记录过一次，今天再记录连续2天，触发奖励
+	yesterday := time.Now().AddDate(0, 0, -1)
+	previous := models.BehaviorRecord{
+		ChildID: childID, RecorderID: parent.userID, BehaviorType: models.BehaviorGood, Category: models.CategoryLife,
+		BehaviorDesc: "昨天的记录", ScoreChange: 5, TemplateID: &templateID, RecordedAt: yesterday,
+	}
+	if err := s.db.Create(&previous).Error; err != nil {
+		t.Fatalf("create previous record: %v", err)
+	}
+	streak := models.ChildStreak{ChildID: childID, TemplateID: templateID, CurrentStreak: 1, BestStreak: 1, LastDate: yesterday.Format(models.StreakDateLayout)}
+	if err := s.db.Create(&streak).Error; err != nil {
+		t.Fatalf("create streak: %v", err)
+	}
+
+	var record struct {
+		ID          uint `json:"id"`
+		ScoreChange int  `json:"score_change"`
+	}
+	parent.mustOK(http.MethodPost, "/api/behaviors/", map[string]interface{}{"child_id": childID, "template_id": templateID}, &record)
+	parent.expectPoints(childID, record.ScoreChange+20)
+
+	// 撤销删除手动录入的记录，同时冲正它触发的连续奖励
+	var undone struct {
+		UndoneID uint `json:"undone_id"`
+	}
+	parent.mustOK(http.MethodPost, "/api/behaviors/undo", nil, &undone)
+	if undone.UndoneID != record.ID {
+		t.Fatalf("undone record = %d, want %d", undone.UndoneID, record.ID)
+	}
+	parent.expectPoints(childID, 0)
+
+	var remaining []models.BehaviorRecord
+	if err := s.db.Where("user_id = ?", childID).Find(&remaining).Error; err != nil {
+		t.Fatalf("load behavior records: %v", err)
+	}
+	if len(remaining) != 1 || remaining[0].ID != previous.ID {
+		t.Fatalf("remaining records = %+v, want only the previous one", remaining)
+	}
+	if err := s.db.First(&streak, streak.ID).Error; err != nil {
+		t.Fatalf("load streak: %v", err)
+	}
+	if streak.CurrentStreak != 1 || streak.LastDate != previous.RecordedAt.Format(models.StreakDateLayout) {
+		t.Fatalf("streak after undo = %+v", streak)
+	}
+
+	// 当天重新记录再次获得奖励
+	parent.mustOK(http.MethodPost, "/api/behaviors/", map[string]interface{}{"child_id": childID, "template_id": templateID}, nil)
+	parent.expectPoints(childID, record.ScoreChange+20)
+}
+
+func TestChildClaimReview(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
+	parent := s.register("爸爸")
+	childID := parent.createChild("小明")
+	child := parent.loginChild(childID)
+
+	claim := map[string]interface{}{"behavior_type": "life", "behavior_desc": "整理房间", "score_change": 5}
+	var record struct {
+		ID     uint   `json:"id"`
+		Status string `json:"status"`
+	}
+	child.mustOK(http.MethodPost, "/api/behaviors/claims", claim, &record)
+	if record.Status != "pending" {
+		t.Fatalf("claim status = %q, want pending", record.Status)
+	}
+	parent.expectPoints(childID, 0)
+
+	// 儿童不能直接记录行为，也不能审核自己的申报
+	child.expectStatus(http.StatusForbidden, http.MethodPost, "/api/behaviors/", map[string]interface{}{
+		"child_id": childID, "behavior_type": "life", "behavior_desc": "测试行为", "score_change": 5,
+	})
+	child.expectStatus(http.StatusForbidden, http.MethodPost, fmt.Sprintf("/api/behaviors/%d/approve", record.ID), nil)
+
+	parent.mustOK(http.MethodPost, fmt.Sprintf("/api/behaviors/%d/approve", record.ID), nil, nil)
+	parent.expectPoints(childID, 5)
+	child.expectPoints(childID, 5)
+	parent.expectStatus(http.StatusConflict, http.MethodPost, fmt.Sprintf("/api/behaviors/%d/approve", record.ID), nil)
+}
@@ -0,0 +1,111 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// invite 生成指定角色的家庭邀请码
+func (c *testClient) invite(role string) string {
+	c.s.t.Helper()
+	var invite struct {
+		Code string `json:"code"`
+	}
+	c.mustOK(http.MethodPost, "/api/family/invites", map[string]string{"role": role}, &invite)
+	return invite.Code
+}
+
+func TestFamilyRolePermissions(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
+	owner := s.register("爸爸")
+	childID := owner.createChild("小明")
+
+	guardian := s.register("妈妈")
+	guardian.mustOK(http.MethodPost, "/api/family/join", map[string]string{"code": owner.invite("guardian")}, nil)
+	viewer := s.register("奶奶")
+	viewer.mustOK(http.MethodPost, "/api/family/join", map[string]string{"code": owner.invite("viewer")}, nil)
+
+	behavior := map[string]interface{}{"child_id": childID, "behavior_type": "life", "behavior_desc": "测试行为", "score_change": 5}
+
+	// 共同监护人可以记录行为，只读成员只能查看
+	guardian.mustOK(http.MethodPost, "/api/behaviors/", behavior, nil)
+	var children []struct {
+		ID              uint `json:"id"`
+		AvailablePoints int  `json:"available_points"`
+	}
+	viewer.mustOK(http.MethodGet, "/api/children/", nil, &children)
+	if len(children) != 1 || children[0].ID != childID || children[0].AvailablePoints != 5 {
+		t.Fatalf("children seen by viewer = %+v", children)
+	}
+	viewer.expectStatus(http.StatusForbidden, http.MethodPost, "/api/behaviors/", behavior)
+	viewer.expectStatus(http.StatusForbidden, http.MethodPost, "/api/children/", map[string]string{"nickname": "小红"})
+
+	// 只有所有者可以管理成员，共同监护人不能删除儿童
+	guardian.expectStatus(http.StatusForbidden, http.MethodPost, "/api/family/invites", map[string]string{"role": "viewer"})
+	guardian.elevate()
+	guardian.expectStatus(http.StatusForbidden, http.MethodDelete, fmt.Sprintf("/api/children/%d", childID), nil)
+
+	// 调整角色权限需要家长模式，收回只读成员的查看权限后立即生效
+	owner.expectStatus(http.StatusForbidden, http.MethodPut, "/api/family/roles/viewer", map[string][]string{"permissions": {"settings:view"}})
+	owner.elevate()
+	owner.mustOK(http.MethodPut, "/api/family/roles/viewer", map[string][]string{"permissions": {"settings:view"}}, nil)
+	viewer.expectStatus(http.StatusForbidden, http.MethodGet, "/api/children/", nil)
+
+	// 移出家庭后看不到原家庭的儿童
+	owner.mustOK(http.MethodDelete, fmt.Sprintf("/api/family/members/%d", guardian.userID), nil, nil)
+	guardian.expectStatus(http.StatusForbidden, http.MethodGet, fmt.Sprintf("/api/users/%d/points", childID), nil)
+	guardian.mustOK(http.MethodGet, "/api/children/", nil, &children)
+	if len(children) != 0 {
+		t.Fatalf("children seen by removed guardian = %+v", children)
+	}
+}
+
+func TestJoinFamilyLeavesOwnSettingsBehind(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
+	owner := s.register("爸爸")
+	guardian := s.register("妈妈")
+
+	var ownTemplates, familyTemplates []struct{}
+	owner.mustOK(http.MethodGet, "/api/behavior-templates/", nil, &familyTemplates)
+	guardian.mustOK(http.MethodGet, "/api/behavior-templates/", nil, &ownTemplates)
+	if len(ownTemplates) == 0 || len(ownTemplates) != len(familyTemplates) {
+		t.Fatalf("default templates: owner %d, guardian %d", len(familyTemplates), len(ownTemplates))
+	}
+
+	// 加入家庭后只看到新家庭的行为目录，不会带入自己原家庭的默认目录
+	code := owner.invite("guardian")
+	guardian.mustOK(http.MethodPost, "/api/family/join", map[string]string{"code": code}, nil)
+	var joined []struct{}
+	guardian.mustOK(http.MethodGet, "/api/behavior-templates/", nil, &joined)
+	if len(joined) != len(familyTemplates) {
+		t.Fatalf("templates after join = %d, want %d", len(joined), len(familyTemplates))
+	}
+
+	guardian.expectStatus(http.StatusConflict, http.MethodPost, "/api/family/join", map[string]string{"code": owner.invite("viewer")})
+
+	// 有儿童的家庭不能离开
+	other := s.register("邻居")
+	other.createChild("小红")
+	other.expectStatus(http.StatusConflict, http.MethodPost, "/api/family/join", map[string]string{"code": owner.invite("viewer")})
+}
+
+func TestChildPermissions(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
+	parent := s.register("爸爸")
+	childID := parent.createChild("小明")
+	siblingID := parent.createChild("小红")
+	child := parent.loginChild(childID)
+
+	child.expectPoints(childID, 0)
+	child.expectStatus(http.StatusForbidden, http.MethodGet, fmt.Sprintf("/api/users/%d/points", siblingID), nil)
+	child.expectStatus(http.StatusForbidden, http.MethodGet, "/api/children/", nil)
+	child.expectStatus(http.StatusForbidden, http.MethodPost, "/api/family/invites", map[string]string{"role": "viewer"})
+
+	// 儿童令牌绑定设备，没有设备标识时拒绝访问
+	child.deviceID = ""
+	child.expectStatus(http.StatusUnauthorized, http.MethodGet, fmt.Sprintf("/api/users/%d/points", childID), nil)
+}
@@ -0,0 +1,71 @@
+package routes_test
+
+import (
+	"net/http"
+	"testing"
+
+	"child-behavior-app/internal/api/middleware"
+	"child-behavior-app/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIdempotencyKeyReplaysResult(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
+	parent := s.register("爸爸")
+	childID := parent.createChild("小明")
+
+	headers := parent.headers()
+	headers[middleware.IdempotencyHeader] = "record-1"
+	body := map[string]interface{}{"child_id": childID, "behavior_type": "learning", "behavior_desc": "完成作业", "score_change": 10}
+	for i := 0; i < 2; i++ {
+		if resp := s.do(http.MethodPost, "/api/behaviors/", body, headers); resp.Status != http.StatusOK {
+			t.Fatalf("attempt %d: status %d: %s", i+1, resp.Status, resp.Message)
+		}
+	}
+	parent.expectPoints(childID, 10)
+
+	// 同一个键用于不同的请求
+	body["score_change"] = 5
+	if resp := s.do(http.MethodPost, "/api/behaviors/", body, headers); resp.Status != http.StatusUnprocessableEntity {
+		t.Fatalf("different request: status %d, want %d", resp.Status, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestIdempotencyKeyReleasedAfterPanic(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
+	parent := s.register("爸爸")
+
+	// 第一次请求处理器 panic，由外层的 Recovery 写出响应
+	calls := 0
+	s.router.POST("/api/test/panic",
+		gin.CustomRecovery(func(c *gin.Context, _ interface{}) {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, utils.ErrorResponse(500, "Internal server error"))
+		}),
+		middleware.AuthMiddleware(s.db),
+		middleware.IdempotencyMiddleware(s.db),
+		func(c *gin.Context) {
+			calls++
+			if calls == 1 {
+				panic("handler failed")
+			}
+			c.JSON(http.StatusOK, utils.SuccessResponse(gin.H{"calls": calls}))
+		})
+
+	headers := parent.headers()
+	headers[middleware.IdempotencyHeader] = "panic-1"
+	if resp := s.do(http.MethodPost, "/api/test/panic", nil, headers); resp.Status != http.StatusInternalServerError {
+		t.Fatalf("panicking request: status %d, want %d", resp.Status, http.StatusInternalServerError)
+	}
+
+	// 重试时占位已经删除，请求重新执行
+	resp := s.do(http.MethodPost, "/api/test/panic", nil, headers)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("retry: status %d: %s", resp.Status, resp.Message)
+	}
+	if calls != 2 {
+		t.Fatalf("handler calls = %d, want 2", calls)
+	}
+}
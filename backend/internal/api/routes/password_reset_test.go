@@ -0,0 +1,180 @@
+package routes_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/utils"
+)
+
+// resetCodePattern 找回密码短信中的验证码
+var resetCodePattern = regexp.MustCompile(`验证码是 (\d{6})`)
+
+// newResetServer 创建把消息写入文件的测试服务，返回消息文件路径
+func newResetServer(t *testing.T) (*testServer, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "messages.log")
+	s := newTestServer(t, func(config *utils.Config) {
+		config.Notify = utils.NotifyConfig{Sender: "file", FilePath: path}
+	})
+	return s, path
+}
+
+// sentResetCodes 读取发送到手机号的全部验证码，按发送顺序排列
+func sentResetCodes(t *testing.T, path, phone string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("read messages: %v", err)
+	}
+	var codes []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 || fields[1] != phone {
+			continue
+		}
+		if match := resetCodePattern.FindStringSubmatch(fields[2]); match != nil {
+			codes = append(codes, match[1])
+		}
+	}
+	return codes
+}
+
+// requestReset 申请找回密码验证码，接口总是返回成功
+func (s *testServer) requestReset(phone string) {
+	s.t.Helper()
+	resp := s.do(http.MethodPost, "/api/auth/password-reset/request", map[string]string{"phone": phone}, nil)
+	if resp.Status != http.StatusOK {
+		s.t.Fatalf("request reset: status %d: %s", resp.Status, resp.Message)
+	}
+}
+
+// confirmReset 使用验证码重设密码，返回状态码
+func (s *testServer) confirmReset(phone, code, newPassword string) int {
+	s.t.Helper()
+	return s.do(http.MethodPost, "/api/auth/password-reset/confirm", map[string]string{
+		"phone": phone, "code": code, "new_password": newPassword,
+	}, nil).Status
+}
+
+// backdateResetCodes 把用户的验证码发送时间提前，模拟发送间隔已过
+func (s *testServer) backdateResetCodes(userID uint, d time.Duration) {
+	s.t.Helper()
+	var codes []models.PasswordResetCode
+	if err := s.db.Where("user_id = ?", userID).Find(&codes).Error; err != nil {
+		s.t.Fatalf("load reset codes: %v", err)
+	}
+	for _, code := range codes {
+		if err := s.db.Model(&code).Update("created_at", code.CreatedAt.Add(-d)).Error; err != nil {
+			s.t.Fatalf("backdate reset code: %v", err)
+		}
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	t.Parallel()
+	s, messages := newResetServer(t)
+	parent := s.register("爸爸")
+
+	// 未注册的手机号同样返回成功，但不发送验证码
+	s.requestReset("13999999999")
+	if codes := sentResetCodes(t, messages, "13999999999"); len(codes) != 0 {
+		t.Fatalf("codes sent to unregistered phone: %v", codes)
+	}
+
+	s.requestReset(parent.phone)
+	codes := sentResetCodes(t, messages, parent.phone)
+	if len(codes) != 1 {
+		t.Fatalf("sent codes = %v, want one", codes)
+	}
+	code := codes[0]
+
+	// 输错验证码不能重设密码
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	if status := s.confirmReset(parent.phone, wrong, "new-secret"); status != http.StatusBadRequest {
+		t.Fatalf("wrong code: status %d, want %d", status, http.StatusBadRequest)
+	}
+
+	if status := s.confirmReset(parent.phone, code, "new-secret"); status != http.StatusOK {
+		t.Fatalf("confirm reset: status %d", status)
+	}
+
+	// 重设后原会话失效，验证码不能再次使用，新密码可以登录
+	parent.expectStatus(http.StatusUnauthorized, http.MethodGet, "/api/auth/verify", nil)
+	if status := s.confirmReset(parent.phone, code, "another-secret"); status != http.StatusBadRequest {
+		t.Fatalf("reused code: status %d, want %d", status, http.StatusBadRequest)
+	}
+	resp := s.do(http.MethodPost, "/api/auth/login", map[string]string{"phone": parent.phone, "password": "new-secret"}, nil)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("login with new password: status %d: %s", resp.Status, resp.Message)
+	}
+}
+
+func TestPasswordResetNewCodeReplacesOld(t *testing.T) {
+	t.Parallel()
+	s, messages := newResetServer(t)
+	parent := s.register("爸爸")
+
+	s.requestReset(parent.phone)
+	// 间隔内重复申请不再发送
+	s.requestReset(parent.phone)
+	if codes := sentResetCodes(t, messages, parent.phone); len(codes) != 1 {
+		t.Fatalf("sent codes = %v, want one", codes)
+	}
+
+	s.backdateResetCodes(parent.userID, models.PasswordResetInterval)
+	s.requestReset(parent.phone)
+	codes := sentResetCodes(t, messages, parent.phone)
+	if len(codes) != 2 {
+		t.Fatalf("sent codes = %v, want two", codes)
+	}
+
+	// 旧验证码已作废，只有最新的验证码有效
+	if codes[0] != codes[1] {
+		if status := s.confirmReset(parent.phone, codes[0], "new-secret"); status != http.StatusBadRequest {
+			t.Fatalf("replaced code: status %d, want %d", status, http.StatusBadRequest)
+		}
+	}
+	if status := s.confirmReset(parent.phone, codes[1], "new-secret"); status != http.StatusOK {
+		t.Fatalf("latest code: status %d", status)
+	}
+}
+
+func TestPasswordResetDailyLimit(t *testing.T) {
+	t.Parallel()
+	s, messages := newResetServer(t)
+	parent := s.register("爸爸")
+
+	for i := 0; i < models.PasswordResetDailyLimit+1; i++ {
+		s.requestReset(parent.phone)
+		s.backdateResetCodes(parent.userID, models.PasswordResetInterval)
+	}
+	codes := sentResetCodes(t, messages, parent.phone)
+	if len(codes) != models.PasswordResetDailyLimit {
+		t.Fatalf("sent %d codes, want %d", len(codes), models.PasswordResetDailyLimit)
+	}
+
+	// 达到上限后最后一个验证码仍然有效
+	if status := s.confirmReset(parent.phone, codes[len(codes)-1], "new-secret"); status != http.StatusOK {
+		t.Fatalf("last code: status %d", status)
+	}
+
+	// 24小时后可以再次申请
+	s.backdateResetCodes(parent.userID, 24*time.Hour)
+	s.requestReset(parent.phone)
+	if codes := sentResetCodes(t, messages, parent.phone); len(codes) != models.PasswordResetDailyLimit+1 {
+		t.Fatalf("sent %d codes after a day, want %d", len(codes), models.PasswordResetDailyLimit+1)
+	}
+}
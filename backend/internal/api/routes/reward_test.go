@@ -0,0 +1,105 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// createReward 在家长模式下创建奖励，返回奖励ID
+func (c *testClient) createReward(name string, points, stock int, requiresApproval bool) uint {
+	c.s.t.Helper()
+	var reward struct {
+		ID uint `json:"id"`
+	}
+	c.mustOK(http.MethodPost, "/api/rewards/", map[string]interface{}{
+		"name": name, "points_cost": points, "stock": stock, "requires_approval": requiresApproval,
+	}, &reward)
+	return reward.ID
+}
+
+// exchangeResult 兑换奖励的返回数据
+type exchangeResult struct {
+	ExchangeID      uint   `json:"exchange_id"`
+	RemainingPoints int    `json:"remaining_points"`
+	Status          string `json:"status"`
+}
+
+func TestManageRewardsRequiresParentMode(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
+	parent := s.register("爸爸")
+
+	body := map[string]interface{}{"name": "冰淇淋", "points_cost": 30, "stock": 5}
+	parent.expectStatus(http.StatusForbidden, http.MethodPost, "/api/rewards/", body)
+
+	parent.elevate()
+	parent.createReward("冰淇淋", 30, 5, false)
+
+	// 家长模式令牌只属于签发它的会话
+	other := s.register("邻居")
+	other.elevated = parent.elevated
+	other.expectStatus(http.StatusForbidden, http.MethodPost, "/api/rewards/", body)
+}
+
+func TestExchangeReward(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
+	parent := s.register("爸爸")
+	childID := parent.createChild("小明")
+	parent.elevate()
+	rewardID := parent.createReward("冰淇淋", 30, 1, false)
+
+	body := map[string]interface{}{"reward_id": rewardID, "points_used": 30, "child_id": childID}
+	parent.expectStatus(http.StatusBadRequest, http.MethodPost, "/api/rewards/exchange", body)
+
+	parent.recordBehavior(childID, 50)
+	var result exchangeResult
+	parent.mustOK(http.MethodPost, "/api/rewards/exchange", body, &result)
+	if result.Status != "completed" || result.RemainingPoints != 20 {
+		t.Fatalf("exchange = %+v", result)
+	}
+	parent.expectPoints(childID, 20)
+
+	// 库存用完后不能再兑换
+	parent.recordBehavior(childID, 50)
+	parent.expectStatus(http.StatusBadRequest, http.MethodPost, "/api/rewards/exchange", body)
+	parent.expectPoints(childID, 70)
+
+	// 其他家庭看不到这个奖励
+	other := s.register("邻居")
+	otherChildID := other.createChild("小红")
+	other.expectStatus(http.StatusNotFound, http.MethodPost, "/api/rewards/exchange", map[string]interface{}{
+		"reward_id": rewardID, "points_used": 30, "child_id": otherChildID,
+	})
+}
+
+func TestExchangeRequiringApproval(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
+	parent := s.register("爸爸")
+	childID := parent.createChild("小明")
+	parent.elevate()
+	rewardID := parent.createReward("去公园", 40, 2, true)
+	parent.recordBehavior(childID, 100)
+	child := parent.loginChild(childID)
+
+	// 儿童兑换需要确认的奖励时先冻结积分
+	body := map[string]interface{}{"reward_id": rewardID, "points_used": 40}
+	var first, second exchangeResult
+	child.mustOK(http.MethodPost, "/api/rewards/exchange", body, &first)
+	child.mustOK(http.MethodPost, "/api/rewards/exchange", body, &second)
+	if first.Status != "pending" || second.RemainingPoints != 20 {
+		t.Fatalf("exchanges = %+v, %+v", first, second)
+	}
+
+	// 取消时退回积分，完成时积分不变
+	parent.mustOK(http.MethodPut, fmt.Sprintf("/api/rewards/exchanges/%d", first.ExchangeID), map[string]string{"status": "cancelled"}, nil)
+	parent.expectPoints(childID, 60)
+	parent.mustOK(http.MethodPut, fmt.Sprintf("/api/rewards/exchanges/%d", second.ExchangeID), map[string]string{"status": "completed"}, nil)
+	parent.expectPoints(childID, 60)
+	parent.expectStatus(http.StatusConflict, http.MethodPut, fmt.Sprintf("/api/rewards/exchanges/%d", second.ExchangeID), map[string]string{"status": "cancelled"})
+
+	// 儿童不能自己确认兑换
+	child.expectStatus(http.StatusForbidden, http.MethodPut, fmt.Sprintf("/api/rewards/exchanges/%d", first.ExchangeID), map[string]string{"status": "completed"})
+}
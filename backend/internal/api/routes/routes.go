@@ -3,26 +3,47 @@ package routes
 import (
 	"child-behavior-app/internal/api/handlers"
 	"child-behavior-app/internal/api/middleware"
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/notify"
+	"child-behavior-app/internal/repository"
+	"child-behavior-app/internal/service"
+	"child-behavior-app/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 // SetupRoutes 设置所有路由
-func SetupRoutes(r *gin.Engine, db *gorm.DB) {
+func SetupRoutes(r *gin.Engine, db *gorm.DB, config *utils.Config) {
+	// 创建服务，儿童、行为、奖励和积分的业务规则通过数据访问接口读写数据
+	store := repository.NewGormStore(db)
+	childService := service.NewChildService(store)
+	pointsService := service.NewPointsService(store)
+	behaviorService := service.NewBehaviorService(store)
+	rewardService := service.NewRewardService(store)
+
 	// 创建处理器实例
 	authHandler := handlers.NewAuthHandler(db)
-	userHandler := handlers.NewUserHandler(db)
-	behaviorHandler := handlers.NewBehaviorHandler(db)
-	rewardHandler := handlers.NewRewardHandler(db)
+	passwordResetHandler := handlers.NewPasswordResetHandler(db, notify.NewSender(config.Notify))
+	webAuthnHandler := handlers.NewWebAuthnHandler(db, config.WebAuthn)
+	userHandler := handlers.NewUserHandler(db, childService, pointsService)
+	behaviorHandler := handlers.NewBehaviorHandler(behaviorService)
+	rewardHandler := handlers.NewRewardHandler(rewardService)
+	templateHandler := handlers.NewBehaviorTemplateHandler(db)
+	choreHandler := handlers.NewChoreHandler(db)
+	streakHandler := handlers.NewStreakHandler(db)
+	achievementHandler := handlers.NewAchievementHandler(db)
+	familyHandler := handlers.NewFamilyHandler(db)
 	statisticsHandler := handlers.NewStatisticsHandler(db)
+	auditHandler := handlers.NewAuditHandler(db)
 	uploadHandler := handlers.NewUploadHandler()
 
 	// 添加全局中间件
 	r.Use(middleware.LoggerMiddleware())
 
-	// API版本分组
+	// API版本分组，未登录的请求按IP限流，被限流的请求不写入审计日志
 	v1 := r.Group("/api")
+	v1.Use(middleware.RateLimitMiddleware(config.Security.RateLimit), middleware.AuditMiddleware(db))
 
 	// 公开路由（不需要认证）
 	public := v1.Group("/")
@@ -32,6 +53,15 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 		{
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
+			auth.POST("/child-login", authHandler.ChildLogin)
+			auth.POST("/refresh", authHandler.RefreshToken)
+			auth.POST("/2fa/login", authHandler.TwoFactorLogin)
+			// 通行密钥登录
+			auth.POST("/webauthn/login/begin", webAuthnHandler.BeginLogin)
+			auth.POST("/webauthn/login/finish", webAuthnHandler.FinishLogin)
+			// 找回密码
+			auth.POST("/password-reset/request", passwordResetHandler.RequestReset)
+			auth.POST("/password-reset/confirm", passwordResetHandler.ConfirmReset)
 		}
 
 		// 文件服务
@@ -40,56 +70,151 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 	}
 
 	// 需要认证的路由
+	// 家长的权限由其在家庭中的角色决定，儿童使用固定的儿童权限
 	protected := v1.Group("/")
-	protected.Use(middleware.AuthMiddleware())
+	// 登录后的请求再按用户限流，同一用户在多个设备上的请求共享额度
+	protected.Use(middleware.AuthMiddleware(db), middleware.RateLimitMiddleware(config.Security.RateLimit), middleware.FamilyMiddleware(db))
+	require := middleware.PermissionMiddleware
+	// 删除儿童、管理奖励等敏感操作还需要家长重新验证身份后获得的家长模式令牌
+	elevated := middleware.ElevatedMiddleware()
 	{
 		// 认证验证
 		protected.GET("/auth/verify", authHandler.VerifyToken)
 		protected.POST("/auth/verify-password", authHandler.VerifyPassword)
 		protected.PUT("/auth/password", authHandler.ChangePassword)
+		protected.POST("/auth/logout", authHandler.Logout)
+		protected.POST("/auth/logout-all", authHandler.LogoutAll)
+		// 两步验证（仅家长）
+		protected.GET("/auth/2fa", authHandler.GetTwoFactorStatus)
+		protected.POST("/auth/2fa/enroll", elevated, authHandler.EnrollTwoFactor)
+		protected.POST("/auth/2fa/verify", elevated, authHandler.VerifyTwoFactor)
+		protected.POST("/auth/2fa/recovery-codes", authHandler.RegenerateRecoveryCodes)
+		protected.POST("/auth/2fa/disable", elevated, authHandler.DisableTwoFactor)
+		// 通行密钥（仅家长），可代替密码进入家长模式，注册和删除需要家长模式
+		protected.POST("/auth/webauthn/register/begin", elevated, webAuthnHandler.BeginRegistration)
+		protected.POST("/auth/webauthn/register/finish", elevated, webAuthnHandler.FinishRegistration)
+		protected.GET("/auth/webauthn/credentials", webAuthnHandler.GetCredentials)
+		protected.DELETE("/auth/webauthn/credentials/:credential_id", elevated, webAuthnHandler.DeleteCredential)
+		protected.POST("/auth/webauthn/verify/begin", webAuthnHandler.BeginVerification)
+		protected.POST("/auth/webauthn/verify/finish", webAuthnHandler.FinishVerification)
 		// 用户相关
 		users := protected.Group("/users")
 		{
 			users.GET("/profile", userHandler.GetUserProfile)
 			users.PUT("/profile", userHandler.UpdateUserProfile)
 			users.GET("/:user_id/points", userHandler.GetUserPoints)
+			users.GET("/:user_id/points/ledger", userHandler.GetPointsLedger)
+			users.POST("/:user_id/points/adjust", require(models.PermPointsAdjust), userHandler.AdjustPoints)
 		}
 
-		// 儿童管理（仅家长）
+		// 儿童管理
 		children := protected.Group("/children")
-		children.Use(middleware.RoleMiddleware("parent"))
 		{
-			children.POST("/", userHandler.CreateChild)
-			children.GET("/", userHandler.GetChildren)
-			children.PUT("/:child_id", userHandler.UpdateChild)
-			children.DELETE("/:child_id", userHandler.DeleteChild)
+			children.POST("/", require(models.PermChildrenManage), userHandler.CreateChild)
+			children.GET("/", require(models.PermChildrenView), userHandler.GetChildren)
+			children.PUT("/:child_id", require(models.PermChildrenManage), userHandler.UpdateChild)
+			children.DELETE("/:child_id", require(models.PermChildrenDelete), elevated, userHandler.DeleteChild)
+			// 儿童登录凭据：配对码、PIN和已配对设备
+			children.POST("/:child_id/pairing-code", require(models.PermChildrenManage), authHandler.CreatePairingCode)
+			children.PUT("/:child_id/pin", require(models.PermChildrenManage), authHandler.SetChildPin)
+			children.GET("/:child_id/devices", require(models.PermChildrenView), authHandler.GetChildDevices)
+			children.DELETE("/:child_id/devices/:device_id", require(models.PermChildrenManage), authHandler.DeleteChildDevice)
+			// 连续天数和成就（儿童可查看自己的）
+			children.GET("/:child_id/streaks", streakHandler.GetChildStreaks)
+			children.GET("/:child_id/achievements", achievementHandler.GetChildAchievements)
 		}
 
 		// 行为管理
 		behaviors := protected.Group("/behaviors")
 		{
-			behaviors.GET("/", behaviorHandler.GetBehaviors)
-			behaviors.GET("/trend", behaviorHandler.GetBehaviorTrend)
-			// 记录行为（仅家长）
-			behaviors.POST("/", middleware.RoleMiddleware("parent"), behaviorHandler.RecordBehavior)
+			behaviors.GET("/", require(models.PermBehaviorsView), behaviorHandler.GetBehaviors)
+			behaviors.GET("/trend", require(models.PermReportsView), behaviorHandler.GetBehaviorTrend)
+			behaviors.POST("/", require(models.PermBehaviorsRecord), middleware.IdempotencyMiddleware(db), behaviorHandler.RecordBehavior)
+			// 儿童自主申报，家长审核
+			behaviors.POST("/claims", require(models.PermBehaviorsClaim), behaviorHandler.SubmitClaim)
+			behaviors.GET("/pending", require(models.PermBehaviorsReview), behaviorHandler.GetPendingClaims)
+			behaviors.POST("/:id/approve", require(models.PermBehaviorsReview), behaviorHandler.ApproveClaim)
+			behaviors.POST("/:id/reject", require(models.PermBehaviorsReview), behaviorHandler.RejectClaim)
+			// 修改、删除和撤销行为记录
+			behaviors.POST("/undo", require(models.PermBehaviorsRecord), behaviorHandler.UndoLastBehavior)
+			behaviors.PUT("/:id", require(models.PermBehaviorsRecord), behaviorHandler.UpdateBehavior)
+			behaviors.DELETE("/:id", require(models.PermBehaviorsRecord), elevated, behaviorHandler.DeleteBehavior)
+		}
+
+		// 行为模板
+		templates := protected.Group("/behavior-templates")
+		{
+			templates.GET("/", templateHandler.GetTemplates)
+			templates.POST("/", require(models.PermTemplatesManage), templateHandler.CreateTemplate)
+			templates.PUT("/:template_id", require(models.PermTemplatesManage), templateHandler.UpdateTemplate)
+			templates.DELETE("/:template_id", require(models.PermTemplatesManage), templateHandler.DeleteTemplate)
+		}
+
+		// 周期任务
+		chores := protected.Group("/chores")
+		{
+			chores.GET("/", choreHandler.GetChores)
+			chores.GET("/instances", choreHandler.GetChoreInstances)
+			chores.POST("/instances/:instance_id/complete", require(models.PermChoresComplete), choreHandler.CompleteChoreInstance)
+			chores.POST("/", require(models.PermChoresManage), choreHandler.CreateChore)
+			chores.PUT("/:chore_id", require(models.PermChoresManage), choreHandler.UpdateChore)
+			chores.DELETE("/:chore_id", require(models.PermChoresManage), choreHandler.DeleteChore)
+		}
+
+		// 连续奖励规则
+		streakRules := protected.Group("/streak-bonus-rules")
+		{
+			streakRules.GET("/", require(models.PermSettingsView), streakHandler.GetBonusRules)
+			streakRules.POST("/", require(models.PermSettingsManage), streakHandler.CreateBonusRule)
+			streakRules.PUT("/:rule_id", require(models.PermSettingsManage), streakHandler.UpdateBonusRule)
+			streakRules.DELETE("/:rule_id", require(models.PermSettingsManage), streakHandler.DeleteBonusRule)
+		}
+
+		// 成就规则和等级配置
+		achievements := protected.Group("/achievements")
+		{
+			achievements.GET("/", require(models.PermSettingsView), achievementHandler.GetAchievements)
+			achievements.POST("/", require(models.PermSettingsManage), achievementHandler.CreateAchievement)
+			achievements.PUT("/:achievement_id", require(models.PermSettingsManage), achievementHandler.UpdateAchievement)
+			achievements.DELETE("/:achievement_id", require(models.PermSettingsManage), achievementHandler.DeleteAchievement)
+		}
+		levels := protected.Group("/levels")
+		{
+			levels.GET("/", require(models.PermSettingsView), achievementHandler.GetLevels)
+			levels.PUT("/", require(models.PermSettingsManage), achievementHandler.UpdateLevels)
 		}
 
 		// 统计报告
 		statistics := protected.Group("/statistics")
 		{
-			statistics.GET("/", statisticsHandler.GetStatistics)
+			statistics.GET("/", require(models.PermReportsView), statisticsHandler.GetStatistics)
 		}
 
 		// 奖励管理
 		rewards := protected.Group("/rewards")
 		{
 			rewards.GET("/", rewardHandler.GetRewards)
-			rewards.POST("/exchange", rewardHandler.ExchangeReward)
+			rewards.POST("/exchange", require(models.PermRewardsExchange), middleware.IdempotencyMiddleware(db), rewardHandler.ExchangeReward)
 			rewards.GET("/exchanges", rewardHandler.GetExchangeRecords)
-			// 创建和更新奖励（仅家长）
-			rewards.POST("/", middleware.RoleMiddleware("parent"), rewardHandler.CreateReward)
-			rewards.PUT("/:reward_id", middleware.RoleMiddleware("parent"), rewardHandler.UpdateReward)
-			rewards.DELETE("/:reward_id", middleware.RoleMiddleware("parent"), rewardHandler.DeleteReward)
+			// 处理待确认的兑换
+			rewards.PUT("/exchanges/:exchange_id", require(models.PermExchangesReview), rewardHandler.UpdateExchange)
+			// 创建、更新和删除奖励
+			rewards.POST("/", require(models.PermRewardsManage), elevated, rewardHandler.CreateReward)
+			rewards.PUT("/:reward_id", require(models.PermRewardsManage), elevated, rewardHandler.UpdateReward)
+			rewards.DELETE("/:reward_id", require(models.PermRewardsManage), elevated, rewardHandler.DeleteReward)
+		}
+
+		// 家庭、成员和角色权限管理
+		family := protected.Group("/family")
+		{
+			family.GET("/", familyHandler.GetFamily)
+			family.POST("/join", familyHandler.JoinFamily)
+			family.POST("/invites", require(models.PermFamilyManage), familyHandler.CreateInvite)
+			family.PUT("/members/:user_id", require(models.PermFamilyManage), elevated, familyHandler.UpdateMember)
+			family.DELETE("/members/:user_id", require(models.PermFamilyManage), elevated, familyHandler.RemoveMember)
+			family.GET("/roles", familyHandler.GetRoles)
+			family.PUT("/roles/:role", require(models.PermFamilyManage), elevated, familyHandler.UpdateRole)
+			family.DELETE("/roles/:role", require(models.PermFamilyManage), elevated, familyHandler.DeleteRole)
 		}
 
 		// 文件上传
@@ -98,6 +223,9 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 			uploads.POST("/file", uploadHandler.UploadFile)
 			uploads.POST("/avatar", uploadHandler.UploadAvatar)
 		}
+
+		// 审计日志
+		protected.GET("/audit", require(models.PermAuditView), auditHandler.GetAuditEvents)
 	}
 
 	// 健康检查
@@ -116,23 +244,98 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 			"description": "API for managing children's behavior and rewards",
 			"endpoints": gin.H{
 				"auth": gin.H{
-					"register": "POST /api/auth/register",
-					"login":    "POST /api/auth/login",
+					"register":               "POST /api/auth/register",
+					"login":                  "POST /api/auth/login",
+					"child_login":            "POST /api/auth/child-login",
+					"refresh":                "POST /api/auth/refresh",
+					"password_reset_request": "POST /api/auth/password-reset/request",
+					"password_reset_confirm": "POST /api/auth/password-reset/confirm",
+					"logout":                 "POST /api/auth/logout",
+					"logout_all":             "POST /api/auth/logout-all",
+					"two_factor_status":      "GET /api/auth/2fa",
+					"two_factor_enroll":      "POST /api/auth/2fa/enroll",
+					"two_factor_verify":      "POST /api/auth/2fa/verify",
+					"two_factor_login":       "POST /api/auth/2fa/login",
+					"recovery_codes":         "POST /api/auth/2fa/recovery-codes",
+					"two_factor_disable":     "POST /api/auth/2fa/disable",
+					"passkey_register":       "POST /api/auth/webauthn/register/begin, /finish",
+					"passkey_credentials":    "GET /api/auth/webauthn/credentials",
+					"passkey_delete":         "DELETE /api/auth/webauthn/credentials/:credential_id",
+					"passkey_verify":         "POST /api/auth/webauthn/verify/begin, /finish",
+					"passkey_login":          "POST /api/auth/webauthn/login/begin, /finish",
 				},
 				"users": gin.H{
 					"profile": "GET/PUT /api/users/profile",
 					"points":  "GET /api/users/:user_id/points",
+					"ledger":  "GET /api/users/:user_id/points/ledger",
+					"adjust":  "POST /api/users/:user_id/points/adjust",
+				},
+				"family": gin.H{
+					"info":          "GET /api/family",
+					"invite":        "POST /api/family/invites",
+					"join":          "POST /api/family/join",
+					"update_member": "PUT /api/family/members/:user_id",
+					"remove_member": "DELETE /api/family/members/:user_id",
+					"roles":         "GET /api/family/roles",
+					"update_role":   "PUT /api/family/roles/:role",
+					"delete_role":   "DELETE /api/family/roles/:role",
+				},
+				"audit": gin.H{
+					"list": "GET /api/audit",
 				},
 				"children": gin.H{
-					"list":   "GET /api/children",
-					"create": "POST /api/children",
-					"update": "PUT /api/children/:child_id",
-					"delete": "DELETE /api/children/:child_id",
+					"list":         "GET /api/children",
+					"create":       "POST /api/children",
+					"update":       "PUT /api/children/:child_id",
+					"delete":       "DELETE /api/children/:child_id",
+					"streaks":      "GET /api/children/:child_id/streaks",
+					"achievements": "GET /api/children/:child_id/achievements",
+					"pairing_code": "POST /api/children/:child_id/pairing-code",
+					"pin":          "PUT /api/children/:child_id/pin",
+					"devices":      "GET /api/children/:child_id/devices",
+					"unpair":       "DELETE /api/children/:child_id/devices/:device_id",
 				},
 				"behaviors": gin.H{
-					"list":   "GET /api/behaviors",
-					"record": "POST /api/behaviors",
-					"trend":  "GET /api/behaviors/trend",
+					"list":    "GET /api/behaviors",
+					"record":  "POST /api/behaviors",
+					"trend":   "GET /api/behaviors/trend",
+					"update":  "PUT /api/behaviors/:id",
+					"delete":  "DELETE /api/behaviors/:id",
+					"undo":    "POST /api/behaviors/undo",
+					"claim":   "POST /api/behaviors/claims",
+					"pending": "GET /api/behaviors/pending",
+					"approve": "POST /api/behaviors/:id/approve",
+					"reject":  "POST /api/behaviors/:id/reject",
+				},
+				"behavior_templates": gin.H{
+					"list":   "GET /api/behavior-templates",
+					"create": "POST /api/behavior-templates",
+					"update": "PUT /api/behavior-templates/:template_id",
+					"delete": "DELETE /api/behavior-templates/:template_id",
+				},
+				"chores": gin.H{
+					"list":      "GET /api/chores",
+					"create":    "POST /api/chores",
+					"update":    "PUT /api/chores/:chore_id",
+					"delete":    "DELETE /api/chores/:chore_id",
+					"instances": "GET /api/chores/instances?date=YYYY-MM-DD",
+					"complete":  "POST /api/chores/instances/:instance_id/complete",
+				},
+				"streak_bonus_rules": gin.H{
+					"list":   "GET /api/streak-bonus-rules",
+					"create": "POST /api/streak-bonus-rules",
+					"update": "PUT /api/streak-bonus-rules/:rule_id",
+					"delete": "DELETE /api/streak-bonus-rules/:rule_id",
+				},
+				"achievements": gin.H{
+					"list":   "GET /api/achievements",
+					"create": "POST /api/achievements",
+					"update": "PUT /api/achievements/:achievement_id",
+					"delete": "DELETE /api/achievements/:achievement_id",
+				},
+				"levels": gin.H{
+					"list":    "GET /api/levels",
+					"replace": "PUT /api/levels",
 				},
 				"rewards": gin.H{
 					"list":      "GET /api/rewards",
@@ -140,6 +343,7 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 					"update":    "PUT /api/rewards/:reward_id",
 					"exchange":  "POST /api/rewards/exchange",
 					"exchanges": "GET /api/rewards/exchanges",
+					"resolve":   "PUT /api/rewards/exchanges/:exchange_id",
 				},
 				"upload": gin.H{
 					"file":   "POST /api/upload/file",
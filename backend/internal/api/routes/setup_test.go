@@ -0,0 +1,272 @@
+package routes_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"child-behavior-app/internal/api/middleware"
+	"child-behavior-app/internal/api/routes"
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/utils"
+	"child-behavior-app/migrations"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	// testPassword 测试家长的登录密码
+	testPassword = "secret123"
+	// testOrigin 测试请求的前端来源
+	testOrigin = "http://localhost:5173"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	if err := utils.InitJWT(); err != nil {
+		panic(err)
+	}
+	utils.InitCache()
+}
+
+// testServer 使用 SQLite 内存数据库和完整路由的测试服务，每个测试一个独立的数据库
+type testServer struct {
+	t      *testing.T
+	db     *gorm.DB
+	router *gin.Engine
+	config *utils.Config
+}
+
+// newTestServer 创建测试服务，configure 可以在创建路由前修改配置
+func newTestServer(t *testing.T, configure ...func(*utils.Config)) *testServer {
+	t.Helper()
+
+	db, err := models.InitDBWithConfig(utils.DatabaseConfig{Driver: utils.DatabaseDriverSQLite, Path: utils.SQLiteMemoryPath})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	db = db.Session(&gorm.Session{Logger: logger.Default.LogMode(logger.Silent)})
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("get sql.DB: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	config := &utils.Config{
+		Notify: utils.NotifyConfig{Sender: "log"},
+		WebAuthn: utils.WebAuthnConfig{
+			RPID:          "localhost",
+			RPDisplayName: "Child Behavior",
+			RPOrigins:     []string{testOrigin},
+		},
+	}
+	for _, fn := range configure {
+		fn(config)
+	}
+
+	router := gin.New()
+	routes.SetupRoutes(router, db, config)
+	return &testServer{t: t, db: db, router: router, config: config}
+}
+
+// testResponse 统一格式的接口响应
+type testResponse struct {
+	Status  int
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// decode 把响应数据解析到 v
+func (r *testResponse) decode(t *testing.T, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(r.Data, v); err != nil {
+		t.Fatalf("decode response data %s: %v", r.Data, err)
+	}
+}
+
+// do 发送请求，body 为 nil 时不带请求体
+func (s *testServer) do(method, path string, body interface{}, headers map[string]string) *testResponse {
+	s.t.Helper()
+
+	var reader *bytes.Reader
+	if body == nil {
+		reader = bytes.NewReader(nil)
+	} else {
+		data, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatalf("encode request body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	recorder := httptest.NewRecorder()
+	s.router.ServeHTTP(recorder, req)
+
+	resp := &testResponse{Status: recorder.Code}
+	if err := json.Unmarshal(recorder.Body.Bytes(), resp); err != nil {
+		s.t.Fatalf("%s %s: decode response %q: %v", method, path, recorder.Body.String(), err)
+	}
+	return resp
+}
+
+// testClient 已登录的用户
+type testClient struct {
+	s        *testServer
+	userID   uint
+	phone    string
+	token    string
+	deviceID string
+	elevated string
+}
+
+// loginResult 注册、登录和儿童登录的返回数据
+type loginResult struct {
+	Token  string `json:"token"`
+	UserID uint   `json:"user_id"`
+}
+
+var phoneSeq int64
+
+// register 注册一个新家长，密码为 testPassword
+func (s *testServer) register(nickname string) *testClient {
+	s.t.Helper()
+	phone := fmt.Sprintf("138%08d", atomic.AddInt64(&phoneSeq, 1))
+	resp := s.do(http.MethodPost, "/api/auth/register", map[string]string{
+		"phone": phone, "password": testPassword, "nickname": nickname, "role": "parent",
+	}, nil)
+	if resp.Status != http.StatusOK {
+		s.t.Fatalf("register %s: status %d: %s", nickname, resp.Status, resp.Message)
+	}
+	var result loginResult
+	resp.decode(s.t, &result)
+	return &testClient{s: s, userID: result.UserID, phone: phone, token: result.Token}
+}
+
+// headers 当前用户的认证请求头
+func (c *testClient) headers() map[string]string {
+	headers := map[string]string{"Authorization": "Bearer " + c.token}
+	if c.deviceID != "" {
+		headers[middleware.DeviceIDHeader] = c.deviceID
+	}
+	if c.elevated != "" {
+		headers[middleware.ElevatedTokenHeader] = c.elevated
+	}
+	return headers
+}
+
+// request 以当前用户发送请求
+func (c *testClient) request(method, path string, body interface{}) *testResponse {
+	c.s.t.Helper()
+	return c.s.do(method, path, body, c.headers())
+}
+
+// mustOK 以当前用户发送请求，要求返回200，out 不为 nil 时解析响应数据
+func (c *testClient) mustOK(method, path string, body, out interface{}) {
+	c.s.t.Helper()
+	resp := c.request(method, path, body)
+	if resp.Status != http.StatusOK {
+		c.s.t.Fatalf("%s %s: status %d: %s", method, path, resp.Status, resp.Message)
+	}
+	if out != nil {
+		resp.decode(c.s.t, out)
+	}
+}
+
+// expectStatus 以当前用户发送请求，要求返回指定状态码
+func (c *testClient) expectStatus(status int, method, path string, body interface{}) *testResponse {
+	c.s.t.Helper()
+	resp := c.request(method, path, body)
+	if resp.Status != status {
+		c.s.t.Fatalf("%s %s: status %d, want %d: %s", method, path, resp.Status, status, resp.Message)
+	}
+	return resp
+}
+
+// elevate 验证密码进入家长模式
+func (c *testClient) elevate() {
+	c.s.t.Helper()
+	var result struct {
+		ElevatedToken string `json:"elevated_token"`
+	}
+	c.mustOK(http.MethodPost, "/api/auth/verify-password", map[string]string{"password": testPassword}, &result)
+	c.elevated = result.ElevatedToken
+}
+
+// createChild 在当前家庭中创建儿童，返回儿童ID
+func (c *testClient) createChild(nickname string) uint {
+	c.s.t.Helper()
+	var child struct {
+		ID uint `json:"id"`
+	}
+	c.mustOK(http.MethodPost, "/api/children/", map[string]interface{}{"nickname": nickname, "age": 8}, &child)
+	return child.ID
+}
+
+// loginChild 家长生成配对码，儿童在新设备上登录
+func (c *testClient) loginChild(childID uint) *testClient {
+	c.s.t.Helper()
+	var pairing struct {
+		Code string `json:"code"`
+	}
+	c.mustOK(http.MethodPost, fmt.Sprintf("/api/children/%d/pairing-code", childID), nil, &pairing)
+
+	deviceID := fmt.Sprintf("device-%d", childID)
+	resp := c.s.do(http.MethodPost, "/api/auth/child-login", map[string]string{
+		"pairing_code": pairing.Code, "device_id": deviceID,
+	}, nil)
+	if resp.Status != http.StatusOK {
+		c.s.t.Fatalf("child login: status %d: %s", resp.Status, resp.Message)
+	}
+	var result loginResult
+	resp.decode(c.s.t, &result)
+	return &testClient{s: c.s, userID: childID, token: result.Token, deviceID: deviceID}
+}
+
+// recordBehavior 记录行为，返回行为记录ID
+func (c *testClient) recordBehavior(childID uint, scoreChange int) uint {
+	c.s.t.Helper()
+	var record struct {
+		ID uint `json:"id"`
+	}
+	c.mustOK(http.MethodPost, "/api/behaviors/", map[string]interface{}{
+		"child_id":      childID,
+		"behavior_type": models.CategoryLife,
+		"behavior_desc": "测试行为",
+		"score_change":  scoreChange,
+	}, &record)
+	return record.ID
+}
+
+// points 获取用户的可用积分和累计总积分
+func (c *testClient) points(userID uint) (available, total int) {
+	c.s.t.Helper()
+	var result struct {
+		TotalPoints     int `json:"total_points"`
+		AvailablePoints int `json:"available_points"`
+	}
+	c.mustOK(http.MethodGet, fmt.Sprintf("/api/users/%d/points", userID), nil, &result)
+	return result.AvailablePoints, result.TotalPoints
+}
+
+// expectPoints 要求用户的可用积分为 want
+func (c *testClient) expectPoints(userID uint, want int) {
+	c.s.t.Helper()
+	if available, _ := c.points(userID); available != want {
+		c.s.t.Fatalf("available points of user %d = %d, want %d", userID, available, want)
+	}
+}
@@ -0,0 +1,144 @@
+package routes_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"child-behavior-app/internal/utils"
+)
+
+// authenticatorCode 按身份验证器应用的算法计算密钥在某个时间步的验证码
+func authenticatorCode(t *testing.T, secret string, step int64) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatalf("decode secret %q: %v", secret, err)
+	}
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+// enableTwoFactor 在家长模式下开启两步验证，返回密钥、使用的时间步和恢复码
+func (c *testClient) enableTwoFactor() (string, int64, []string) {
+	c.s.t.Helper()
+	var enroll struct {
+		Secret string `json:"secret"`
+	}
+	c.mustOK(http.MethodPost, "/api/auth/2fa/enroll", nil, &enroll)
+
+	step := utils.TOTPStep(time.Now())
+	var enabled struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	c.mustOK(http.MethodPost, "/api/auth/2fa/verify", map[string]string{"code": authenticatorCode(c.s.t, enroll.Secret, step)}, &enabled)
+	return enroll.Secret, step, enabled.RecoveryCodes
+}
+
+// loginSecondStep 使用密码登录，返回两步验证令牌
+func (s *testServer) loginSecondStep(phone string) string {
+	s.t.Helper()
+	resp := s.do(http.MethodPost, "/api/auth/login", map[string]string{"phone": phone, "password": testPassword}, nil)
+	var result struct {
+		TwoFactorRequired bool   `json:"two_factor_required"`
+		PreAuthToken      string `json:"pre_auth_token"`
+	}
+	if resp.Status != http.StatusOK {
+		s.t.Fatalf("login: status %d: %s", resp.Status, resp.Message)
+	}
+	resp.decode(s.t, &result)
+	if !result.TwoFactorRequired || result.PreAuthToken == "" {
+		s.t.Fatalf("login without second step: %s", resp.Data)
+	}
+	return result.PreAuthToken
+}
+
+func TestTwoFactorRequiresParentMode(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
+	parent := s.register("爸爸")
+
+	parent.expectStatus(http.StatusForbidden, http.MethodPost, "/api/auth/2fa/enroll", nil)
+	parent.expectStatus(http.StatusForbidden, http.MethodPost, "/api/auth/2fa/verify", map[string]string{"code": "123456"})
+	parent.expectStatus(http.StatusForbidden, http.MethodPost, "/api/auth/2fa/disable", map[string]string{"password": testPassword, "code": "123456"})
+
+	parent.elevate()
+	secret, step, _ := parent.enableTwoFactor()
+
+	// 关闭时输错密码计入家长模式验证的失败次数
+	parent.elevated = ""
+	parent.expectStatus(http.StatusForbidden, http.MethodPost, "/api/auth/2fa/disable", map[string]string{"password": testPassword, "code": "123456"})
+	parent.elevate()
+	disable := map[string]string{"password": "wrong-password", "code": authenticatorCode(t, secret, step+1)}
+	for i := 0; i < 4; i++ {
+		parent.expectStatus(http.StatusUnauthorized, http.MethodPost, "/api/auth/2fa/disable", disable)
+	}
+	parent.expectStatus(http.StatusTooManyRequests, http.MethodPost, "/api/auth/2fa/disable", disable)
+	disable["password"] = testPassword
+	parent.expectStatus(http.StatusTooManyRequests, http.MethodPost, "/api/auth/2fa/disable", disable)
+	parent.expectStatus(http.StatusTooManyRequests, http.MethodPost, "/api/auth/verify-password", map[string]string{"password": testPassword})
+
+	var status struct {
+		Enabled bool `json:"enabled"`
+	}
+	parent.mustOK(http.MethodGet, "/api/auth/2fa", nil, &status)
+	if !status.Enabled {
+		t.Fatal("two-factor authentication disabled while locked out")
+	}
+}
+
+func TestTwoFactorLoginRejectsReplay(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
+	parent := s.register("爸爸")
+	parent.elevate()
+	secret, step, recoveryCodes := parent.enableTwoFactor()
+	if len(recoveryCodes) == 0 {
+		t.Fatal("no recovery codes returned")
+	}
+
+	secondStep := func(code string) *testResponse {
+		return s.do(http.MethodPost, "/api/auth/2fa/login", map[string]string{
+			"pre_auth_token": s.loginSecondStep(parent.phone), "code": code,
+		}, nil)
+	}
+
+	// 开启时使用过的验证码不能再用于登录
+	if resp := secondStep(authenticatorCode(t, secret, step)); resp.Status != http.StatusUnauthorized {
+		t.Fatalf("replayed enrollment code: status %d, want %d", resp.Status, http.StatusUnauthorized)
+	}
+
+	// 下一个时间步的验证码在允许的时钟误差内，只能使用一次
+	next := authenticatorCode(t, secret, step+1)
+	resp := secondStep(next)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("next step code: status %d: %s", resp.Status, resp.Message)
+	}
+	var login loginResult
+	resp.decode(t, &login)
+	if login.UserID != parent.userID || login.Token == "" {
+		t.Fatalf("two-factor login result = %+v", login)
+	}
+	if resp := secondStep(next); resp.Status != http.StatusUnauthorized {
+		t.Fatalf("replayed login code: status %d, want %d", resp.Status, http.StatusUnauthorized)
+	}
+
+	// 恢复码同样只能使用一次
+	if resp := secondStep(recoveryCodes[0]); resp.Status != http.StatusOK {
+		t.Fatalf("recovery code: status %d: %s", resp.Status, resp.Message)
+	}
+	if resp := secondStep(recoveryCodes[0]); resp.Status != http.StatusUnauthorized {
+		t.Fatalf("reused recovery code: status %d, want %d", resp.Status, http.StatusUnauthorized)
+	}
+}
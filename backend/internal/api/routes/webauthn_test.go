@@ -0,0 +1,282 @@
+package routes_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+)
+
+// 验证器数据中的标志位
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttestedData = 0x40
+)
+
+// softAuthenticator 软件实现的平台验证器，使用 ECDSA P-256 密钥和 none 证明格式
+type softAuthenticator struct {
+	t            *testing.T
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+// newSoftAuthenticator 创建带有新密钥对的验证器
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		t.Fatalf("generate credential ID: %v", err)
+	}
+	return &softAuthenticator{t: t, key: key, credentialID: credentialID}
+}
+
+// ceremonyOptions begin 接口返回的流程参数中测试用到的字段
+type ceremonyOptions struct {
+	SessionID string `json:"session_id"`
+	Options   struct {
+		PublicKey struct {
+			Challenge string `json:"challenge"`
+			RPID      string `json:"rpId"`
+			RP        struct {
+				ID string `json:"id"`
+			} `json:"rp"`
+			User struct {
+				ID string `json:"id"`
+			} `json:"user"`
+		} `json:"publicKey"`
+	} `json:"options"`
+}
+
+// encode base64url 编码，与浏览器返回的凭据格式一致
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// clientData 生成浏览器的 clientDataJSON
+func (a *softAuthenticator) clientData(ceremonyType, challenge string) []byte {
+	data, err := json.Marshal(map[string]string{"type": ceremonyType, "challenge": challenge, "origin": testOrigin})
+	if err != nil {
+		a.t.Fatalf("encode client data: %v", err)
+	}
+	return data
+}
+
+// authenticatorData 生成验证器数据：RP ID 哈希、标志位、签名计数和可选的凭据数据
+func (a *softAuthenticator) authenticatorData(rpID string, flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attested...)
+}
+
+// create 模拟 navigator.credentials.create，返回注册凭据
+func (a *softAuthenticator) create(options ceremonyOptions) map[string]interface{} {
+	a.t.Helper()
+	userHandle, err := base64.RawURLEncoding.DecodeString(options.Options.PublicKey.User.ID)
+	if err != nil {
+		a.t.Fatalf("decode user handle: %v", err)
+	}
+	a.userHandle = userHandle
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  1, // P-256
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		a.t.Fatalf("encode public key: %v", err)
+	}
+	attested := make([]byte, 16) // AAGUID 全为0
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, publicKey...)
+
+	authData := a.authenticatorData(options.Options.PublicKey.RP.ID, flagUserPresent|flagUserVerified|flagAttestedData, attested)
+	attestation, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": authData,
+	})
+	if err != nil {
+		a.t.Fatalf("encode attestation object: %v", err)
+	}
+
+	return map[string]interface{}{
+		"id":    encode(a.credentialID),
+		"rawId": encode(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    encode(a.clientData("webauthn.create", options.Options.PublicKey.Challenge)),
+			"attestationObject": encode(attestation),
+		},
+	}
+}
+
+// get 模拟 navigator.credentials.get，签名计数加一后返回断言
+func (a *softAuthenticator) get(options ceremonyOptions) map[string]interface{} {
+	a.t.Helper()
+	a.signCount++
+	authData := a.authenticatorData(options.Options.PublicKey.RPID, flagUserPresent|flagUserVerified, nil)
+	clientData := a.clientData("webauthn.get", options.Options.PublicKey.Challenge)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		a.t.Fatalf("sign assertion: %v", err)
+	}
+
+	return map[string]interface{}{
+		"id":    encode(a.credentialID),
+		"rawId": encode(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    encode(clientData),
+			"authenticatorData": encode(authData),
+			"signature":         encode(signature),
+			"userHandle":        encode(a.userHandle),
+		},
+	}
+}
+
+// beginCeremony 调用 begin 接口
+func (c *testClient) beginCeremony(path string) ceremonyOptions {
+	c.s.t.Helper()
+	var options ceremonyOptions
+	c.mustOK(http.MethodPost, path, nil, &options)
+	return options
+}
+
+// registerPasskey 在家长模式下注册通行密钥，返回凭据记录ID
+func (c *testClient) registerPasskey(authenticator *softAuthenticator) uint {
+	c.s.t.Helper()
+	options := c.beginCeremony("/api/auth/webauthn/register/begin")
+	var record struct {
+		ID   uint   `json:"id"`
+		Name string `json:"name"`
+	}
+	c.mustOK(http.MethodPost, "/api/auth/webauthn/register/finish", map[string]interface{}{
+		"session_id": options.SessionID,
+		"name":       "测试设备",
+		"credential": authenticator.create(options),
+	}, &record)
+	if record.ID == 0 || record.Name != "测试设备" {
+		c.s.t.Fatalf("registered credential = %+v", record)
+	}
+	return record.ID
+}
+
+// verifyPasskey 使用通行密钥进入家长模式，返回响应
+func (c *testClient) verifyPasskey(authenticator *softAuthenticator) *testResponse {
+	c.s.t.Helper()
+	options := c.beginCeremony("/api/auth/webauthn/verify/begin")
+	return c.request(http.MethodPost, "/api/auth/webauthn/verify/finish", map[string]interface{}{
+		"session_id": options.SessionID,
+		"credential": authenticator.get(options),
+	})
+}
+
+func TestPasskeyManagementRequiresParentMode(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
+	parent := s.register("爸爸")
+	authenticator := newSoftAuthenticator(t)
+
+	parent.expectStatus(http.StatusForbidden, http.MethodPost, "/api/auth/webauthn/register/begin", nil)
+	parent.expectStatus(http.StatusForbidden, http.MethodPost, "/api/auth/webauthn/register/finish", map[string]interface{}{
+		"session_id": "unknown", "credential": map[string]string{},
+	})
+
+	parent.elevate()
+	credentialID := parent.registerPasskey(authenticator)
+
+	var credentials []struct {
+		ID uint `json:"id"`
+	}
+	parent.mustOK(http.MethodGet, "/api/auth/webauthn/credentials", nil, &credentials)
+	if len(credentials) != 1 || credentials[0].ID != credentialID {
+		t.Fatalf("credentials = %+v", credentials)
+	}
+
+	// 删除通行密钥同样需要家长模式
+	path := fmt.Sprintf("/api/auth/webauthn/credentials/%d", credentialID)
+	elevated := parent.elevated
+	parent.elevated = ""
+	parent.expectStatus(http.StatusForbidden, http.MethodDelete, path, nil)
+	parent.elevated = elevated
+	parent.mustOK(http.MethodDelete, path, nil, nil)
+	parent.mustOK(http.MethodGet, "/api/auth/webauthn/credentials", nil, &credentials)
+	if len(credentials) != 0 {
+		t.Fatalf("credentials after delete = %+v", credentials)
+	}
+}
+
+func TestPasskeyVerificationAndLogin(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
+	parent := s.register("爸爸")
+	authenticator := newSoftAuthenticator(t)
+	parent.elevate()
+	parent.registerPasskey(authenticator)
+
+	// 通行密钥代替密码进入家长模式
+	parent.elevated = ""
+	resp := parent.verifyPasskey(authenticator)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("passkey verification: status %d: %s", resp.Status, resp.Message)
+	}
+	var elevated struct {
+		ElevatedToken string `json:"elevated_token"`
+	}
+	resp.decode(t, &elevated)
+	parent.elevated = elevated.ElevatedToken
+	parent.createReward("冰淇淋", 30, 5, false)
+
+	// 通行密钥直接登录，不需要手机号和密码
+	var login ceremonyOptions
+	s.do(http.MethodPost, "/api/auth/webauthn/login/begin", nil, nil).decode(t, &login)
+	resp = s.do(http.MethodPost, "/api/auth/webauthn/login/finish", map[string]interface{}{
+		"session_id": login.SessionID,
+		"credential": authenticator.get(login),
+	}, nil)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("passkey login: status %d: %s", resp.Status, resp.Message)
+	}
+	var result loginResult
+	resp.decode(t, &result)
+	if result.UserID != parent.userID || result.Token == "" {
+		t.Fatalf("passkey login result = %+v", result)
+	}
+
+	// 签名计数没有增长时视为复制的凭据
+	authenticator.signCount--
+	if resp := parent.verifyPasskey(authenticator); resp.Status != http.StatusUnauthorized {
+		t.Fatalf("cloned passkey: status %d, want %d", resp.Status, http.StatusUnauthorized)
+	}
+
+	// 通行密钥只属于注册它的家长，其他家长没有可用的通行密钥
+	other := s.register("邻居")
+	if resp := other.request(http.MethodPost, "/api/auth/webauthn/verify/begin", nil); resp.Status != http.StatusBadRequest {
+		t.Fatalf("verify without passkey: status %d, want %d", resp.Status, http.StatusBadRequest)
+	}
+}
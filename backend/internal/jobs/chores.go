@@ -0,0 +1,25 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"child-behavior-app/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// RunChoreJobs 执行一次周期任务作业
+func RunChoreJobs(db *gorm.DB, now time.Time) {
+	if err := models.MaterializeChoreInstances(db, now); err != nil {
+		log.Printf("Failed to materialize chore instances: %v", err)
+	}
+
+	missed, err := models.MarkMissedChores(db, now)
+	if err != nil {
+		log.Printf("Failed to mark missed chores: %v", err)
+	}
+	if missed > 0 {
+		log.Printf("Marked %d chore instances as missed", missed)
+	}
+}
@@ -0,0 +1,20 @@
+package jobs
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StartScheduler 启动后台作业
+// 每个周期生成当天的任务实例、标记错过的任务，并清零已中断的连续天数
+func StartScheduler(db *gorm.DB, interval time.Duration) {
+	go func() {
+		for {
+			now := time.Now()
+			RunChoreJobs(db, now)
+			RunStreakJobs(db, now)
+			time.Sleep(interval)
+		}
+	}()
+}
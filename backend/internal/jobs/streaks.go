@@ -0,0 +1,21 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"child-behavior-app/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// RunStreakJobs 执行一次连续天数作业，把昨天和今天都没有记录的连续天数清零
+func RunStreakJobs(db *gorm.DB, now time.Time) {
+	reset, err := models.ResetBrokenStreaks(db, now)
+	if err != nil {
+		log.Printf("Failed to reset broken streaks: %v", err)
+	}
+	if reset > 0 {
+		log.Printf("Reset %d broken streaks", reset)
+	}
+}
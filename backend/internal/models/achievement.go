@@ -0,0 +1,182 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 成就规则类型
+const (
+	AchievementRuleBehaviorCount = "behavior_count" // 累计加分行为次数
+	AchievementRuleCategoryCount = "category_count" // 指定分类的加分行为次数
+	AchievementRuleExchangeCount = "exchange_count" // 累计完成兑换奖励的次数（不含待确认和已取消）
+	AchievementRuleStreakDays    = "streak_days"    // 任一行为模板的最长连续天数
+	AchievementRuleTotalPoints   = "total_points"   // 累计总积分
+)
+
+// AchievementRuleTypes 支持的成就规则类型
+var AchievementRuleTypes = map[string]bool{
+	AchievementRuleBehaviorCount: true,
+	AchievementRuleCategoryCount: true,
+	AchievementRuleExchangeCount: true,
+	AchievementRuleStreakDays:    true,
+	AchievementRuleTotalPoints:   true,
+}
+
+// defaultLevels 家长未配置等级时使用的默认等级
+var defaultLevels = []Level{
+	{Level: 1, Name: "新手", MinPoints: 0},
+	{Level: 2, Name: "进步之星", MinPoints: 50},
+	{Level: 3, Name: "优秀之星", MinPoints: 150},
+	{Level: 4, Name: "闪耀之星", MinPoints: 300},
+	{Level: 5, Name: "超级之星", MinPoints: 500},
+}
+
+// defaultAchievements 新家长的默认成就
+var defaultAchievements = []Achievement{
+	{Name: "第一步", Description: "获得第一次表扬", Icon: "star", RuleType: AchievementRuleBehaviorCount, Threshold: 1},
+	{Name: "好习惯养成中", Description: "累计获得50次表扬", Icon: "star", RuleType: AchievementRuleBehaviorCount, Threshold: 50},
+	{Name: "爱学习", Description: "完成10次学习行为", Icon: "book-open", RuleType: AchievementRuleCategoryCount, Category: CategoryLearning, Threshold: 10},
+	{Name: "小帮手", Description: "完成10次生活行为", Icon: "home", RuleType: AchievementRuleCategoryCount, Category: CategoryLife, Threshold: 10},
+	{Name: "首次兑换", Description: "第一次兑换奖励", Icon: "gift", RuleType: AchievementRuleExchangeCount, Threshold: 1},
+	{Name: "坚持一周", Description: "连续7天完成同一行为", Icon: "flame", RuleType: AchievementRuleStreakDays, Threshold: 7},
+	{Name: "坚持一个月", Description: "连续30天完成同一行为", Icon: "flame", RuleType: AchievementRuleStreakDays, Threshold: 30},
+	{Name: "百分达人", Description: "累计获得100积分", Icon: "trophy", RuleType: AchievementRuleTotalPoints, Threshold: 100},
+}
+
+// LoadLevels 获取家庭配置的等级，按门槛升序，未配置时返回默认等级
+func LoadLevels(db *gorm.DB, familyID uint) ([]Level, error) {
+	var levels []Level
+	if err := db.Where("created_by IN (?)", FamilyMemberIDs(db, familyID)).Order("min_points ASC").Find(&levels).Error; err != nil {
+		return nil, fmt.Errorf("failed to load levels: %w", err)
+	}
+	if len(levels) == 0 {
+		levels = make([]Level, len(defaultLevels))
+		copy(levels, defaultLevels)
+	}
+	return levels, nil
+}
+
+// LevelForPoints 根据累计总积分确定等级，levels 需按门槛升序
+func LevelForPoints(levels []Level, totalPoints int) Level {
+	current := Level{Level: 1}
+	for _, level := range levels {
+		if totalPoints < level.MinPoints {
+			break
+		}
+		current = level
+	}
+	return current
+}
+
+// SeedAchievements 家庭还没有任何成就规则时写入默认成就，创建人为家庭所有者
+func SeedAchievements(db *gorm.DB, familyID uint) error {
+	var count int64
+	if err := db.Model(&Achievement{}).Where("created_by IN (?)", FamilyMemberIDs(db, familyID)).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to count achievements: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	var family Family
+	if err := db.First(&family, familyID).Error; err != nil {
+		return fmt.Errorf("failed to load family: %w", err)
+	}
+
+	achievements := make([]Achievement, len(defaultAchievements))
+	for i, achievement := range defaultAchievements {
+		achievement.CreatedBy = family.OwnerID
+		achievement.IsActive = true
+		achievements[i] = achievement
+	}
+	if err := db.Create(&achievements).Error; err != nil {
+		return fmt.Errorf("failed to seed achievements: %w", err)
+	}
+	return nil
+}
+
+// AchievementProgress 计算儿童在某条成就规则上的当前进度
+func AchievementProgress(db *gorm.DB, childID uint, achievement *Achievement) (int, error) {
+	var value int64
+	var err error
+
+	switch achievement.RuleType {
+	case AchievementRuleBehaviorCount:
+		err = db.Model(&BehaviorRecord{}).
+			Where("user_id = ? AND status = ? AND behavior_type = ?", childID, BehaviorStatusApproved, BehaviorGood).
+			Count(&value).Error
+	case AchievementRuleCategoryCount:
+		err = db.Model(&BehaviorRecord{}).
+			Where("user_id = ? AND status = ? AND behavior_type = ? AND category = ?", childID, BehaviorStatusApproved, BehaviorGood, achievement.Category).
+			Count(&value).Error
+	case AchievementRuleExchangeCount:
+		err = db.Model(&ExchangeRecord{}).
+			Where("user_id = ? AND status = ?", childID, ExchangeStatusCompleted).
+			Count(&value).Error
+	case AchievementRuleStreakDays:
+		err = db.Model(&ChildStreak{}).Where("child_id = ?", childID).
+			Select("COALESCE(MAX(best_streak), 0)").Scan(&value).Error
+	case AchievementRuleTotalPoints:
+		err = db.Model(&UserPoints{}).Where("user_id = ?", childID).
+			Select("COALESCE(MAX(total_points), 0)").Scan(&value).Error
+	default:
+		return 0, fmt.Errorf("unknown achievement rule type %q", achievement.RuleType)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute achievement progress: %w", err)
+	}
+	return int(value), nil
+}
+
+// EvaluateAchievements 检查儿童尚未解锁的成就，达到条件的写入解锁记录并返回
+// 在行为生效和兑换奖励后调用，已解锁的成就不会重复写入
+func EvaluateAchievements(db *gorm.DB, childID uint) ([]ChildAchievement, error) {
+	var child User
+	if err := db.First(&child, childID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load child: %w", err)
+	}
+	if child.FamilyID == nil {
+		return nil, nil
+	}
+
+	if err := SeedAchievements(db, *child.FamilyID); err != nil {
+		return nil, err
+	}
+
+	var achievements []Achievement
+	if err := db.Where("created_by IN (?) AND is_active = ?", FamilyMemberIDs(db, *child.FamilyID), true).
+		Where("id NOT IN (?)", db.Model(&ChildAchievement{}).Select("achievement_id").Where("child_id = ?", childID)).
+		Find(&achievements).Error; err != nil {
+		return nil, fmt.Errorf("failed to load achievements: %w", err)
+	}
+
+	var unlocked []ChildAchievement
+	now := time.Now()
+	for i := range achievements {
+		progress, err := AchievementProgress(db, childID, &achievements[i])
+		if err != nil {
+			return nil, err
+		}
+		if progress < achievements[i].Threshold {
+			continue
+		}
+		unlocked = append(unlocked, ChildAchievement{
+			ChildID:       childID,
+			AchievementID: achievements[i].ID,
+			UnlockedAt:    now,
+			Achievement:   achievements[i],
+		})
+	}
+
+	if len(unlocked) == 0 {
+		return nil, nil
+	}
+	if err := db.Omit("Achievement").Clauses(clause.OnConflict{DoNothing: true}).Create(&unlocked).Error; err != nil {
+		return nil, fmt.Errorf("failed to unlock achievements: %w", err)
+	}
+	return unlocked, nil
+}
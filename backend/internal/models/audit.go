@@ -0,0 +1,159 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// 处理器通过 gin 上下文向审计中间件补充信息的键
+const (
+	// AuditChangeKey 修改的对象及修改前后的内容，值为 AuditChange
+	AuditChangeKey = "audit_change"
+	// AuditUserKey 未登录接口（登录、找回密码等）涉及的账户，值为 *User
+	AuditUserKey = "audit_user"
+)
+
+// AuditChange 一次修改的对象及修改前后的内容，Before 或 After 为 nil 分别表示创建或删除
+type AuditChange struct {
+	TargetType string
+	TargetID   uint
+	Before     interface{}
+	After      interface{}
+}
+
+// auditSensitiveFields 不写入审计日志的字段
+var auditSensitiveFields = map[string]bool{
+	"password":       true,
+	"old_password":   true,
+	"new_password":   true,
+	"pin":            true,
+	"code":           true,
+	"pairing_code":   true,
+	"token":          true,
+	"refresh_token":  true,
+	"pre_auth_token": true,
+	"elevated_token": true,
+	"secret":         true,
+	"credential":     true,
+}
+
+// auditIgnoredFields 每次修改都会变化、没有记录意义的字段
+var auditIgnoredFields = map[string]bool{
+	"updated_at": true,
+}
+
+// toAuditFields 将对象转换为字段表，去掉敏感字段和关联对象，只保留表中的字段
+func toAuditFields(value interface{}) (map[string]interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	redactAuditFields(fields)
+	for key, value := range fields {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			delete(fields, key)
+			continue
+		}
+		if auditIgnoredFields[key] {
+			delete(fields, key)
+		}
+	}
+	return fields, nil
+}
+
+// redactAuditFields 递归去掉敏感字段
+func redactAuditFields(fields map[string]interface{}) {
+	for key, value := range fields {
+		if auditSensitiveFields[strings.ToLower(key)] {
+			delete(fields, key)
+			continue
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			redactAuditFields(nested)
+		}
+	}
+}
+
+// encodeAuditFields 字段表编码为 JSON，为空时返回空字符串
+func encodeAuditFields(fields map[string]interface{}) (string, error) {
+	if fields == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Diff 计算修改前后发生变化的字段
+// 修改时只保留前后不同的字段，创建和删除时保留完整内容
+func (change AuditChange) Diff() (string, string, error) {
+	before, err := toAuditFields(change.Before)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode audit before: %w", err)
+	}
+	after, err := toAuditFields(change.After)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode audit after: %w", err)
+	}
+
+	if before != nil && after != nil {
+		for key := range before {
+			if reflect.DeepEqual(before[key], after[key]) {
+				delete(before, key)
+				delete(after, key)
+			}
+		}
+	}
+
+	beforeJSON, err := encodeAuditFields(before)
+	if err != nil {
+		return "", "", err
+	}
+	afterJSON, err := encodeAuditFields(after)
+	if err != nil {
+		return "", "", err
+	}
+	return beforeJSON, afterJSON, nil
+}
+
+// RedactAuditBody 去掉 JSON 请求体中的敏感字段，未标注修改内容的请求用它作为修改后的内容
+// 请求体不是 JSON 对象时返回空字符串
+func RedactAuditBody(body []byte) string {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil || len(fields) == 0 {
+		return ""
+	}
+	redactAuditFields(fields)
+	encoded, err := encodeAuditFields(fields)
+	if err != nil {
+		return ""
+	}
+	return encoded
+}
+
+// CreateAuditEvent 写入审计日志，超长的字段截断
+func CreateAuditEvent(db *gorm.DB, event *AuditEvent) error {
+	event.Action = truncate(event.Action, 100)
+	event.TargetType = truncate(event.TargetType, 50)
+	event.TargetID = truncate(event.TargetID, 50)
+	event.IP = truncate(event.IP, 45)
+	event.UserAgent = truncate(event.UserAgent, 255)
+	if err := db.Create(event).Error; err != nil {
+		return fmt.Errorf("failed to create audit event: %w", err)
+	}
+	return nil
+}
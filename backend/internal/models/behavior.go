@@ -0,0 +1,144 @@
+package models
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// 行为极性，存储在 behavior_records.behavior_type 列
+const (
+	BehaviorGood = "good"
+	BehaviorBad  = "bad"
+)
+
+// 行为记录状态，儿童自主申报的记录在家长审核通过前不影响积分
+const (
+	BehaviorStatusApproved = "approved"
+	BehaviorStatusPending  = "pending"
+	BehaviorStatusRejected = "rejected"
+)
+
+// 行为记录来源，撤销只针对家长或儿童手动录入的记录
+const (
+	BehaviorSourceManual      = "manual"       // 家长记录或儿童申报
+	BehaviorSourceChore       = "chore"        // 完成任务
+	BehaviorSourceChoreMissed = "chore_missed" // 错过任务的扣分
+	BehaviorSourceStreakBonus = "streak_bonus" // 连续奖励
+)
+
+// 行为分类，存储在 behavior_records.category 列
+const (
+	CategoryLearning = "learning"
+	CategoryLife     = "life"
+	CategorySocial   = "social"
+	CategoryEmotion  = "emotion"
+	CategoryExercise = "exercise"
+	CategoryEating   = "eating"
+	CategoryOther    = "other"
+)
+
+// BehaviorCategoryNames 行为分类的中文名称
+var BehaviorCategoryNames = map[string]string{
+	CategoryLearning: "学习",
+	CategoryLife:     "生活",
+	CategorySocial:   "社交",
+	CategoryEmotion:  "情感",
+	CategoryExercise: "运动",
+	CategoryEating:   "饮食",
+	CategoryOther:    "其他",
+}
+
+// BehaviorPolarity 根据积分正负确定行为极性
+func BehaviorPolarity(scoreChange int) string {
+	if scoreChange > 0 {
+		return BehaviorGood
+	}
+	return BehaviorBad
+}
+
+// NormalizeBehaviorCategory 校验前端传入的分类，未知分类归为 other
+func NormalizeBehaviorCategory(category string) string {
+	if _, ok := BehaviorCategoryNames[category]; ok {
+		return category
+	}
+	return CategoryOther
+}
+
+// defaultBehaviorTemplates 新家长的默认行为目录
+var defaultBehaviorTemplates = []BehaviorTemplate{
+	{Name: "主动完成作业", Category: CategoryLearning, DefaultPoints: 10, Icon: "book-open"},
+	{Name: "认真听课", Category: CategoryLearning, DefaultPoints: 5, Icon: "book-open"},
+	{Name: "阅读课外书", Category: CategoryLearning, DefaultPoints: 5, Icon: "book-open"},
+	{Name: "整理房间", Category: CategoryLife, DefaultPoints: 5, Icon: "home"},
+	{Name: "帮忙做家务", Category: CategoryLife, DefaultPoints: 10, Icon: "home"},
+	{Name: "按时睡觉", Category: CategoryLife, DefaultPoints: 5, Icon: "home"},
+	{Name: "帮助他人", Category: CategorySocial, DefaultPoints: 10, Icon: "users"},
+	{Name: "分享玩具", Category: CategorySocial, DefaultPoints: 5, Icon: "users"},
+	{Name: "控制情绪", Category: CategoryEmotion, DefaultPoints: 5, Icon: "heart"},
+	{Name: "道歉认错", Category: CategoryEmotion, DefaultPoints: 5, Icon: "heart"},
+	{Name: "坚持锻炼", Category: CategoryExercise, DefaultPoints: 5, Icon: "gamepad-2"},
+	{Name: "不挑食", Category: CategoryEating, DefaultPoints: 5, Icon: "utensils"},
+	{Name: "发脾气", Category: CategoryEmotion, DefaultPoints: -5, Icon: "heart"},
+	{Name: "拖延作业", Category: CategoryLearning, DefaultPoints: -5, Icon: "book-open"},
+}
+
+// SeedBehaviorTemplates 为家长写入默认行为目录
+func SeedBehaviorTemplates(db *gorm.DB, parentID uint) error {
+	templates := make([]BehaviorTemplate, len(defaultBehaviorTemplates))
+	for i, template := range defaultBehaviorTemplates {
+		template.CreatedBy = parentID
+		template.IsActive = true
+		templates[i] = template
+	}
+	if err := db.Create(&templates).Error; err != nil {
+		return fmt.Errorf("failed to seed behavior templates: %w", err)
+	}
+	return nil
+}
+
+// CreateBehaviorRecord 写入行为记录，已生效的记录同时写入积分流水
+// 调用方应在事务中调用，流水的操作人为记录人
+func CreateBehaviorRecord(tx *gorm.DB, record *BehaviorRecord) (*UserPoints, error) {
+	if err := tx.Create(record).Error; err != nil {
+		return nil, fmt.Errorf("failed to create behavior record: %w", err)
+	}
+	if record.Status != BehaviorStatusApproved {
+		return nil, nil
+	}
+	return ApproveBehaviorRecord(tx, record, record.RecorderID, "")
+}
+
+// ApproveBehaviorRecord 为已生效的行为记录写入积分流水，加分记录同时更新连续天数并检查成就，
+// 返回包含连续奖励在内的最新积分，调用方应在事务中调用
+func ApproveBehaviorRecord(tx *gorm.DB, record *BehaviorRecord, actorID uint, note string) (*UserPoints, error) {
+	userPoints, err := ApplyPointTransaction(tx, &PointTransaction{
+		UserID:     record.ChildID,
+		SourceType: PointSourceBehavior,
+		SourceID:   record.ID,
+		Delta:      record.ScoreChange,
+		ActorID:    actorID,
+		Note:       note,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if record.ScoreChange <= 0 {
+		return userPoints, nil
+	}
+
+	if record.TemplateID != nil {
+		if err := UpdateStreak(tx, record); err != nil {
+			return nil, err
+		}
+		// 连续奖励可能已经变更积分，重新读取
+		if err := tx.Where("user_id = ?", record.ChildID).First(userPoints).Error; err != nil {
+			return nil, fmt.Errorf("failed to reload user points: %w", err)
+		}
+	}
+
+	if _, err := EvaluateAchievements(tx, record.ChildID); err != nil {
+		return nil, err
+	}
+	return userPoints, nil
+}
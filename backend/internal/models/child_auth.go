@@ -0,0 +1,124 @@
+package models
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PairingCodeTTL 配对码有效期
+const PairingCodeTTL = 10 * time.Minute
+
+// shortCodeAlphabet 配对码和邀请码字符集，去掉了容易混淆的 0/O/1/I
+const shortCodeAlphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// shortCodeLength 配对码和邀请码长度
+const shortCodeLength = 8
+
+// ErrPairingCodeInvalid 配对码不存在、已使用或已过期
+var ErrPairingCodeInvalid = errors.New("pairing code is invalid or expired")
+
+// generateShortCode 生成随机配对码或邀请码
+func generateShortCode() (string, error) {
+	code := make([]byte, shortCodeLength)
+	max := big.NewInt(int64(len(shortCodeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = shortCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// CreatePairingCode 为儿童生成新的配对码，该儿童之前未使用的配对码同时作废
+func CreatePairingCode(db *gorm.DB, childID, parentID uint) (*PairingCode, error) {
+	pairing := PairingCode{
+		ChildID:   childID,
+		CreatedBy: parentID,
+		ExpiresAt: time.Now().Add(PairingCodeTTL),
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("child_id = ? AND used_at IS NULL", childID).Delete(&PairingCode{}).Error; err != nil {
+			return err
+		}
+
+		// 配对码有唯一索引，极少数情况下冲突时重新生成
+		for attempt := 0; attempt < 3; attempt++ {
+			code, err := generateShortCode()
+			if err != nil {
+				return err
+			}
+			pairing.Code = code
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&pairing)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 1 {
+				return nil
+			}
+		}
+		return errors.New("failed to generate a unique pairing code")
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pairing code: %w", err)
+	}
+	return &pairing, nil
+}
+
+// RedeemPairingCode 使用配对码，成功后配对码失效，调用方应在事务中调用
+func RedeemPairingCode(tx *gorm.DB, code string) (*PairingCode, error) {
+	var pairing PairingCode
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", code).First(&pairing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPairingCodeInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if pairing.UsedAt != nil || time.Now().After(pairing.ExpiresAt) {
+		return nil, ErrPairingCodeInvalid
+	}
+
+	now := time.Now()
+	pairing.UsedAt = &now
+	if err := tx.Model(&pairing).Update("used_at", pairing.UsedAt).Error; err != nil {
+		return nil, err
+	}
+	return &pairing, nil
+}
+
+// RegisterChildDevice 记录儿童在设备上登录，设备首次登录时创建记录
+func RegisterChildDevice(tx *gorm.DB, childID uint, deviceID, deviceName string) error {
+	device := ChildDevice{
+		ChildID:     childID,
+		DeviceID:    deviceID,
+		DeviceName:  deviceName,
+		LastLoginAt: time.Now(),
+	}
+	updates := []string{"last_login_at", "updated_at"}
+	if deviceName != "" {
+		updates = append(updates, "device_name")
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "child_id"}, {Name: "device_id"}},
+		DoUpdates: clause.AssignmentColumns(updates),
+	}).Create(&device).Error
+}
+
+// IsFamilyDevice 判断设备是否已经与该家庭的某个孩子配对过
+// 只有配对过的设备才能使用PIN登录
+func IsFamilyDevice(db *gorm.DB, familyID uint, deviceID string) (bool, error) {
+	var count int64
+	err := db.Model(&ChildDevice{}).
+		Where("device_id = ? AND child_id IN (?)", deviceID, FamilyChildIDs(db, familyID)).
+		Count(&count).Error
+	return count > 0, err
+}
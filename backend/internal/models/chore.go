@@ -0,0 +1,224 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 周期任务重复规则
+const (
+	RecurrenceDaily    = "daily"
+	RecurrenceWeekdays = "weekdays"
+	RecurrenceWeekly   = "weekly"
+)
+
+// 周期任务实例状态
+const (
+	ChoreStatusPending = "pending"
+	ChoreStatusDone    = "done"
+	ChoreStatusMissed  = "missed"
+)
+
+// ChoreDateLayout 周期任务实例日期格式
+const ChoreDateLayout = "2006-01-02"
+
+// ErrChoreNotPending 任务实例已完成或已错过
+var ErrChoreNotPending = errors.New("chore instance is not pending")
+
+// ParseWeekDays 解析星期列表，0为周日
+func ParseWeekDays(weekDays string) ([]time.Weekday, error) {
+	var days []time.Weekday
+	for _, part := range strings.Split(weekDays, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		day, err := strconv.Atoi(part)
+		if err != nil || day < 0 || day > 6 {
+			return nil, fmt.Errorf("invalid week day %q", part)
+		}
+		days = append(days, time.Weekday(day))
+	}
+	return days, nil
+}
+
+// FormatWeekDays 把星期列表格式化为存储格式
+func FormatWeekDays(days []int) string {
+	parts := make([]string, 0, len(days))
+	for _, day := range days {
+		parts = append(parts, strconv.Itoa(day))
+	}
+	return strings.Join(parts, ",")
+}
+
+// OccursOn 判断周期任务在指定日期是否需要完成
+func (c *Chore) OccursOn(day time.Time) bool {
+	switch c.Recurrence {
+	case RecurrenceDaily:
+		return true
+	case RecurrenceWeekdays:
+		return day.Weekday() != time.Saturday && day.Weekday() != time.Sunday
+	case RecurrenceWeekly:
+		days, err := ParseWeekDays(c.WeekDays)
+		if err != nil {
+			return false
+		}
+		for _, weekday := range days {
+			if day.Weekday() == weekday {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsAllDay 是否为全天任务，全天任务当天结束前完成即可
+func (c *Chore) IsAllDay() bool {
+	return c.DueTime == ""
+}
+
+// DueAtOn 计算周期任务在指定日期的截止时间，全天任务为当天的最后一分钟
+func (c *Chore) DueAtOn(day time.Time) time.Time {
+	dueTime, err := time.Parse("15:04", c.DueTime)
+	if c.IsAllDay() || err != nil {
+		return time.Date(day.Year(), day.Month(), day.Day(), 23, 59, 0, 0, day.Location())
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), dueTime.Hour(), dueTime.Minute(), 0, 0, day.Location())
+}
+
+// MaterializeChoreInstances 为指定日期生成所有启用任务的实例，已存在的实例不会重复生成
+func MaterializeChoreInstances(db *gorm.DB, day time.Time) error {
+	var chores []Chore
+	if err := db.Where("is_active = ?", true).Find(&chores).Error; err != nil {
+		return fmt.Errorf("failed to load chores: %w", err)
+	}
+
+	dueDate := day.Format(ChoreDateLayout)
+	var instances []ChoreInstance
+	for _, chore := range chores {
+		if !chore.OccursOn(day) {
+			continue
+		}
+		instances = append(instances, ChoreInstance{
+			ChoreID: chore.ID,
+			ChildID: chore.ChildID,
+			DueDate: dueDate,
+			DueAt:   chore.DueAtOn(day),
+			Status:  ChoreStatusPending,
+		})
+	}
+
+	if len(instances) == 0 {
+		return nil
+	}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&instances).Error; err != nil {
+		return fmt.Errorf("failed to create chore instances: %w", err)
+	}
+	return nil
+}
+
+// CompleteChoreInstance 标记任务实例完成，并自动生成对应的行为记录和积分流水
+// 调用方应在事务中调用
+func CompleteChoreInstance(tx *gorm.DB, instanceID uint, actorID uint) (*ChoreInstance, *UserPoints, error) {
+	var instance ChoreInstance
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Chore").First(&instance, instanceID).Error; err != nil {
+		return nil, nil, err
+	}
+	if instance.Status != ChoreStatusPending {
+		return nil, nil, ErrChoreNotPending
+	}
+
+	record := BehaviorRecord{
+		ChildID:      instance.ChildID,
+		RecorderID:   actorID,
+		BehaviorType: BehaviorPolarity(instance.Chore.Points),
+		Category:     instance.Chore.Category,
+		BehaviorDesc: instance.Chore.Name,
+		ScoreChange:  instance.Chore.Points,
+		TemplateID:   instance.Chore.TemplateID,
+		Status:       BehaviorStatusApproved,
+		Source:       BehaviorSourceChore,
+		RecordedAt:   time.Now(),
+	}
+	userPoints, err := CreateBehaviorRecord(tx, &record)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	instance.Status = ChoreStatusDone
+	instance.CompletedAt = &now
+	instance.CompletedBy = &actorID
+	instance.BehaviorRecordID = &record.ID
+	if err := tx.Model(&instance).Updates(map[string]interface{}{
+		"status":             instance.Status,
+		"completed_at":       instance.CompletedAt,
+		"completed_by":       instance.CompletedBy,
+		"behavior_record_id": instance.BehaviorRecordID,
+	}).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to update chore instance: %w", err)
+	}
+
+	return &instance, userPoints, nil
+}
+
+// MarkMissedChores 把截止时间在 before 之前仍未完成的实例标记为错过，全天任务到 before 当天才算错过，
+// 配置了扣分的任务同时生成扣分的行为记录，返回处理的实例数量
+func MarkMissedChores(db *gorm.DB, before time.Time) (int, error) {
+	allDay := db.Model(&Chore{}).Select("id").Where("due_time = ?", "")
+	var instanceIDs []uint
+	if err := db.Model(&ChoreInstance{}).
+		Where("status = ?", ChoreStatusPending).
+		Where("(chore_id IN (?) AND due_date < ?) OR (chore_id NOT IN (?) AND due_at < ?)",
+			allDay, before.Format(ChoreDateLayout), allDay, before).
+		Pluck("id", &instanceIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to load overdue chore instances: %w", err)
+	}
+
+	missed := 0
+	for _, instanceID := range instanceIDs {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var instance ChoreInstance
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Chore").First(&instance, instanceID).Error; err != nil {
+				return err
+			}
+			if instance.Status != ChoreStatusPending {
+				return nil
+			}
+
+			updates := map[string]interface{}{"status": ChoreStatusMissed}
+			if instance.Chore.PenaltyPoints > 0 {
+				record := BehaviorRecord{
+					ChildID:      instance.ChildID,
+					RecorderID:   instance.Chore.CreatedBy,
+					BehaviorType: BehaviorBad,
+					Category:     instance.Chore.Category,
+					BehaviorDesc: "未完成：" + instance.Chore.Name,
+					ScoreChange:  -instance.Chore.PenaltyPoints,
+					TemplateID:   instance.Chore.TemplateID,
+					Status:       BehaviorStatusApproved,
+					Source:       BehaviorSourceChoreMissed,
+					RecordedAt:   instance.DueAt,
+				}
+				if _, err := CreateBehaviorRecord(tx, &record); err != nil {
+					return err
+				}
+				updates["behavior_record_id"] = record.ID
+			}
+
+			missed++
+			return tx.Model(&instance).Updates(updates).Error
+		})
+		if err != nil {
+			return missed, fmt.Errorf("failed to mark chore instance %d as missed: %w", instanceID, err)
+		}
+	}
+
+	return missed, nil
+}
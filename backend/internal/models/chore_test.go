@@ -0,0 +1,94 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/utils"
+	"child-behavior-app/migrations"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openDatabase 打开执行了全部迁移的 SQLite 内存数据库
+func openDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := models.InitDBWithConfig(utils.DatabaseConfig{Driver: utils.DatabaseDriverSQLite, Path: utils.SQLiteMemoryPath})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	db = db.Session(&gorm.Session{Logger: logger.Default.LogMode(logger.Silent)})
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("get sql.DB: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func TestMarkMissedChores(t *testing.T) {
+	db := openDatabase(t)
+	parent := models.User{Nickname: "爸爸", Role: "parent"}
+	if err := db.Create(&parent).Error; err != nil {
+		t.Fatalf("create parent: %v", err)
+	}
+	child := models.User{Nickname: "小明", Role: "child", ParentID: &parent.ID}
+	if err := db.Create(&child).Error; err != nil {
+		t.Fatalf("create child: %v", err)
+	}
+
+	chores := map[string]*models.Chore{"09:00": nil, "18:00": nil, "": nil}
+	for dueTime := range chores {
+		chore := models.Chore{
+			ChildID: child.ID, Name: "任务" + dueTime, Category: models.CategoryLife, Points: 5, PenaltyPoints: 2,
+			Recurrence: models.RecurrenceDaily, DueTime: dueTime, IsActive: true, CreatedBy: parent.ID,
+		}
+		if err := db.Create(&chore).Error; err != nil {
+			t.Fatalf("create chore: %v", err)
+		}
+		chores[dueTime] = &chore
+	}
+
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)
+	if err := models.MaterializeChoreInstances(db, day); err != nil {
+		t.Fatalf("materialize: %v", err)
+	}
+	status := func(dueTime string) string {
+		t.Helper()
+		var instance models.ChoreInstance
+		if err := db.Where("chore_id = ?", chores[dueTime].ID).First(&instance).Error; err != nil {
+			t.Fatalf("load instance: %v", err)
+		}
+		return instance.Status
+	}
+
+	// 下午3点只有上午9点截止的任务错过，全天任务和晚上截止的任务仍可完成
+	missed, err := models.MarkMissedChores(db, day.Add(15*time.Hour))
+	if err != nil {
+		t.Fatalf("mark missed at 15:00: %v", err)
+	}
+	if missed != 1 || status("09:00") != models.ChoreStatusMissed || status("18:00") != models.ChoreStatusPending || status("") != models.ChoreStatusPending {
+		t.Fatalf("at 15:00: missed %d, statuses %s/%s/%s", missed, status("09:00"), status("18:00"), status(""))
+	}
+	var penalty models.BehaviorRecord
+	if err := db.Where("user_id = ? AND source = ?", child.ID, models.BehaviorSourceChoreMissed).First(&penalty).Error; err != nil {
+		t.Fatalf("load penalty record: %v", err)
+	}
+	if penalty.ScoreChange != -2 || !penalty.RecordedAt.Equal(day.Add(9*time.Hour)) {
+		t.Fatalf("penalty record = %+v", penalty)
+	}
+
+	// 第二天剩下的任务都已错过
+	missed, err = models.MarkMissedChores(db, day.Add(24*time.Hour+30*time.Minute))
+	if err != nil {
+		t.Fatalf("mark missed next day: %v", err)
+	}
+	if missed != 2 || status("18:00") != models.ChoreStatusMissed || status("") != models.ChoreStatusMissed {
+		t.Fatalf("next day: missed %d, statuses %s/%s", missed, status("18:00"), status(""))
+	}
+}
@@ -0,0 +1,9 @@
+package models
+
+// 兑换记录状态
+// 需要家长确认的兑换先扣除（冻结）积分和库存，确认后完成，取消时退回积分并恢复库存
+const (
+	ExchangeStatusPending   = "pending"
+	ExchangeStatusCompleted = "completed"
+	ExchangeStatusCancelled = "cancelled"
+)
@@ -0,0 +1,220 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 家庭成员角色
+const (
+	FamilyRoleOwner    = "owner"    // 创建者，可以管理成员
+	FamilyRoleGuardian = "guardian" // 共同监护人，可以记录行为和管理奖励
+	FamilyRoleViewer   = "viewer"   // 只读成员，例如祖父母
+)
+
+// FamilyInviteTTL 家庭邀请码有效期
+const FamilyInviteTTL = 7 * 24 * time.Hour
+
+// 家庭相关错误
+var (
+	// ErrFamilyInviteInvalid 邀请码不存在、已使用或已过期
+	ErrFamilyInviteInvalid = errors.New("family invite is invalid or expired")
+	// ErrAlreadyFamilyMember 用户已经是该家庭的成员
+	ErrAlreadyFamilyMember = errors.New("user already belongs to this family")
+	// ErrFamilyNotEmpty 用户当前的家庭还有儿童或其他成员，不能离开
+	ErrFamilyNotEmpty = errors.New("current family has children or other members")
+)
+
+// FamilyMemberIDs 家庭中所有家长的用户ID子查询，用于 created_by IN (?) 等条件
+func FamilyMemberIDs(db *gorm.DB, familyID uint) *gorm.DB {
+	return db.Model(&FamilyMember{}).Select("user_id").Where("family_id = ?", familyID)
+}
+
+// FamilyChildIDs 家庭中所有儿童的用户ID子查询
+func FamilyChildIDs(db *gorm.DB, familyID uint) *gorm.DB {
+	return db.Model(&User{}).Select("id").Where("family_id = ? AND role = ?", familyID, "child")
+}
+
+// FindFamilyChild 获取属于家庭的儿童
+func FindFamilyChild(db *gorm.DB, familyID, childID uint) (*User, error) {
+	var child User
+	if err := db.Where("id = ? AND family_id = ? AND role = ?", childID, familyID, "child").First(&child).Error; err != nil {
+		return nil, err
+	}
+	return &child, nil
+}
+
+// GetFamilyMember 获取家长所在家庭的成员记录
+func GetFamilyMember(db *gorm.DB, userID uint) (*FamilyMember, error) {
+	var member FamilyMember
+	if err := db.Where("user_id = ?", userID).First(&member).Error; err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// CreateFamily 为家长创建家庭并设为所有者，同时写入默认行为目录，调用方应在事务中调用
+func CreateFamily(tx *gorm.DB, owner *User) (*Family, error) {
+	family := Family{
+		Name:    owner.Nickname + "的家庭",
+		OwnerID: owner.ID,
+	}
+	if err := tx.Create(&family).Error; err != nil {
+		return nil, fmt.Errorf("failed to create family: %w", err)
+	}
+
+	member := FamilyMember{FamilyID: family.ID, UserID: owner.ID, Role: FamilyRoleOwner}
+	if err := tx.Create(&member).Error; err != nil {
+		return nil, fmt.Errorf("failed to create family member: %w", err)
+	}
+
+	if err := tx.Model(owner).Update("family_id", family.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to update user family: %w", err)
+	}
+	owner.FamilyID = &family.ID
+
+	if err := SeedBehaviorTemplates(tx, owner.ID); err != nil {
+		return nil, err
+	}
+	return &family, nil
+}
+
+// CreateFamilyInvite 生成家庭邀请码
+func CreateFamilyInvite(db *gorm.DB, familyID, creatorID uint, role string) (*FamilyInvite, error) {
+	invite := FamilyInvite{
+		FamilyID:  familyID,
+		Role:      role,
+		CreatedBy: creatorID,
+		ExpiresAt: time.Now().Add(FamilyInviteTTL),
+	}
+
+	// 邀请码有唯一索引，极少数情况下冲突时重新生成
+	for attempt := 0; attempt < 3; attempt++ {
+		code, err := generateShortCode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate invite code: %w", err)
+		}
+		invite.Code = code
+		result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&invite)
+		if result.Error != nil {
+			return nil, fmt.Errorf("failed to create family invite: %w", result.Error)
+		}
+		if result.RowsAffected == 1 {
+			return &invite, nil
+		}
+	}
+	return nil, errors.New("failed to generate a unique invite code")
+}
+
+// AcceptFamilyInvite 使用邀请码加入家庭
+// 家长注册时会自动创建只有自己的家庭，加入新家庭时原家庭如果没有儿童和其他成员则连同其配置一并删除
+func AcceptFamilyInvite(db *gorm.DB, userID uint, code string) (*FamilyMember, error) {
+	var member FamilyMember
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var invite FamilyInvite
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", code).First(&invite).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrFamilyInviteInvalid
+		}
+		if err != nil {
+			return err
+		}
+		if invite.UsedAt != nil || time.Now().After(invite.ExpiresAt) {
+			return ErrFamilyInviteInvalid
+		}
+
+		if err := leaveEmptyFamily(tx, userID, invite.FamilyID); err != nil {
+			return err
+		}
+
+		member = FamilyMember{FamilyID: invite.FamilyID, UserID: userID, Role: invite.Role}
+		if err := tx.Create(&member).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&User{}).Where("id = ?", userID).Update("family_id", invite.FamilyID).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		return tx.Model(&invite).Updates(map[string]interface{}{
+			"used_by": userID,
+			"used_at": now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// familyOwnedModels 以 created_by 归属家庭的配置，成员离开时转给家庭所有者
+var familyOwnedModels = []interface{}{
+	&BehaviorTemplate{},
+	&Reward{},
+	&Chore{},
+	&StreakBonusRule{},
+	&Achievement{},
+	&Level{},
+}
+
+// RemoveFamilyMember 将家长移出家庭
+// 其创建的模板、奖励等配置转给家庭所有者，被移除的家长获得一个新的空家庭
+func RemoveFamilyMember(db *gorm.DB, member *FamilyMember) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var family Family
+		if err := tx.First(&family, member.FamilyID).Error; err != nil {
+			return err
+		}
+		for _, model := range familyOwnedModels {
+			if err := tx.Model(model).Where("created_by = ?", member.UserID).Update("created_by", family.OwnerID).Error; err != nil {
+				return fmt.Errorf("failed to transfer family settings: %w", err)
+			}
+		}
+
+		if err := tx.Delete(member).Error; err != nil {
+			return err
+		}
+		var user User
+		if err := tx.First(&user, member.UserID).Error; err != nil {
+			return err
+		}
+		_, err := CreateFamily(tx, &user)
+		return err
+	})
+}
+
+// leaveEmptyFamily 离开当前家庭，只允许离开没有儿童和其他成员的家庭
+// 原家庭及用户在其中创建的行为目录、奖励等配置一并删除，不会带入新家庭
+func leaveEmptyFamily(tx *gorm.DB, userID, targetFamilyID uint) error {
+	current, err := GetFamilyMember(tx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if current.FamilyID == targetFamilyID {
+		return ErrAlreadyFamilyMember
+	}
+
+	var others int64
+	if err := tx.Model(&FamilyMember{}).Where("family_id = ? AND user_id <> ?", current.FamilyID, userID).Count(&others).Error; err != nil {
+		return err
+	}
+	var children int64
+	if err := tx.Model(&User{}).Where("family_id = ? AND role = ?", current.FamilyID, "child").Count(&children).Error; err != nil {
+		return err
+	}
+	if others > 0 || children > 0 {
+		return ErrFamilyNotEmpty
+	}
+
+	if err := deleteFamilySettings(tx, []uint{userID}); err != nil {
+		return err
+	}
+	return deleteFamilyRows(tx, current.FamilyID)
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 登录失败锁定的类型
+const (
+	LockoutScopeLogin          = "login"           // 家长手机号登录，按手机号计数
+	LockoutScopeVerifyPassword = "verify_password" // 进入家长模式的密码验证，按用户计数
+	LockoutScopeChildPin       = "child_pin"       // 儿童PIN登录，按儿童计数
+	LockoutScopeTwoFactor      = "two_factor"      // 两步验证码，按用户计数
+)
+
+const (
+	// lockoutThreshold 连续失败达到该次数后开始锁定
+	lockoutThreshold = 5
+	// lockoutBaseDuration 首次锁定的时长，之后每次失败翻倍
+	lockoutBaseDuration = time.Minute
+	// lockoutMaxDuration 单次锁定的最长时长
+	lockoutMaxDuration = time.Hour
+	// lockoutResetAfter 最后一次失败超过该时间后重新计数
+	lockoutResetAfter = 24 * time.Hour
+)
+
+// LockStatus 登录锁定状态
+type LockStatus struct {
+	Locked            bool       `json:"locked"`
+	LockedUntil       *time.Time `json:"locked_until,omitempty"`
+	RetryAfter        int        `json:"retry_after"`        // 距离解锁的秒数
+	RemainingAttempts int        `json:"remaining_attempts"` // 下一次锁定前还能失败的次数
+}
+
+// lockoutDuration 计算第 failures 次失败后的锁定时长，未达到阈值时不锁定
+func lockoutDuration(failures int) time.Duration {
+	if failures < lockoutThreshold {
+		return 0
+	}
+	duration := lockoutBaseDuration
+	for i := lockoutThreshold; i < failures && duration < lockoutMaxDuration; i++ {
+		duration *= 2
+	}
+	if duration > lockoutMaxDuration {
+		duration = lockoutMaxDuration
+	}
+	return duration
+}
+
+// lockStatus 根据失败记录构建锁定状态
+func (l *LoginLockout) lockStatus(now time.Time) LockStatus {
+	failures := l.Failures
+	if l.LastFailedAt != nil && now.Sub(*l.LastFailedAt) > lockoutResetAfter {
+		failures = 0
+	}
+
+	status := LockStatus{RemainingAttempts: lockoutThreshold - failures}
+	if status.RemainingAttempts < 1 {
+		// 达到阈值后，锁定结束只允许再尝试一次
+		status.RemainingAttempts = 1
+	}
+	if l.LockedUntil != nil && l.LockedUntil.After(now) {
+		status.Locked = true
+		status.LockedUntil = l.LockedUntil
+		status.RetryAfter = int(math.Ceil(l.LockedUntil.Sub(now).Seconds()))
+		status.RemainingAttempts = 0
+	}
+	return status
+}
+
+// GetLockStatus 获取登录锁定状态，没有失败记录时返回未锁定
+func GetLockStatus(db *gorm.DB, scope, subject string, now time.Time) (LockStatus, error) {
+	var lockout LoginLockout
+	err := db.Where("scope = ? AND subject = ?", scope, truncate(subject, 100)).Limit(1).Find(&lockout).Error
+	if err != nil {
+		return LockStatus{}, fmt.Errorf("failed to get lock status: %w", err)
+	}
+	return lockout.lockStatus(now), nil
+}
+
+// RecordLoginFailure 记录一次失败，达到阈值后按失败次数递增锁定时长
+func RecordLoginFailure(db *gorm.DB, scope, subject string, now time.Time) (LockStatus, error) {
+	subject = truncate(subject, 100)
+
+	var status LockStatus
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// 先占位再加锁，保证并发的失败请求逐个计数
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&LoginLockout{Scope: scope, Subject: subject}).Error; err != nil {
+			return err
+		}
+
+		var lockout LoginLockout
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("scope = ? AND subject = ?", scope, subject).First(&lockout).Error; err != nil {
+			return err
+		}
+
+		if lockout.LastFailedAt != nil && now.Sub(*lockout.LastFailedAt) > lockoutResetAfter {
+			lockout.Failures = 0
+		}
+		lockout.Failures++
+		lockout.LastFailedAt = &now
+		if duration := lockoutDuration(lockout.Failures); duration > 0 {
+			lockedUntil := now.Add(duration)
+			lockout.LockedUntil = &lockedUntil
+		}
+
+		if err := tx.Model(&lockout).Updates(map[string]interface{}{
+			"failures":       lockout.Failures,
+			"last_failed_at": lockout.LastFailedAt,
+			"locked_until":   lockout.LockedUntil,
+		}).Error; err != nil {
+			return err
+		}
+		status = lockout.lockStatus(now)
+		return nil
+	})
+	if err != nil {
+		return LockStatus{}, fmt.Errorf("failed to record login failure: %w", err)
+	}
+	return status, nil
+}
+
+// ClearLoginFailures 清除失败记录，验证成功或管理员解锁时调用
+func ClearLoginFailures(db *gorm.DB, scope, subject string) error {
+	return db.Where("scope = ? AND subject = ?", scope, truncate(subject, 100)).Delete(&LoginLockout{}).Error
+}
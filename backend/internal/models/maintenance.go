@@ -0,0 +1,373 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// 运维操作错误
+var (
+	// ErrMergeSameUser 不能把账户合并到自身
+	ErrMergeSameUser = errors.New("cannot merge a user into itself")
+	// ErrMergeRoleMismatch 合并的两个账户角色不同
+	ErrMergeRoleMismatch = errors.New("users to merge have different roles")
+	// ErrMergeFamilyMismatch 合并的两个儿童账户不在同一个家庭
+	ErrMergeFamilyMismatch = errors.New("children to merge must belong to the same family")
+	// ErrMergeFamilyNotEmpty 重复的家长账户所在家庭还有其他成员或儿童
+	ErrMergeFamilyNotEmpty = errors.New("duplicate parent belongs to another family with members or children")
+)
+
+// userColumn 引用用户ID的列
+type userColumn struct {
+	model  interface{}
+	column string
+}
+
+// userDataColumns 只属于该用户的数据，彻底删除用户时一并删除
+var userDataColumns = []userColumn{
+	{&UserPoints{}, "user_id"},
+	{&PointTransaction{}, "user_id"},
+	// 行为记录的 child_id 字段对应 user_id 列
+	{&BehaviorRecord{}, "user_id"},
+	{&ExchangeRecord{}, "user_id"},
+	{&ChoreInstance{}, "child_id"},
+	{&Chore{}, "child_id"},
+	{&ChildStreak{}, "child_id"},
+	{&ChildAchievement{}, "child_id"},
+	{&PairingCode{}, "child_id"},
+	{&ChildDevice{}, "child_id"},
+}
+
+// userCredentialColumns 用户的登录凭据和会话，合并或删除账户时删除
+// 通行密钥绑定了用户ID，不能转给其他账户
+var userCredentialColumns = []userColumn{
+	{&Session{}, "user_id"},
+	{&RecoveryCode{}, "user_id"},
+	{&PasswordResetCode{}, "user_id"},
+	{&WebAuthnCredential{}, "user_id"},
+	{&IdempotencyKey{}, "user_id"},
+}
+
+// mergedUserColumns 合并账户时从重复账户转给保留账户的列
+// 有唯一索引的表由 mergeUniqueRows 单独处理，积分记录在合并后按流水重新计算
+var mergedUserColumns = []userColumn{
+	{&PointTransaction{}, "user_id"},
+	{&PointTransaction{}, "actor_id"},
+	{&BehaviorRecord{}, "user_id"},
+	{&BehaviorRecord{}, "recorder_id"},
+	{&BehaviorRecord{}, "reviewer_id"},
+	{&ExchangeRecord{}, "user_id"},
+	{&ExchangeRecord{}, "reviewer_id"},
+	{&Chore{}, "child_id"},
+	{&ChoreInstance{}, "child_id"},
+	{&ChoreInstance{}, "completed_by"},
+	{&PairingCode{}, "child_id"},
+	{&PairingCode{}, "created_by"},
+	{&FamilyInvite{}, "created_by"},
+	{&FamilyInvite{}, "used_by"},
+	{&User{}, "parent_id"},
+}
+
+// UnlockUser 清除用户所有类型的登录失败锁定
+func UnlockUser(db *gorm.DB, user *User) error {
+	subject := strconv.FormatUint(uint64(user.ID), 10)
+	for _, scope := range []string{LockoutScopeVerifyPassword, LockoutScopeChildPin, LockoutScopeTwoFactor} {
+		if err := ClearLoginFailures(db, scope, subject); err != nil {
+			return fmt.Errorf("failed to clear %s lockout: %w", scope, err)
+		}
+	}
+	if user.Phone != nil {
+		if err := ClearLoginFailures(db, LockoutScopeLogin, *user.Phone); err != nil {
+			return fmt.Errorf("failed to clear %s lockout: %w", LockoutScopeLogin, err)
+		}
+	}
+	return nil
+}
+
+// ResetUserPassword 设置新的密码哈希，撤销所有会话并清除登录锁定
+func ResetUserPassword(db *gorm.DB, user *User, hashedPassword string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("password", hashedPassword).Error; err != nil {
+			return fmt.Errorf("failed to update password: %w", err)
+		}
+		if err := RevokeUserSessions(tx, user.ID); err != nil {
+			return fmt.Errorf("failed to revoke sessions: %w", err)
+		}
+		return UnlockUser(tx, user)
+	})
+}
+
+// MergeUsers 将重复账户的数据并入保留账户，然后删除重复账户，两个账户的角色需相同
+// 儿童需在同一个家庭；家长不在同一个家庭时，重复账户的家庭不能有其他成员或儿童，合并后该家庭被删除。
+// 重复账户的会话和登录凭据被删除，连续天数和成就按保留账户合并，积分按合并后的流水重新计算；审计日志保持原样
+func MergeUsers(db *gorm.DB, keepID, duplicateID uint) (*User, error) {
+	if keepID == duplicateID {
+		return nil, ErrMergeSameUser
+	}
+
+	var keep User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var duplicate User
+		if err := tx.First(&keep, keepID).Error; err != nil {
+			return fmt.Errorf("failed to load user %d: %w", keepID, err)
+		}
+		if err := tx.First(&duplicate, duplicateID).Error; err != nil {
+			return fmt.Errorf("failed to load user %d: %w", duplicateID, err)
+		}
+		if keep.Role != duplicate.Role {
+			return ErrMergeRoleMismatch
+		}
+
+		if keep.Role == "child" {
+			if keep.FamilyID == nil || duplicate.FamilyID == nil || *keep.FamilyID != *duplicate.FamilyID {
+				return ErrMergeFamilyMismatch
+			}
+		} else if err := mergeParentFamily(tx, &keep, &duplicate); err != nil {
+			return err
+		}
+
+		if err := mergeUniqueRows(tx, keep.ID, duplicate.ID); err != nil {
+			return err
+		}
+		for _, ref := range mergedUserColumns {
+			if err := tx.Model(ref.model).Where(ref.column+" = ?", duplicate.ID).Update(ref.column, keep.ID).Error; err != nil {
+				return fmt.Errorf("failed to merge %s: %w", ref.column, err)
+			}
+		}
+		for _, model := range familyOwnedModels {
+			if err := tx.Model(model).Where("created_by = ?", duplicate.ID).Update("created_by", keep.ID).Error; err != nil {
+				return fmt.Errorf("failed to merge family settings: %w", err)
+			}
+		}
+
+		duplicates := []User{duplicate}
+		if err := deleteUserData(tx, duplicates, append(userCredentialColumns, userColumn{&UserPoints{}, "user_id"})); err != nil {
+			return err
+		}
+		if err := deleteUsers(tx, duplicates); err != nil {
+			return err
+		}
+		if _, err := RebuildUserPoints(tx, keep.ID); err != nil {
+			return err
+		}
+		return tx.First(&keep, keep.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &keep, nil
+}
+
+// mergeParentFamily 处理重复家长账户的家庭成员关系
+// 同一家庭时删除重复账户的成员记录，重复账户是所有者时由保留账户接任；
+// 不同家庭时删除重复账户只有自己的家庭及其配置
+func mergeParentFamily(tx *gorm.DB, keep, duplicate *User) error {
+	member, err := GetFamilyMember(tx, duplicate.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if keep.FamilyID != nil && *keep.FamilyID == member.FamilyID {
+		if member.Role == FamilyRoleOwner {
+			if err := tx.Model(&Family{}).Where("id = ?", member.FamilyID).Update("owner_id", keep.ID).Error; err != nil {
+				return fmt.Errorf("failed to transfer family owner: %w", err)
+			}
+			if err := tx.Model(&FamilyMember{}).Where("user_id = ?", keep.ID).Update("role", FamilyRoleOwner).Error; err != nil {
+				return fmt.Errorf("failed to transfer family owner: %w", err)
+			}
+		}
+		return tx.Delete(member).Error
+	}
+
+	var others int64
+	if err := tx.Model(&FamilyMember{}).Where("family_id = ? AND user_id <> ?", member.FamilyID, duplicate.ID).Count(&others).Error; err != nil {
+		return err
+	}
+	var children int64
+	if err := tx.Model(&User{}).Where("family_id = ? AND role = ?", member.FamilyID, "child").Count(&children).Error; err != nil {
+		return err
+	}
+	if others > 0 || children > 0 {
+		return ErrMergeFamilyNotEmpty
+	}
+	// 空家庭的配置只属于重复账户，不并入保留账户的家庭
+	if err := deleteFamilySettings(tx, []uint{duplicate.ID}); err != nil {
+		return err
+	}
+	return deleteFamilyRows(tx, member.FamilyID)
+}
+
+// mergeUniqueRows 合并有唯一索引的数据，保留账户已有相同记录时删除重复账户的记录
+func mergeUniqueRows(tx *gorm.DB, keepID, duplicateID uint) error {
+	// 连续天数取两者中较新的当前连续天数和较大的最好成绩
+	var streaks []ChildStreak
+	if err := tx.Where("child_id = ?", duplicateID).Find(&streaks).Error; err != nil {
+		return fmt.Errorf("failed to load streaks: %w", err)
+	}
+	for _, streak := range streaks {
+		var kept ChildStreak
+		err := tx.Where("child_id = ? AND template_id = ?", keepID, streak.TemplateID).First(&kept).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if err := tx.Model(&streak).Update("child_id", keepID).Error; err != nil {
+				return fmt.Errorf("failed to merge streak: %w", err)
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load streak: %w", err)
+		}
+		if streak.LastDate > kept.LastDate {
+			kept.CurrentStreak = streak.CurrentStreak
+			kept.LastDate = streak.LastDate
+		}
+		if streak.BestStreak > kept.BestStreak {
+			kept.BestStreak = streak.BestStreak
+		}
+		if err := tx.Save(&kept).Error; err != nil {
+			return fmt.Errorf("failed to merge streak: %w", err)
+		}
+		if err := tx.Delete(&streak).Error; err != nil {
+			return fmt.Errorf("failed to merge streak: %w", err)
+		}
+	}
+
+	conflicts := []struct {
+		model  interface{}
+		owner  string
+		unique string
+	}{
+		{&ChildAchievement{}, "child_id", "achievement_id"},
+		{&ChildDevice{}, "child_id", "device_id"},
+		{&Level{}, "created_by", "level"},
+	}
+	for _, conflict := range conflicts {
+		kept := tx.Model(conflict.model).Select(conflict.unique).Where(conflict.owner+" = ?", keepID)
+		if err := tx.Where(conflict.owner+" = ? AND "+conflict.unique+" IN (?)", duplicateID, kept).
+			Delete(conflict.model).Error; err != nil {
+			return fmt.Errorf("failed to merge %T: %w", conflict.model, err)
+		}
+		if conflict.owner == "created_by" {
+			// 家庭配置随 familyOwnedModels 一起转移
+			continue
+		}
+		if err := tx.Model(conflict.model).Where(conflict.owner+" = ?", duplicateID).Update(conflict.owner, keepID).Error; err != nil {
+			return fmt.Errorf("failed to merge %T: %w", conflict.model, err)
+		}
+	}
+	return nil
+}
+
+// DeleteFamily 彻底删除家庭：家长和儿童账户及其全部数据、家庭配置、邀请码、角色权限和审计日志，返回被删除的用户
+func DeleteFamily(db *gorm.DB, familyID uint) ([]User, error) {
+	var users []User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var family Family
+		if err := tx.First(&family, familyID).Error; err != nil {
+			return fmt.Errorf("failed to load family %d: %w", familyID, err)
+		}
+
+		if err := tx.Where("family_id = ? OR id IN (?)", familyID, FamilyMemberIDs(tx, familyID)).
+			Order("id ASC").Find(&users).Error; err != nil {
+			return fmt.Errorf("failed to load family users: %w", err)
+		}
+		var parentIDs []uint
+		for _, user := range users {
+			if user.Role == "parent" {
+				parentIDs = append(parentIDs, user.ID)
+			}
+		}
+
+		// 按外键依赖的顺序删除：儿童的数据、家长创建的配置、家庭、用户
+		if err := deleteUserData(tx, users, append(userDataColumns, userCredentialColumns...)); err != nil {
+			return err
+		}
+		if err := deleteFamilySettings(tx, parentIDs); err != nil {
+			return err
+		}
+		if err := tx.Where("family_id = ?", familyID).Delete(&AuditEvent{}).Error; err != nil {
+			return fmt.Errorf("failed to delete audit events: %w", err)
+		}
+		if err := deleteFamilyRows(tx, familyID); err != nil {
+			return err
+		}
+		return deleteUsers(tx, users)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// deleteFamilySettings 删除家长创建的家庭配置
+func deleteFamilySettings(tx *gorm.DB, parentIDs []uint) error {
+	if len(parentIDs) == 0 {
+		return nil
+	}
+	for _, model := range familyOwnedModels {
+		if err := tx.Where("created_by IN ?", parentIDs).Delete(model).Error; err != nil {
+			return fmt.Errorf("failed to delete family settings: %w", err)
+		}
+	}
+	return nil
+}
+
+// deleteFamilyRows 删除家庭本身及其成员记录、邀请码和角色权限
+func deleteFamilyRows(tx *gorm.DB, familyID uint) error {
+	for _, model := range []interface{}{&FamilyMember{}, &FamilyInvite{}, &FamilyRolePermission{}} {
+		if err := tx.Where("family_id = ?", familyID).Delete(model).Error; err != nil {
+			return fmt.Errorf("failed to delete %T: %w", model, err)
+		}
+	}
+	return tx.Delete(&Family{}, familyID).Error
+}
+
+// userIDsOf 获取用户ID列表
+func userIDsOf(users []User) []uint {
+	userIDs := make([]uint, len(users))
+	for i, user := range users {
+		userIDs[i] = user.ID
+	}
+	return userIDs
+}
+
+// deleteUserData 删除用户的登录锁定记录以及 columns 中属于这些用户的数据
+func deleteUserData(tx *gorm.DB, users []User, columns []userColumn) error {
+	if len(users) == 0 {
+		return nil
+	}
+	for i := range users {
+		if err := UnlockUser(tx, &users[i]); err != nil {
+			return err
+		}
+	}
+
+	userIDs := userIDsOf(users)
+	for _, ref := range columns {
+		if err := tx.Where(ref.column+" IN ?", userIDs).Delete(ref.model).Error; err != nil {
+			return fmt.Errorf("failed to delete %T: %w", ref.model, err)
+		}
+	}
+	return nil
+}
+
+// deleteUsers 删除用户账户，引用这些用户的数据需已经删除或转移
+func deleteUsers(tx *gorm.DB, users []User) error {
+	if len(users) == 0 {
+		return nil
+	}
+	userIDs := userIDsOf(users)
+	// 先解除儿童账户与创建人的关联，再删除用户
+	if err := tx.Model(&User{}).Where("parent_id IN ?", userIDs).Update("parent_id", nil).Error; err != nil {
+		return fmt.Errorf("failed to detach children: %w", err)
+	}
+	if err := tx.Where("id IN ?", userIDs).Delete(&User{}).Error; err != nil {
+		return fmt.Errorf("failed to delete users: %w", err)
+	}
+	return nil
+}
@@ -1,55 +1,313 @@
 package models
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
 	"time"
 
 	"child-behavior-app/internal/utils"
 
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
 // User 用户表
 type User struct {
+	ID           uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Phone        *string   `json:"phone" gorm:"uniqueIndex;size:20"`
+	Password     *string   `json:"-" gorm:"size:255"`
+	Nickname     string    `json:"nickname" gorm:"size:50;not null"`
+	Email        string    `json:"email" gorm:"size:100"`
+	Avatar       string    `json:"avatar" gorm:"size:255"`
+	Age          int       `json:"age" gorm:"default:0"`
+	Gender       string    `json:"gender" gorm:"size:10"`
+	Role         string    `json:"role" gorm:"size:20;not null"` // parent 或 child
+	ParentID     *uint     `json:"parent_id" gorm:"index"`       // 创建儿童账户的家长
+	FamilyID     *uint     `json:"family_id" gorm:"index"`
+	Pin          *string   `json:"-" gorm:"size:255"`                          // 儿童登录PIN的哈希，由家长设置
+	TokenVersion int       `json:"-" gorm:"default:0;not null"`                // 修改密码或退出所有设备时递增
+	TOTPSecret   *string   `json:"-" gorm:"size:64"`                           // 两步验证密钥，开启前为待验证的密钥
+	TOTPEnabled  bool      `json:"totp_enabled" gorm:"default:false;not null"` // 是否已开启两步验证
+	TOTPLastStep int64     `json:"-" gorm:"default:0;not null"`                // 最近一次使用的验证码时间步，防止重放
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+
+	// 关联关系
+	Parent   *User  `json:"parent,omitempty" gorm:"foreignKey:ParentID"`
+	Children []User `json:"children,omitempty" gorm:"foreignKey:ParentID"`
+}
+
+// Family 家庭表，家长和儿童都属于一个家庭
+type Family struct {
 	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
-	Phone     *string   `json:"phone" gorm:"uniqueIndex;size:20"`
-	Password  *string   `json:"-" gorm:"size:255"`
-	Nickname  string    `json:"nickname" gorm:"size:50;not null"`
-	Email     string    `json:"email" gorm:"size:100"`
-	Avatar    string    `json:"avatar" gorm:"size:255"`
-	Age       int       `json:"age" gorm:"default:0"`
-	Gender    string    `json:"gender" gorm:"size:10"`
-	Role      string    `json:"role" gorm:"type:enum('parent','child');not null"`
-	ParentID  *uint     `json:"parent_id" gorm:"index"`
+	Name      string    `json:"name" gorm:"size:100;not null"`
+	OwnerID   uint      `json:"owner_id" gorm:"not null;index"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 
 	// 关联关系
-	Parent   *User  `json:"parent,omitempty" gorm:"foreignKey:ParentID"`
-	Children []User `json:"children,omitempty" gorm:"foreignKey:ParentID"`
+	Members []FamilyMember `json:"members,omitempty" gorm:"foreignKey:FamilyID"`
+}
+
+// FamilyMember 家庭成员表，记录家长在家庭中的角色，儿童通过 users.family_id 关联家庭
+type FamilyMember struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	FamilyID  uint      `json:"family_id" gorm:"not null;index"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex"`
+	Role      string    `json:"role" gorm:"size:20;not null"` // owner/guardian/viewer
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// 关联关系
+	User User `json:"user" gorm:"foreignKey:UserID"`
+}
+
+// FamilyInvite 家庭邀请码表，被邀请的家长使用邀请码加入家庭
+type FamilyInvite struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	FamilyID  uint       `json:"family_id" gorm:"not null;index"`
+	Code      string     `json:"code" gorm:"size:16;not null;uniqueIndex"`
+	Role      string     `json:"role" gorm:"size:20;not null"`
+	CreatedBy uint       `json:"created_by" gorm:"not null"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedBy    *uint      `json:"used_by"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// FamilyRolePermission 家庭角色权限表，家庭可以调整内置角色的权限或添加自定义角色（如保姆、老师）
+type FamilyRolePermission struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	FamilyID   uint      `json:"family_id" gorm:"not null;uniqueIndex:idx_family_role_permission"`
+	Role       string    `json:"role" gorm:"size:20;not null;uniqueIndex:idx_family_role_permission"`
+	Permission string    `json:"permission" gorm:"size:50;not null;uniqueIndex:idx_family_role_permission"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Session 登录会话表，保存刷新令牌的哈希，每次刷新时轮换
+type Session struct {
+	ID                uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID            uint       `json:"user_id" gorm:"not null;index"`
+	TokenHash         string     `json:"-" gorm:"size:64;not null;uniqueIndex"`
+	PreviousTokenHash string     `json:"-" gorm:"size:64;index"` // 上一个刷新令牌，再次出现说明令牌泄露
+	TokenVersion      int        `json:"-" gorm:"not null"`
+	DeviceID          string     `json:"device_id" gorm:"size:64"`
+	UserAgent         string     `json:"user_agent" gorm:"size:255"`
+	IPAddress         string     `json:"ip_address" gorm:"size:45"`
+	ExpiresAt         time.Time  `json:"expires_at" gorm:"not null"`
+	LastUsedAt        time.Time  `json:"last_used_at"`
+	RevokedAt         *time.Time `json:"revoked_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// PairingCode 儿童设备配对码表，家长生成，一次性使用
+type PairingCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Code      string     `json:"code" gorm:"size:16;not null;uniqueIndex"`
+	ChildID   uint       `json:"child_id" gorm:"not null;index"`
+	CreatedBy uint       `json:"created_by" gorm:"not null"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// RecoveryCode 两步验证恢复码表，丢失身份验证器时代替验证码登录，每个只能使用一次
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"size:64;not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// WebAuthnCredential 家长注册的通行密钥（WebAuthn凭据）表
+type WebAuthnCredential struct {
+	ID              uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID          uint       `json:"user_id" gorm:"not null;index"`
+	CredentialID    string     `json:"credential_id" gorm:"size:255;not null;uniqueIndex"` // base64url 编码的凭据ID
+	PublicKey       []byte     `json:"-" gorm:"not null"`                                  // COSE 格式的公钥
+	AttestationType string     `json:"-" gorm:"size:32"`
+	AAGUID          []byte     `json:"-" gorm:"size:16"`
+	SignCount       uint32     `json:"-" gorm:"default:0;not null"`
+	Transports      string     `json:"-" gorm:"size:100"` // 逗号分隔的传输方式
+	BackupEligible  bool       `json:"backup_eligible" gorm:"default:false;not null"`
+	BackupState     bool       `json:"backup_state" gorm:"default:false;not null"`
+	Name            string     `json:"name" gorm:"size:50"`
+	LastUsedAt      *time.Time `json:"last_used_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// PasswordResetCode 找回密码验证码表，只保存验证码的哈希
+type PasswordResetCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"size:64;not null"`
+	Attempts  int        `json:"attempts" gorm:"default:0;not null"` // 输错次数
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ChildDevice 已配对的儿童设备表
+type ChildDevice struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	ChildID     uint      `json:"child_id" gorm:"not null;uniqueIndex:idx_child_device"`
+	DeviceID    string    `json:"device_id" gorm:"size:64;not null;uniqueIndex:idx_child_device"`
+	DeviceName  string    `json:"device_name" gorm:"size:100"`
+	LastLoginAt time.Time `json:"last_login_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
 }
 
 // BehaviorRecord 行为记录表
 type BehaviorRecord struct {
-	ID           uint      `json:"id" gorm:"primaryKey;autoIncrement"`
-	ChildID      uint      `json:"child_id" gorm:"column:user_id;not null;index"`
-	RecorderID   uint      `json:"recorder_id" gorm:"not null;index"`
-	BehaviorType string    `json:"behavior_type" gorm:"column:behavior_type;size:20;not null"`
-	BehaviorDesc string    `json:"behavior_desc" gorm:"column:description;type:text;not null"`
-	ScoreChange  int       `json:"score_change" gorm:"column:points;not null"`
-	ImageURL     string    `json:"image_url" gorm:"column:image_url;size:255"`
-	RecordedAt   time.Time `json:"recorded_at" gorm:"not null"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	ChildID      uint       `json:"child_id" gorm:"column:user_id;not null;index"`
+	RecorderID   uint       `json:"recorder_id" gorm:"not null;index"`
+	BehaviorType string     `json:"behavior_type" gorm:"column:behavior_type;size:20;not null"` // 行为极性：good/bad
+	Category     string     `json:"category" gorm:"column:category;size:20;not null;default:'';index"`
+	BehaviorDesc string     `json:"behavior_desc" gorm:"column:description;type:text;not null"`
+	ScoreChange  int        `json:"score_change" gorm:"column:points;not null"`
+	ImageURL     string     `json:"image_url" gorm:"column:image_url;size:255"`
+	TemplateID   *uint      `json:"template_id" gorm:"index"`
+	Status       string     `json:"status" gorm:"size:20;not null;default:'approved';index"`
+	Source       string     `json:"source" gorm:"size:20;not null;default:'manual'"`
+	TriggerID    *uint      `json:"trigger_id" gorm:"index"` // 连续奖励记录关联触发它的行为记录
+	ReviewerID   *uint      `json:"reviewer_id"`
+	ReviewNote   string     `json:"review_note" gorm:"size:255"`
+	ReviewedAt   *time.Time `json:"reviewed_at"`
+	RecordedAt   time.Time  `json:"recorded_at" gorm:"not null"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
 
 	// 关联关系
 	Child    User `json:"child" gorm:"foreignKey:ChildID"`
 	Recorder User `json:"recorder" gorm:"foreignKey:RecorderID"`
 }
 
+// BehaviorTemplate 行为模板表（家长自定义的行为目录）
+type BehaviorTemplate struct {
+	ID            uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name          string    `json:"name" gorm:"size:100;not null"`
+	Category      string    `json:"category" gorm:"size:20;not null;index"`
+	DefaultPoints int       `json:"default_points" gorm:"not null"`
+	Icon          string    `json:"icon" gorm:"size:50"`
+	IsActive      bool      `json:"is_active" gorm:"default:true;not null"`
+	CreatedBy     uint      `json:"created_by" gorm:"not null;index"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Chore 周期任务表
+type Chore struct {
+	ID            uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	ChildID       uint      `json:"child_id" gorm:"not null;index"`
+	TemplateID    *uint     `json:"template_id" gorm:"index"`
+	Name          string    `json:"name" gorm:"size:100;not null"`
+	Category      string    `json:"category" gorm:"size:20;not null"`
+	Points        int       `json:"points" gorm:"not null"`
+	PenaltyPoints int       `json:"penalty_points" gorm:"default:0;not null"` // 错过时扣除的积分，0表示不扣分
+	Recurrence    string    `json:"recurrence" gorm:"size:20;not null"`       // daily, weekdays, weekly
+	WeekDays      string    `json:"week_days" gorm:"size:20"`                 // weekly时的星期列表，如"1,3,5"，0为周日
+	DueTime       string    `json:"due_time" gorm:"size:5;not null"`          // HH:MM，为空表示全天任务
+	IsActive      bool      `json:"is_active" gorm:"default:true;not null"`
+	CreatedBy     uint      `json:"created_by" gorm:"not null;index"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+
+	// 关联关系
+	Child User `json:"-" gorm:"foreignKey:ChildID"`
+}
+
+// ChoreInstance 周期任务每日实例表
+type ChoreInstance struct {
+	ID               uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	ChoreID          uint       `json:"chore_id" gorm:"not null;uniqueIndex:idx_chore_due_date"`
+	ChildID          uint       `json:"child_id" gorm:"not null;index"`
+	DueDate          string     `json:"due_date" gorm:"size:10;not null;uniqueIndex:idx_chore_due_date;index"` // YYYY-MM-DD
+	DueAt            time.Time  `json:"due_at" gorm:"not null"`
+	Status           string     `json:"status" gorm:"size:20;not null;default:'pending';index"`
+	CompletedAt      *time.Time `json:"completed_at"`
+	CompletedBy      *uint      `json:"completed_by"`
+	BehaviorRecordID *uint      `json:"behavior_record_id"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+
+	// 关联关系
+	Chore Chore `json:"chore" gorm:"foreignKey:ChoreID"`
+}
+
+// ChildStreak 儿童按行为模板的连续天数表
+type ChildStreak struct {
+	ID            uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	ChildID       uint      `json:"child_id" gorm:"not null;uniqueIndex:idx_streak_child_template"`
+	TemplateID    uint      `json:"template_id" gorm:"not null;uniqueIndex:idx_streak_child_template"`
+	CurrentStreak int       `json:"current_streak" gorm:"default:0;not null"`
+	BestStreak    int       `json:"best_streak" gorm:"default:0;not null"`
+	LastDate      string    `json:"last_date" gorm:"size:10;not null"` // YYYY-MM-DD
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+
+	// 关联关系
+	Template BehaviorTemplate `json:"-" gorm:"foreignKey:TemplateID"`
+}
+
+// StreakBonusRule 连续天数奖励规则表
+type StreakBonusRule struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	TemplateID  *uint     `json:"template_id" gorm:"index"` // 为空时适用于所有行为模板
+	Days        int       `json:"days" gorm:"not null"`
+	BonusPoints int       `json:"bonus_points" gorm:"not null"`
+	IsActive    bool      `json:"is_active" gorm:"default:true;not null"`
+	CreatedBy   uint      `json:"created_by" gorm:"not null;index"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Level 等级表，每个家长可以配置自己的等级门槛
+type Level struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Level     int       `json:"level" gorm:"not null;uniqueIndex:idx_level_owner_level"`
+	Name      string    `json:"name" gorm:"size:50;not null"`
+	MinPoints int       `json:"min_points" gorm:"not null"` // 达到该等级所需的累计总积分
+	CreatedBy uint      `json:"created_by" gorm:"not null;uniqueIndex:idx_level_owner_level"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Achievement 成就规则表
+type Achievement struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name        string    `json:"name" gorm:"size:100;not null"`
+	Description string    `json:"description" gorm:"size:255"`
+	Icon        string    `json:"icon" gorm:"size:50"`
+	RuleType    string    `json:"rule_type" gorm:"size:20;not null"`
+	Category    string    `json:"category" gorm:"size:20"` // 仅 category_count 规则使用
+	Threshold   int       `json:"threshold" gorm:"not null"`
+	IsActive    bool      `json:"is_active" gorm:"default:true;not null"`
+	CreatedBy   uint      `json:"created_by" gorm:"not null;index"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ChildAchievement 儿童已解锁的成就表
+type ChildAchievement struct {
+	ID            uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	ChildID       uint      `json:"child_id" gorm:"not null;uniqueIndex:idx_child_achievement"`
+	AchievementID uint      `json:"achievement_id" gorm:"not null;uniqueIndex:idx_child_achievement"`
+	UnlockedAt    time.Time `json:"unlocked_at" gorm:"not null"`
+	CreatedAt     time.Time `json:"created_at"`
+
+	// 关联关系
+	Achievement Achievement `json:"achievement" gorm:"foreignKey:AchievementID"`
+}
+
 // UserPoints 积分表
 type UserPoints struct {
 	ID              uint      `json:"id" gorm:"primaryKey;autoIncrement"`
@@ -63,18 +321,33 @@ type UserPoints struct {
 	User User `json:"user" gorm:"foreignKey:UserID"`
 }
 
+// PointTransaction 积分流水表（只追加，UserPoints 由其推导）
+type PointTransaction struct {
+	ID           uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID       uint      `json:"user_id" gorm:"not null;index"`
+	SourceType   string    `json:"source_type" gorm:"size:20;not null;index:idx_point_source"`
+	SourceID     uint      `json:"source_id" gorm:"index:idx_point_source"`
+	Delta        int       `json:"delta" gorm:"not null"` // 实际计入可用积分的变化
+	BalanceAfter int       `json:"balance_after" gorm:"not null"`
+	TotalAfter   int       `json:"total_after" gorm:"not null"`
+	ActorID      uint      `json:"actor_id" gorm:"not null;index"`
+	Note         string    `json:"note" gorm:"size:255"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
 // Reward 奖励表
 type Reward struct {
-	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
-	Name        string    `json:"name" gorm:"size:100;not null"`
-	Description string    `json:"description" gorm:"type:text"`
-	Points      int       `json:"points" gorm:"not null"`
-	Image       string    `json:"image" gorm:"size:255"`
-	Stock       int       `json:"stock" gorm:"default:1;not null"`
-	IsActive    bool      `json:"is_active" gorm:"default:true;not null"`
-	CreatedBy   uint      `json:"created_by" gorm:"not null;index"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID               uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name             string    `json:"name" gorm:"size:100;not null"`
+	Description      string    `json:"description" gorm:"type:text"`
+	Points           int       `json:"points" gorm:"not null"`
+	Image            string    `json:"image" gorm:"size:255"`
+	Stock            int       `json:"stock" gorm:"default:1;not null"`
+	IsActive         bool      `json:"is_active" gorm:"default:true;not null"`
+	RequiresApproval bool      `json:"requires_approval" gorm:"default:false;not null"` // 儿童兑换需家长确认
+	CreatedBy        uint      `json:"created_by" gorm:"not null;index"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
 
 	// 关联关系
 	Creator User `json:"creator" gorm:"foreignKey:CreatedBy"`
@@ -82,20 +355,67 @@ type Reward struct {
 
 // ExchangeRecord 兑换记录表
 type ExchangeRecord struct {
-	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
-	UserID      uint      `json:"user_id" gorm:"not null;index"`
-	RewardID    uint      `json:"reward_id" gorm:"not null;index"`
-	PointsUsed  int       `json:"points_used" gorm:"column:points_used;not null"`
-	ExchangedAt time.Time `json:"exchanged_at" gorm:"not null"`
-	Status      string    `json:"status" gorm:"type:enum('pending','completed','cancelled');default:'completed';not null"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID      uint       `json:"user_id" gorm:"not null;index"`
+	RewardID    uint       `json:"reward_id" gorm:"not null;index"`
+	PointsUsed  int        `json:"points_used" gorm:"column:points_used;not null"`
+	ExchangedAt time.Time  `json:"exchanged_at" gorm:"not null"`
+	Status      string     `json:"status" gorm:"size:20;default:'completed';not null"` // pending、completed 或 cancelled
+	ReviewerID  *uint      `json:"reviewer_id"`
+	ReviewNote  string     `json:"review_note" gorm:"size:255"`
+	ReviewedAt  *time.Time `json:"reviewed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
 
 	// 关联关系
 	User   User   `json:"user" gorm:"foreignKey:UserID"`
 	Reward Reward `json:"reward" gorm:"foreignKey:RewardID"`
 }
 
+// IdempotencyKey 幂等键表，记录带 Idempotency-Key 请求的首次响应
+type IdempotencyKey struct {
+	ID           uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID       uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_idempotency_user_key"`
+	Key          string    `json:"key" gorm:"size:64;not null;uniqueIndex:idx_idempotency_user_key"`
+	Method       string    `json:"method" gorm:"size:10;not null"`
+	Path         string    `json:"path" gorm:"size:255;not null"`
+	RequestHash  string    `json:"request_hash" gorm:"size:64;not null"`
+	Completed    bool      `json:"completed" gorm:"default:false;not null"`
+	StatusCode   int       `json:"status_code" gorm:"default:0;not null"`
+	ResponseBody string    `json:"response_body" gorm:"type:text"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// LoginLockout 登录失败计数表，连续失败达到阈值后按递增时长锁定
+type LoginLockout struct {
+	ID           uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Scope        string     `json:"scope" gorm:"size:20;not null;uniqueIndex:idx_login_lockout_subject"`
+	Subject      string     `json:"subject" gorm:"size:100;not null;uniqueIndex:idx_login_lockout_subject"` // 手机号或用户ID
+	Failures     int        `json:"failures" gorm:"default:0;not null"`
+	LastFailedAt *time.Time `json:"last_failed_at"`
+	LockedUntil  *time.Time `json:"locked_until"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// AuditEvent 审计日志表，记录账户和数据的每一次修改
+type AuditEvent struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	FamilyID   *uint     `json:"family_id" gorm:"index:idx_audit_family_created"`
+	ActorID    *uint     `json:"actor_id" gorm:"index"` // 未登录或登录失败时为空
+	ActorRole  string    `json:"actor_role" gorm:"size:20"`
+	Action     string    `json:"action" gorm:"size:100;not null;index"` // 请求方法和路由，如 DELETE /api/v1/children/:child_id
+	TargetType string    `json:"target_type" gorm:"size:50"`
+	TargetID   string    `json:"target_id" gorm:"size:50"`
+	StatusCode int       `json:"status_code" gorm:"not null"`
+	IP         string    `json:"ip" gorm:"size:45"`
+	UserAgent  string    `json:"user_agent" gorm:"size:255"`
+	Before     string    `json:"before" gorm:"type:text"` // 修改前发生变化的字段，JSON
+	After      string    `json:"after" gorm:"type:text"`  // 修改后发生变化的字段或请求内容，JSON
+	CreatedAt  time.Time `json:"created_at" gorm:"index:idx_audit_family_created"`
+}
+
 // InitDB 初始化数据库连接（使用配置文件）
 func InitDB() *gorm.DB {
 	// 加载配置文件
@@ -107,6 +427,15 @@ func InitDB() *gorm.DB {
 
 	// 使用配置文件初始化数据库连接
 	db, err := InitDBWithConfig(config.Database)
+	if config.Database.Driver == utils.DatabaseDriverSQLite {
+		if err != nil {
+			log.Printf("Failed to open SQLite database: %v", err)
+			log.Fatal("SQLite connection failed")
+		}
+		log.Printf("Successfully opened SQLite database %s", config.Database.Path)
+		return db
+	}
+
 	if err != nil {
 		log.Printf("Failed to connect to MariaDB: %v", err)
 		log.Printf("Please ensure MariaDB is running and the database '%s' exists", config.Database.DBName)
@@ -118,8 +447,20 @@ func InitDB() *gorm.DB {
 	return db
 }
 
-// InitDBWithConfig 使用配置初始化MariaDB数据库连接
+// InitDBWithConfig 按 database.driver 初始化数据库连接，默认使用MariaDB
 func InitDBWithConfig(config utils.DatabaseConfig) (*gorm.DB, error) {
+	switch config.Driver {
+	case "", utils.DatabaseDriverMySQL:
+		return initMySQL(config)
+	case utils.DatabaseDriverSQLite:
+		return initSQLite(config)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", config.Driver)
+	}
+}
+
+// initMySQL 初始化MariaDB数据库连接
+func initMySQL(config utils.DatabaseConfig) (*gorm.DB, error) {
 	dsn := config.GetDSN()
 
 	// 使用MySQL驱动连接MariaDB（兼容）
@@ -143,18 +484,34 @@ func InitDBWithConfig(config utils.DatabaseConfig) (*gorm.DB, error) {
 	return db, nil
 }
 
-// AutoMigrate 自动迁移数据库表
-func AutoMigrate(db *gorm.DB) error {
-	err := db.AutoMigrate(
-		&User{},
-		&BehaviorRecord{},
-		&UserPoints{},
-		&Reward{},
-		&ExchangeRecord{},
-	)
+// initSQLite 初始化SQLite数据库连接，path 为 :memory: 时使用内存数据库
+func initSQLite(config utils.DatabaseConfig) (*gorm.DB, error) {
+	if config.Path == "" {
+		return nil, errors.New("database.path is required for sqlite")
+	}
+	if config.Path != utils.SQLiteMemoryPath {
+		if err := os.MkdirAll(filepath.Dir(config.Path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
+	}
+
+	db, err := gorm.Open(sqlite.Open(config.GetSQLiteDSN()), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Info),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+
+	sqlDB, err := db.DB()
 	if err != nil {
-		return fmt.Errorf("failed to migrate database: %w", err)
+		return nil, fmt.Errorf("failed to get underlying sql.DB: %w", err)
 	}
-	log.Println("Database migration completed successfully")
-	return nil
+
+	// SQLite 同一时间只允许一个写入，内存数据库只存在于打开它的连接中，
+	// 因此只使用一个长期保持的连接
+	sqlDB.SetMaxOpenConns(1)
+	sqlDB.SetMaxIdleConns(1)
+	sqlDB.SetConnMaxLifetime(0)
+
+	return db, nil
 }
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// PasswordResetTTL 找回密码验证码有效期
+	PasswordResetTTL = 10 * time.Minute
+	// PasswordResetInterval 同一用户两次发送验证码的最短间隔
+	PasswordResetInterval = time.Minute
+	// PasswordResetDailyLimit 同一用户24小时内最多发送的验证码数量
+	PasswordResetDailyLimit = 5
+	// passwordResetDailyWindow 发送数量上限的统计窗口，早于窗口的验证码记录会被清理
+	passwordResetDailyWindow = 24 * time.Hour
+	// passwordResetMaxAttempts 验证码最多可以输错的次数，超过后作废
+	passwordResetMaxAttempts = 5
+	// passwordResetCodeLength 验证码位数
+	passwordResetCodeLength = 6
+)
+
+// 找回密码错误
+var (
+	// ErrPasswordResetTooFrequent 距离上次发送验证码的时间太短
+	ErrPasswordResetTooFrequent = errors.New("password reset code was requested too recently")
+	// ErrPasswordResetDailyLimit 24小时内发送的验证码已达到上限
+	ErrPasswordResetDailyLimit = errors.New("password reset code daily limit reached")
+	// ErrPasswordResetInvalid 验证码错误、已使用、已过期或输错次数过多
+	ErrPasswordResetInvalid = errors.New("password reset code is invalid or expired")
+)
+
+// hashPasswordResetCode 计算验证码的存储哈希
+func hashPasswordResetCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// generatePasswordResetCode 生成随机数字验证码
+func generatePasswordResetCode() (string, error) {
+	code := make([]byte, passwordResetCodeLength)
+	ten := big.NewInt(10)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, ten)
+		if err != nil {
+			return "", err
+		}
+		code[i] = byte('0' + n.Int64())
+	}
+	return string(code), nil
+}
+
+// CreatePasswordResetCode 为用户生成新的找回密码验证码，返回验证码明文，该用户之前未使用的验证码同时作废
+// 作废的验证码保留到统计窗口结束，用于计算24小时内的发送数量
+func CreatePasswordResetCode(db *gorm.DB, userID uint, now time.Time) (string, error) {
+	code, err := generatePasswordResetCode()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate password reset code: %w", err)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// 锁定用户，防止并发请求绕过发送间隔
+		var user User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, userID).Error; err != nil {
+			return err
+		}
+
+		windowStart := now.Add(-passwordResetDailyWindow)
+		if err := tx.Where("user_id = ? AND created_at <= ?", userID, windowStart).Delete(&PasswordResetCode{}).Error; err != nil {
+			return err
+		}
+
+		var recent int64
+		if err := tx.Model(&PasswordResetCode{}).
+			Where("user_id = ? AND created_at > ?", userID, now.Add(-PasswordResetInterval)).
+			Count(&recent).Error; err != nil {
+			return err
+		}
+		if recent > 0 {
+			return ErrPasswordResetTooFrequent
+		}
+
+		var today int64
+		if err := tx.Model(&PasswordResetCode{}).
+			Where("user_id = ? AND created_at > ?", userID, windowStart).
+			Count(&today).Error; err != nil {
+			return err
+		}
+		if today >= PasswordResetDailyLimit {
+			return ErrPasswordResetDailyLimit
+		}
+
+		// 之前未使用的验证码立即过期
+		if err := tx.Model(&PasswordResetCode{}).
+			Where("user_id = ? AND used_at IS NULL AND expires_at > ?", userID, now).
+			Update("expires_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&PasswordResetCode{
+			UserID:    userID,
+			CodeHash:  hashPasswordResetCode(code),
+			ExpiresAt: now.Add(PasswordResetTTL),
+			CreatedAt: now,
+		}).Error
+	})
+	if errors.Is(err, ErrPasswordResetTooFrequent) || errors.Is(err, ErrPasswordResetDailyLimit) {
+		return "", err
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to create password reset code: %w", err)
+	}
+	return code, nil
+}
+
+// ResetPasswordWithCode 使用验证码重设密码，成功后验证码失效并撤销用户的所有会话
+// 输错时记录次数，达到上限后验证码作废
+func ResetPasswordWithCode(db *gorm.DB, userID uint, code, hashedPassword string, now time.Time) error {
+	matched := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var reset PasswordResetCode
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND used_at IS NULL", userID).
+			Order("id DESC").First(&reset).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if now.After(reset.ExpiresAt) || reset.Attempts >= passwordResetMaxAttempts {
+			return nil
+		}
+
+		if subtle.ConstantTimeCompare([]byte(hashPasswordResetCode(code)), []byte(reset.CodeHash)) != 1 {
+			// 输错次数需要提交，不能随事务回滚
+			return tx.Model(&reset).Update("attempts", gorm.Expr("attempts + ?", 1)).Error
+		}
+
+		matched = true
+		if err := tx.Model(&reset).Update("used_at", now).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&User{}).Where("id = ?", userID).Update("password", hashedPassword).Error; err != nil {
+			return err
+		}
+		return RevokeUserSessions(tx, userID)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reset password: %w", err)
+	}
+	if !matched {
+		return ErrPasswordResetInvalid
+	}
+	return nil
+}
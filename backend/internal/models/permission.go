@@ -0,0 +1,204 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"gorm.io/gorm"
+)
+
+// 权限
+const (
+	PermChildrenView    = "children:view"    // 查看儿童列表和设备
+	PermChildrenManage  = "children:manage"  // 创建和修改儿童、配对码、PIN和设备
+	PermChildrenDelete  = "children:delete"  // 删除儿童账户
+	PermBehaviorsView   = "behaviors:view"   // 查看行为记录
+	PermBehaviorsRecord = "behaviors:record" // 记录、修改、删除和撤销行为
+	PermBehaviorsReview = "behaviors:review" // 审核儿童申报
+	PermBehaviorsClaim  = "behaviors:claim"  // 儿童申报行为
+	PermPointsAdjust    = "points:adjust"    // 手动调整积分
+	PermTemplatesManage = "templates:manage" // 管理行为模板
+	PermChoresManage    = "chores:manage"    // 管理周期任务
+	PermChoresComplete  = "chores:complete"  // 完成周期任务
+	PermRewardsManage   = "rewards:manage"   // 管理奖励
+	PermRewardsExchange = "rewards:exchange" // 兑换奖励
+	PermExchangesReview = "exchanges:review" // 处理待确认的兑换
+	PermSettingsView    = "settings:view"    // 查看连续奖励、成就和等级配置
+	PermSettingsManage  = "settings:manage"  // 修改连续奖励、成就和等级配置
+	PermReportsView     = "reports:view"     // 查看统计和趋势
+	PermFamilyManage    = "family:manage"    // 邀请和管理家庭成员及角色权限
+	PermAuditView       = "audit:view"       // 查看审计日志
+)
+
+// FamilyRoleChild 儿童在家庭中的角色，儿童账户通过 users.family_id 关联家庭
+const FamilyRoleChild = "child"
+
+// AllPermissions 所有权限，家庭所有者拥有全部权限
+var AllPermissions = []string{
+	PermChildrenView, PermChildrenManage, PermChildrenDelete,
+	PermBehaviorsView, PermBehaviorsRecord, PermBehaviorsReview, PermBehaviorsClaim,
+	PermPointsAdjust, PermTemplatesManage, PermChoresManage, PermChoresComplete,
+	PermRewardsManage, PermRewardsExchange, PermExchangesReview,
+	PermSettingsView, PermSettingsManage, PermReportsView, PermFamilyManage,
+	PermAuditView,
+}
+
+// childOnlyPermissions 只能授予儿童的权限，这些操作以当前用户作为儿童
+var childOnlyPermissions = map[string]bool{
+	PermBehaviorsClaim: true,
+}
+
+// defaultRolePermissions 内置角色的默认权限，家庭未自定义时使用
+var defaultRolePermissions = map[string][]string{
+	FamilyRoleGuardian: {
+		PermChildrenView, PermChildrenManage,
+		PermBehaviorsView, PermBehaviorsRecord, PermBehaviorsReview,
+		PermPointsAdjust, PermTemplatesManage, PermChoresManage, PermChoresComplete,
+		PermRewardsManage, PermRewardsExchange, PermExchangesReview,
+		PermSettingsView, PermSettingsManage, PermReportsView,
+	},
+	FamilyRoleViewer: {
+		PermChildrenView, PermBehaviorsView, PermSettingsView, PermReportsView,
+	},
+	FamilyRoleChild: {
+		PermBehaviorsView, PermBehaviorsClaim, PermChoresComplete, PermRewardsExchange, PermReportsView,
+	},
+}
+
+// familyRolePattern 角色名只能包含小写字母、数字和下划线
+var familyRolePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,19}$`)
+
+// ErrRoleNotEditable 家庭所有者和儿童的权限不能修改
+var ErrRoleNotEditable = errors.New("role permissions cannot be changed")
+
+// ErrRoleInUse 角色仍有成员或未使用的邀请码，不能删除
+var ErrRoleInUse = errors.New("role is still in use")
+
+// ErrInvalidPermissions 权限列表为空或包含无效权限
+var ErrInvalidPermissions = errors.New("invalid permissions")
+
+// IsPermission 判断权限是否有效
+func IsPermission(permission string) bool {
+	for _, p := range AllPermissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEditableRole 判断角色的权限能否由家庭自定义，所有者和儿童的权限固定
+func IsEditableRole(role string) bool {
+	return familyRolePattern.MatchString(role) && role != FamilyRoleOwner && role != FamilyRoleChild
+}
+
+// LoadRolePermissions 获取家庭中某个角色的权限
+// 所有者拥有全部权限，儿童使用默认权限，其他角色优先使用家庭自定义的配置
+func LoadRolePermissions(db *gorm.DB, familyID uint, role string) ([]string, error) {
+	switch role {
+	case FamilyRoleOwner:
+		return AllPermissions, nil
+	case FamilyRoleChild:
+		return defaultRolePermissions[FamilyRoleChild], nil
+	}
+
+	var permissions []string
+	if err := db.Model(&FamilyRolePermission{}).
+		Where("family_id = ? AND role = ?", familyID, role).
+		Pluck("permission", &permissions).Error; err != nil {
+		return nil, fmt.Errorf("failed to load role permissions: %w", err)
+	}
+	if len(permissions) == 0 {
+		permissions = defaultRolePermissions[role]
+	}
+	return permissions, nil
+}
+
+// LoadFamilyRoles 获取家庭中所有可分配给家长的角色及其权限，包括内置角色和自定义角色
+func LoadFamilyRoles(db *gorm.DB, familyID uint) (map[string][]string, error) {
+	var rows []FamilyRolePermission
+	if err := db.Where("family_id = ?", familyID).Order("role ASC, permission ASC").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load family roles: %w", err)
+	}
+
+	roles := map[string][]string{
+		FamilyRoleOwner: AllPermissions,
+	}
+	for _, row := range rows {
+		roles[row.Role] = append(roles[row.Role], row.Permission)
+	}
+	for _, role := range []string{FamilyRoleGuardian, FamilyRoleViewer} {
+		if _, ok := roles[role]; !ok {
+			roles[role] = defaultRolePermissions[role]
+		}
+	}
+	return roles, nil
+}
+
+// FamilyRoleExists 判断角色能否分配给家庭中的家长，所有者角色不能通过邀请或修改获得
+func FamilyRoleExists(db *gorm.DB, familyID uint, role string) (bool, error) {
+	if !IsEditableRole(role) {
+		return false, nil
+	}
+	if _, ok := defaultRolePermissions[role]; ok {
+		return true, nil
+	}
+	var count int64
+	err := db.Model(&FamilyRolePermission{}).Where("family_id = ? AND role = ?", familyID, role).Count(&count).Error
+	return count > 0, err
+}
+
+// SetRolePermissions 整体替换家庭中某个角色的权限，角色不存在时创建自定义角色
+func SetRolePermissions(db *gorm.DB, familyID uint, role string, permissions []string) error {
+	if !IsEditableRole(role) {
+		return ErrRoleNotEditable
+	}
+
+	rows := make([]FamilyRolePermission, 0, len(permissions))
+	seen := make(map[string]bool)
+	for _, permission := range permissions {
+		if !IsPermission(permission) || childOnlyPermissions[permission] {
+			return fmt.Errorf("%w: %q", ErrInvalidPermissions, permission)
+		}
+		if seen[permission] {
+			continue
+		}
+		seen[permission] = true
+		rows = append(rows, FamilyRolePermission{FamilyID: familyID, Role: role, Permission: permission})
+	}
+	// 空列表会让内置角色回退到默认权限，因此至少需要一项权限
+	if len(rows) == 0 {
+		return ErrInvalidPermissions
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("family_id = ? AND role = ?", familyID, role).Delete(&FamilyRolePermission{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&rows).Error
+	})
+}
+
+// DeleteFamilyRole 删除自定义角色，内置角色删除后恢复默认权限
+func DeleteFamilyRole(db *gorm.DB, familyID uint, role string) error {
+	if !IsEditableRole(role) {
+		return ErrRoleNotEditable
+	}
+
+	if _, builtin := defaultRolePermissions[role]; !builtin {
+		var members int64
+		if err := db.Model(&FamilyMember{}).Where("family_id = ? AND role = ?", familyID, role).Count(&members).Error; err != nil {
+			return err
+		}
+		var invites int64
+		if err := db.Model(&FamilyInvite{}).Where("family_id = ? AND role = ? AND used_at IS NULL", familyID, role).Count(&invites).Error; err != nil {
+			return err
+		}
+		if members > 0 || invites > 0 {
+			return ErrRoleInUse
+		}
+	}
+
+	return db.Where("family_id = ? AND role = ?", familyID, role).Delete(&FamilyRolePermission{}).Error
+}
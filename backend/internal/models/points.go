@@ -0,0 +1,117 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 积分流水来源类型
+const (
+	PointSourceBehavior   = "behavior"
+	PointSourceExchange   = "exchange"
+	PointSourceRefund     = "refund"
+	PointSourceAdjustment = "adjustment"
+	PointSourceOpening    = "opening"
+)
+
+// countsTowardTotal 判断该来源的积分变化是否计入累计总积分
+// 兑换和退款只影响可用积分，累计总积分只记录获得与扣除
+func countsTowardTotal(sourceType string) bool {
+	switch sourceType {
+	case PointSourceExchange, PointSourceRefund:
+		return false
+	default:
+		return true
+	}
+}
+
+// LockUserPoints 在事务中以 SELECT ... FOR UPDATE 锁定用户积分行
+func LockUserPoints(tx *gorm.DB, userID uint) (*UserPoints, error) {
+	var userPoints UserPoints
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&userPoints).Error; err != nil {
+		return nil, err
+	}
+	return &userPoints, nil
+}
+
+// ApplyPointTransaction 写入一条积分流水并同步更新 UserPoints
+// 调用方应在事务中调用，积分行会被加锁，txn 的 BalanceAfter/TotalAfter 由本函数计算。
+// 扣分超过可用积分时只扣到0，txn.Delta 改为实际扣除的积分，冲销时按流水而不是原始分值退回
+func ApplyPointTransaction(tx *gorm.DB, txn *PointTransaction) (*UserPoints, error) {
+	userPoints, err := LockUserPoints(tx, txn.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		userPoints = &UserPoints{UserID: txn.UserID}
+		if err := tx.Create(userPoints).Error; err != nil {
+			return nil, fmt.Errorf("failed to create user points: %w", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load user points: %w", err)
+	}
+
+	if userPoints.AvailablePoints+txn.Delta < 0 {
+		txn.Delta = -userPoints.AvailablePoints
+	}
+	ApplyPointDelta(userPoints, txn.SourceType, txn.Delta)
+	txn.BalanceAfter = userPoints.AvailablePoints
+	txn.TotalAfter = userPoints.TotalPoints
+
+	if err := tx.Create(txn).Error; err != nil {
+		return nil, fmt.Errorf("failed to create point transaction: %w", err)
+	}
+	if err := tx.Save(userPoints).Error; err != nil {
+		return nil, fmt.Errorf("failed to update user points: %w", err)
+	}
+
+	return userPoints, nil
+}
+
+// SourcePointDelta 汇总同一来源已写入流水的积分变化，即该来源当前实际计入的积分
+func SourcePointDelta(db *gorm.DB, sourceType string, sourceID uint) (int, error) {
+	var sum int
+	err := db.Model(&PointTransaction{}).Where("source_type = ? AND source_id = ?", sourceType, sourceID).
+		Select("COALESCE(SUM(delta), 0)").Scan(&sum).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum point transactions: %w", err)
+	}
+	return sum, nil
+}
+
+// ApplyPointDelta 按来源类型把积分变化累加到 UserPoints，可用积分不会低于0
+func ApplyPointDelta(userPoints *UserPoints, sourceType string, delta int) {
+	if countsTowardTotal(sourceType) {
+		userPoints.TotalPoints += delta
+	}
+	userPoints.AvailablePoints += delta
+	if userPoints.AvailablePoints < 0 {
+		userPoints.AvailablePoints = 0
+	}
+}
+
+// RebuildUserPoints 按流水顺序重放，重新计算用户积分
+func RebuildUserPoints(db *gorm.DB, userID uint) (*UserPoints, error) {
+	var transactions []PointTransaction
+	if err := db.Where("user_id = ?", userID).Order("id ASC").Find(&transactions).Error; err != nil {
+		return nil, fmt.Errorf("failed to load point transactions: %w", err)
+	}
+
+	var userPoints UserPoints
+	err := db.Where("user_id = ?", userID).First(&userPoints).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to load user points: %w", err)
+	}
+	userPoints.UserID = userID
+	userPoints.TotalPoints = 0
+	userPoints.AvailablePoints = 0
+
+	for _, txn := range transactions {
+		ApplyPointDelta(&userPoints, txn.SourceType, txn.Delta)
+	}
+
+	if err := db.Save(&userPoints).Error; err != nil {
+		return nil, fmt.Errorf("failed to save user points: %w", err)
+	}
+	return &userPoints, nil
+}
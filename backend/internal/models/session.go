@@ -0,0 +1,186 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 会话错误
+var (
+	// ErrSessionInvalid 刷新令牌不存在、已过期或已撤销
+	ErrSessionInvalid = errors.New("session is invalid or expired")
+	// ErrSessionReused 已轮换的刷新令牌被再次使用，会话已被撤销
+	ErrSessionReused = errors.New("refresh token reuse detected")
+)
+
+// newRefreshToken 生成随机刷新令牌，返回令牌及其哈希
+func newRefreshToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashRefreshToken(token), nil
+}
+
+// HashRefreshToken 计算刷新令牌的存储哈希，数据库中不保存明文令牌
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateSession 为用户创建登录会话，返回会话和刷新令牌明文
+func CreateSession(db *gorm.DB, user *User, deviceID, userAgent, ipAddress string, ttl time.Duration) (*Session, string, error) {
+	token, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	now := time.Now()
+	session := Session{
+		UserID:       user.ID,
+		TokenHash:    hash,
+		TokenVersion: user.TokenVersion,
+		DeviceID:     deviceID,
+		UserAgent:    truncate(userAgent, 255),
+		IPAddress:    truncate(ipAddress, 45),
+		ExpiresAt:    now.Add(ttl),
+		LastUsedAt:   now,
+	}
+	if err := db.Create(&session).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to create session: %w", err)
+	}
+	return &session, token, nil
+}
+
+// RotateSession 使用刷新令牌换取新的刷新令牌，会话有效期顺延
+// 已轮换的旧令牌再次出现时撤销整个会话
+func RotateSession(db *gorm.DB, refreshToken string, ttl time.Duration) (*Session, *User, string, error) {
+	hash := HashRefreshToken(refreshToken)
+
+	var session Session
+	err := db.Where("token_hash = ?", hash).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 旧令牌被重放，说明令牌可能已泄露，撤销对应会话
+		result := db.Model(&Session{}).
+			Where("previous_token_hash = ? AND revoked_at IS NULL", hash).
+			Update("revoked_at", time.Now())
+		if result.Error != nil {
+			return nil, nil, "", result.Error
+		}
+		if result.RowsAffected > 0 {
+			return nil, nil, "", ErrSessionReused
+		}
+		return nil, nil, "", ErrSessionInvalid
+	}
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	var user User
+	var token string
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// 加锁后重新读取，防止同一刷新令牌被并发使用
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&session, session.ID).Error; err != nil {
+			return err
+		}
+		if session.TokenHash != hash {
+			return ErrSessionInvalid
+		}
+		var err error
+		token, err = rotateSession(tx, &session, &user, ttl)
+		return err
+	})
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return &session, &user, token, nil
+}
+
+// rotateSession 校验已锁定的会话并轮换刷新令牌
+func rotateSession(tx *gorm.DB, session *Session, user *User, ttl time.Duration) (string, error) {
+	now := time.Now()
+	if session.RevokedAt != nil || now.After(session.ExpiresAt) {
+		return "", ErrSessionInvalid
+	}
+
+	if err := tx.First(user, session.UserID).Error; err != nil {
+		return "", ErrSessionInvalid
+	}
+	if user.TokenVersion != session.TokenVersion {
+		return "", ErrSessionInvalid
+	}
+
+	token, newHash, err := newRefreshToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	session.PreviousTokenHash = session.TokenHash
+	session.TokenHash = newHash
+	session.ExpiresAt = now.Add(ttl)
+	session.LastUsedAt = now
+	if err := tx.Model(session).Updates(map[string]interface{}{
+		"previous_token_hash": session.PreviousTokenHash,
+		"token_hash":          session.TokenHash,
+		"expires_at":          session.ExpiresAt,
+		"last_used_at":        session.LastUsedAt,
+	}).Error; err != nil {
+		return "", fmt.Errorf("failed to rotate session: %w", err)
+	}
+
+	return token, nil
+}
+
+// IsSessionActive 判断访问令牌对应的会话和令牌版本是否仍然有效
+func IsSessionActive(db *gorm.DB, userID, sessionID uint, tokenVersion int) (bool, error) {
+	var count int64
+	err := db.Model(&Session{}).
+		Joins("JOIN users ON users.id = sessions.user_id").
+		Where("sessions.id = ? AND sessions.user_id = ? AND sessions.revoked_at IS NULL AND sessions.expires_at > ?", sessionID, userID, time.Now()).
+		Where("sessions.token_version = users.token_version AND users.token_version = ?", tokenVersion).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// RevokeSession 撤销用户的一个会话
+func RevokeSession(db *gorm.DB, userID, sessionID uint) error {
+	return db.Model(&Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeUserSessions 撤销用户的所有会话并递增令牌版本，已签发的访问令牌立即失效
+func RevokeUserSessions(db *gorm.DB, userID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&User{}).Where("id = ?", userID).
+			Update("token_version", gorm.Expr("token_version + ?", 1)).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", time.Now()).Error
+	})
+}
+
+// RevokeDeviceSessions 撤销用户在某个设备上的所有会话
+func RevokeDeviceSessions(db *gorm.DB, userID uint, deviceID string) error {
+	return db.Model(&Session{}).
+		Where("user_id = ? AND device_id = ? AND revoked_at IS NULL", userID, deviceID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// truncate 截断超出列长度的字符串
+func truncate(value string, max int) string {
+	if len(value) > max {
+		return value[:max]
+	}
+	return value
+}
@@ -0,0 +1,170 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StreakDateLayout 连续天数使用的日期格式
+const StreakDateLayout = "2006-01-02"
+
+// IsBroken 判断连续天数在 now 时是否已经中断（昨天和今天都没有记录）
+func (s *ChildStreak) IsBroken(now time.Time) bool {
+	yesterday := now.AddDate(0, 0, -1).Format(StreakDateLayout)
+	return s.LastDate < yesterday
+}
+
+// UpdateStreak 根据一条已生效的行为记录更新连续天数，达到奖励规则时写入奖励记录
+// 只统计使用行为模板的加分记录，调用方应在事务中调用
+func UpdateStreak(tx *gorm.DB, record *BehaviorRecord) error {
+	if record.TemplateID == nil || record.ScoreChange <= 0 || record.Status != BehaviorStatusApproved {
+		return nil
+	}
+
+	day := record.RecordedAt.Format(StreakDateLayout)
+	yesterday := record.RecordedAt.AddDate(0, 0, -1).Format(StreakDateLayout)
+
+	var streak ChildStreak
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("child_id = ? AND template_id = ?", record.ChildID, *record.TemplateID).
+		First(&streak).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		streak = ChildStreak{ChildID: record.ChildID, TemplateID: *record.TemplateID}
+	} else if err != nil {
+		return fmt.Errorf("failed to load streak: %w", err)
+	}
+
+	switch {
+	case streak.LastDate >= day:
+		// 当天已经计数（或补录更早的记录），连续天数不变
+		return nil
+	case streak.LastDate == yesterday:
+		streak.CurrentStreak++
+	default:
+		streak.CurrentStreak = 1
+	}
+	streak.LastDate = day
+	if streak.CurrentStreak > streak.BestStreak {
+		streak.BestStreak = streak.CurrentStreak
+	}
+
+	if err := tx.Save(&streak).Error; err != nil {
+		return fmt.Errorf("failed to save streak: %w", err)
+	}
+
+	return applyStreakBonus(tx, record, &streak)
+}
+
+// RevertStreak 撤销行为记录后回退它计入的连续天数，调用方应在删除记录后、在同一事务中调用
+// 只有该记录是连续天数最后一天唯一的记录时才回退，按剩余记录重新计算当前连续天数，最佳纪录不变
+func RevertStreak(tx *gorm.DB, record *BehaviorRecord) error {
+	if record.TemplateID == nil || record.ScoreChange <= 0 || record.Status != BehaviorStatusApproved {
+		return nil
+	}
+
+	var streak ChildStreak
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("child_id = ? AND template_id = ?", record.ChildID, *record.TemplateID).
+		First(&streak).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load streak: %w", err)
+	}
+	// 从数据库读出的时间可能不是本地时区，按记录时的本地日期计算
+	recordedAt := record.RecordedAt.Local()
+	day := recordedAt.Format(StreakDateLayout)
+	if streak.LastDate != day {
+		return nil
+	}
+
+	// 连续天数最多回溯 CurrentStreak 天
+	dayStart, err := time.ParseInLocation(StreakDateLayout, day, time.Local)
+	if err != nil {
+		return fmt.Errorf("failed to parse streak date: %w", err)
+	}
+	var times []time.Time
+	if err := tx.Model(&BehaviorRecord{}).
+		Where("user_id = ? AND template_id = ? AND status = ? AND points > 0 AND recorded_at >= ? AND recorded_at < ?",
+			record.ChildID, *record.TemplateID, BehaviorStatusApproved,
+			dayStart.AddDate(0, 0, -streak.CurrentStreak), dayStart.AddDate(0, 0, 1)).
+		Pluck("recorded_at", &times).Error; err != nil {
+		return fmt.Errorf("failed to load streak records: %w", err)
+	}
+	days := make(map[string]bool, len(times))
+	for _, at := range times {
+		days[at.Local().Format(StreakDateLayout)] = true
+	}
+	if days[day] {
+		return nil
+	}
+
+	streak.CurrentStreak = 0
+	streak.LastDate = ""
+	for date := dayStart.AddDate(0, 0, -1); days[date.Format(StreakDateLayout)]; date = date.AddDate(0, 0, -1) {
+		if streak.LastDate == "" {
+			streak.LastDate = date.Format(StreakDateLayout)
+		}
+		streak.CurrentStreak++
+	}
+	if err := tx.Save(&streak).Error; err != nil {
+		return fmt.Errorf("failed to save streak: %w", err)
+	}
+	return nil
+}
+
+// applyStreakBonus 连续天数恰好达到奖励规则的天数时写入奖励记录
+func applyStreakBonus(tx *gorm.DB, record *BehaviorRecord, streak *ChildStreak) error {
+	var template BehaviorTemplate
+	if err := tx.First(&template, streak.TemplateID).Error; err != nil {
+		return fmt.Errorf("failed to load behavior template: %w", err)
+	}
+
+	var child User
+	if err := tx.First(&child, record.ChildID).Error; err != nil {
+		return fmt.Errorf("failed to load child: %w", err)
+	}
+	if child.FamilyID == nil {
+		return nil
+	}
+
+	var rules []StreakBonusRule
+	if err := tx.Where("created_by IN (?) AND is_active = ? AND days = ? AND (template_id IS NULL OR template_id = ?)",
+		FamilyMemberIDs(tx, *child.FamilyID), true, streak.CurrentStreak, streak.TemplateID).Find(&rules).Error; err != nil {
+		return fmt.Errorf("failed to load streak bonus rules: %w", err)
+	}
+
+	for _, rule := range rules {
+		// 奖励记录不关联行为模板，避免再次计入连续天数
+		bonus := BehaviorRecord{
+			ChildID:      record.ChildID,
+			RecorderID:   rule.CreatedBy,
+			BehaviorType: BehaviorPolarity(rule.BonusPoints),
+			Category:     template.Category,
+			BehaviorDesc: fmt.Sprintf("连续%d天%s奖励", streak.CurrentStreak, template.Name),
+			ScoreChange:  rule.BonusPoints,
+			Status:       BehaviorStatusApproved,
+			Source:       BehaviorSourceStreakBonus,
+			TriggerID:    &record.ID,
+			RecordedAt:   record.RecordedAt,
+		}
+		if _, err := CreateBehaviorRecord(tx, &bonus); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResetBrokenStreaks 把已经中断的连续天数清零，最佳纪录保留
+func ResetBrokenStreaks(db *gorm.DB, now time.Time) (int64, error) {
+	yesterday := now.AddDate(0, 0, -1).Format(StreakDateLayout)
+	result := db.Model(&ChildStreak{}).
+		Where("current_streak > 0 AND last_date < ?", yesterday).
+		Update("current_streak", 0)
+	return result.RowsAffected, result.Error
+}
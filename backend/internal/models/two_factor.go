@@ -0,0 +1,172 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"child-behavior-app/internal/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// recoveryCodeCount 每次生成的恢复码数量
+const recoveryCodeCount = 10
+
+// 两步验证错误
+var (
+	// ErrTwoFactorEnabled 已开启两步验证，需要先关闭才能重新绑定
+	ErrTwoFactorEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrTwoFactorNotEnabled 未开启两步验证或尚未申请绑定
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrTwoFactorCodeInvalid 验证码或恢复码错误、已使用
+	ErrTwoFactorCodeInvalid = errors.New("two-factor code is invalid")
+)
+
+// normalizeRecoveryCode 统一恢复码格式，输入时可以省略连字符、使用小写
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// hashRecoveryCode 计算恢复码的存储哈希
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// BeginTwoFactorEnrollment 为家长生成新的待验证密钥，验证通过前不影响登录
+func BeginTwoFactorEnrollment(db *gorm.DB, user *User) (string, error) {
+	if user.TOTPEnabled {
+		return "", ErrTwoFactorEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate TOTP secret: %w", err)
+	}
+	if err := db.Model(user).Update("totp_secret", secret).Error; err != nil {
+		return "", fmt.Errorf("failed to save TOTP secret: %w", err)
+	}
+	return secret, nil
+}
+
+// EnableTwoFactor 使用待验证密钥的验证码开启两步验证，返回新生成的恢复码明文
+func EnableTwoFactor(db *gorm.DB, userID uint, code string, now time.Time) ([]string, error) {
+	var codes []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var user User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+		if user.TOTPEnabled {
+			return ErrTwoFactorEnabled
+		}
+		if user.TOTPSecret == nil {
+			return ErrTwoFactorNotEnabled
+		}
+
+		step, ok := utils.ValidateTOTP(*user.TOTPSecret, code, now)
+		if !ok {
+			return ErrTwoFactorCodeInvalid
+		}
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"totp_enabled":   true,
+			"totp_last_step": step,
+		}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = ReplaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// VerifyTwoFactor 校验验证码或恢复码
+// 同一个验证码只能使用一次，恢复码使用后失效
+func VerifyTwoFactor(db *gorm.DB, userID uint, code string, now time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var user User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			return err
+		}
+		if !user.TOTPEnabled || user.TOTPSecret == nil {
+			return ErrTwoFactorNotEnabled
+		}
+
+		code = strings.TrimSpace(code)
+		if step, ok := utils.ValidateTOTP(*user.TOTPSecret, code, now); ok {
+			if step <= user.TOTPLastStep {
+				return ErrTwoFactorCodeInvalid
+			}
+			return tx.Model(&user).Update("totp_last_step", step).Error
+		}
+
+		result := tx.Model(&RecoveryCode{}).
+			Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashRecoveryCode(code)).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTwoFactorCodeInvalid
+		}
+		return nil
+	})
+}
+
+// ReplaceRecoveryCodes 重新生成恢复码，之前的恢复码全部作废，返回恢复码明文
+func ReplaceRecoveryCodes(db *gorm.DB, userID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	rows := make([]RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := generateShortCode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		code := raw[:4] + "-" + raw[4:]
+		codes = append(codes, code)
+		rows = append(rows, RecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(code)})
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// CountRecoveryCodes 统计未使用的恢复码数量
+func CountRecoveryCodes(db *gorm.DB, userID uint) (int64, error) {
+	var count int64
+	err := db.Model(&RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// DisableTwoFactor 关闭两步验证，清除密钥和恢复码
+func DisableTwoFactor(db *gorm.DB, userID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_secret":    nil,
+			"totp_enabled":   false,
+			"totp_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error
+	})
+}
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"gorm.io/gorm"
+)
+
+// ErrWebAuthnCredentialNotFound 凭据不存在或不属于该用户
+var ErrWebAuthnCredentialNotFound = errors.New("webauthn credential not found")
+
+// WebAuthnUser 实现 webauthn.User，包装家长账户及其已注册的凭据
+type WebAuthnUser struct {
+	User        *User
+	Credentials []WebAuthnCredential
+}
+
+// WebAuthnUserHandle 用户在验证器中的用户句柄，使用用户ID，不包含个人信息
+func WebAuthnUserHandle(userID uint) []byte {
+	return []byte(strconv.FormatUint(uint64(userID), 10))
+}
+
+func (u *WebAuthnUser) WebAuthnID() []byte {
+	return WebAuthnUserHandle(u.User.ID)
+}
+
+func (u *WebAuthnUser) WebAuthnName() string {
+	if phone := u.User.Phone; phone != nil && *phone != "" {
+		return *phone
+	}
+	return u.User.Nickname
+}
+
+func (u *WebAuthnUser) WebAuthnDisplayName() string {
+	return u.User.Nickname
+}
+
+func (u *WebAuthnUser) WebAuthnIcon() string {
+	return ""
+}
+
+func (u *WebAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.Credentials))
+	for _, credential := range u.Credentials {
+		credentials = append(credentials, credential.Credential())
+	}
+	return credentials
+}
+
+// CredentialDescriptors 已注册凭据的描述，用于注册时排除和验证时限定凭据
+func (u *WebAuthnUser) CredentialDescriptors() []protocol.CredentialDescriptor {
+	descriptors := make([]protocol.CredentialDescriptor, 0, len(u.Credentials))
+	for _, credential := range u.WebAuthnCredentials() {
+		descriptors = append(descriptors, credential.Descriptor())
+	}
+	return descriptors
+}
+
+// Credential 转换为 webauthn 库使用的凭据
+func (c WebAuthnCredential) Credential() webauthn.Credential {
+	id, _ := base64.RawURLEncoding.DecodeString(c.CredentialID)
+
+	var transports []protocol.AuthenticatorTransport
+	if c.Transports != "" {
+		for _, transport := range strings.Split(c.Transports, ",") {
+			transports = append(transports, protocol.AuthenticatorTransport(transport))
+		}
+	}
+
+	return webauthn.Credential{
+		ID:              id,
+		PublicKey:       c.PublicKey,
+		AttestationType: c.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			BackupEligible: c.BackupEligible,
+			BackupState:    c.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    c.AAGUID,
+			SignCount: c.SignCount,
+		},
+	}
+}
+
+// LoadWebAuthnUser 获取用户及其已注册的凭据
+func LoadWebAuthnUser(db *gorm.DB, userID uint) (*WebAuthnUser, error) {
+	var user User
+	if err := db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	var credentials []WebAuthnCredential
+	if err := db.Where("user_id = ?", userID).Order("id ASC").Find(&credentials).Error; err != nil {
+		return nil, fmt.Errorf("failed to load webauthn credentials: %w", err)
+	}
+	return &WebAuthnUser{User: &user, Credentials: credentials}, nil
+}
+
+// FindWebAuthnUser 根据验证器返回的凭据ID和用户句柄查找用户，用于通行密钥登录
+func FindWebAuthnUser(db *gorm.DB, rawID, userHandle []byte) (*WebAuthnUser, error) {
+	var credential WebAuthnCredential
+	err := db.Where("credential_id = ?", base64.RawURLEncoding.EncodeToString(rawID)).First(&credential).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWebAuthnCredentialNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if string(userHandle) != string(WebAuthnUserHandle(credential.UserID)) {
+		return nil, ErrWebAuthnCredentialNotFound
+	}
+	return LoadWebAuthnUser(db, credential.UserID)
+}
+
+// SaveWebAuthnCredential 保存注册成功的凭据
+func SaveWebAuthnCredential(db *gorm.DB, userID uint, name string, credential *webauthn.Credential) (*WebAuthnCredential, error) {
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	record := WebAuthnCredential{
+		UserID:          userID,
+		CredentialID:    base64.RawURLEncoding.EncodeToString(credential.ID),
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		Transports:      truncate(strings.Join(transports, ","), 100),
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+		Name:            truncate(name, 50),
+	}
+	if err := db.Create(&record).Error; err != nil {
+		return nil, fmt.Errorf("failed to save webauthn credential: %w", err)
+	}
+	return &record, nil
+}
+
+// RecordWebAuthnUse 验证成功后更新签名计数和备份状态
+func RecordWebAuthnUse(db *gorm.DB, userID uint, credential *webauthn.Credential, now time.Time) error {
+	return db.Model(&WebAuthnCredential{}).
+		Where("user_id = ? AND credential_id = ?", userID, base64.RawURLEncoding.EncodeToString(credential.ID)).
+		Updates(map[string]interface{}{
+			"sign_count":   credential.Authenticator.SignCount,
+			"backup_state": credential.Flags.BackupState,
+			"last_used_at": now,
+		}).Error
+}
@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"child-behavior-app/internal/utils"
+)
+
+// MessageSender 短信或邮件发送接口，接入短信、邮件服务商时实现该接口
+type MessageSender interface {
+	// Send 向手机号或邮箱发送一条消息
+	Send(to, message string) error
+}
+
+// NewSender 根据配置创建消息发送器，未配置时写入日志
+func NewSender(config utils.NotifyConfig) MessageSender {
+	switch config.Sender {
+	case "file":
+		return NewFileSender(config.FilePath)
+	default:
+		return LogSender{}
+	}
+}
+
+// LogSender 把消息写入日志，用于本地开发
+type LogSender struct{}
+
+// Send 把消息写入日志
+func (LogSender) Send(to, message string) error {
+	log.Printf("[notify] to=%s message=%q", to, message)
+	return nil
+}
+
+// FileSender 把消息逐行追加到文件，便于本地开发和测试时读取验证码
+type FileSender struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileSender 创建写入指定文件的消息发送器
+func NewFileSender(path string) *FileSender {
+	if path == "" {
+		path = "logs/messages.log"
+	}
+	return &FileSender{path: path}
+}
+
+// Send 把消息追加到文件
+func (s *FileSender) Send(to, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create message directory: %w", err)
+	}
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open message file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := fmt.Fprintf(file, "%s\t%s\t%s\n", time.Now().Format(time.RFC3339), to, message); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,377 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"child-behavior-app/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormStore 基于 gorm 的数据访问实现
+type GormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore 创建基于 gorm 的数据访问实现
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+func (s *GormStore) Children() ChildRepository {
+	return gormChildRepository{db: s.db}
+}
+
+func (s *GormStore) Points() PointsRepository {
+	return gormPointsRepository{db: s.db}
+}
+
+func (s *GormStore) Behaviors() BehaviorRepository {
+	return gormBehaviorRepository{db: s.db}
+}
+
+func (s *GormStore) Rewards() RewardRepository {
+	return gormRewardRepository{db: s.db}
+}
+
+func (s *GormStore) Progress() ProgressRepository {
+	return gormProgressRepository{db: s.db}
+}
+
+func (s *GormStore) Transaction(fn func(tx Store) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&GormStore{db: tx})
+	})
+}
+
+// translateError 将 gorm 的记录不存在错误转换为 ErrNotFound
+func translateError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// paginate 按分页参数限制查询，gorm 会忽略负数的偏移量
+func paginate(query *gorm.DB, page Page) *gorm.DB {
+	query = query.Offset(page.Offset)
+	if page.Limit > 0 {
+		query = query.Limit(page.Limit)
+	}
+	return query
+}
+
+type gormChildRepository struct {
+	db *gorm.DB
+}
+
+func (r gormChildRepository) FindUser(userID uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.First(&user, userID).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+func (r gormChildRepository) FindUsers(userIDs []uint) ([]models.User, error) {
+	var users []models.User
+	if len(userIDs) == 0 {
+		return users, nil
+	}
+	err := r.db.Where("id IN ?", userIDs).Find(&users).Error
+	return users, err
+}
+
+func (r gormChildRepository) FindFamilyChild(familyID, childID uint) (*models.User, error) {
+	child, err := models.FindFamilyChild(r.db, familyID, childID)
+	return child, translateError(err)
+}
+
+func (r gormChildRepository) ListFamilyChildren(familyID uint) ([]models.User, error) {
+	var children []models.User
+	err := r.db.Where("family_id = ? AND role = ?", familyID, "child").Order("id ASC").Find(&children).Error
+	return children, err
+}
+
+func (r gormChildRepository) Create(child *models.User) error {
+	return r.db.Create(child).Error
+}
+
+func (r gormChildRepository) UpdateProfile(child *models.User) error {
+	return r.db.Model(child).Select("nickname", "age", "gender", "avatar").Updates(child).Error
+}
+
+func (r gormChildRepository) Delete(child *models.User) error {
+	// 删除积分记录和积分流水
+	cascades := []struct {
+		column string
+		model  interface{}
+	}{
+		{"user_id", &models.UserPoints{}},
+		{"user_id", &models.PointTransaction{}},
+		// 行为记录的 child_id 字段对应 user_id 列
+		{"user_id", &models.BehaviorRecord{}},
+		{"user_id", &models.ExchangeRecord{}},
+		// 登录会话、配对码和已配对设备
+		{"user_id", &models.Session{}},
+		{"child_id", &models.PairingCode{}},
+		{"child_id", &models.ChildDevice{}},
+		// 连续天数和成就
+		{"child_id", &models.ChildStreak{}},
+		{"child_id", &models.ChildAchievement{}},
+	}
+	for _, cascade := range cascades {
+		if err := r.db.Where(cascade.column+" = ?", child.ID).Delete(cascade.model).Error; err != nil {
+			return err
+		}
+	}
+	return r.db.Delete(child).Error
+}
+
+type gormPointsRepository struct {
+	db *gorm.DB
+}
+
+func (r gormPointsRepository) Get(userID uint) (*models.UserPoints, error) {
+	var userPoints models.UserPoints
+	if err := r.db.Where("user_id = ?", userID).First(&userPoints).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &userPoints, nil
+}
+
+func (r gormPointsRepository) Lock(userID uint) (*models.UserPoints, error) {
+	userPoints, err := models.LockUserPoints(r.db, userID)
+	return userPoints, translateError(err)
+}
+
+func (r gormPointsRepository) Create(userPoints *models.UserPoints) error {
+	return r.db.Create(userPoints).Error
+}
+
+func (r gormPointsRepository) Apply(txn *models.PointTransaction) (*models.UserPoints, error) {
+	return models.ApplyPointTransaction(r.db, txn)
+}
+
+func (r gormPointsRepository) SourceDelta(sourceType string, sourceID uint) (int, error) {
+	return models.SourcePointDelta(r.db, sourceType, sourceID)
+}
+
+func (r gormPointsRepository) ListTransactions(userID uint, sourceType string, page Page) ([]models.PointTransaction, int64, error) {
+	query := r.db.Model(&models.PointTransaction{}).Where("user_id = ?", userID)
+	if sourceType != "" {
+		query = query.Where("source_type = ?", sourceType)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var transactions []models.PointTransaction
+	err := paginate(query.Order("id DESC"), page).Find(&transactions).Error
+	return transactions, total, err
+}
+
+type gormBehaviorRepository struct {
+	db *gorm.DB
+}
+
+func (r gormBehaviorRepository) FindActiveTemplate(familyID, templateID uint) (*models.BehaviorTemplate, error) {
+	var template models.BehaviorTemplate
+	if err := r.db.Where("id = ? AND created_by IN (?) AND is_active = ?", templateID, models.FamilyMemberIDs(r.db, familyID), true).
+		First(&template).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &template, nil
+}
+
+func (r gormBehaviorRepository) Create(record *models.BehaviorRecord) error {
+	return r.db.Create(record).Error
+}
+
+func (r gormBehaviorRepository) Save(record *models.BehaviorRecord) error {
+	return r.db.Save(record).Error
+}
+
+func (r gormBehaviorRepository) Delete(record *models.BehaviorRecord) error {
+	return r.db.Delete(record).Error
+}
+
+func (r gormBehaviorRepository) LockFamilyBehavior(familyID, behaviorID uint) (*models.BehaviorRecord, error) {
+	var record models.BehaviorRecord
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND user_id IN (?)", behaviorID, models.FamilyChildIDs(r.db, familyID)).
+		First(&record).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &record, nil
+}
+
+func (r gormBehaviorRepository) LockLastRecorded(recorderID uint, since time.Time) (*models.BehaviorRecord, error) {
+	var record models.BehaviorRecord
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("recorder_id = ? AND source = ? AND created_at >= ?", recorderID, models.BehaviorSourceManual, since).
+		Order("id DESC").First(&record).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &record, nil
+}
+
+func (r gormBehaviorRepository) LockTriggered(recordID uint) ([]models.BehaviorRecord, error) {
+	var records []models.BehaviorRecord
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("trigger_id = ?", recordID).Order("id ASC").Find(&records).Error
+	return records, err
+}
+
+func (r gormBehaviorRepository) List(filter BehaviorFilter, page Page) ([]models.BehaviorRecord, int64, error) {
+	var records []models.BehaviorRecord
+	if len(filter.ChildIDs) == 0 {
+		return records, 0, nil
+	}
+
+	query := r.db.Model(&models.BehaviorRecord{}).Where("user_id IN ?", filter.ChildIDs)
+	if filter.BehaviorType != "" {
+		query = query.Where("behavior_type = ?", filter.BehaviorType)
+	}
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	if filter.TemplateID != nil {
+		query = query.Where("template_id = ?", *filter.TemplateID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.RecordedFrom != nil {
+		query = query.Where("recorded_at >= ?", *filter.RecordedFrom)
+	}
+	if filter.RecordedTo != nil {
+		query = query.Where("recorded_at <= ?", *filter.RecordedTo)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	order := "recorded_at DESC"
+	if filter.OldestFirst {
+		order = "recorded_at ASC"
+	}
+	err := paginate(query.Order(order), page).Find(&records).Error
+	return records, total, err
+}
+
+type gormRewardRepository struct {
+	db *gorm.DB
+}
+
+func (r gormRewardRepository) Create(reward *models.Reward) error {
+	return r.db.Create(reward).Error
+}
+
+func (r gormRewardRepository) Save(reward *models.Reward) error {
+	return r.db.Save(reward).Error
+}
+
+func (r gormRewardRepository) Delete(reward *models.Reward) error {
+	return r.db.Delete(reward).Error
+}
+
+func (r gormRewardRepository) FindFamilyReward(familyID, rewardID uint) (*models.Reward, error) {
+	var reward models.Reward
+	if err := r.db.Where("id = ? AND created_by IN (?)", rewardID, models.FamilyMemberIDs(r.db, familyID)).
+		First(&reward).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &reward, nil
+}
+
+func (r gormRewardRepository) LockFamilyReward(familyID, rewardID uint) (*models.Reward, error) {
+	var reward models.Reward
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND created_by IN (?)", rewardID, models.FamilyMemberIDs(r.db, familyID)).
+		First(&reward).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &reward, nil
+}
+
+func (r gormRewardRepository) List(familyID uint, isActive *bool, page Page) ([]models.Reward, int64, error) {
+	query := r.db.Model(&models.Reward{}).Where("created_by IN (?)", models.FamilyMemberIDs(r.db, familyID))
+	if isActive != nil {
+		query = query.Where("is_active = ?", *isActive)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var rewards []models.Reward
+	err := paginate(query.Order("created_at DESC"), page).Find(&rewards).Error
+	return rewards, total, err
+}
+
+func (r gormRewardRepository) CountExchanges(rewardID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.ExchangeRecord{}).Where("reward_id = ?", rewardID).Count(&count).Error
+	return count, err
+}
+
+func (r gormRewardRepository) RestoreStock(rewardID uint) error {
+	return r.db.Model(&models.Reward{}).Where("id = ?", rewardID).Update("stock", gorm.Expr("stock + ?", 1)).Error
+}
+
+func (r gormRewardRepository) CreateExchange(exchange *models.ExchangeRecord) error {
+	return r.db.Create(exchange).Error
+}
+
+func (r gormRewardRepository) SaveExchange(exchange *models.ExchangeRecord) error {
+	return r.db.Omit(clause.Associations).Save(exchange).Error
+}
+
+func (r gormRewardRepository) LockExchange(exchangeID uint) (*models.ExchangeRecord, error) {
+	var exchange models.ExchangeRecord
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&exchange, exchangeID).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &exchange, nil
+}
+
+func (r gormRewardRepository) ListExchanges(filter ExchangeFilter, page Page) ([]models.ExchangeRecord, int64, error) {
+	var exchanges []models.ExchangeRecord
+	if len(filter.UserIDs) == 0 {
+		return exchanges, 0, nil
+	}
+
+	query := r.db.Model(&models.ExchangeRecord{}).Where("user_id IN ?", filter.UserIDs)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := paginate(query.Preload("Reward").Order("exchanged_at DESC"), page).Find(&exchanges).Error
+	return exchanges, total, err
+}
+
+type gormProgressRepository struct {
+	db *gorm.DB
+}
+
+func (r gormProgressRepository) ApproveBehavior(record *models.BehaviorRecord, actorID uint, note string) (*models.UserPoints, error) {
+	return models.ApproveBehaviorRecord(r.db, record, actorID, note)
+}
+
+func (r gormProgressRepository) RevertStreak(record *models.BehaviorRecord) error {
+	return models.RevertStreak(r.db, record)
+}
+
+func (r gormProgressRepository) EvaluateAchievements(childID uint) error {
+	_, err := models.EvaluateAchievements(r.db, childID)
+	return err
+}
@@ -0,0 +1,605 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"child-behavior-app/internal/models"
+)
+
+// MemoryStore 内存数据访问实现，不依赖数据库，用于单独验证服务层的业务规则
+// 家庭成员按用户的 family_id 和角色判断；行为生效时只写入积分流水，不计算连续天数和成就；
+// 事务串行执行，出错时恢复事务开始时的数据
+type MemoryStore struct {
+	mu     *sync.Mutex
+	tables *memoryTables
+	inTx   bool
+}
+
+// memoryTables 内存中的数据表，记录按值保存，读写时复制
+type memoryTables struct {
+	lastID       uint
+	users        map[uint]models.User
+	points       map[uint]models.UserPoints // 按 user_id 索引
+	transactions []models.PointTransaction
+	behaviors    map[uint]models.BehaviorRecord
+	templates    map[uint]models.BehaviorTemplate
+	rewards      map[uint]models.Reward
+	exchanges    map[uint]models.ExchangeRecord
+}
+
+// NewMemoryStore 创建空的内存数据访问实现
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		mu: &sync.Mutex{},
+		tables: &memoryTables{
+			users:     make(map[uint]models.User),
+			points:    make(map[uint]models.UserPoints),
+			behaviors: make(map[uint]models.BehaviorRecord),
+			templates: make(map[uint]models.BehaviorTemplate),
+			rewards:   make(map[uint]models.Reward),
+			exchanges: make(map[uint]models.ExchangeRecord),
+		},
+	}
+}
+
+// clone 复制全部数据表，用于事务回滚
+func (t *memoryTables) clone() *memoryTables {
+	copied := &memoryTables{
+		lastID:       t.lastID,
+		users:        make(map[uint]models.User, len(t.users)),
+		points:       make(map[uint]models.UserPoints, len(t.points)),
+		transactions: append([]models.PointTransaction(nil), t.transactions...),
+		behaviors:    make(map[uint]models.BehaviorRecord, len(t.behaviors)),
+		templates:    make(map[uint]models.BehaviorTemplate, len(t.templates)),
+		rewards:      make(map[uint]models.Reward, len(t.rewards)),
+		exchanges:    make(map[uint]models.ExchangeRecord, len(t.exchanges)),
+	}
+	for id, user := range t.users {
+		copied.users[id] = user
+	}
+	for id, userPoints := range t.points {
+		copied.points[id] = userPoints
+	}
+	for id, record := range t.behaviors {
+		copied.behaviors[id] = record
+	}
+	for id, template := range t.templates {
+		copied.templates[id] = template
+	}
+	for id, reward := range t.rewards {
+		copied.rewards[id] = reward
+	}
+	for id, exchange := range t.exchanges {
+		copied.exchanges[id] = exchange
+	}
+	return copied
+}
+
+// nextID 分配自增ID，所有表共用一个序列
+func (t *memoryTables) nextID() uint {
+	t.lastID++
+	return t.lastID
+}
+
+// isFamilyParent 判断用户是否为家庭中的家长
+func (t *memoryTables) isFamilyParent(familyID, userID uint) bool {
+	user, ok := t.users[userID]
+	return ok && user.Role == "parent" && user.FamilyID != nil && *user.FamilyID == familyID
+}
+
+// isFamilyChild 判断用户是否为家庭中的儿童
+func (t *memoryTables) isFamilyChild(familyID, userID uint) bool {
+	user, ok := t.users[userID]
+	return ok && user.Role == "child" && user.FamilyID != nil && *user.FamilyID == familyID
+}
+
+// lock 事务外的每次调用加锁，事务中已持有锁
+func (s *MemoryStore) lock() func() {
+	if s.inTx {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+// AddUser 写入用户，用于准备家长等不由服务层创建的数据，ID 为 0 时自动分配
+func (s *MemoryStore) AddUser(user *models.User) {
+	defer s.lock()()
+	if user.ID == 0 {
+		user.ID = s.tables.nextID()
+	} else if user.ID > s.tables.lastID {
+		s.tables.lastID = user.ID
+	}
+	s.tables.users[user.ID] = *user
+}
+
+// AddTemplate 写入行为模板，ID 为 0 时自动分配
+func (s *MemoryStore) AddTemplate(template *models.BehaviorTemplate) {
+	defer s.lock()()
+	if template.ID == 0 {
+		template.ID = s.tables.nextID()
+	} else if template.ID > s.tables.lastID {
+		s.tables.lastID = template.ID
+	}
+	s.tables.templates[template.ID] = *template
+}
+
+// Transactions 获取用户的全部积分流水，按写入顺序
+func (s *MemoryStore) Transactions(userID uint) []models.PointTransaction {
+	defer s.lock()()
+	var transactions []models.PointTransaction
+	for _, txn := range s.tables.transactions {
+		if txn.UserID == userID {
+			transactions = append(transactions, txn)
+		}
+	}
+	return transactions
+}
+
+func (s *MemoryStore) Children() ChildRepository {
+	return memoryChildRepository{s}
+}
+
+func (s *MemoryStore) Points() PointsRepository {
+	return memoryPointsRepository{s}
+}
+
+func (s *MemoryStore) Behaviors() BehaviorRepository {
+	return memoryBehaviorRepository{s}
+}
+
+func (s *MemoryStore) Rewards() RewardRepository {
+	return memoryRewardRepository{s}
+}
+
+func (s *MemoryStore) Progress() ProgressRepository {
+	return memoryProgressRepository{s}
+}
+
+func (s *MemoryStore) Transaction(fn func(tx Store) error) error {
+	defer s.lock()()
+	snapshot := s.tables.clone()
+	if err := fn(&MemoryStore{mu: s.mu, tables: s.tables, inTx: true}); err != nil {
+		*s.tables = *snapshot
+		return err
+	}
+	return nil
+}
+
+// pageOf 按分页参数截取，负数的偏移量视为0
+func pageOf[T any](items []T, page Page) []T {
+	if page.Offset >= len(items) {
+		return []T{}
+	}
+	if page.Offset > 0 {
+		items = items[page.Offset:]
+	}
+	if page.Limit > 0 && page.Limit < len(items) {
+		items = items[:page.Limit]
+	}
+	return items
+}
+
+// containsID 判断ID是否在列表中
+func containsID(ids []uint, id uint) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+type memoryChildRepository struct {
+	s *MemoryStore
+}
+
+func (r memoryChildRepository) FindUser(userID uint) (*models.User, error) {
+	defer r.s.lock()()
+	user, ok := r.s.tables.users[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r memoryChildRepository) FindUsers(userIDs []uint) ([]models.User, error) {
+	defer r.s.lock()()
+	var users []models.User
+	for _, id := range userIDs {
+		if user, ok := r.s.tables.users[id]; ok {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (r memoryChildRepository) FindFamilyChild(familyID, childID uint) (*models.User, error) {
+	defer r.s.lock()()
+	if !r.s.tables.isFamilyChild(familyID, childID) {
+		return nil, ErrNotFound
+	}
+	child := r.s.tables.users[childID]
+	return &child, nil
+}
+
+func (r memoryChildRepository) ListFamilyChildren(familyID uint) ([]models.User, error) {
+	defer r.s.lock()()
+	var children []models.User
+	for id, user := range r.s.tables.users {
+		if r.s.tables.isFamilyChild(familyID, id) {
+			children = append(children, user)
+		}
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].ID < children[j].ID })
+	return children, nil
+}
+
+func (r memoryChildRepository) Create(child *models.User) error {
+	defer r.s.lock()()
+	child.ID = r.s.tables.nextID()
+	child.CreatedAt = time.Now()
+	child.UpdatedAt = child.CreatedAt
+	r.s.tables.users[child.ID] = *child
+	return nil
+}
+
+func (r memoryChildRepository) UpdateProfile(child *models.User) error {
+	defer r.s.lock()()
+	stored, ok := r.s.tables.users[child.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.Nickname = child.Nickname
+	stored.Age = child.Age
+	stored.Gender = child.Gender
+	stored.Avatar = child.Avatar
+	stored.UpdatedAt = time.Now()
+	r.s.tables.users[child.ID] = stored
+	return nil
+}
+
+func (r memoryChildRepository) Delete(child *models.User) error {
+	defer r.s.lock()()
+	t := r.s.tables
+	delete(t.points, child.ID)
+	var transactions []models.PointTransaction
+	for _, txn := range t.transactions {
+		if txn.UserID != child.ID {
+			transactions = append(transactions, txn)
+		}
+	}
+	t.transactions = transactions
+	for id, record := range t.behaviors {
+		if record.ChildID == child.ID {
+			delete(t.behaviors, id)
+		}
+	}
+	for id, exchange := range t.exchanges {
+		if exchange.UserID == child.ID {
+			delete(t.exchanges, id)
+		}
+	}
+	delete(t.users, child.ID)
+	return nil
+}
+
+type memoryPointsRepository struct {
+	s *MemoryStore
+}
+
+func (r memoryPointsRepository) Get(userID uint) (*models.UserPoints, error) {
+	defer r.s.lock()()
+	userPoints, ok := r.s.tables.points[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &userPoints, nil
+}
+
+func (r memoryPointsRepository) Lock(userID uint) (*models.UserPoints, error) {
+	return r.Get(userID)
+}
+
+func (r memoryPointsRepository) Create(userPoints *models.UserPoints) error {
+	defer r.s.lock()()
+	userPoints.ID = r.s.tables.nextID()
+	userPoints.CreatedAt = time.Now()
+	userPoints.UpdatedAt = userPoints.CreatedAt
+	r.s.tables.points[userPoints.UserID] = *userPoints
+	return nil
+}
+
+func (r memoryPointsRepository) Apply(txn *models.PointTransaction) (*models.UserPoints, error) {
+	defer r.s.lock()()
+	t := r.s.tables
+	userPoints, ok := t.points[txn.UserID]
+	if !ok {
+		userPoints = models.UserPoints{ID: t.nextID(), UserID: txn.UserID, CreatedAt: time.Now()}
+	}
+
+	if userPoints.AvailablePoints+txn.Delta < 0 {
+		txn.Delta = -userPoints.AvailablePoints
+	}
+	models.ApplyPointDelta(&userPoints, txn.SourceType, txn.Delta)
+	userPoints.UpdatedAt = time.Now()
+	txn.ID = t.nextID()
+	txn.BalanceAfter = userPoints.AvailablePoints
+	txn.TotalAfter = userPoints.TotalPoints
+	txn.CreatedAt = userPoints.UpdatedAt
+
+	t.transactions = append(t.transactions, *txn)
+	t.points[txn.UserID] = userPoints
+	return &userPoints, nil
+}
+
+func (r memoryPointsRepository) SourceDelta(sourceType string, sourceID uint) (int, error) {
+	defer r.s.lock()()
+	sum := 0
+	for _, txn := range r.s.tables.transactions {
+		if txn.SourceType == sourceType && txn.SourceID == sourceID {
+			sum += txn.Delta
+		}
+	}
+	return sum, nil
+}
+
+func (r memoryPointsRepository) ListTransactions(userID uint, sourceType string, page Page) ([]models.PointTransaction, int64, error) {
+	defer r.s.lock()()
+	var transactions []models.PointTransaction
+	for i := len(r.s.tables.transactions) - 1; i >= 0; i-- {
+		txn := r.s.tables.transactions[i]
+		if txn.UserID == userID && (sourceType == "" || txn.SourceType == sourceType) {
+			transactions = append(transactions, txn)
+		}
+	}
+	return pageOf(transactions, page), int64(len(transactions)), nil
+}
+
+type memoryBehaviorRepository struct {
+	s *MemoryStore
+}
+
+func (r memoryBehaviorRepository) FindActiveTemplate(familyID, templateID uint) (*models.BehaviorTemplate, error) {
+	defer r.s.lock()()
+	template, ok := r.s.tables.templates[templateID]
+	if !ok || !template.IsActive || !r.s.tables.isFamilyParent(familyID, template.CreatedBy) {
+		return nil, ErrNotFound
+	}
+	return &template, nil
+}
+
+func (r memoryBehaviorRepository) Create(record *models.BehaviorRecord) error {
+	defer r.s.lock()()
+	if record.Source == "" {
+		record.Source = models.BehaviorSourceManual
+	}
+	record.ID = r.s.tables.nextID()
+	record.CreatedAt = time.Now()
+	record.UpdatedAt = record.CreatedAt
+	r.s.tables.behaviors[record.ID] = *record
+	return nil
+}
+
+func (r memoryBehaviorRepository) Save(record *models.BehaviorRecord) error {
+	defer r.s.lock()()
+	if _, ok := r.s.tables.behaviors[record.ID]; !ok {
+		return ErrNotFound
+	}
+	record.UpdatedAt = time.Now()
+	r.s.tables.behaviors[record.ID] = *record
+	return nil
+}
+
+func (r memoryBehaviorRepository) Delete(record *models.BehaviorRecord) error {
+	defer r.s.lock()()
+	delete(r.s.tables.behaviors, record.ID)
+	return nil
+}
+
+func (r memoryBehaviorRepository) LockFamilyBehavior(familyID, behaviorID uint) (*models.BehaviorRecord, error) {
+	defer r.s.lock()()
+	record, ok := r.s.tables.behaviors[behaviorID]
+	if !ok || !r.s.tables.isFamilyChild(familyID, record.ChildID) {
+		return nil, ErrNotFound
+	}
+	return &record, nil
+}
+
+func (r memoryBehaviorRepository) LockLastRecorded(recorderID uint, since time.Time) (*models.BehaviorRecord, error) {
+	defer r.s.lock()()
+	var last *models.BehaviorRecord
+	for _, record := range r.s.tables.behaviors {
+		if record.RecorderID != recorderID || record.Source != models.BehaviorSourceManual || record.CreatedAt.Before(since) {
+			continue
+		}
+		if last == nil || record.ID > last.ID {
+			record := record
+			last = &record
+		}
+	}
+	if last == nil {
+		return nil, ErrNotFound
+	}
+	return last, nil
+}
+
+func (r memoryBehaviorRepository) LockTriggered(recordID uint) ([]models.BehaviorRecord, error) {
+	defer r.s.lock()()
+	var records []models.BehaviorRecord
+	for _, record := range r.s.tables.behaviors {
+		if record.TriggerID != nil && *record.TriggerID == recordID {
+			records = append(records, record)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	return records, nil
+}
+
+func (r memoryBehaviorRepository) List(filter BehaviorFilter, page Page) ([]models.BehaviorRecord, int64, error) {
+	defer r.s.lock()()
+	var records []models.BehaviorRecord
+	for _, record := range r.s.tables.behaviors {
+		switch {
+		case !containsID(filter.ChildIDs, record.ChildID),
+			filter.BehaviorType != "" && record.BehaviorType != filter.BehaviorType,
+			filter.Category != "" && record.Category != filter.Category,
+			filter.TemplateID != nil && (record.TemplateID == nil || *record.TemplateID != *filter.TemplateID),
+			filter.Status != "" && record.Status != filter.Status,
+			filter.RecordedFrom != nil && record.RecordedAt.Before(*filter.RecordedFrom),
+			filter.RecordedTo != nil && record.RecordedAt.After(*filter.RecordedTo):
+			continue
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if filter.OldestFirst {
+			return records[i].RecordedAt.Before(records[j].RecordedAt)
+		}
+		return records[i].RecordedAt.After(records[j].RecordedAt)
+	})
+	return pageOf(records, page), int64(len(records)), nil
+}
+
+type memoryRewardRepository struct {
+	s *MemoryStore
+}
+
+func (r memoryRewardRepository) Create(reward *models.Reward) error {
+	defer r.s.lock()()
+	reward.ID = r.s.tables.nextID()
+	reward.CreatedAt = time.Now()
+	reward.UpdatedAt = reward.CreatedAt
+	r.s.tables.rewards[reward.ID] = *reward
+	return nil
+}
+
+func (r memoryRewardRepository) Save(reward *models.Reward) error {
+	defer r.s.lock()()
+	if _, ok := r.s.tables.rewards[reward.ID]; !ok {
+		return ErrNotFound
+	}
+	reward.UpdatedAt = time.Now()
+	r.s.tables.rewards[reward.ID] = *reward
+	return nil
+}
+
+func (r memoryRewardRepository) Delete(reward *models.Reward) error {
+	defer r.s.lock()()
+	delete(r.s.tables.rewards, reward.ID)
+	return nil
+}
+
+func (r memoryRewardRepository) FindFamilyReward(familyID, rewardID uint) (*models.Reward, error) {
+	defer r.s.lock()()
+	reward, ok := r.s.tables.rewards[rewardID]
+	if !ok || !r.s.tables.isFamilyParent(familyID, reward.CreatedBy) {
+		return nil, ErrNotFound
+	}
+	return &reward, nil
+}
+
+func (r memoryRewardRepository) LockFamilyReward(familyID, rewardID uint) (*models.Reward, error) {
+	return r.FindFamilyReward(familyID, rewardID)
+}
+
+func (r memoryRewardRepository) List(familyID uint, isActive *bool, page Page) ([]models.Reward, int64, error) {
+	defer r.s.lock()()
+	var rewards []models.Reward
+	for _, reward := range r.s.tables.rewards {
+		if !r.s.tables.isFamilyParent(familyID, reward.CreatedBy) || (isActive != nil && reward.IsActive != *isActive) {
+			continue
+		}
+		rewards = append(rewards, reward)
+	}
+	sort.Slice(rewards, func(i, j int) bool { return rewards[i].ID > rewards[j].ID })
+	return pageOf(rewards, page), int64(len(rewards)), nil
+}
+
+func (r memoryRewardRepository) CountExchanges(rewardID uint) (int64, error) {
+	defer r.s.lock()()
+	var count int64
+	for _, exchange := range r.s.tables.exchanges {
+		if exchange.RewardID == rewardID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r memoryRewardRepository) RestoreStock(rewardID uint) error {
+	defer r.s.lock()()
+	if reward, ok := r.s.tables.rewards[rewardID]; ok {
+		reward.Stock++
+		r.s.tables.rewards[rewardID] = reward
+	}
+	return nil
+}
+
+func (r memoryRewardRepository) CreateExchange(exchange *models.ExchangeRecord) error {
+	defer r.s.lock()()
+	exchange.ID = r.s.tables.nextID()
+	exchange.CreatedAt = time.Now()
+	exchange.UpdatedAt = exchange.CreatedAt
+	r.s.tables.exchanges[exchange.ID] = *exchange
+	return nil
+}
+
+func (r memoryRewardRepository) SaveExchange(exchange *models.ExchangeRecord) error {
+	defer r.s.lock()()
+	if _, ok := r.s.tables.exchanges[exchange.ID]; !ok {
+		return ErrNotFound
+	}
+	exchange.UpdatedAt = time.Now()
+	r.s.tables.exchanges[exchange.ID] = *exchange
+	return nil
+}
+
+func (r memoryRewardRepository) LockExchange(exchangeID uint) (*models.ExchangeRecord, error) {
+	defer r.s.lock()()
+	exchange, ok := r.s.tables.exchanges[exchangeID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &exchange, nil
+}
+
+func (r memoryRewardRepository) ListExchanges(filter ExchangeFilter, page Page) ([]models.ExchangeRecord, int64, error) {
+	defer r.s.lock()()
+	var exchanges []models.ExchangeRecord
+	for _, exchange := range r.s.tables.exchanges {
+		if !containsID(filter.UserIDs, exchange.UserID) || (filter.Status != "" && exchange.Status != filter.Status) {
+			continue
+		}
+		exchange.Reward = r.s.tables.rewards[exchange.RewardID]
+		exchanges = append(exchanges, exchange)
+	}
+	sort.Slice(exchanges, func(i, j int) bool { return exchanges[i].ExchangedAt.After(exchanges[j].ExchangedAt) })
+	return pageOf(exchanges, page), int64(len(exchanges)), nil
+}
+
+// memoryProgressRepository 内存实现只写入行为的积分流水，不计算连续天数和成就
+type memoryProgressRepository struct {
+	s *MemoryStore
+}
+
+func (r memoryProgressRepository) ApproveBehavior(record *models.BehaviorRecord, actorID uint, note string) (*models.UserPoints, error) {
+	return memoryPointsRepository{r.s}.Apply(&models.PointTransaction{
+		UserID:     record.ChildID,
+		SourceType: models.PointSourceBehavior,
+		SourceID:   record.ID,
+		Delta:      record.ScoreChange,
+		ActorID:    actorID,
+		Note:       note,
+	})
+}
+
+func (memoryProgressRepository) RevertStreak(*models.BehaviorRecord) error {
+	return nil
+}
+
+func (memoryProgressRepository) EvaluateAchievements(uint) error {
+	return nil
+}
@@ -0,0 +1,148 @@
+// Package repository 数据访问接口，服务层只通过这些接口读写数据
+// GormStore 用于运行环境，MemoryStore 是不依赖数据库的内存实现，用于单独验证业务规则
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"child-behavior-app/internal/models"
+)
+
+// ErrNotFound 记录不存在，或不属于指定的家庭
+var ErrNotFound = errors.New("record not found")
+
+// Page 分页参数，Limit 为 0 时返回全部
+type Page struct {
+	Offset int
+	Limit  int
+}
+
+// Store 数据访问入口
+type Store interface {
+	Children() ChildRepository
+	Points() PointsRepository
+	Behaviors() BehaviorRepository
+	Rewards() RewardRepository
+	Progress() ProgressRepository
+	// Transaction 在事务中执行 fn，fn 中应只使用 tx 访问数据，返回错误时回滚
+	Transaction(fn func(tx Store) error) error
+}
+
+// ChildRepository 用户和儿童账户
+type ChildRepository interface {
+	// FindUser 按ID获取用户
+	FindUser(userID uint) (*models.User, error)
+	// FindUsers 批量获取用户，不存在的ID忽略
+	FindUsers(userIDs []uint) ([]models.User, error)
+	// FindFamilyChild 获取属于家庭的儿童
+	FindFamilyChild(familyID, childID uint) (*models.User, error)
+	// ListFamilyChildren 获取家庭中的儿童，按ID排序
+	ListFamilyChildren(familyID uint) ([]models.User, error)
+	// Create 创建儿童账户
+	Create(child *models.User) error
+	// UpdateProfile 保存儿童的昵称、年龄、性别和头像
+	UpdateProfile(child *models.User) error
+	// Delete 删除儿童账户及其积分、行为、兑换、登录设备、连续天数和成就等数据
+	Delete(child *models.User) error
+}
+
+// PointsRepository 积分和积分流水
+type PointsRepository interface {
+	// Get 获取用户积分
+	Get(userID uint) (*models.UserPoints, error)
+	// Lock 在事务中锁定并获取用户积分
+	Lock(userID uint) (*models.UserPoints, error)
+	// Create 创建用户积分
+	Create(userPoints *models.UserPoints) error
+	// Apply 写入一条积分流水并按 models.ApplyPointDelta 同步更新用户积分，没有积分记录时创建，
+	// 扣分超过可用积分时 txn.Delta 改为实际扣除的积分，txn 的 BalanceAfter/TotalAfter 由实现计算，应在事务中调用
+	Apply(txn *models.PointTransaction) (*models.UserPoints, error)
+	// SourceDelta 同一来源已写入流水的积分变化之和
+	SourceDelta(sourceType string, sourceID uint) (int, error)
+	// ListTransactions 获取积分流水，按ID倒序，sourceType 为空时不过滤
+	ListTransactions(userID uint, sourceType string, page Page) ([]models.PointTransaction, int64, error)
+}
+
+// BehaviorFilter 行为记录查询条件，空值表示不过滤
+type BehaviorFilter struct {
+	// ChildIDs 儿童范围，必填，为空时没有结果
+	ChildIDs     []uint
+	BehaviorType string
+	Category     string
+	TemplateID   *uint
+	Status       string
+	// RecordedFrom、RecordedTo 记录时间范围，包含两端
+	RecordedFrom *time.Time
+	RecordedTo   *time.Time
+	// OldestFirst 按记录时间正序，默认倒序
+	OldestFirst bool
+}
+
+// BehaviorRepository 行为记录和行为模板
+type BehaviorRepository interface {
+	// FindActiveTemplate 获取家庭成员创建的、启用中的行为模板
+	FindActiveTemplate(familyID, templateID uint) (*models.BehaviorTemplate, error)
+	// Create 创建行为记录
+	Create(record *models.BehaviorRecord) error
+	// Save 保存行为记录
+	Save(record *models.BehaviorRecord) error
+	// Delete 删除行为记录
+	Delete(record *models.BehaviorRecord) error
+	// LockFamilyBehavior 在事务中锁定属于家庭儿童的行为记录
+	LockFamilyBehavior(familyID, behaviorID uint) (*models.BehaviorRecord, error)
+	// LockLastRecorded 在事务中锁定记录人在 since 之后手动录入的最后一条行为记录，
+	// 任务和连续奖励等系统生成的记录不包括在内
+	LockLastRecorded(recorderID uint, since time.Time) (*models.BehaviorRecord, error)
+	// LockTriggered 在事务中锁定由行为记录触发的连续奖励记录
+	LockTriggered(recordID uint) ([]models.BehaviorRecord, error)
+	// List 按条件获取行为记录
+	List(filter BehaviorFilter, page Page) ([]models.BehaviorRecord, int64, error)
+}
+
+// ExchangeFilter 兑换记录查询条件
+type ExchangeFilter struct {
+	// UserIDs 兑换人范围，必填，为空时没有结果
+	UserIDs []uint
+	// Status 为空时不过滤
+	Status string
+}
+
+// RewardRepository 奖励和兑换记录
+type RewardRepository interface {
+	// Create 创建奖励
+	Create(reward *models.Reward) error
+	// Save 保存奖励
+	Save(reward *models.Reward) error
+	// Delete 删除奖励
+	Delete(reward *models.Reward) error
+	// FindFamilyReward 获取家庭成员创建的奖励
+	FindFamilyReward(familyID, rewardID uint) (*models.Reward, error)
+	// LockFamilyReward 在事务中锁定家庭成员创建的奖励
+	LockFamilyReward(familyID, rewardID uint) (*models.Reward, error)
+	// List 获取家庭成员创建的奖励，按创建时间倒序，isActive 为 nil 时不过滤
+	List(familyID uint, isActive *bool, page Page) ([]models.Reward, int64, error)
+	// CountExchanges 奖励的兑换记录数
+	CountExchanges(rewardID uint) (int64, error)
+	// RestoreStock 奖励库存加一
+	RestoreStock(rewardID uint) error
+	// CreateExchange 创建兑换记录
+	CreateExchange(exchange *models.ExchangeRecord) error
+	// SaveExchange 保存兑换记录
+	SaveExchange(exchange *models.ExchangeRecord) error
+	// LockExchange 在事务中锁定兑换记录
+	LockExchange(exchangeID uint) (*models.ExchangeRecord, error)
+	// ListExchanges 按条件获取兑换记录及其奖励，按兑换时间倒序
+	ListExchanges(filter ExchangeFilter, page Page) ([]models.ExchangeRecord, int64, error)
+}
+
+// ProgressRepository 行为发放积分后的连续天数和成就
+type ProgressRepository interface {
+	// ApproveBehavior 为已生效的行为记录写入积分流水，加分记录同时更新连续天数并检查成就，
+	// 返回包含连续奖励在内的最新积分，规则见 models.ApproveBehaviorRecord
+	ApproveBehavior(record *models.BehaviorRecord, actorID uint, note string) (*models.UserPoints, error)
+	// RevertStreak 撤销行为记录后回退它计入的连续天数，规则见 models.RevertStreak
+	RevertStreak(record *models.BehaviorRecord) error
+	// EvaluateAchievements 检查儿童尚未解锁的成就，达到条件的解锁
+	EvaluateAchievements(childID uint) error
+}
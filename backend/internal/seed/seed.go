@@ -0,0 +1,508 @@
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/repository"
+	"child-behavior-app/internal/service"
+	"child-behavior-app/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// 演示家庭的登录信息
+const (
+	DemoOwnerPhone    = "19900000001" // 家庭所有者（爸爸）
+	DemoGuardianPhone = "19900000002" // 共同监护人（妈妈）
+	DemoPassword      = "demo123"
+	DemoChildPin      = "1234"
+)
+
+// 默认参数，参数相同时每次生成的数据相同
+const (
+	DefaultSeed  = 42
+	DefaultDays  = 90
+	DefaultUntil = "2026-06-30" // 历史截止日期，格式为 YYYY-MM-DD
+)
+
+// recentDays 最近几天的申报和兑换保持待确认，便于演示审核流程
+const recentDays = 2
+
+// ErrAlreadySeeded 演示家庭已存在
+var ErrAlreadySeeded = errors.New("demo family already exists")
+
+// Options 演示数据参数，种子、天数和截止时间相同时生成的数据相同
+type Options struct {
+	Seed  int64     // 随机数种子
+	Days  int       // 行为历史的天数，包含截止当天
+	Until time.Time // 历史截止时间，晚于该时间的记录不生成，为零时使用 DefaultUntil 当天结束
+}
+
+// Summary 生成结果
+type Summary struct {
+	FamilyID  uint
+	Parents   int
+	Children  int
+	Templates int
+	Behaviors int
+	Claims    int
+	Rewards   int
+	Exchanges int
+}
+
+// demoParent 演示家长
+type demoParent struct {
+	nickname string
+	phone    string
+	role     string
+}
+
+// demoChild 演示儿童及其行为倾向
+type demoChild struct {
+	nickname  string
+	age       int
+	gender    string
+	habit     string  // 每天坚持的行为模板，用于产生连续天数
+	habitRate float64 // 每天完成坚持行为的概率
+	goodRate  float64 // 其他行为中加分行为的比例
+	claimRate float64 // 每天自己申报一次行为的概率
+}
+
+var demoParents = []demoParent{
+	{nickname: "演示爸爸", phone: DemoOwnerPhone, role: models.FamilyRoleOwner},
+	{nickname: "演示妈妈", phone: DemoGuardianPhone, role: models.FamilyRoleGuardian},
+}
+
+var demoChildren = []demoChild{
+	{nickname: "小明", age: 9, gender: "male", habit: "阅读课外书", habitRate: 0.9, goodRate: 0.85, claimRate: 0.15},
+	{nickname: "小红", age: 6, gender: "female", habit: "按时睡觉", habitRate: 0.75, goodRate: 0.7, claimRate: 0.3},
+}
+
+var demoRewards = []models.Reward{
+	{Name: "看一集动画片", Description: "周末可以多看一集动画片", Points: 20, Stock: 50},
+	{Name: "冰淇淋", Description: "任选一个冰淇淋", Points: 30, Stock: 20},
+	{Name: "晚睡半小时", Description: "周五晚上可以晚睡半小时", Points: 40, Stock: 30},
+	{Name: "周末去公园", Description: "全家一起去公园玩", Points: 60, Stock: 10, RequiresApproval: true},
+	{Name: "新绘本", Description: "自己挑选一本绘本", Points: 80, Stock: 5, RequiresApproval: true},
+	{Name: "乐高积木", Description: "一套小号乐高积木", Points: 200, Stock: 2, RequiresApproval: true},
+}
+
+var demoStreakBonusRules = []models.StreakBonusRule{
+	{Days: 7, BonusPoints: 20},
+	{Days: 30, BonusPoints: 50},
+}
+
+// ParseUntil 解析 YYYY-MM-DD 格式的截止日期，返回当天的最后一秒，包含截止当天的全部记录
+func ParseUntil(day string) (time.Time, error) {
+	date, err := time.ParseInLocation("2006-01-02", day, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid until date %q, expected YYYY-MM-DD", day)
+	}
+	return date.AddDate(0, 0, 1).Add(-time.Second), nil
+}
+
+// Exists 判断演示家庭是否已经存在
+func Exists(db *gorm.DB) (bool, error) {
+	var count int64
+	if err := db.Model(&models.User{}).Where("phone = ?", DemoOwnerPhone).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check demo family: %w", err)
+	}
+	return count > 0, nil
+}
+
+// Run 在事务中生成演示家庭：家长、儿童、行为模板、连续奖励规则、周期任务、行为历史、奖励和兑换记录
+// 行为记录、申报审核和兑换通过 service 中的业务规则写入，积分、连续天数和成就与正常使用产生的数据一致；
+// 记录的创建时间使用模拟的历史时间。演示家庭已存在时返回 ErrAlreadySeeded
+func Run(db *gorm.DB, opts Options) (*Summary, error) {
+	if opts.Days <= 0 {
+		opts.Days = DefaultDays
+	}
+	if opts.Until.IsZero() {
+		until, err := ParseUntil(DefaultUntil)
+		if err != nil {
+			return nil, err
+		}
+		opts.Until = until
+	}
+
+	exists, err := Exists(db)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrAlreadySeeded
+	}
+
+	var summary *Summary
+	err = db.Transaction(func(tx *gorm.DB) error {
+		g := &generator{rand: rand.New(rand.NewSource(opts.Seed)), until: opts.Until}
+		// 自动填写的创建和更新时间以及服务中的记录时间都使用模拟时钟
+		clock := func() time.Time { return g.clock }
+		g.tx = tx.Session(&gorm.Session{NowFunc: clock})
+		store := repository.NewGormStore(g.tx)
+		g.behaviorService = service.NewBehaviorService(store).WithClock(clock)
+		g.rewardService = service.NewRewardService(store).WithClock(clock)
+		if err := g.generate(opts.Days); err != nil {
+			return err
+		}
+		summary = &g.summary
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// generator 按模拟时钟依次生成演示数据
+type generator struct {
+	tx      *gorm.DB
+	rand    *rand.Rand
+	clock   time.Time
+	until   time.Time
+	summary Summary
+
+	behaviorService *service.BehaviorService
+	rewardService   *service.RewardService
+
+	parents   []models.User
+	children  []models.User
+	templates map[string]models.BehaviorTemplate
+	good      []models.BehaviorTemplate // 加分模板，按ID排序
+	bad       []models.BehaviorTemplate // 扣分模板，按ID排序
+	rewards   []models.Reward
+}
+
+// generate 生成全部数据，基础数据在历史开始前一天创建
+func (g *generator) generate(days int) error {
+	untilDay := time.Date(g.until.Year(), g.until.Month(), g.until.Day(), 0, 0, 0, 0, g.until.Location())
+	start := untilDay.AddDate(0, 0, -(days - 1))
+	g.clock = start.AddDate(0, 0, -1).Add(9 * time.Hour)
+
+	if err := g.createFamily(); err != nil {
+		return err
+	}
+	if err := g.createTemplates(); err != nil {
+		return err
+	}
+	if err := g.createRewards(); err != nil {
+		return err
+	}
+
+	for day := 0; day < days; day++ {
+		date := start.AddDate(0, 0, day)
+		recent := days-day <= recentDays
+		for i := range g.children {
+			if err := g.simulateDay(&g.children[i], demoChildren[i], date, recent); err != nil {
+				return err
+			}
+		}
+	}
+
+	// 成就的解锁时间由 EvaluateAchievements 写入当前时间，改为模拟的解锁时间
+	childIDs := make([]uint, len(g.children))
+	for i, child := range g.children {
+		childIDs[i] = child.ID
+	}
+	if err := g.tx.Model(&models.ChildAchievement{}).Where("child_id IN ?", childIDs).
+		Update("unlocked_at", gorm.Expr("created_at")).Error; err != nil {
+		return fmt.Errorf("failed to backdate achievements: %w", err)
+	}
+	return nil
+}
+
+// createFamily 创建家长、家庭和儿童
+func (g *generator) createFamily() error {
+	password, err := utils.HashPassword(DemoPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	pin, err := utils.HashPassword(DemoChildPin)
+	if err != nil {
+		return fmt.Errorf("failed to hash pin: %w", err)
+	}
+
+	var family *models.Family
+	for _, p := range demoParents {
+		phone := p.phone
+		parent := models.User{Phone: &phone, Password: &password, Nickname: p.nickname, Role: "parent"}
+		if err := g.tx.Create(&parent).Error; err != nil {
+			return fmt.Errorf("failed to create parent: %w", err)
+		}
+
+		if p.role == models.FamilyRoleOwner {
+			if family, err = models.CreateFamily(g.tx, &parent); err != nil {
+				return err
+			}
+		} else {
+			member := models.FamilyMember{FamilyID: family.ID, UserID: parent.ID, Role: p.role}
+			if err := g.tx.Create(&member).Error; err != nil {
+				return fmt.Errorf("failed to create family member: %w", err)
+			}
+			if err := g.tx.Model(&parent).Update("family_id", family.ID).Error; err != nil {
+				return fmt.Errorf("failed to update parent family: %w", err)
+			}
+		}
+
+		if err := g.tx.Create(&models.UserPoints{UserID: parent.ID}).Error; err != nil {
+			return fmt.Errorf("failed to create user points: %w", err)
+		}
+		g.parents = append(g.parents, parent)
+	}
+	g.summary.FamilyID = family.ID
+	g.summary.Parents = len(g.parents)
+
+	owner := g.parents[0]
+	for _, c := range demoChildren {
+		child := models.User{
+			Nickname: c.nickname,
+			Age:      c.age,
+			Gender:   c.gender,
+			Role:     "child",
+			ParentID: &owner.ID,
+			FamilyID: &family.ID,
+			Pin:      &pin,
+		}
+		if err := g.tx.Create(&child).Error; err != nil {
+			return fmt.Errorf("failed to create child: %w", err)
+		}
+		if err := g.tx.Create(&models.UserPoints{UserID: child.ID}).Error; err != nil {
+			return fmt.Errorf("failed to create user points: %w", err)
+		}
+		g.children = append(g.children, child)
+	}
+	g.summary.Children = len(g.children)
+	return nil
+}
+
+// createTemplates 读取创建家庭时写入的默认行为目录，写入连续奖励规则和每个儿童的每日任务
+func (g *generator) createTemplates() error {
+	owner := g.parents[0]
+	var templates []models.BehaviorTemplate
+	if err := g.tx.Where("created_by = ?", owner.ID).Order("id ASC").Find(&templates).Error; err != nil {
+		return fmt.Errorf("failed to load behavior templates: %w", err)
+	}
+	g.templates = make(map[string]models.BehaviorTemplate, len(templates))
+	for _, template := range templates {
+		g.templates[template.Name] = template
+		if template.DefaultPoints > 0 {
+			g.good = append(g.good, template)
+		} else {
+			g.bad = append(g.bad, template)
+		}
+	}
+	g.summary.Templates = len(templates)
+
+	for _, rule := range demoStreakBonusRules {
+		rule.CreatedBy = owner.ID
+		rule.IsActive = true
+		if err := g.tx.Create(&rule).Error; err != nil {
+			return fmt.Errorf("failed to create streak bonus rule: %w", err)
+		}
+	}
+
+	for i, child := range g.children {
+		template := g.templates[demoChildren[i].habit]
+		chore := models.Chore{
+			ChildID:    child.ID,
+			TemplateID: &template.ID,
+			Name:       template.Name,
+			Category:   template.Category,
+			Points:     template.DefaultPoints,
+			Recurrence: "daily",
+			DueTime:    "21:00",
+			IsActive:   true,
+			CreatedBy:  owner.ID,
+		}
+		if err := g.tx.Create(&chore).Error; err != nil {
+			return fmt.Errorf("failed to create chore: %w", err)
+		}
+	}
+	return nil
+}
+
+// createRewards 创建奖励
+func (g *generator) createRewards() error {
+	for _, reward := range demoRewards {
+		reward.CreatedBy = g.parents[0].ID
+		reward.IsActive = true
+		if err := g.tx.Create(&reward).Error; err != nil {
+			return fmt.Errorf("failed to create reward: %w", err)
+		}
+		g.rewards = append(g.rewards, reward)
+	}
+	g.summary.Rewards = len(g.rewards)
+	return nil
+}
+
+// dayEvent 一天中的一次行为
+type dayEvent struct {
+	at       time.Time
+	template models.BehaviorTemplate
+	claim    bool // 儿童自己申报
+}
+
+// simulateDay 生成儿童一天的行为、申报和兑换，recent 为最近几天，申报和兑换保持待确认
+func (g *generator) simulateDay(child *models.User, profile demoChild, date time.Time, recent bool) error {
+	var events []dayEvent
+	if g.rand.Float64() < profile.habitRate {
+		events = append(events, dayEvent{at: g.timeOfDay(date, 19, 22), template: g.templates[profile.habit]})
+	}
+	// 周末的行为更多
+	count := g.rand.Intn(4)
+	if weekday := date.Weekday(); weekday == time.Saturday || weekday == time.Sunday {
+		count++
+	}
+	for i := 0; i < count; i++ {
+		template := g.bad[g.rand.Intn(len(g.bad))]
+		if g.rand.Float64() < profile.goodRate {
+			template = g.good[g.rand.Intn(len(g.good))]
+		}
+		events = append(events, dayEvent{at: g.timeOfDay(date, 7, 21), template: template})
+	}
+	if g.rand.Float64() < profile.claimRate {
+		events = append(events, dayEvent{at: g.timeOfDay(date, 16, 20), template: g.good[g.rand.Intn(len(g.good))], claim: true})
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].at.Before(events[j].at) })
+
+	for _, event := range events {
+		if event.at.After(g.until) {
+			continue
+		}
+		var err error
+		if event.claim {
+			err = g.claim(child, event.template, event.at, recent)
+		} else {
+			err = g.record(child, event.template, event.at)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if g.rand.Float64() < 0.2 {
+		if at := g.timeOfDay(date, 19, 21); !at.After(g.until) {
+			return g.exchange(child, at, recent)
+		}
+	}
+	return nil
+}
+
+// timeOfDay 在当天 fromHour 到 toHour 之间随机取一个时间
+func (g *generator) timeOfDay(date time.Time, fromHour, toHour int) time.Time {
+	minutes := fromHour*60 + g.rand.Intn((toHour-fromHour)*60)
+	return date.Add(time.Duration(minutes) * time.Minute)
+}
+
+// randomParent 随机选择一位家长作为记录人或审核人
+func (g *generator) randomParent() models.User {
+	return g.parents[g.rand.Intn(len(g.parents))]
+}
+
+// parentActor 以家长身份操作
+func (g *generator) parentActor(parent models.User) service.Actor {
+	return service.Actor{UserID: parent.ID, Role: "parent", FamilyID: g.summary.FamilyID}
+}
+
+// childActor 以儿童身份操作
+func (g *generator) childActor(child *models.User) service.Actor {
+	return service.Actor{UserID: child.ID, Role: "child", FamilyID: g.summary.FamilyID}
+}
+
+// templateInput 使用行为模板记录行为，分类、描述和积分取模板的值
+func templateInput(child *models.User, template models.BehaviorTemplate) service.BehaviorInput {
+	templateID := template.ID
+	return service.BehaviorInput{ChildID: child.ID, TemplateID: &templateID}
+}
+
+// record 家长记录一条行为
+func (g *generator) record(child *models.User, template models.BehaviorTemplate, at time.Time) error {
+	g.clock = at
+	if _, err := g.behaviorService.Record(g.parentActor(g.randomParent()), templateInput(child, template)); err != nil {
+		return fmt.Errorf("failed to record behavior: %w", err)
+	}
+	g.summary.Behaviors++
+	return nil
+}
+
+// claim 儿童申报一条行为，较早的申报由家长在一小时后审核，多数通过
+func (g *generator) claim(child *models.User, template models.BehaviorTemplate, at time.Time, recent bool) error {
+	g.clock = at
+	record, err := g.behaviorService.Submit(g.childActor(child), templateInput(child, template))
+	if err != nil {
+		return fmt.Errorf("failed to submit claim: %w", err)
+	}
+	g.summary.Behaviors++
+	g.summary.Claims++
+
+	reviewedAt := at.Add(time.Hour)
+	if recent || reviewedAt.After(g.until) {
+		return nil
+	}
+
+	g.clock = reviewedAt
+	reviewer := g.randomParent()
+	status, note := models.BehaviorStatusApproved, ""
+	if g.rand.Float64() < 0.2 {
+		status, note = models.BehaviorStatusRejected, "今天没有做到哦"
+	}
+	if _, _, _, err := g.behaviorService.Review(g.parentActor(reviewer), record.ID, status, note); err != nil {
+		return fmt.Errorf("failed to review claim: %w", err)
+	}
+	return nil
+}
+
+// exchange 儿童用可用积分兑换一个买得起的奖励，需要确认的奖励较早时由家长在两小时后处理
+func (g *generator) exchange(child *models.User, at time.Time, recent bool) error {
+	g.clock = at
+	userPoints, err := models.LockUserPoints(g.tx, child.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load user points: %w", err)
+	}
+
+	var affordable []int
+	for i, reward := range g.rewards {
+		if reward.IsActive && reward.Stock > 0 && reward.Points <= userPoints.AvailablePoints {
+			affordable = append(affordable, i)
+		}
+	}
+	if len(affordable) == 0 {
+		return nil
+	}
+	reward := &g.rewards[affordable[g.rand.Intn(len(affordable))]]
+
+	result, err := g.rewardService.Exchange(g.childActor(child), reward.ID, 0)
+	if err != nil {
+		return fmt.Errorf("failed to exchange reward: %w", err)
+	}
+	*reward = result.Reward
+	g.summary.Exchanges++
+
+	reviewedAt := at.Add(2 * time.Hour)
+	if result.Exchange.Status != models.ExchangeStatusPending || recent || reviewedAt.After(g.until) {
+		return nil
+	}
+	return g.resolveExchange(&result.Exchange, reward, reviewedAt)
+}
+
+// resolveExchange 家长处理待确认的兑换，多数完成，取消时退回积分并恢复库存
+func (g *generator) resolveExchange(exchange *models.ExchangeRecord, reward *models.Reward, at time.Time) error {
+	g.clock = at
+	reviewer := g.randomParent()
+	status, note := models.ExchangeStatusCompleted, ""
+	if g.rand.Float64() < 0.15 {
+		status, note = models.ExchangeStatusCancelled, "这周先不兑换了"
+	}
+	if _, _, err := g.rewardService.ResolveExchange(g.parentActor(reviewer), exchange.ID, status, note); err != nil {
+		return fmt.Errorf("failed to resolve exchange: %w", err)
+	}
+	if status == models.ExchangeStatusCancelled {
+		reward.Stock++
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package seed_test
+
+import (
+	"errors"
+	"testing"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/seed"
+	"child-behavior-app/internal/utils"
+	"child-behavior-app/migrations"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openDatabase 打开执行了全部迁移的 SQLite 内存数据库
+func openDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := models.InitDBWithConfig(utils.DatabaseConfig{Driver: utils.DatabaseDriverSQLite, Path: utils.SQLiteMemoryPath})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	db = db.Session(&gorm.Session{Logger: logger.Default.LogMode(logger.Silent)})
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("get sql.DB: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// snapshot 演示数据中用于比较的部分：生成结果、每个用户的积分、全部积分流水和兑换记录
+type snapshot struct {
+	summary      seed.Summary
+	points       []models.UserPoints
+	transactions []models.PointTransaction
+	exchanges    []models.ExchangeRecord
+}
+
+func runSeed(t *testing.T, db *gorm.DB) snapshot {
+	t.Helper()
+	summary, err := seed.Run(db, seed.Options{Seed: seed.DefaultSeed, Days: 30})
+	if err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	result := snapshot{summary: *summary}
+	if err := db.Order("user_id").Find(&result.points).Error; err != nil {
+		t.Fatalf("load user points: %v", err)
+	}
+	if err := db.Order("id").Find(&result.transactions).Error; err != nil {
+		t.Fatalf("load point transactions: %v", err)
+	}
+	if err := db.Order("id").Find(&result.exchanges).Error; err != nil {
+		t.Fatalf("load exchange records: %v", err)
+	}
+	return result
+}
+
+func TestRunIsReproducible(t *testing.T) {
+	first := runSeed(t, openDatabase(t))
+	second := runSeed(t, openDatabase(t))
+
+	if first.summary != second.summary {
+		t.Fatalf("summaries differ: %+v vs %+v", first.summary, second.summary)
+	}
+	if first.summary.Behaviors == 0 || first.summary.Claims == 0 || first.summary.Exchanges == 0 {
+		t.Fatalf("summary = %+v, want behaviors, claims and exchanges", first.summary)
+	}
+	if len(first.transactions) != len(second.transactions) {
+		t.Fatalf("transaction counts differ: %d vs %d", len(first.transactions), len(second.transactions))
+	}
+	for i := range first.transactions {
+		a, b := first.transactions[i], second.transactions[i]
+		if a.SourceType != b.SourceType || a.Delta != b.Delta || a.BalanceAfter != b.BalanceAfter || !a.CreatedAt.Equal(b.CreatedAt) {
+			t.Fatalf("transaction %d differs: %+v vs %+v", i, a, b)
+		}
+	}
+	for i := range first.points {
+		a, b := first.points[i], second.points[i]
+		if a.TotalPoints != b.TotalPoints || a.AvailablePoints != b.AvailablePoints {
+			t.Fatalf("points of user %d differ: %+v vs %+v", a.UserID, a, b)
+		}
+	}
+
+	// 流水时间为模拟的历史时间，不晚于默认截止日期
+	until, err := seed.ParseUntil(seed.DefaultUntil)
+	if err != nil {
+		t.Fatalf("parse default until: %v", err)
+	}
+	for _, transaction := range first.transactions {
+		if transaction.CreatedAt.After(until) {
+			t.Fatalf("transaction %d created at %s, after %s", transaction.ID, transaction.CreatedAt, until)
+		}
+	}
+	// 兑换和确认时间同样使用模拟时钟
+	for _, exchange := range first.exchanges {
+		if exchange.ExchangedAt.After(until) || (exchange.ReviewedAt != nil && exchange.ReviewedAt.After(until)) {
+			t.Fatalf("exchange %d at %s reviewed %v, after %s", exchange.ID, exchange.ExchangedAt, exchange.ReviewedAt, until)
+		}
+	}
+}
+
+func TestRunRefusesExistingFamily(t *testing.T) {
+	db := openDatabase(t)
+	runSeed(t, db)
+	if _, err := seed.Run(db, seed.Options{}); !errors.Is(err, seed.ErrAlreadySeeded) {
+		t.Fatalf("second run: %v, want %v", err, seed.ErrAlreadySeeded)
+	}
+}
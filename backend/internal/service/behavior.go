@@ -0,0 +1,491 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/repository"
+)
+
+// 行为记录错误
+var (
+	// ErrBehaviorIncomplete 未使用行为模板时缺少分类、描述或积分
+	ErrBehaviorIncomplete = errors.New("behavior_type, behavior_desc and a non-zero score_change are required without template_id")
+	// ErrTemplateNotFound 行为模板不存在、已停用或不属于当前家庭
+	ErrTemplateNotFound = errors.New("behavior template not found or inactive")
+	// ErrZeroScoreChange 积分变化不能为0
+	ErrZeroScoreChange = errors.New("score change cannot be zero")
+	// ErrClaimDeducts 申报只能加分，扣分行为由家长记录
+	ErrClaimDeducts = errors.New("claims can only add points")
+	// ErrBehaviorNotFound 行为记录不存在或不属于当前家庭
+	ErrBehaviorNotFound = errors.New("behavior not found or permission denied")
+	// ErrNothingToUndo 撤销窗口内没有可以撤销的记录
+	ErrNothingToUndo = errors.New("no behavior to undo")
+	// ErrClaimAlreadyReviewed 申报已被审核
+	ErrClaimAlreadyReviewed = errors.New("claim already reviewed")
+)
+
+// BehaviorUndoWindow 家长可以撤销最近一条行为记录的时间窗口
+const BehaviorUndoWindow = 5 * time.Minute
+
+// BehaviorService 行为记录、申报审核和行为统计
+type BehaviorService struct {
+	store repository.Store
+	// now 当前时间，默认为 time.Now
+	now func() time.Time
+}
+
+func NewBehaviorService(store repository.Store) *BehaviorService {
+	return &BehaviorService{store: store, now: time.Now}
+}
+
+// WithClock 返回使用指定时钟的服务，记录和审核时间取自 now，用于按模拟时间生成演示数据
+func (s *BehaviorService) WithClock(now func() time.Time) *BehaviorService {
+	clone := *s
+	clone.now = now
+	return &clone
+}
+
+// BehaviorInput 记录或申报行为的内容
+// 使用行为模板时分类、描述和积分可以省略，默认取模板的值
+type BehaviorInput struct {
+	ChildID     uint
+	TemplateID  *uint
+	Category    string
+	Description string
+	ScoreChange int
+	ImageURL    string
+}
+
+// BehaviorChanges 修改行为记录的内容，空值表示不修改
+type BehaviorChanges struct {
+	Category    string
+	Description string
+	ScoreChange *int
+	ImageURL    string
+}
+
+// BehaviorQuery 行为记录查询条件，空值表示不过滤
+type BehaviorQuery struct {
+	ChildID      *uint
+	BehaviorType string
+	Category     string
+	TemplateID   *uint
+	// Status 为 all 时返回全部状态，为空时只返回已生效的记录
+	Status       string
+	RecordedFrom *time.Time
+	RecordedTo   *time.Time
+}
+
+// BehaviorList 行为记录及相关用户的昵称
+type BehaviorList struct {
+	Records []models.BehaviorRecord
+	Total   int64
+	// Names 儿童和记录人的昵称
+	Names map[uint]string
+}
+
+// TrendDay 一天中加分和扣分行为的数量
+type TrendDay struct {
+	Date      string `json:"date"`
+	GoodCount int    `json:"good_count"`
+	BadCount  int    `json:"bad_count"`
+}
+
+// complete 使用行为模板补全内容并校验必填字段，模板需由家庭成员创建
+func (s *BehaviorService) complete(store repository.Store, familyID uint, input *BehaviorInput) error {
+	if input.TemplateID != nil {
+		template, err := store.Behaviors().FindActiveTemplate(familyID, *input.TemplateID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrTemplateNotFound
+		}
+		if err != nil {
+			return err
+		}
+		input.Category = template.Category
+		if input.Description == "" {
+			input.Description = template.Name
+		}
+		if input.ScoreChange == 0 {
+			input.ScoreChange = template.DefaultPoints
+		}
+	}
+
+	if input.Category == "" || input.Description == "" || input.ScoreChange == 0 {
+		return ErrBehaviorIncomplete
+	}
+	return nil
+}
+
+// newRecord 根据内容构建行为记录，分类未知时归为 other，极性由积分正负确定
+func (s *BehaviorService) newRecord(input BehaviorInput, recorderID uint, status string) models.BehaviorRecord {
+	return models.BehaviorRecord{
+		ChildID:      input.ChildID,
+		RecorderID:   recorderID,
+		BehaviorType: models.BehaviorPolarity(input.ScoreChange),
+		Category:     models.NormalizeBehaviorCategory(input.Category),
+		BehaviorDesc: input.Description,
+		ScoreChange:  input.ScoreChange,
+		ImageURL:     input.ImageURL,
+		TemplateID:   input.TemplateID,
+		Status:       status,
+		Source:       models.BehaviorSourceManual,
+		RecordedAt:   s.now(),
+	}
+}
+
+// reverse 删除行为记录并冲销其积分，待审核或已拒绝的记录从未计入积分，只删除记录
+// 冲销的是流水中实际计入的积分：扣分时可用积分不足的部分没有扣除，也不会退回。
+// 该记录触发的连续奖励是单独的行为记录，保留不变，连续天数也不重新计算；需要时家长可以单独删除奖励记录
+func (s *BehaviorService) reverse(tx repository.Store, record *models.BehaviorRecord, actorID uint, note string) (*models.UserPoints, error) {
+	if err := tx.Behaviors().Delete(record); err != nil {
+		return nil, err
+	}
+	if record.Status != models.BehaviorStatusApproved {
+		return currentPoints(tx, record.ChildID)
+	}
+	applied, err := tx.Points().SourceDelta(models.PointSourceBehavior, record.ID)
+	if err != nil {
+		return nil, err
+	}
+	if applied == 0 {
+		return currentPoints(tx, record.ChildID)
+	}
+	return tx.Points().Apply(&models.PointTransaction{
+		UserID:     record.ChildID,
+		SourceType: models.PointSourceBehavior,
+		SourceID:   record.ID,
+		Delta:      -applied,
+		ActorID:    actorID,
+		Note:       note,
+	})
+}
+
+// lockFamilyBehavior 锁定属于家庭儿童的行为记录，不存在时返回 ErrBehaviorNotFound
+func lockFamilyBehavior(tx repository.Store, familyID, behaviorID uint) (*models.BehaviorRecord, error) {
+	record, err := tx.Behaviors().LockFamilyBehavior(familyID, behaviorID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrBehaviorNotFound
+	}
+	return record, err
+}
+
+// Record 家长为家庭中的儿童记录行为，记录立即生效
+// 行为记录和积分流水在同一事务中写入，扣分后可用积分不会低于0
+func (s *BehaviorService) Record(actor Actor, input BehaviorInput) (*models.BehaviorRecord, error) {
+	if _, err := findFamilyChild(s.store, actor.FamilyID, input.ChildID); err != nil {
+		return nil, err
+	}
+	if err := s.complete(s.store, actor.FamilyID, &input); err != nil {
+		return nil, err
+	}
+
+	record := s.newRecord(input, actor.UserID, models.BehaviorStatusApproved)
+	err := s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Behaviors().Create(&record); err != nil {
+			return err
+		}
+		_, err := tx.Progress().ApproveBehavior(&record, actor.UserID, "")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Submit 儿童申报完成的行为，等待家长审核，审核通过前不影响积分
+func (s *BehaviorService) Submit(actor Actor, input BehaviorInput) (*models.BehaviorRecord, error) {
+	child, err := s.store.Children().FindUser(actor.UserID)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && child.FamilyID == nil) {
+		return nil, ErrNotInFamily
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	input.ChildID = child.ID
+	if err := s.complete(s.store, *child.FamilyID, &input); err != nil {
+		return nil, err
+	}
+	if input.ScoreChange < 0 {
+		return nil, ErrClaimDeducts
+	}
+
+	record := s.newRecord(input, child.ID, models.BehaviorStatusPending)
+	if err := s.store.Behaviors().Create(&record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Review 家长审核申报，status 为审核后的状态，通过时发放积分
+// 返回审核前、审核后的记录，通过时同时返回最新积分
+func (s *BehaviorService) Review(actor Actor, behaviorID uint, status, comment string) (*models.BehaviorRecord, *models.BehaviorRecord, *models.UserPoints, error) {
+	var before, record *models.BehaviorRecord
+	var userPoints *models.UserPoints
+	err := s.store.Transaction(func(tx repository.Store) error {
+		var err error
+		record, err = lockFamilyBehavior(tx, actor.FamilyID, behaviorID)
+		if err != nil {
+			return err
+		}
+		if record.Status != models.BehaviorStatusPending {
+			return ErrClaimAlreadyReviewed
+		}
+		original := *record
+		before = &original
+
+		now := s.now()
+		reviewerID := actor.UserID
+		record.Status = status
+		record.ReviewerID = &reviewerID
+		record.ReviewNote = comment
+		record.ReviewedAt = &now
+		if err := tx.Behaviors().Save(record); err != nil {
+			return err
+		}
+
+		if status != models.BehaviorStatusApproved {
+			return nil
+		}
+		userPoints, err = tx.Progress().ApproveBehavior(record, actor.UserID, "审核通过申报")
+		return err
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return before, record, userPoints, nil
+}
+
+// PendingClaims 获取家庭中待审核的申报，按记录时间正序，childID 为 nil 时返回所有儿童的申报
+func (s *BehaviorService) PendingClaims(familyID uint, childID *uint) (*BehaviorList, error) {
+	// 指定的儿童不属于当前家庭时没有结果
+	childIDs, err := visibleChildIDs(s.store, Actor{Role: "parent", FamilyID: familyID}, childID)
+	if errors.Is(err, ErrChildNotFound) {
+		childIDs = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	filter := repository.BehaviorFilter{ChildIDs: childIDs, Status: models.BehaviorStatusPending, OldestFirst: true}
+	return s.list(filter, repository.Page{})
+}
+
+// Update 修改行为记录，已生效记录的积分差额写入流水
+// 返回修改前、修改后的记录，积分有变化时同时返回最新积分
+func (s *BehaviorService) Update(actor Actor, behaviorID uint, changes BehaviorChanges) (*models.BehaviorRecord, *models.BehaviorRecord, *models.UserPoints, error) {
+	if changes.ScoreChange != nil && *changes.ScoreChange == 0 {
+		return nil, nil, nil, ErrZeroScoreChange
+	}
+
+	var before, record *models.BehaviorRecord
+	var userPoints *models.UserPoints
+	err := s.store.Transaction(func(tx repository.Store) error {
+		var err error
+		record, err = lockFamilyBehavior(tx, actor.FamilyID, behaviorID)
+		if err != nil {
+			return err
+		}
+		original := *record
+		before = &original
+
+		changed := false
+		if changes.Description != "" {
+			record.BehaviorDesc = changes.Description
+			changed = true
+		}
+		if changes.ImageURL != "" {
+			record.ImageURL = changes.ImageURL
+			changed = true
+		}
+		if changes.Category != "" {
+			record.Category = models.NormalizeBehaviorCategory(changes.Category)
+			changed = true
+		}
+
+		scoreChanged := false
+		if changes.ScoreChange != nil && *changes.ScoreChange != record.ScoreChange {
+			record.ScoreChange = *changes.ScoreChange
+			record.BehaviorType = models.BehaviorPolarity(record.ScoreChange)
+			scoreChanged = true
+			changed = true
+		}
+
+		if !changed {
+			return nil
+		}
+		if err := tx.Behaviors().Save(record); err != nil {
+			return err
+		}
+
+		// 待审核或已拒绝的记录不影响积分
+		if !scoreChanged || record.Status != models.BehaviorStatusApproved {
+			return nil
+		}
+		// 按流水中实际计入的积分计算差额，原来的扣分可能因可用积分不足没有全部扣除
+		applied, err := tx.Points().SourceDelta(models.PointSourceBehavior, record.ID)
+		if err != nil {
+			return err
+		}
+		if delta := record.ScoreChange - applied; delta != 0 {
+			userPoints, err = tx.Points().Apply(&models.PointTransaction{
+				UserID:     record.ChildID,
+				SourceType: models.PointSourceBehavior,
+				SourceID:   record.ID,
+				Delta:      delta,
+				ActorID:    actor.UserID,
+				Note:       "修改行为记录",
+			})
+		}
+		return err
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return before, record, userPoints, nil
+}
+
+// Delete 删除家庭中的行为记录并冲销积分，返回被删除的记录和最新积分
+func (s *BehaviorService) Delete(actor Actor, behaviorID uint) (*models.BehaviorRecord, *models.UserPoints, error) {
+	var record *models.BehaviorRecord
+	var userPoints *models.UserPoints
+	err := s.store.Transaction(func(tx repository.Store) error {
+		var err error
+		record, err = lockFamilyBehavior(tx, actor.FamilyID, behaviorID)
+		if err != nil {
+			return err
+		}
+		userPoints, err = s.reverse(tx, record, actor.UserID, "删除行为记录")
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return record, userPoints, nil
+}
+
+// Undo 撤销操作人在撤销窗口内手动录入的最后一条行为记录，该记录触发的连续奖励一并撤销，
+// 计入的连续天数也回退，返回被撤销的记录和最新积分
+func (s *BehaviorService) Undo(actor Actor) (*models.BehaviorRecord, *models.UserPoints, error) {
+	var record *models.BehaviorRecord
+	var userPoints *models.UserPoints
+	err := s.store.Transaction(func(tx repository.Store) error {
+		var err error
+		record, err = tx.Behaviors().LockLastRecorded(actor.UserID, s.now().Add(-BehaviorUndoWindow))
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrNothingToUndo
+		}
+		if err != nil {
+			return err
+		}
+
+		// 奖励在原记录之后生效，先冲销奖励
+		bonuses, err := tx.Behaviors().LockTriggered(record.ID)
+		if err != nil {
+			return err
+		}
+		for i := len(bonuses) - 1; i >= 0; i-- {
+			if _, err := s.reverse(tx, &bonuses[i], actor.UserID, "撤销行为记录"); err != nil {
+				return err
+			}
+		}
+		userPoints, err = s.reverse(tx, record, actor.UserID, "撤销行为记录")
+		if err != nil {
+			return err
+		}
+		// 误记的行为不计入连续天数，之后重新记录时可以正常获得奖励
+		return tx.Progress().RevertStreak(record)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return record, userPoints, nil
+}
+
+// List 分页获取操作人可以查看的行为记录，按记录时间倒序
+func (s *BehaviorService) List(actor Actor, query BehaviorQuery, page repository.Page) (*BehaviorList, error) {
+	childIDs, err := visibleChildIDs(s.store, actor, query.ChildID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := repository.BehaviorFilter{
+		ChildIDs:     childIDs,
+		BehaviorType: query.BehaviorType,
+		Category:     query.Category,
+		TemplateID:   query.TemplateID,
+		Status:       query.Status,
+		RecordedFrom: query.RecordedFrom,
+		RecordedTo:   query.RecordedTo,
+	}
+	switch query.Status {
+	case "":
+		filter.Status = models.BehaviorStatusApproved
+	case "all":
+		filter.Status = ""
+	}
+	return s.list(filter, page)
+}
+
+// list 获取行为记录及儿童和记录人的昵称
+func (s *BehaviorService) list(filter repository.BehaviorFilter, page repository.Page) (*BehaviorList, error) {
+	records, total, err := s.store.Behaviors().List(filter, page)
+	if err != nil {
+		return nil, err
+	}
+
+	var userIDs []uint
+	for _, record := range records {
+		userIDs = append(userIDs, record.ChildID, record.RecorderID)
+	}
+	names, err := userNames(s.store, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	return &BehaviorList{Records: records, Total: total, Names: names}, nil
+}
+
+// Trend 统计最近 days 天每天已生效的加分和扣分行为数量，按日期正序
+func (s *BehaviorService) Trend(actor Actor, childID *uint, days int) ([]TrendDay, error) {
+	childIDs, err := visibleChildIDs(s.store, actor, childID)
+	if err != nil {
+		return nil, err
+	}
+
+	if days < 0 {
+		days = 0
+	}
+	now := s.now()
+	since := now.AddDate(0, 0, -days)
+	records, _, err := s.store.Behaviors().List(repository.BehaviorFilter{
+		ChildIDs:     childIDs,
+		Status:       models.BehaviorStatusApproved,
+		RecordedFrom: &since,
+	}, repository.Page{})
+	if err != nil {
+		return nil, err
+	}
+
+	trend := make([]TrendDay, 0, days)
+	index := make(map[string]int, days)
+	for i := days - 1; i >= 0; i-- {
+		date := now.AddDate(0, 0, -i).Format("2006-01-02")
+		index[date] = len(trend)
+		trend = append(trend, TrendDay{Date: date})
+	}
+	for _, record := range records {
+		i, ok := index[record.RecordedAt.Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		switch record.BehaviorType {
+		case models.BehaviorGood:
+			trend[i].GoodCount++
+		case models.BehaviorBad:
+			trend[i].BadCount++
+		}
+	}
+	return trend, nil
+}
@@ -0,0 +1,183 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/repository"
+	"child-behavior-app/internal/service"
+)
+
+func TestBehaviorReversesClampedDeduction(t *testing.T) {
+	store := repository.NewMemoryStore()
+	f := newFamily(store, 1)
+	behaviors := service.NewBehaviorService(store)
+
+	record := func(score int) *models.BehaviorRecord {
+		t.Helper()
+		input := service.BehaviorInput{ChildID: f.child.UserID, Category: "learning", Description: "行为", ScoreChange: score}
+		record, err := behaviors.Record(f.parent, input)
+		if err != nil {
+			t.Fatalf("record %d: %v", score, err)
+		}
+		return record
+	}
+
+	record(3)
+	expectPoints(t, store, f.child.UserID, 3)
+
+	// 可用积分只有3分，扣10分只扣除3分，删除时只退回实际扣除的3分
+	deduction := record(-10)
+	expectPoints(t, store, f.child.UserID, 0)
+	if _, userPoints, err := behaviors.Delete(f.parent, deduction.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	} else if userPoints.AvailablePoints != 3 {
+		t.Fatalf("points after delete = %d, want 3", userPoints.AvailablePoints)
+	}
+
+	// 修改按流水中实际扣除的积分计算差额
+	deduction = record(-10)
+	expectPoints(t, store, f.child.UserID, 0)
+	score := -1
+	before, updated, _, err := behaviors.Update(f.parent, deduction.ID, service.BehaviorChanges{ScoreChange: &score})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if before.ScoreChange != -10 || updated.ScoreChange != -1 || updated.BehaviorType != models.BehaviorBad {
+		t.Fatalf("update: before %+v, after %+v", before, updated)
+	}
+	expectPoints(t, store, f.child.UserID, 2)
+
+	// 撤销同样只退回实际扣除的1分
+	undone, userPoints, err := behaviors.Undo(f.parent)
+	if err != nil {
+		t.Fatalf("undo: %v", err)
+	}
+	if undone.ID != deduction.ID || userPoints.AvailablePoints != 3 {
+		t.Fatalf("undo: record %d, points %d", undone.ID, userPoints.AvailablePoints)
+	}
+	if _, _, err := behaviors.Undo(f.parent); err != nil {
+		t.Fatalf("second undo: %v", err)
+	}
+	expectPoints(t, store, f.child.UserID, 0)
+	if _, _, err := behaviors.Undo(f.parent); !errors.Is(err, service.ErrNothingToUndo) {
+		t.Fatalf("third undo: %v, want %v", err, service.ErrNothingToUndo)
+	}
+}
+
+func TestBehaviorUndoReversesTriggeredRecords(t *testing.T) {
+	store := repository.NewMemoryStore()
+	f := newFamily(store, 1)
+	behaviors := service.NewBehaviorService(store)
+
+	record, err := behaviors.Record(f.parent, service.BehaviorInput{ChildID: f.child.UserID, Category: "learning", Description: "完成作业", ScoreChange: 5})
+	if err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	// 内存实现不计算连续天数，直接写入该记录触发的连续奖励
+	bonus := models.BehaviorRecord{
+		ChildID: f.child.UserID, RecorderID: f.parent.UserID, BehaviorType: models.BehaviorGood, Category: "learning",
+		BehaviorDesc: "连续奖励", ScoreChange: 20, Status: models.BehaviorStatusApproved,
+		Source: models.BehaviorSourceStreakBonus, TriggerID: &record.ID, RecordedAt: record.RecordedAt,
+	}
+	err = store.Transaction(func(tx repository.Store) error {
+		if err := tx.Behaviors().Create(&bonus); err != nil {
+			return err
+		}
+		_, err := tx.Progress().ApproveBehavior(&bonus, f.parent.UserID, "")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("create bonus: %v", err)
+	}
+	expectPoints(t, store, f.child.UserID, 25)
+
+	// 奖励记录不是手动录入的，撤销的是原记录，奖励一并冲销
+	undone, userPoints, err := behaviors.Undo(f.parent)
+	if err != nil {
+		t.Fatalf("undo: %v", err)
+	}
+	if undone.ID != record.ID || userPoints.AvailablePoints != 0 {
+		t.Fatalf("undo: record %d, points %d", undone.ID, userPoints.AvailablePoints)
+	}
+	list, err := behaviors.List(f.parent, service.BehaviorQuery{Status: "all"}, repository.Page{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if list.Total != 0 {
+		t.Fatalf("records after undo = %d, want 0", list.Total)
+	}
+}
+
+func TestBehaviorReviewClaims(t *testing.T) {
+	store := repository.NewMemoryStore()
+	f := newFamily(store, 1)
+	behaviors := service.NewBehaviorService(store)
+
+	submit := func() *models.BehaviorRecord {
+		t.Helper()
+		claim, err := behaviors.Submit(f.child, service.BehaviorInput{Category: "life", Description: "整理房间", ScoreChange: 4})
+		if err != nil {
+			t.Fatalf("submit: %v", err)
+		}
+		if claim.Status != models.BehaviorStatusPending {
+			t.Fatalf("claim status = %s, want %s", claim.Status, models.BehaviorStatusPending)
+		}
+		return claim
+	}
+	if _, err := behaviors.Submit(f.child, service.BehaviorInput{Category: "life", Description: "扣分", ScoreChange: -4}); !errors.Is(err, service.ErrClaimDeducts) {
+		t.Fatalf("deducting claim: %v, want %v", err, service.ErrClaimDeducts)
+	}
+
+	// 审核通过后发放积分，不能重复审核
+	claim := submit()
+	before, reviewed, userPoints, err := behaviors.Review(f.parent, claim.ID, models.BehaviorStatusApproved, "")
+	if err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	if before.Status != models.BehaviorStatusPending || reviewed.Status != models.BehaviorStatusApproved || userPoints.AvailablePoints != 4 {
+		t.Fatalf("approve: before %s, after %s, points %+v", before.Status, reviewed.Status, userPoints)
+	}
+	if _, _, _, err := behaviors.Review(f.parent, claim.ID, models.BehaviorStatusRejected, ""); !errors.Is(err, service.ErrClaimAlreadyReviewed) {
+		t.Fatalf("review again: %v, want %v", err, service.ErrClaimAlreadyReviewed)
+	}
+
+	// 拒绝的申报从未计入积分，修改和删除都不影响积分
+	claim = submit()
+	if _, _, userPoints, err := behaviors.Review(f.parent, claim.ID, models.BehaviorStatusRejected, "没有完成"); err != nil || userPoints != nil {
+		t.Fatalf("reject: points %+v, err %v", userPoints, err)
+	}
+	score := 10
+	if _, _, _, err := behaviors.Update(f.parent, claim.ID, service.BehaviorChanges{ScoreChange: &score}); err != nil {
+		t.Fatalf("update rejected claim: %v", err)
+	}
+	if _, _, err := behaviors.Delete(f.parent, claim.ID); err != nil {
+		t.Fatalf("delete rejected claim: %v", err)
+	}
+	expectPoints(t, store, f.child.UserID, 4)
+}
+
+func TestBehaviorOtherFamily(t *testing.T) {
+	store := repository.NewMemoryStore()
+	f := newFamily(store, 1)
+	other := newFamily(store, 2)
+	behaviors := service.NewBehaviorService(store)
+
+	input := service.BehaviorInput{ChildID: other.child.UserID, Category: "learning", Description: "完成作业", ScoreChange: 5}
+	if _, err := behaviors.Record(f.parent, input); !errors.Is(err, service.ErrChildNotFound) {
+		t.Fatalf("record for other family: %v, want %v", err, service.ErrChildNotFound)
+	}
+
+	record, err := behaviors.Record(other.parent, input)
+	if err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if _, _, err := behaviors.Delete(f.parent, record.ID); !errors.Is(err, service.ErrBehaviorNotFound) {
+		t.Fatalf("delete other family's record: %v, want %v", err, service.ErrBehaviorNotFound)
+	}
+	if _, _, _, err := behaviors.Review(f.parent, record.ID, models.BehaviorStatusApproved, ""); !errors.Is(err, service.ErrBehaviorNotFound) {
+		t.Fatalf("review other family's record: %v, want %v", err, service.ErrBehaviorNotFound)
+	}
+	expectPoints(t, store, other.child.UserID, 5)
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/repository"
+)
+
+// ChildService 儿童账户管理
+type ChildService struct {
+	store repository.Store
+}
+
+func NewChildService(store repository.Store) *ChildService {
+	return &ChildService{store: store}
+}
+
+// ChildProfile 儿童资料，更新时空值表示不修改
+type ChildProfile struct {
+	Nickname string
+	Age      int
+	Gender   string
+	Avatar   string
+}
+
+// ChildWithPoints 儿童及其积分
+type ChildWithPoints struct {
+	Child  models.User
+	Points models.UserPoints
+}
+
+// Get 获取属于家庭的儿童
+func (s *ChildService) Get(familyID, childID uint) (*models.User, error) {
+	return findFamilyChild(s.store, familyID, childID)
+}
+
+// Create 创建儿童账户并归入家长所在家庭，同时创建积分记录
+// 儿童账户不需要手机号和密码，由家长管理
+func (s *ChildService) Create(actor Actor, profile ChildProfile) (*models.User, error) {
+	parentID := actor.UserID
+	familyID := actor.FamilyID
+	child := models.User{
+		Nickname: profile.Nickname,
+		Age:      profile.Age,
+		Gender:   profile.Gender,
+		Avatar:   profile.Avatar,
+		Role:     "child",
+		ParentID: &parentID,
+		FamilyID: &familyID,
+	}
+
+	err := s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Children().Create(&child); err != nil {
+			return err
+		}
+		return tx.Points().Create(&models.UserPoints{UserID: child.ID})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &child, nil
+}
+
+// List 获取家庭中的儿童及其积分
+func (s *ChildService) List(familyID uint) ([]ChildWithPoints, error) {
+	children, err := s.store.Children().ListFamilyChildren(familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ChildWithPoints, 0, len(children))
+	for _, child := range children {
+		item := ChildWithPoints{Child: child, Points: models.UserPoints{UserID: child.ID}}
+		if userPoints, err := s.store.Points().Get(child.ID); err == nil {
+			item.Points = *userPoints
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// Update 更新儿童资料，返回修改前后的儿童
+func (s *ChildService) Update(familyID, childID uint, profile ChildProfile) (*models.User, *models.User, error) {
+	child, err := findFamilyChild(s.store, familyID, childID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	before := *child
+	if profile.Nickname != "" {
+		child.Nickname = profile.Nickname
+	}
+	if profile.Age > 0 {
+		child.Age = profile.Age
+	}
+	if profile.Gender != "" {
+		child.Gender = profile.Gender
+	}
+	if profile.Avatar != "" {
+		child.Avatar = profile.Avatar
+	}
+	if err := s.store.Children().UpdateProfile(child); err != nil {
+		return nil, nil, err
+	}
+
+	// 重新读取，获取数据库更新后的时间等字段
+	if updated, err := s.store.Children().FindUser(child.ID); err == nil {
+		child = updated
+	}
+	return &before, child, nil
+}
+
+// Delete 删除儿童账户及其全部数据，返回被删除的儿童
+func (s *ChildService) Delete(familyID, childID uint) (*models.User, error) {
+	var child *models.User
+	err := s.store.Transaction(func(tx repository.Store) error {
+		var err error
+		child, err = findFamilyChild(tx, familyID, childID)
+		if err != nil {
+			return err
+		}
+		return tx.Children().Delete(child)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return child, nil
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"errors"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/repository"
+)
+
+// PointsService 积分查询和手动调整
+type PointsService struct {
+	store repository.Store
+}
+
+func NewPointsService(store repository.Store) *PointsService {
+	return &PointsService{store: store}
+}
+
+// CheckAccess 检查操作人能否查看用户的积分
+// 儿童只能查看自己的积分，家长可以查看家庭成员的积分
+func (s *PointsService) CheckAccess(actor Actor, userID uint) error {
+	if actor.Role == "child" && userID != actor.UserID {
+		return ErrPermissionDenied
+	}
+
+	if actor.IsParent() {
+		user, err := s.store.Children().FindUser(userID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if user.FamilyID == nil || *user.FamilyID != actor.FamilyID {
+			return ErrPermissionDenied
+		}
+	}
+	return nil
+}
+
+// Get 获取用户积分
+func (s *PointsService) Get(actor Actor, userID uint) (*models.UserPoints, error) {
+	if err := s.CheckAccess(actor, userID); err != nil {
+		return nil, err
+	}
+	userPoints, err := s.store.Points().Get(userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrPointsNotFound
+	}
+	return userPoints, err
+}
+
+// Ledger 分页获取用户的积分流水，sourceType 为空时返回全部来源
+func (s *PointsService) Ledger(actor Actor, userID uint, sourceType string, page repository.Page) ([]models.PointTransaction, int64, error) {
+	if err := s.CheckAccess(actor, userID); err != nil {
+		return nil, 0, err
+	}
+	return s.store.Points().ListTransactions(userID, sourceType, page)
+}
+
+// Adjust 家长手动调整家庭中儿童的积分，写入一条调整流水
+// 返回调整流水以及调整前、调整后的积分
+func (s *PointsService) Adjust(actor Actor, childID uint, delta int, note string) (*models.PointTransaction, *models.UserPoints, *models.UserPoints, error) {
+	txn := models.PointTransaction{
+		UserID:     childID,
+		SourceType: models.PointSourceAdjustment,
+		Delta:      delta,
+		ActorID:    actor.UserID,
+		Note:       note,
+	}
+
+	var before, userPoints *models.UserPoints
+	err := s.store.Transaction(func(tx repository.Store) error {
+		if _, err := findFamilyChild(tx, actor.FamilyID, childID); err != nil {
+			return err
+		}
+		current, err := currentPoints(tx, childID)
+		if err != nil {
+			return err
+		}
+		original := *current
+		before = &original
+		userPoints, err = tx.Points().Apply(&txn)
+		return err
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return &txn, before, userPoints, nil
+}
+
+// currentPoints 获取用户积分，没有积分记录时返回零积分
+func currentPoints(store repository.Store, userID uint) (*models.UserPoints, error) {
+	userPoints, err := store.Points().Get(userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return &models.UserPoints{UserID: userID}, nil
+	}
+	return userPoints, err
+}
@@ -0,0 +1,63 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+
+	"child-behavior-app/internal/repository"
+	"child-behavior-app/internal/service"
+)
+
+func TestAdjustPoints(t *testing.T) {
+	store := repository.NewMemoryStore()
+	f := newFamily(store, 1)
+	other := newFamily(store, 2)
+	points := service.NewPointsService(store)
+
+	txn, before, after, err := points.Adjust(f.parent, f.child.UserID, 5, "奖励")
+	if err != nil {
+		t.Fatalf("adjust: %v", err)
+	}
+	if before.AvailablePoints != 0 || after.AvailablePoints != 5 || txn.BalanceAfter != 5 {
+		t.Fatalf("adjust: before %d, after %d, balance %d", before.AvailablePoints, after.AvailablePoints, txn.BalanceAfter)
+	}
+
+	// 扣分超过可用积分时只扣到0，流水记录实际扣除的积分
+	txn, before, after, err = points.Adjust(f.parent, f.child.UserID, -8, "扣分")
+	if err != nil {
+		t.Fatalf("adjust: %v", err)
+	}
+	if before.AvailablePoints != 5 || after.AvailablePoints != 0 || txn.Delta != -5 {
+		t.Fatalf("deduct: before %d, after %d, delta %d", before.AvailablePoints, after.AvailablePoints, txn.Delta)
+	}
+
+	if _, _, _, err := points.Adjust(f.parent, other.child.UserID, 5, "奖励"); !errors.Is(err, service.ErrChildNotFound) {
+		t.Fatalf("adjust other family's child: %v, want %v", err, service.ErrChildNotFound)
+	}
+	expectPoints(t, store, f.child.UserID, 0)
+}
+
+func TestCheckPointsAccess(t *testing.T) {
+	store := repository.NewMemoryStore()
+	f := newFamily(store, 1)
+	other := newFamily(store, 2)
+	points := service.NewPointsService(store)
+
+	tests := []struct {
+		name   string
+		actor  service.Actor
+		userID uint
+		want   error
+	}{
+		{"parent views own child", f.parent, f.child.UserID, nil},
+		{"child views self", f.child, f.child.UserID, nil},
+		{"parent views other family's child", f.parent, other.child.UserID, service.ErrPermissionDenied},
+		{"child views other child", f.child, other.child.UserID, service.ErrPermissionDenied},
+		{"parent views missing user", f.parent, 999, service.ErrUserNotFound},
+	}
+	for _, tt := range tests {
+		if err := points.CheckAccess(tt.actor, tt.userID); !errors.Is(err, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/repository"
+)
+
+// 奖励和兑换错误
+var (
+	// ErrRewardNotFound 奖励不存在或不属于当前家庭
+	ErrRewardNotFound = errors.New("reward not found")
+	// ErrRewardInactive 奖励已下架
+	ErrRewardInactive = errors.New("reward is not active")
+	// ErrRewardOutOfStock 奖励库存不足
+	ErrRewardOutOfStock = errors.New("reward is out of stock")
+	// ErrInsufficientPoints 可用积分不足
+	ErrInsufficientPoints = errors.New("insufficient points")
+	// ErrChildRequired 家长兑换时需要指定儿童
+	ErrChildRequired = errors.New("child ID is required for parent")
+	// ErrExchangeNotFound 兑换记录不存在或不属于当前家庭
+	ErrExchangeNotFound = errors.New("exchange record not found or permission denied")
+	// ErrExchangeNotPending 兑换记录已处理
+	ErrExchangeNotPending = errors.New("exchange record is not pending")
+)
+
+// RewardService 奖励管理、兑换和兑换确认
+type RewardService struct {
+	store repository.Store
+	// now 当前时间，默认为 time.Now
+	now func() time.Time
+}
+
+func NewRewardService(store repository.Store) *RewardService {
+	return &RewardService{store: store, now: time.Now}
+}
+
+// WithClock 返回使用指定时钟的服务，兑换和确认时间取自 now，用于按模拟时间生成演示数据
+func (s *RewardService) WithClock(now func() time.Time) *RewardService {
+	clone := *s
+	clone.now = now
+	return &clone
+}
+
+// RewardInput 创建奖励的内容
+type RewardInput struct {
+	Name             string
+	Description      string
+	Points           int
+	Image            string
+	Stock            int
+	RequiresApproval bool
+}
+
+// RewardChanges 修改奖励的内容，空值表示不修改，Stock 为负数时不修改
+type RewardChanges struct {
+	Name             string
+	Description      string
+	Points           int
+	Image            string
+	Stock            int
+	IsActive         *bool
+	RequiresApproval *bool
+}
+
+// ExchangeResult 兑换结果
+type ExchangeResult struct {
+	Exchange models.ExchangeRecord
+	Reward   models.Reward
+	Points   models.UserPoints
+}
+
+// ExchangeList 兑换记录及兑换人的昵称
+type ExchangeList struct {
+	Exchanges []models.ExchangeRecord
+	Total     int64
+	Names     map[uint]string
+}
+
+// findFamilyReward 获取家庭成员创建的奖励，不存在时返回 ErrRewardNotFound
+func findFamilyReward(store repository.Store, familyID, rewardID uint) (*models.Reward, error) {
+	reward, err := store.Rewards().FindFamilyReward(familyID, rewardID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrRewardNotFound
+	}
+	return reward, err
+}
+
+// Create 创建奖励
+func (s *RewardService) Create(actor Actor, input RewardInput) (*models.Reward, error) {
+	reward := models.Reward{
+		Name:             input.Name,
+		Description:      input.Description,
+		Points:           input.Points,
+		Image:            input.Image,
+		Stock:            input.Stock,
+		RequiresApproval: input.RequiresApproval,
+		CreatedBy:        actor.UserID,
+		IsActive:         true,
+	}
+	if err := s.store.Rewards().Create(&reward); err != nil {
+		return nil, err
+	}
+	return &reward, nil
+}
+
+// List 分页获取家庭成员创建的奖励，家长和儿童看到的奖励相同
+func (s *RewardService) List(familyID uint, isActive *bool, page repository.Page) ([]models.Reward, int64, error) {
+	return s.store.Rewards().List(familyID, isActive, page)
+}
+
+// Update 修改家庭中的奖励，返回修改前后的奖励
+func (s *RewardService) Update(familyID, rewardID uint, changes RewardChanges) (*models.Reward, *models.Reward, error) {
+	reward, err := findFamilyReward(s.store, familyID, rewardID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	before := *reward
+	if changes.Name != "" {
+		reward.Name = changes.Name
+	}
+	if changes.Description != "" {
+		reward.Description = changes.Description
+	}
+	if changes.Points > 0 {
+		reward.Points = changes.Points
+	}
+	if changes.Image != "" {
+		reward.Image = changes.Image
+	}
+	if changes.Stock >= 0 {
+		reward.Stock = changes.Stock
+	}
+	if changes.IsActive != nil {
+		reward.IsActive = *changes.IsActive
+	}
+	if changes.RequiresApproval != nil {
+		reward.RequiresApproval = *changes.RequiresApproval
+	}
+
+	if err := s.store.Rewards().Save(reward); err != nil {
+		return nil, nil, err
+	}
+	return &before, reward, nil
+}
+
+// Delete 删除家庭中的奖励，已有兑换记录的奖励只下架，不物理删除
+// 返回删除前的奖励，下架时同时返回下架后的奖励，物理删除时为 nil
+func (s *RewardService) Delete(familyID, rewardID uint) (*models.Reward, *models.Reward, error) {
+	reward, err := findFamilyReward(s.store, familyID, rewardID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	exchangeCount, err := s.store.Rewards().CountExchanges(reward.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	before := *reward
+	if exchangeCount > 0 {
+		reward.IsActive = false
+		if err := s.store.Rewards().Save(reward); err != nil {
+			return nil, nil, err
+		}
+		return &before, reward, nil
+	}
+
+	if err := s.store.Rewards().Delete(reward); err != nil {
+		return nil, nil, err
+	}
+	return &before, nil, nil
+}
+
+// Exchange 兑换奖励，儿童为自己兑换，家长为家庭中的儿童兑换
+// 奖励和积分在事务中加锁，防止并发超卖或重复扣分；需要家长确认的奖励由儿童兑换时先冻结积分和库存
+func (s *RewardService) Exchange(actor Actor, rewardID, childID uint) (*ExchangeResult, error) {
+	targetUserID := actor.UserID
+	if actor.IsParent() {
+		if childID == 0 {
+			return nil, ErrChildRequired
+		}
+		if _, err := findFamilyChild(s.store, actor.FamilyID, childID); err != nil {
+			return nil, err
+		}
+		targetUserID = childID
+	}
+
+	var result ExchangeResult
+	err := s.store.Transaction(func(tx repository.Store) error {
+		reward, err := tx.Rewards().LockFamilyReward(actor.FamilyID, rewardID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrRewardNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if !reward.IsActive {
+			return ErrRewardInactive
+		}
+		if reward.Stock <= 0 {
+			return ErrRewardOutOfStock
+		}
+
+		userPoints, err := tx.Points().Lock(targetUserID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrPointsNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if userPoints.AvailablePoints < reward.Points {
+			return ErrInsufficientPoints
+		}
+
+		reward.Stock--
+		if err := tx.Rewards().Save(reward); err != nil {
+			return err
+		}
+
+		status := models.ExchangeStatusCompleted
+		if reward.RequiresApproval && !actor.IsParent() {
+			status = models.ExchangeStatusPending
+		}
+		exchange := models.ExchangeRecord{
+			UserID:      targetUserID,
+			RewardID:    reward.ID,
+			PointsUsed:  reward.Points,
+			ExchangedAt: s.now(),
+			Status:      status,
+		}
+		if err := tx.Rewards().CreateExchange(&exchange); err != nil {
+			return err
+		}
+
+		// 扣除积分并写入流水
+		userPoints, err = tx.Points().Apply(&models.PointTransaction{
+			UserID:     targetUserID,
+			SourceType: models.PointSourceExchange,
+			SourceID:   exchange.ID,
+			Delta:      -reward.Points,
+			ActorID:    actor.UserID,
+			Note:       reward.Name,
+		})
+		if err != nil {
+			return err
+		}
+
+		// 待确认的兑换可能被取消退款，确认完成时才检查兑换相关的成就
+		if status == models.ExchangeStatusCompleted {
+			if err := tx.Progress().EvaluateAchievements(targetUserID); err != nil {
+				return err
+			}
+		}
+
+		result = ExchangeResult{Exchange: exchange, Reward: *reward, Points: *userPoints}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListExchanges 分页获取操作人可以查看的兑换记录，status 为空时返回全部状态
+func (s *RewardService) ListExchanges(actor Actor, childID *uint, status string, page repository.Page) (*ExchangeList, error) {
+	userIDs, err := visibleChildIDs(s.store, actor, childID)
+	if err != nil {
+		return nil, err
+	}
+
+	exchanges, total, err := s.store.Rewards().ListExchanges(repository.ExchangeFilter{UserIDs: userIDs, Status: status}, page)
+	if err != nil {
+		return nil, err
+	}
+
+	var exchangeUserIDs []uint
+	for _, exchange := range exchanges {
+		exchangeUserIDs = append(exchangeUserIDs, exchange.UserID)
+	}
+	names, err := userNames(s.store, exchangeUserIDs)
+	if err != nil {
+		return nil, err
+	}
+	return &ExchangeList{Exchanges: exchanges, Total: total, Names: names}, nil
+}
+
+// ResolveExchange 家长处理家庭中待确认的兑换记录，status 为完成或取消
+// 兑换时积分已经扣除（冻结），完成时不再变动积分，只检查兑换相关的成就；取消时退回积分并恢复库存，同时返回最新积分
+func (s *RewardService) ResolveExchange(actor Actor, exchangeID uint, status, note string) (*models.ExchangeRecord, *models.UserPoints, error) {
+	var exchange *models.ExchangeRecord
+	var userPoints *models.UserPoints
+	err := s.store.Transaction(func(tx repository.Store) error {
+		var err error
+		exchange, err = tx.Rewards().LockExchange(exchangeID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrExchangeNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		// 兑换记录需属于当前家庭的儿童
+		if _, err := findFamilyChild(tx, actor.FamilyID, exchange.UserID); errors.Is(err, ErrChildNotFound) {
+			return ErrExchangeNotFound
+		} else if err != nil {
+			return err
+		}
+
+		if exchange.Status != models.ExchangeStatusPending {
+			return ErrExchangeNotPending
+		}
+
+		now := s.now()
+		reviewerID := actor.UserID
+		exchange.Status = status
+		exchange.ReviewerID = &reviewerID
+		exchange.ReviewNote = note
+		exchange.ReviewedAt = &now
+		if err := tx.Rewards().SaveExchange(exchange); err != nil {
+			return err
+		}
+
+		if status == models.ExchangeStatusCompleted {
+			return tx.Progress().EvaluateAchievements(exchange.UserID)
+		}
+		if err := tx.Rewards().RestoreStock(exchange.RewardID); err != nil {
+			return err
+		}
+		userPoints, err = tx.Points().Apply(&models.PointTransaction{
+			UserID:     exchange.UserID,
+			SourceType: models.PointSourceRefund,
+			SourceID:   exchange.ID,
+			Delta:      exchange.PointsUsed,
+			ActorID:    actor.UserID,
+			Note:       "取消兑换",
+		})
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return exchange, userPoints, nil
+}
@@ -0,0 +1,82 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/repository"
+	"child-behavior-app/internal/service"
+)
+
+func TestExchangeAndRefund(t *testing.T) {
+	store := repository.NewMemoryStore()
+	f := newFamily(store, 1)
+	other := newFamily(store, 2)
+	rewards := service.NewRewardService(store)
+	points := service.NewPointsService(store)
+
+	reward, err := rewards.Create(f.parent, service.RewardInput{Name: "看动画片", Points: 10, Stock: 2, RequiresApproval: true})
+	if err != nil {
+		t.Fatalf("create reward: %v", err)
+	}
+	stock := func() int {
+		t.Helper()
+		reward, err := store.Rewards().FindFamilyReward(1, reward.ID)
+		if err != nil {
+			t.Fatalf("find reward: %v", err)
+		}
+		return reward.Stock
+	}
+
+	// 积分不足时不扣积分也不扣库存
+	if _, _, _, err := points.Adjust(f.parent, f.child.UserID, 5, "初始积分"); err != nil {
+		t.Fatalf("adjust: %v", err)
+	}
+	if _, err := rewards.Exchange(f.child, reward.ID, 0); !errors.Is(err, service.ErrInsufficientPoints) {
+		t.Fatalf("exchange: %v, want %v", err, service.ErrInsufficientPoints)
+	}
+	if stock() != 2 {
+		t.Fatalf("stock = %d, want 2", stock())
+	}
+	expectPoints(t, store, f.child.UserID, 5)
+
+	if _, err := rewards.Exchange(f.parent, reward.ID, 0); !errors.Is(err, service.ErrChildRequired) {
+		t.Fatalf("parent exchange without child: %v, want %v", err, service.ErrChildRequired)
+	}
+
+	// 需要确认的奖励先冻结积分和库存
+	if _, _, _, err := points.Adjust(f.parent, f.child.UserID, 10, "奖励"); err != nil {
+		t.Fatalf("adjust: %v", err)
+	}
+	result, err := rewards.Exchange(f.child, reward.ID, 0)
+	if err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	if result.Exchange.Status != models.ExchangeStatusPending || result.Points.AvailablePoints != 5 || stock() != 1 {
+		t.Fatalf("exchange: status %s, points %d, stock %d", result.Exchange.Status, result.Points.AvailablePoints, stock())
+	}
+
+	// 其他家庭的家长不能处理
+	if _, _, err := rewards.ResolveExchange(other.parent, result.Exchange.ID, models.ExchangeStatusCancelled, ""); !errors.Is(err, service.ErrExchangeNotFound) {
+		t.Fatalf("resolve by other family: %v, want %v", err, service.ErrExchangeNotFound)
+	}
+
+	// 取消时退回积分并恢复库存
+	exchange, userPoints, err := rewards.ResolveExchange(f.parent, result.Exchange.ID, models.ExchangeStatusCancelled, "今天不行")
+	if err != nil {
+		t.Fatalf("reject: %v", err)
+	}
+	if exchange.Status != models.ExchangeStatusCancelled || userPoints.AvailablePoints != 15 || stock() != 2 {
+		t.Fatalf("reject: status %s, points %d, stock %d", exchange.Status, userPoints.AvailablePoints, stock())
+	}
+	if _, _, err := rewards.ResolveExchange(f.parent, result.Exchange.ID, models.ExchangeStatusCompleted, ""); !errors.Is(err, service.ErrExchangeNotPending) {
+		t.Fatalf("resolve again: %v, want %v", err, service.ErrExchangeNotPending)
+	}
+
+	transactions := store.Transactions(f.child.UserID)
+	last := transactions[len(transactions)-1]
+	if last.SourceType != models.PointSourceRefund || last.SourceID != exchange.ID || last.Delta != 10 {
+		t.Fatalf("refund transaction = %+v", last)
+	}
+}
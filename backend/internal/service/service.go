@@ -0,0 +1,95 @@
+// Package service 儿童、行为、奖励和积分的业务规则
+// 服务只通过 repository.Store 读写数据，处理器负责解析请求、调用服务并把业务错误转换为响应
+package service
+
+import (
+	"errors"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/repository"
+)
+
+// 业务错误
+var (
+	// ErrChildNotFound 儿童不存在或不属于当前家庭
+	ErrChildNotFound = errors.New("child not found or permission denied")
+	// ErrUserNotFound 用户不存在
+	ErrUserNotFound = errors.New("user not found")
+	// ErrPermissionDenied 无权访问
+	ErrPermissionDenied = errors.New("permission denied")
+	// ErrNotInFamily 儿童账户未加入家庭
+	ErrNotInFamily = errors.New("child account is not linked to a family")
+	// ErrPointsNotFound 用户没有积分记录
+	ErrPointsNotFound = errors.New("points record not found")
+)
+
+// Actor 发起操作的用户
+type Actor struct {
+	UserID   uint
+	Role     string // parent 或 child
+	FamilyID uint
+}
+
+// IsParent 是否为家长
+func (a Actor) IsParent() bool {
+	return a.Role == "parent"
+}
+
+// findFamilyChild 获取属于家庭的儿童，不存在时返回 ErrChildNotFound
+func findFamilyChild(store repository.Store, familyID, childID uint) (*models.User, error) {
+	child, err := store.Children().FindFamilyChild(familyID, childID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrChildNotFound
+	}
+	return child, err
+}
+
+// visibleChildIDs 获取操作人可以查看的儿童范围
+// 家长可以查看家庭中的所有儿童，指定 childID 时只查看该儿童；儿童只能查看自己
+func visibleChildIDs(store repository.Store, actor Actor, childID *uint) ([]uint, error) {
+	if !actor.IsParent() {
+		return []uint{actor.UserID}, nil
+	}
+	if childID != nil {
+		if _, err := findFamilyChild(store, actor.FamilyID, *childID); err != nil {
+			return nil, err
+		}
+		return []uint{*childID}, nil
+	}
+
+	children, err := store.Children().ListFamilyChildren(actor.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	childIDs := make([]uint, 0, len(children))
+	for _, child := range children {
+		childIDs = append(childIDs, child.ID)
+	}
+	return childIDs, nil
+}
+
+// userNames 批量获取用户昵称
+func userNames(store repository.Store, userIDs []uint) (map[uint]string, error) {
+	users, err := store.Children().FindUsers(uniqueIDs(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[uint]string, len(users))
+	for _, user := range users {
+		names[user.ID] = user.Nickname
+	}
+	return names, nil
+}
+
+// uniqueIDs 去掉重复的ID，保持原有顺序
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	var unique []uint
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
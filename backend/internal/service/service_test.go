@@ -0,0 +1,39 @@
+package service_test
+
+import (
+	"testing"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/repository"
+	"child-behavior-app/internal/service"
+)
+
+// family 内存数据中的一个家庭：一位家长和一个儿童
+type family struct {
+	parent service.Actor
+	child  service.Actor
+}
+
+// newFamily 在内存数据中创建家庭ID为 familyID 的家长和儿童
+func newFamily(store *repository.MemoryStore, familyID uint) family {
+	parent := models.User{Nickname: "家长", Role: "parent", FamilyID: &familyID}
+	store.AddUser(&parent)
+	child := models.User{Nickname: "儿童", Role: "child", FamilyID: &familyID, ParentID: &parent.ID}
+	store.AddUser(&child)
+	return family{
+		parent: service.Actor{UserID: parent.ID, Role: "parent", FamilyID: familyID},
+		child:  service.Actor{UserID: child.ID, Role: "child", FamilyID: familyID},
+	}
+}
+
+// expectPoints 检查儿童的可用积分
+func expectPoints(t *testing.T, store repository.Store, childID uint, want int) {
+	t.Helper()
+	userPoints, err := store.Points().Get(childID)
+	if err != nil {
+		t.Fatalf("get points: %v", err)
+	}
+	if userPoints.AvailablePoints != want {
+		t.Fatalf("available points = %d, want %d", userPoints.AvailablePoints, want)
+	}
+}
@@ -19,6 +19,8 @@ type Config struct {
 	Log        LogConfig        `mapstructure:"log"`
 	Cache      CacheConfig      `mapstructure:"cache"`
 	Security   SecurityConfig   `mapstructure:"security"`
+	Notify     NotifyConfig     `mapstructure:"notify"`
+	WebAuthn   WebAuthnConfig   `mapstructure:"webauthn"`
 	Development DevelopmentConfig `mapstructure:"development"`
 	Production  ProductionConfig  `mapstructure:"production"`
 }
@@ -31,8 +33,19 @@ type AppConfig struct {
 	Mode    string `mapstructure:"mode"`
 }
 
+// 支持的数据库类型
+const (
+	DatabaseDriverMySQL  = "mysql"  // MySQL/MariaDB
+	DatabaseDriverSQLite = "sqlite" // SQLite，用于本地开发和测试
+)
+
+// SQLiteMemoryPath SQLite 内存数据库路径，数据只在进程运行期间存在
+const SQLiteMemoryPath = ":memory:"
+
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
+	Driver          string `mapstructure:"driver"` // mysql（默认）或 sqlite
+	Path            string `mapstructure:"path"`   // sqlite 数据库文件路径，:memory: 为内存数据库
 	Host            string `mapstructure:"host"`
 	Port            int    `mapstructure:"port"`
 	Username        string `mapstructure:"username"`
@@ -48,9 +61,10 @@ type DatabaseConfig struct {
 
 // JWTConfig JWT配置
 type JWTConfig struct {
-	Secret       string `mapstructure:"secret"`
-	ExpiresHours int    `mapstructure:"expires_hours"`
-	Issuer       string `mapstructure:"issuer"`
+	Secret              string `mapstructure:"secret"`
+	ExpiresHours        int    `mapstructure:"expires_hours"`         // 访问令牌有效期
+	RefreshExpiresHours int    `mapstructure:"refresh_expires_hours"` // 刷新令牌（会话）有效期
+	Issuer              string `mapstructure:"issuer"`
 }
 
 // UploadConfig 文件上传配置
@@ -91,12 +105,28 @@ type CacheConfig struct {
 
 // SecurityConfig 安全配置
 type SecurityConfig struct {
-	BcryptCost int `mapstructure:"bcrypt_cost"`
-	RateLimit  struct {
-		Enabled           bool `mapstructure:"enabled"`
-		RequestsPerMinute int  `mapstructure:"requests_per_minute"`
-		Burst             int  `mapstructure:"burst"`
-	} `mapstructure:"rate_limit"`
+	BcryptCost int             `mapstructure:"bcrypt_cost"`
+	RateLimit  RateLimitConfig `mapstructure:"rate_limit"`
+}
+
+// RateLimitConfig 请求限流配置，按IP和用户分别计算
+type RateLimitConfig struct {
+	Enabled           bool `mapstructure:"enabled"`
+	RequestsPerMinute int  `mapstructure:"requests_per_minute"`
+	Burst             int  `mapstructure:"burst"`
+}
+
+// NotifyConfig 消息发送配置，用于发送找回密码验证码
+type NotifyConfig struct {
+	Sender   string `mapstructure:"sender"`    // log：写入日志；file：追加到文件
+	FilePath string `mapstructure:"file_path"` // sender 为 file 时的文件路径
+}
+
+// WebAuthnConfig WebAuthn（通行密钥）配置
+type WebAuthnConfig struct {
+	RPID          string   `mapstructure:"rp_id"`           // 依赖方ID，一般为前端域名（不含协议和端口）
+	RPDisplayName string   `mapstructure:"rp_display_name"` // 验证时向用户展示的名称
+	RPOrigins     []string `mapstructure:"rp_origins"`      // 允许发起验证的前端来源
 }
 
 // DevelopmentConfig 开发环境配置
@@ -162,6 +192,8 @@ func setDefaults() {
 	viper.SetDefault("app.mode", "debug")
 	
 	// 数据库默认配置
+	viper.SetDefault("database.driver", DatabaseDriverMySQL)
+	viper.SetDefault("database.path", "data/child_behavior.db")
 	viper.SetDefault("database.host", "localhost")
 	viper.SetDefault("database.port", 3306)
 	viper.SetDefault("database.username", "root")
@@ -176,7 +208,8 @@ func setDefaults() {
 	
 	// JWT默认配置
 	viper.SetDefault("jwt.secret", "your-secret-key-change-this-in-production")
-	viper.SetDefault("jwt.expires_hours", 24)
+	viper.SetDefault("jwt.expires_hours", 1)
+	viper.SetDefault("jwt.refresh_expires_hours", 720)
 	viper.SetDefault("jwt.issuer", "child-behavior-app")
 	
 	// 上传默认配置
@@ -191,6 +224,43 @@ func setDefaults() {
 	
 	// 安全默认配置
 	viper.SetDefault("security.bcrypt_cost", 12)
+	viper.SetDefault("security.rate_limit.enabled", true)
+	viper.SetDefault("security.rate_limit.requests_per_minute", 60)
+	viper.SetDefault("security.rate_limit.burst", 10)
+
+	// 消息发送默认配置
+	viper.SetDefault("notify.sender", "log")
+	viper.SetDefault("notify.file_path", "logs/messages.log")
+
+	// WebAuthn默认配置
+	viper.SetDefault("webauthn.rp_id", "localhost")
+	viper.SetDefault("webauthn.rp_display_name", "Child Behavior")
+	viper.SetDefault("webauthn.rp_origins", []string{"http://localhost:5173", "http://localhost:3000"})
+
+	// 环境默认配置，开发环境启动时自动执行迁移，生产环境需要手动执行
+	viper.SetDefault("development.auto_migrate", true)
+	viper.SetDefault("production.auto_migrate", false)
+}
+
+// IsProduction 是否为生产环境，app.mode 为 release 时使用 production 配置，否则使用 development 配置
+func (c *Config) IsProduction() bool {
+	return c.App.Mode == "release"
+}
+
+// AutoMigrate 当前环境启动时是否自动执行未执行的数据库迁移
+func (c *Config) AutoMigrate() bool {
+	if c.IsProduction() {
+		return c.Production.AutoMigrate
+	}
+	return c.Development.AutoMigrate
+}
+
+// SeedData 当前环境启动时是否在演示家庭不存在时写入演示数据
+func (c *Config) SeedData() bool {
+	if c.IsProduction() {
+		return c.Production.SeedData
+	}
+	return c.Development.SeedData
 }
 
 // overrideFromEnv 从环境变量覆盖敏感配置
@@ -236,6 +306,11 @@ func (c *DatabaseConfig) GetDSN() string {
 	)
 }
 
+// GetSQLiteDSN 获取SQLite数据库连接字符串，开启外键约束，写入冲突时等待而不是立即失败
+func (c *DatabaseConfig) GetSQLiteDSN() string {
+	return c.Path + "?_foreign_keys=1&_busy_timeout=5000"
+}
+
 // EnsureUploadDirs 确保上传目录存在
 func (c *UploadConfig) EnsureUploadDirs() error {
 	dirs := []string{c.UploadDir, c.AvatarDir}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret RFC 6238 附录B中 SHA1 测试向量使用的密钥
+const rfc6238Secret = "12345678901234567890"
+
+// RFC 6238 附录B的 SHA1 测试向量，验证码取8位结果的后6位
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		if got := totpCode([]byte(rfc6238Secret), TOTPStep(time.Unix(v.unix, 0))); got != v.code {
+			t.Errorf("totpCode at %d = %s, want %s", v.unix, got, v.code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte(rfc6238Secret))
+
+	for _, v := range rfc6238Vectors {
+		now := time.Unix(v.unix, 0)
+		step, ok := ValidateTOTP(secret, v.code, now)
+		if !ok || step != TOTPStep(now) {
+			t.Errorf("ValidateTOTP(%s) at %d = %d, %v, want step %d", v.code, v.unix, step, ok, TOTPStep(now))
+		}
+	}
+
+	// 前后一个时间步内的验证码仍然有效，返回验证码所在的时间步用于拒绝重放
+	now := time.Unix(1111111109, 0)
+	for _, offset := range []int64{-1, 1} {
+		want := TOTPStep(now) + offset
+		code := totpCode([]byte(rfc6238Secret), want)
+		if step, ok := ValidateTOTP(secret, code, now); !ok || step != want {
+			t.Errorf("code of step offset %d = %d, %v, want step %d", offset, step, ok, want)
+		}
+	}
+	for _, offset := range []int64{-2, 2} {
+		code := totpCode([]byte(rfc6238Secret), TOTPStep(now)+offset)
+		if _, ok := ValidateTOTP(secret, code, now); ok {
+			t.Errorf("code of step offset %d accepted", offset)
+		}
+	}
+
+	// 小写密钥同样可以使用，格式错误的验证码和密钥被拒绝
+	if _, ok := ValidateTOTP("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", "081804", now); !ok {
+		t.Error("lowercase secret rejected")
+	}
+	for _, code := range []string{"", "08180", "0818040"} {
+		if _, ok := ValidateTOTP(secret, code, now); ok {
+			t.Errorf("code %q accepted", code)
+		}
+	}
+	if _, ok := ValidateTOTP("not base32!", "081804", now); ok {
+		t.Error("invalid secret accepted")
+	}
+}
@@ -10,8 +10,13 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-// JWT密钥 - 从配置文件加载
-var jwtSecret []byte
+// JWT密钥和有效期 - 从配置文件加载
+var (
+	jwtSecret       []byte
+	jwtIssuer       string
+	accessTokenTTL  = time.Hour
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
 
 // 内存缓存
 var (
@@ -22,11 +27,28 @@ var (
 
 // Claims JWT声明
 type Claims struct {
-	UserID uint   `json:"user_id"`
-	Role   string `json:"role"`
+	UserID       uint   `json:"user_id"`
+	Role         string `json:"role"`
+	DeviceID     string `json:"device_id,omitempty"` // 儿童令牌绑定的设备
+	SessionID    uint   `json:"session_id"`
+	TokenVersion int    `json:"token_version"`
+	Scope        string `json:"scope,omitempty"` // 为空表示访问令牌
 	jwt.RegisteredClaims
 }
 
+// TokenScopePreAuth 已通过密码验证、等待两步验证码的令牌，只能用于完成两步验证
+const TokenScopePreAuth = "pre_auth"
+
+// PreAuthTokenTTL 两步验证令牌有效期
+const PreAuthTokenTTL = 5 * time.Minute
+
+// TokenScopeElevated 家长重新验证身份（密码或通行密钥）后签发的令牌，
+// 与访问令牌一起使用，用于删除儿童、管理奖励等敏感操作
+const TokenScopeElevated = "elevated"
+
+// ElevatedTokenTTL 家长模式令牌有效期
+const ElevatedTokenTTL = 5 * time.Minute
+
 // InitJWT 初始化JWT密钥
 func InitJWT() error {
 	config, err := LoadConfig()
@@ -34,9 +56,26 @@ func InitJWT() error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 	jwtSecret = []byte(config.JWT.Secret)
+	jwtIssuer = config.JWT.Issuer
+	if config.JWT.ExpiresHours > 0 {
+		accessTokenTTL = time.Duration(config.JWT.ExpiresHours) * time.Hour
+	}
+	if config.JWT.RefreshExpiresHours > 0 {
+		refreshTokenTTL = time.Duration(config.JWT.RefreshExpiresHours) * time.Hour
+	}
 	return nil
 }
 
+// AccessTokenTTL 访问令牌有效期
+func AccessTokenTTL() time.Duration {
+	return accessTokenTTL
+}
+
+// RefreshTokenTTL 刷新令牌有效期
+func RefreshTokenTTL() time.Duration {
+	return refreshTokenTTL
+}
+
 // HashPassword 密码哈希
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
@@ -49,14 +88,48 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
-// GenerateToken 生成JWT令牌
-func GenerateToken(userID uint, role string) (string, error) {
+// GenerateToken 生成访问令牌，claims 中的用户、会话和令牌版本由调用方填写
+func GenerateToken(claims Claims) (string, error) {
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Issuer:    jwtIssuer,
+		ExpiresAt: jwt.NewNumericDate(now.Add(accessTokenTTL)),
+		IssuedAt:  jwt.NewNumericDate(now),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtSecret)
+}
+
+// GeneratePreAuthToken 生成两步验证令牌，不关联会话，不能访问其他接口
+func GeneratePreAuthToken(userID uint, role string) (string, error) {
+	now := time.Now()
 	claims := Claims{
 		UserID: userID,
 		Role:   role,
+		Scope:  TokenScopePreAuth,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    jwtIssuer,
+			ExpiresAt: jwt.NewNumericDate(now.Add(PreAuthTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtSecret)
+}
+
+// GenerateElevatedToken 生成家长模式令牌，绑定当前会话，会话撤销后随之失效
+func GenerateElevatedToken(userID uint, sessionID uint) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		UserID:    userID,
+		SessionID: sessionID,
+		Scope:     TokenScopeElevated,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    jwtIssuer,
+			ExpiresAt: jwt.NewNumericDate(now.Add(ElevatedTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
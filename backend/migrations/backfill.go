@@ -0,0 +1,214 @@
+package migrations
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// goMigrations 用代码实现的迁移，版本号与脚本迁移共用
+var goMigrations = []Migration{
+	{Version: 20, Name: "backfill_legacy_data", Up: backfillLegacyData, Down: noop},
+}
+
+// noop 只补写数据的迁移回滚时不需要处理
+func noop(*gorm.DB) error {
+	return nil
+}
+
+// backfillLegacyData 为引入家庭、行为分类和积分流水之前的数据补写对应记录，新数据库执行时没有影响
+// 数据迁移按执行时的表结构读写，不使用 internal/models 中的模型，模型以后的修改不会影响已有的迁移
+func backfillLegacyData(db *gorm.DB) error {
+	if err := backfillFamilies(db); err != nil {
+		return fmt.Errorf("failed to backfill families: %w", err)
+	}
+	if err := backfillBehaviorCategories(db); err != nil {
+		return fmt.Errorf("failed to backfill behavior categories: %w", err)
+	}
+	if err := backfillPointLedger(db); err != nil {
+		return fmt.Errorf("failed to backfill point ledger: %w", err)
+	}
+	return nil
+}
+
+// legacyFamily 补建的家庭
+type legacyFamily struct {
+	ID        uint
+	Name      string
+	OwnerID   uint
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (legacyFamily) TableName() string {
+	return "families"
+}
+
+// legacyTemplate 补建家庭时写入的默认行为目录，与该版本注册时写入的目录一致
+var legacyTemplates = []struct {
+	name     string
+	category string
+	points   int
+	icon     string
+}{
+	{"主动完成作业", "learning", 10, "book-open"},
+	{"认真听课", "learning", 5, "book-open"},
+	{"阅读课外书", "learning", 5, "book-open"},
+	{"整理房间", "life", 5, "home"},
+	{"帮忙做家务", "life", 10, "home"},
+	{"按时睡觉", "life", 5, "home"},
+	{"帮助他人", "social", 10, "users"},
+	{"分享玩具", "social", 5, "users"},
+	{"控制情绪", "emotion", 5, "heart"},
+	{"道歉认错", "emotion", 5, "heart"},
+	{"坚持锻炼", "exercise", 5, "gamepad-2"},
+	{"不挑食", "eating", 5, "utensils"},
+	{"发脾气", "emotion", -5, "heart"},
+	{"拖延作业", "learning", -5, "book-open"},
+}
+
+// backfillFamilies 为没有家庭的家长创建家庭并设为所有者，把其儿童归入该家庭，
+// 还没有行为目录的家长同时写入默认目录
+func backfillFamilies(db *gorm.DB) error {
+	var parents []struct {
+		ID       uint
+		Nickname string
+	}
+	if err := db.Table("users").Select("id, nickname").
+		Where("role = ? AND family_id IS NULL", "parent").Order("id").Find(&parents).Error; err != nil {
+		return err
+	}
+
+	for _, parent := range parents {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			now := time.Now()
+			family := legacyFamily{Name: parent.Nickname + "的家庭", OwnerID: parent.ID, CreatedAt: now, UpdatedAt: now}
+			if err := tx.Create(&family).Error; err != nil {
+				return err
+			}
+			if err := tx.Table("family_members").Create(map[string]interface{}{
+				"family_id":  family.ID,
+				"user_id":    parent.ID,
+				"role":       "owner",
+				"created_at": now,
+				"updated_at": now,
+			}).Error; err != nil {
+				return err
+			}
+			if err := tx.Table("users").
+				Where("id = ? OR (parent_id = ? AND family_id IS NULL)", parent.ID, parent.ID).
+				Update("family_id", family.ID).Error; err != nil {
+				return err
+			}
+
+			// 家庭版本之前注册的家长可能已经有行为目录
+			var templates int64
+			if err := tx.Table("behavior_templates").Where("created_by = ?", parent.ID).Count(&templates).Error; err != nil {
+				return err
+			}
+			if templates > 0 {
+				return nil
+			}
+			rows := make([]map[string]interface{}, len(legacyTemplates))
+			for i, template := range legacyTemplates {
+				rows[i] = map[string]interface{}{
+					"name":           template.name,
+					"category":       template.category,
+					"default_points": template.points,
+					"icon":           template.icon,
+					"is_active":      true,
+					"created_by":     parent.ID,
+					"created_at":     now,
+					"updated_at":     now,
+				}
+			}
+			return tx.Table("behavior_templates").Create(rows).Error
+		})
+		if err != nil {
+			return fmt.Errorf("failed to backfill family for user %d: %w", parent.ID, err)
+		}
+	}
+	return nil
+}
+
+// legacyCategoryKeywords 历史数据没有分类，迁移时按描述关键词推断，都不匹配时归为 other
+var legacyCategoryKeywords = []struct {
+	category string
+	keywords []string
+}{
+	{"learning", []string{"学习", "作业", "读书"}},
+	{"life", []string{"整理", "卫生", "生活"}},
+	{"social", []string{"朋友", "分享", "合作"}},
+	{"emotion", []string{"情绪", "开心", "生气"}},
+	{"exercise", []string{"运动", "跑步", "锻炼"}},
+	{"eating", []string{"吃饭", "饮食", "挑食"}},
+}
+
+// backfillBehaviorCategories 为没有分类的行为记录补写分类
+func backfillBehaviorCategories(db *gorm.DB) error {
+	for _, rule := range legacyCategoryKeywords {
+		conditions := db.Where("description LIKE ?", "%"+rule.keywords[0]+"%")
+		for _, keyword := range rule.keywords[1:] {
+			conditions = conditions.Or("description LIKE ?", "%"+keyword+"%")
+		}
+		if err := db.Table("behavior_records").Where("category = ?", "").Where(conditions).
+			Update("category", rule.category).Error; err != nil {
+			return fmt.Errorf("failed to backfill category %s: %w", rule.category, err)
+		}
+	}
+
+	if err := db.Table("behavior_records").Where("category = ?", "").Update("category", "other").Error; err != nil {
+		return fmt.Errorf("failed to backfill category other: %w", err)
+	}
+	return nil
+}
+
+// backfillPointLedger 为已有积分但没有流水的用户补写期初流水
+// 先写入计入总积分的期初余额，再用兑换或退款校正到当前可用积分，按流水重放的结果与原积分一致
+func backfillPointLedger(db *gorm.DB) error {
+	var pointsList []struct {
+		UserID          uint
+		TotalPoints     int
+		AvailablePoints int
+	}
+	err := db.Table("user_points").Select("user_id, total_points, available_points").
+		Where("(total_points <> 0 OR available_points <> 0) AND user_id NOT IN (?)",
+			db.Table("point_transactions").Select("user_id")).
+		Order("user_id").Find(&pointsList).Error
+	if err != nil {
+		return err
+	}
+
+	for _, points := range pointsList {
+		now := time.Now()
+		transaction := func(sourceType string, delta, balance int, note string) map[string]interface{} {
+			return map[string]interface{}{
+				"user_id":       points.UserID,
+				"source_type":   sourceType,
+				"delta":         delta,
+				"balance_after": balance,
+				"total_after":   points.TotalPoints,
+				"actor_id":      points.UserID,
+				"note":          note,
+				"created_at":    now,
+			}
+		}
+
+		// 可用积分不能为负，期初余额为负时可用积分按0计
+		balance := points.TotalPoints
+		if balance < 0 {
+			balance = 0
+		}
+		rows := []map[string]interface{}{transaction("opening", points.TotalPoints, balance, "期初余额")}
+		if diff := points.AvailablePoints - balance; diff < 0 {
+			rows = append(rows, transaction("exchange", diff, points.AvailablePoints, "期初已兑换"))
+		} else if diff > 0 {
+			rows = append(rows, transaction("refund", diff, points.AvailablePoints, "期初余额校正"))
+		}
+		if err := db.Table("point_transactions").Create(rows).Error; err != nil {
+			return fmt.Errorf("failed to backfill point ledger for user %d: %w", points.UserID, err)
+		}
+	}
+	return nil
+}
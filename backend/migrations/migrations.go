@@ -0,0 +1,366 @@
+// Package migrations 数据库版本迁移
+// 每个版本在 mysql/ 和 sqlite/ 目录中各有一对 NNNN_name.up.sql 和 NNNN_name.down.sql 脚本，
+// 无法用 SQL 表达的数据迁移在 goMigrations 中用代码实现，已执行的版本记录在 schema_migrations 表中
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed mysql/*.sql sqlite/*.sql
+var scripts embed.FS
+
+// 迁移错误
+var (
+	// ErrSchemaOutdated 存在未执行的迁移
+	ErrSchemaOutdated = errors.New("database schema is out of date")
+	// ErrSchemaTooNew 数据库中记录了当前程序不认识的版本，通常是用旧版本程序连接了新数据库
+	ErrSchemaTooNew = errors.New("database schema is newer than this build")
+	// ErrUnknownVersion 指定的版本不存在
+	ErrUnknownVersion = errors.New("unknown migration version")
+)
+
+// scriptPattern 迁移脚本文件名，如 0001_initial_schema.up.sql
+var scriptPattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration 一个迁移版本
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(db *gorm.DB) error
+	Down    func(db *gorm.DB) error
+}
+
+// SchemaMigration 已执行的迁移版本
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:100;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status 迁移版本的执行状态
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// All 获取数据库类型（mysql 或 sqlite）的全部迁移，按版本排序
+func All(dialect string) ([]Migration, error) {
+	byVersion := make(map[int]*Migration)
+	scriptsByVersion := make(map[int]map[string]string)
+
+	entries, err := fs.ReadDir(scripts, dialect)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported database dialect %q: %w", dialect, err)
+	}
+	for _, entry := range entries {
+		match := scriptPattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration script name %q", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(scripts, path.Join(dialect, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration script %s: %w", entry.Name(), err)
+		}
+
+		if m, ok := byVersion[version]; ok && m.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d has conflicting names %q and %q", version, m.Name, match[2])
+		}
+		byVersion[version] = &Migration{Version: version, Name: match[2]}
+		if scriptsByVersion[version] == nil {
+			scriptsByVersion[version] = make(map[string]string)
+		}
+		scriptsByVersion[version][match[3]] = string(content)
+	}
+
+	for version, pair := range scriptsByVersion {
+		up, hasUp := pair["up"]
+		down, hasDown := pair["down"]
+		if !hasUp || !hasDown {
+			return nil, fmt.Errorf("migration version %d must have both up and down scripts", version)
+		}
+		byVersion[version].Up = execScript(up)
+		byVersion[version].Down = execScript(down)
+	}
+
+	for _, m := range goMigrations {
+		if _, ok := byVersion[m.Version]; ok {
+			return nil, fmt.Errorf("duplicate migration version %d", m.Version)
+		}
+		m := m
+		byVersion[m.Version] = &m
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// execScript 逐条执行脚本中的语句，语句以行尾的分号结束，-- 开头的行为注释
+func execScript(script string) func(db *gorm.DB) error {
+	return func(db *gorm.DB) error {
+		for _, statement := range splitStatements(script) {
+			if err := db.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// splitStatements 拆分脚本中的语句
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
+
+// appliedVersions 获取已执行的版本，schema_migrations 表不存在时创建
+func appliedVersions(db *gorm.DB) (map[int]SchemaMigration, error) {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	var rows []SchemaMigration
+	if err := db.Order("version ASC").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load schema migrations: %w", err)
+	}
+	applied := make(map[int]SchemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// GetStatus 获取每个迁移版本的执行状态，数据库中存在程序不认识的版本时返回 ErrSchemaTooNew
+func GetStatus(db *gorm.DB) ([]Status, error) {
+	migrations, err := All(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(migrations))
+	known := make(map[int]bool, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = true
+		status := Status{Version: m.Version, Name: m.Name}
+		if row, ok := applied[m.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	for version := range applied {
+		if !known[version] {
+			return statuses, fmt.Errorf("%w: version %d is applied but not known", ErrSchemaTooNew, version)
+		}
+	}
+	return statuses, nil
+}
+
+// CurrentVersion 已执行的最高版本，未执行过任何迁移时为 0
+func CurrentVersion(db *gorm.DB) (int, error) {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return 0, err
+	}
+	current := 0
+	for version := range applied {
+		if version > current {
+			current = version
+		}
+	}
+	return current, nil
+}
+
+// LatestVersion 程序中该数据库类型最新的迁移版本
+func LatestVersion(dialect string) (int, error) {
+	migrations, err := All(dialect)
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// Pending 获取未执行的迁移
+func Pending(db *gorm.DB) ([]Migration, error) {
+	migrations, err := All(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := GetStatus(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for i, status := range statuses {
+		if !status.Applied {
+			pending = append(pending, migrations[i])
+		}
+	}
+	return pending, nil
+}
+
+// apply 执行一个版本的升级脚本并记录版本
+// MySQL 的表结构语句会隐式提交，脚本中途失败时已执行的语句不会回滚，需要手动撤销后再重新执行
+func apply(db *gorm.DB, m Migration) error {
+	if err := m.Up(db); err != nil {
+		return fmt.Errorf("failed to apply migration %04d_%s: %w", m.Version, m.Name, err)
+	}
+	record := SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}
+	if err := db.Create(&record).Error; err != nil {
+		return fmt.Errorf("failed to record migration %04d_%s: %w", m.Version, m.Name, err)
+	}
+	return nil
+}
+
+// revert 执行一个版本的回滚脚本并删除版本记录
+func revert(db *gorm.DB, m Migration) error {
+	if err := m.Down(db); err != nil {
+		return fmt.Errorf("failed to revert migration %04d_%s: %w", m.Version, m.Name, err)
+	}
+	if err := db.Delete(&SchemaMigration{}, m.Version).Error; err != nil {
+		return fmt.Errorf("failed to remove migration record %04d_%s: %w", m.Version, m.Name, err)
+	}
+	return nil
+}
+
+// Up 按版本顺序执行所有未执行的迁移，返回本次执行的迁移
+func Up(db *gorm.DB) ([]Migration, error) {
+	latest, err := LatestVersion(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	return To(db, latest)
+}
+
+// Down 按版本倒序回滚最近执行的 steps 个迁移，返回本次回滚的迁移
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+	migrations, err := All(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := GetStatus(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for i := len(statuses) - 1; i >= 0 && len(reverted) < steps; i-- {
+		if !statuses[i].Applied {
+			continue
+		}
+		if err := revert(db, migrations[i]); err != nil {
+			return reverted, err
+		}
+		reverted = append(reverted, migrations[i])
+	}
+	return reverted, nil
+}
+
+// To 迁移到指定版本：执行不超过该版本的未执行迁移，回滚高于该版本的已执行迁移
+// version 为 0 时回滚全部迁移，返回本次执行或回滚的迁移
+func To(db *gorm.DB, version int) ([]Migration, error) {
+	migrations, err := All(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 {
+		found := false
+		for _, m := range migrations {
+			if m.Version == version {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+		}
+	}
+
+	statuses, err := GetStatus(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []Migration
+	for i := len(statuses) - 1; i >= 0; i-- {
+		if statuses[i].Applied && statuses[i].Version > version {
+			if err := revert(db, migrations[i]); err != nil {
+				return changed, err
+			}
+			changed = append(changed, migrations[i])
+		}
+	}
+	for i, status := range statuses {
+		if !status.Applied && status.Version <= version {
+			if err := apply(db, migrations[i]); err != nil {
+				return changed, err
+			}
+			changed = append(changed, migrations[i])
+		}
+	}
+	return changed, nil
+}
+
+// EnsureSchema 启动时检查表结构是否为最新
+// 存在未执行的迁移时，autoApply 为 true 则自动执行，否则返回 ErrSchemaOutdated
+func EnsureSchema(db *gorm.DB, autoApply bool) ([]Migration, error) {
+	pending, err := Pending(db)
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+	if !autoApply {
+		return nil, fmt.Errorf("%w: %d pending migration(s) starting at %04d_%s, run `migrate up` first",
+			ErrSchemaOutdated, len(pending), pending[0].Version, pending[0].Name)
+	}
+	return Up(db)
+}
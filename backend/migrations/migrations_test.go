@@ -0,0 +1,255 @@
+package migrations_test
+
+import (
+	"testing"
+	"time"
+
+	"child-behavior-app/internal/models"
+	"child-behavior-app/internal/utils"
+	"child-behavior-app/migrations"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 引入版本迁移之前的表结构，与当时启动时自动建表使用的模型一致
+// SQLite 不支持 enum，role 和 status 改为字符串
+type baselineUser struct {
+	ID        uint    `gorm:"primaryKey;autoIncrement"`
+	Phone     *string `gorm:"uniqueIndex;size:20"`
+	Password  *string `gorm:"size:255"`
+	Nickname  string  `gorm:"size:50;not null"`
+	Email     string  `gorm:"size:100"`
+	Avatar    string  `gorm:"size:255"`
+	Age       int     `gorm:"default:0"`
+	Gender    string  `gorm:"size:10"`
+	Role      string  `gorm:"size:20;not null"`
+	ParentID  *uint   `gorm:"index"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (baselineUser) TableName() string { return "users" }
+
+type baselineBehaviorRecord struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement"`
+	ChildID      uint      `gorm:"column:user_id;not null;index"`
+	RecorderID   uint      `gorm:"not null;index"`
+	BehaviorType string    `gorm:"column:behavior_type;size:20;not null"`
+	BehaviorDesc string    `gorm:"column:description;type:text;not null"`
+	ScoreChange  int       `gorm:"column:points;not null"`
+	ImageURL     string    `gorm:"column:image_url;size:255"`
+	RecordedAt   time.Time `gorm:"not null"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (baselineBehaviorRecord) TableName() string { return "behavior_records" }
+
+type baselineUserPoints struct {
+	ID              uint `gorm:"primaryKey;autoIncrement"`
+	UserID          uint `gorm:"uniqueIndex;not null"`
+	TotalPoints     int  `gorm:"default:0;not null"`
+	AvailablePoints int  `gorm:"default:0;not null"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+func (baselineUserPoints) TableName() string { return "user_points" }
+
+type baselineReward struct {
+	ID          uint   `gorm:"primaryKey;autoIncrement"`
+	Name        string `gorm:"size:100;not null"`
+	Description string `gorm:"type:text"`
+	Points      int    `gorm:"not null"`
+	Image       string `gorm:"size:255"`
+	Stock       int    `gorm:"default:1;not null"`
+	IsActive    bool   `gorm:"default:true;not null"`
+	CreatedBy   uint   `gorm:"not null;index"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (baselineReward) TableName() string { return "rewards" }
+
+type baselineExchangeRecord struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement"`
+	UserID      uint      `gorm:"not null;index"`
+	RewardID    uint      `gorm:"not null;index"`
+	PointsUsed  int       `gorm:"column:points_used;not null"`
+	ExchangedAt time.Time `gorm:"not null"`
+	Status      string    `gorm:"size:20;default:'completed';not null"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (baselineExchangeRecord) TableName() string { return "exchange_records" }
+
+// openDatabase 打开 SQLite 内存数据库
+func openDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := models.InitDBWithConfig(utils.DatabaseConfig{Driver: utils.DatabaseDriverSQLite, Path: utils.SQLiteMemoryPath})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	db = db.Session(&gorm.Session{Logger: logger.Default.LogMode(logger.Silent)})
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("get sql.DB: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// seedBaseline 按初始表结构建表并写入一个家长、一个儿童及其行为、积分和兑换记录
+func seedBaseline(t *testing.T, db *gorm.DB) (parent, child baselineUser) {
+	t.Helper()
+	err := db.AutoMigrate(&baselineUser{}, &baselineBehaviorRecord{}, &baselineUserPoints{}, &baselineReward{}, &baselineExchangeRecord{})
+	if err != nil {
+		t.Fatalf("create baseline tables: %v", err)
+	}
+
+	phone := "13800000000"
+	parent = baselineUser{Phone: &phone, Nickname: "爸爸", Role: "parent"}
+	if err := db.Create(&parent).Error; err != nil {
+		t.Fatalf("create parent: %v", err)
+	}
+	child = baselineUser{Nickname: "小明", Role: "child", ParentID: &parent.ID}
+	if err := db.Create(&child).Error; err != nil {
+		t.Fatalf("create child: %v", err)
+	}
+
+	now := time.Now()
+	records := []baselineBehaviorRecord{
+		{ChildID: child.ID, RecorderID: parent.ID, BehaviorType: "good", BehaviorDesc: "按时完成作业", ScoreChange: 10, RecordedAt: now},
+		{ChildID: child.ID, RecorderID: parent.ID, BehaviorType: "good", BehaviorDesc: "晚饭后去跑步", ScoreChange: 5, RecordedAt: now},
+		{ChildID: child.ID, RecorderID: parent.ID, BehaviorType: "good", BehaviorDesc: "给爷爷打电话", ScoreChange: 5, RecordedAt: now},
+	}
+	if err := db.Create(&records).Error; err != nil {
+		t.Fatalf("create behavior records: %v", err)
+	}
+	reward := baselineReward{Name: "冰淇淋", Points: 15, CreatedBy: parent.ID, IsActive: true}
+	if err := db.Create(&reward).Error; err != nil {
+		t.Fatalf("create reward: %v", err)
+	}
+	exchange := baselineExchangeRecord{UserID: child.ID, RewardID: reward.ID, PointsUsed: 15, ExchangedAt: now, Status: "completed"}
+	if err := db.Create(&exchange).Error; err != nil {
+		t.Fatalf("create exchange record: %v", err)
+	}
+	points := baselineUserPoints{UserID: child.ID, TotalPoints: 20, AvailablePoints: 5}
+	if err := db.Create(&points).Error; err != nil {
+		t.Fatalf("create user points: %v", err)
+	}
+	return parent, child
+}
+
+func TestUpgradeBaselineDatabase(t *testing.T) {
+	db := openDatabase(t)
+	parent, child := seedBaseline(t, db)
+
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+
+	// 家长成为新家庭的所有者，儿童归入该家庭，并写入默认行为目录
+	var users []models.User
+	if err := db.Order("id").Find(&users).Error; err != nil {
+		t.Fatalf("load users: %v", err)
+	}
+	if len(users) != 2 || users[0].FamilyID == nil || users[1].FamilyID == nil || *users[0].FamilyID != *users[1].FamilyID {
+		t.Fatalf("users after upgrade = %+v", users)
+	}
+	var family models.Family
+	if err := db.First(&family, *users[0].FamilyID).Error; err != nil {
+		t.Fatalf("load family: %v", err)
+	}
+	if family.OwnerID != parent.ID || family.Name != "爸爸的家庭" {
+		t.Fatalf("family = %+v", family)
+	}
+	var member models.FamilyMember
+	if err := db.Where("user_id = ?", parent.ID).First(&member).Error; err != nil {
+		t.Fatalf("load family member: %v", err)
+	}
+	if member.FamilyID != family.ID || member.Role != models.FamilyRoleOwner {
+		t.Fatalf("family member = %+v", member)
+	}
+	var templates int64
+	if err := db.Model(&models.BehaviorTemplate{}).Where("created_by = ? AND is_active = ?", parent.ID, true).Count(&templates).Error; err != nil {
+		t.Fatalf("count behavior templates: %v", err)
+	}
+	if templates == 0 {
+		t.Fatal("no behavior templates seeded for the parent")
+	}
+
+	// 行为记录按描述补写分类，历史记录视为已生效
+	var records []models.BehaviorRecord
+	if err := db.Order("id").Find(&records).Error; err != nil {
+		t.Fatalf("load behavior records: %v", err)
+	}
+	wantCategories := []string{models.CategoryLearning, models.CategoryExercise, models.CategoryOther}
+	for i, record := range records {
+		if record.Category != wantCategories[i] || record.Status != models.BehaviorStatusApproved {
+			t.Fatalf("behavior record %d: category %q status %q, want %q approved", i, record.Category, record.Status, wantCategories[i])
+		}
+	}
+
+	// 期初流水重放后与原积分一致
+	var transactions []models.PointTransaction
+	if err := db.Where("user_id = ?", child.ID).Order("id").Find(&transactions).Error; err != nil {
+		t.Fatalf("load point transactions: %v", err)
+	}
+	if len(transactions) != 2 || transactions[0].SourceType != models.PointSourceOpening || transactions[1].SourceType != models.PointSourceExchange {
+		t.Fatalf("point transactions = %+v", transactions)
+	}
+	rebuilt, err := models.RebuildUserPoints(db, child.ID)
+	if err != nil {
+		t.Fatalf("rebuild user points: %v", err)
+	}
+	if rebuilt.TotalPoints != 20 || rebuilt.AvailablePoints != 5 {
+		t.Fatalf("rebuilt points = %d/%d, want 20/5", rebuilt.TotalPoints, rebuilt.AvailablePoints)
+	}
+
+	// 历史兑换记录视为已完成
+	var exchange models.ExchangeRecord
+	if err := db.First(&exchange).Error; err != nil {
+		t.Fatalf("load exchange record: %v", err)
+	}
+	if exchange.Status != models.ExchangeStatusCompleted {
+		t.Fatalf("exchange status = %q", exchange.Status)
+	}
+
+	// 再次执行没有待执行的迁移
+	if applied, err := migrations.Up(db); err != nil || len(applied) != 0 {
+		t.Fatalf("second migrate up: applied %d, err %v", len(applied), err)
+	}
+}
+
+func TestMigrateDownAndUp(t *testing.T) {
+	db := openDatabase(t)
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	latest, err := migrations.LatestVersion(db.Dialector.Name())
+	if err != nil {
+		t.Fatalf("latest version: %v", err)
+	}
+
+	// 逐个版本回滚到初始表结构，再全部回滚
+	for version := latest - 1; version >= 0; version-- {
+		if _, err := migrations.To(db, version); err != nil {
+			t.Fatalf("migrate to %d: %v", version, err)
+		}
+	}
+	for _, table := range []string{"users", "point_transactions", "families"} {
+		if db.Migrator().HasTable(table) {
+			t.Fatalf("table %s still exists after reverting all migrations", table)
+		}
+	}
+
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatalf("migrate up again: %v", err)
+	}
+	if current, err := migrations.CurrentVersion(db); err != nil || current != latest {
+		t.Fatalf("current version = %d, %v, want %d", current, err, latest)
+	}
+}
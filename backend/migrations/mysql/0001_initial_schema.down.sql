@@ -0,0 +1,7 @@
+-- 删除初始表
+
+DROP TABLE IF EXISTS `exchange_records`;
+DROP TABLE IF EXISTS `rewards`;
+DROP TABLE IF EXISTS `user_points`;
+DROP TABLE IF EXISTS `behavior_records`;
+DROP TABLE IF EXISTS `users`;
@@ -0,0 +1,83 @@
+-- 初始表结构（MySQL/MariaDB），与引入版本迁移之前启动时自动建表创建的表一致
+-- 使用 CREATE TABLE IF NOT EXISTS，已有的数据库执行后结构不变，只记录版本
+
+CREATE TABLE IF NOT EXISTS `users` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `phone` varchar(20),
+  `password` varchar(255),
+  `nickname` varchar(50) NOT NULL,
+  `email` varchar(100),
+  `avatar` varchar(255),
+  `age` bigint DEFAULT 0,
+  `gender` varchar(10),
+  `role` enum('parent','child') NOT NULL,
+  `parent_id` bigint unsigned,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_users_phone` (`phone`),
+  INDEX `idx_users_parent_id` (`parent_id`),
+  CONSTRAINT `fk_users_children` FOREIGN KEY (`parent_id`) REFERENCES `users`(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `behavior_records` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `recorder_id` bigint unsigned NOT NULL,
+  `behavior_type` varchar(20) NOT NULL,
+  `description` text NOT NULL,
+  `points` bigint NOT NULL,
+  `image_url` varchar(255),
+  `recorded_at` datetime(3) NOT NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_behavior_records_child_id` (`user_id`),
+  INDEX `idx_behavior_records_recorder_id` (`recorder_id`),
+  CONSTRAINT `fk_behavior_records_child` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`),
+  CONSTRAINT `fk_behavior_records_recorder` FOREIGN KEY (`recorder_id`) REFERENCES `users`(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `user_points` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `total_points` bigint NOT NULL DEFAULT 0,
+  `available_points` bigint NOT NULL DEFAULT 0,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_user_points_user_id` (`user_id`),
+  CONSTRAINT `fk_user_points_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `rewards` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `name` varchar(100) NOT NULL,
+  `description` text,
+  `points` bigint NOT NULL,
+  `image` varchar(255),
+  `stock` bigint NOT NULL DEFAULT 1,
+  `is_active` boolean NOT NULL DEFAULT true,
+  `created_by` bigint unsigned NOT NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_rewards_created_by` (`created_by`),
+  CONSTRAINT `fk_rewards_creator` FOREIGN KEY (`created_by`) REFERENCES `users`(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `exchange_records` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `reward_id` bigint unsigned NOT NULL,
+  `points_used` bigint NOT NULL,
+  `exchanged_at` datetime(3) NOT NULL,
+  `status` enum('pending','completed','cancelled') NOT NULL DEFAULT 'completed',
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_exchange_records_user_id` (`user_id`),
+  INDEX `idx_exchange_records_reward_id` (`reward_id`),
+  CONSTRAINT `fk_exchange_records_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`),
+  CONSTRAINT `fk_exchange_records_reward` FOREIGN KEY (`reward_id`) REFERENCES `rewards`(`id`)
+);
@@ -0,0 +1,18 @@
+-- 积分流水表
+
+CREATE TABLE IF NOT EXISTS `point_transactions` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `source_type` varchar(20) NOT NULL,
+  `source_id` bigint unsigned,
+  `delta` bigint NOT NULL,
+  `balance_after` bigint NOT NULL,
+  `total_after` bigint NOT NULL,
+  `actor_id` bigint unsigned NOT NULL,
+  `note` varchar(255),
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_point_transactions_user_id` (`user_id`),
+  INDEX `idx_point_source` (`source_type`,`source_id`),
+  INDEX `idx_point_transactions_actor_id` (`actor_id`)
+);
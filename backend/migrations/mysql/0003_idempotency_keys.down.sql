@@ -0,0 +1,3 @@
+-- 回滚：积分写入接口的幂等键表
+
+DROP TABLE IF EXISTS `idempotency_keys`;
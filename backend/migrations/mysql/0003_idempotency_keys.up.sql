@@ -0,0 +1,17 @@
+-- 积分写入接口的幂等键表
+
+CREATE TABLE IF NOT EXISTS `idempotency_keys` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `key` varchar(64) NOT NULL,
+  `method` varchar(10) NOT NULL,
+  `path` varchar(255) NOT NULL,
+  `request_hash` varchar(64) NOT NULL,
+  `completed` boolean NOT NULL DEFAULT false,
+  `status_code` bigint NOT NULL DEFAULT 0,
+  `response_body` text,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_idempotency_user_key` (`user_id`,`key`)
+);
@@ -0,0 +1,4 @@
+-- 回滚：行为记录的分类
+
+DROP INDEX `idx_behavior_records_category` ON `behavior_records`;
+ALTER TABLE `behavior_records` DROP COLUMN `category`;
@@ -0,0 +1,4 @@
+-- 行为记录的分类
+
+ALTER TABLE `behavior_records` ADD COLUMN `category` varchar(20) NOT NULL DEFAULT '';
+CREATE INDEX `idx_behavior_records_category` ON `behavior_records`(`category`);
@@ -0,0 +1,19 @@
+-- 家长自定义的行为模板表，行为记录关联使用的模板
+
+ALTER TABLE `behavior_records` ADD COLUMN `template_id` bigint unsigned;
+CREATE INDEX `idx_behavior_records_template_id` ON `behavior_records`(`template_id`);
+
+CREATE TABLE IF NOT EXISTS `behavior_templates` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `name` varchar(100) NOT NULL,
+  `category` varchar(20) NOT NULL,
+  `default_points` bigint NOT NULL,
+  `icon` varchar(50),
+  `is_active` boolean NOT NULL DEFAULT true,
+  `created_by` bigint unsigned NOT NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_behavior_templates_category` (`category`),
+  INDEX `idx_behavior_templates_created_by` (`created_by`)
+);
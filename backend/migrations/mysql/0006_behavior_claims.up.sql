@@ -0,0 +1,7 @@
+-- 儿童申报的行为记录需要家长审核
+
+ALTER TABLE `behavior_records` ADD COLUMN `status` varchar(20) NOT NULL DEFAULT 'approved';
+ALTER TABLE `behavior_records` ADD COLUMN `reviewer_id` bigint unsigned;
+ALTER TABLE `behavior_records` ADD COLUMN `review_note` varchar(255);
+ALTER TABLE `behavior_records` ADD COLUMN `reviewed_at` datetime(3) NULL;
+CREATE INDEX `idx_behavior_records_status` ON `behavior_records`(`status`);
@@ -0,0 +1,43 @@
+-- 周期任务表和每天的任务实例表
+
+CREATE TABLE IF NOT EXISTS `chores` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `child_id` bigint unsigned NOT NULL,
+  `template_id` bigint unsigned,
+  `name` varchar(100) NOT NULL,
+  `category` varchar(20) NOT NULL,
+  `points` bigint NOT NULL,
+  `penalty_points` bigint NOT NULL DEFAULT 0,
+  `recurrence` varchar(20) NOT NULL,
+  `week_days` varchar(20),
+  `due_time` varchar(5) NOT NULL,
+  `is_active` boolean NOT NULL DEFAULT true,
+  `created_by` bigint unsigned NOT NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_chores_child_id` (`child_id`),
+  INDEX `idx_chores_template_id` (`template_id`),
+  INDEX `idx_chores_created_by` (`created_by`),
+  CONSTRAINT `fk_chores_child` FOREIGN KEY (`child_id`) REFERENCES `users`(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `chore_instances` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `chore_id` bigint unsigned NOT NULL,
+  `child_id` bigint unsigned NOT NULL,
+  `due_date` varchar(10) NOT NULL,
+  `due_at` datetime(3) NOT NULL,
+  `status` varchar(20) NOT NULL DEFAULT 'pending',
+  `completed_at` datetime(3) NULL,
+  `completed_by` bigint unsigned,
+  `behavior_record_id` bigint unsigned,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_chore_due_date` (`chore_id`,`due_date`),
+  INDEX `idx_chore_instances_child_id` (`child_id`),
+  INDEX `idx_chore_instances_due_date` (`due_date`),
+  INDEX `idx_chore_instances_status` (`status`),
+  CONSTRAINT `fk_chore_instances_chore` FOREIGN KEY (`chore_id`) REFERENCES `chores`(`id`)
+);
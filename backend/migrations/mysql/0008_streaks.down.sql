@@ -0,0 +1,4 @@
+-- 回滚：连续打卡天数表和连续奖励规则表
+
+DROP TABLE IF EXISTS `streak_bonus_rules`;
+DROP TABLE IF EXISTS `child_streaks`;
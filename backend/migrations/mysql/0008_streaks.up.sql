@@ -0,0 +1,29 @@
+-- 连续打卡天数表和连续奖励规则表
+
+CREATE TABLE IF NOT EXISTS `child_streaks` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `child_id` bigint unsigned NOT NULL,
+  `template_id` bigint unsigned NOT NULL,
+  `current_streak` bigint NOT NULL DEFAULT 0,
+  `best_streak` bigint NOT NULL DEFAULT 0,
+  `last_date` varchar(10) NOT NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_streak_child_template` (`child_id`,`template_id`),
+  CONSTRAINT `fk_child_streaks_template` FOREIGN KEY (`template_id`) REFERENCES `behavior_templates`(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `streak_bonus_rules` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `template_id` bigint unsigned,
+  `days` bigint NOT NULL,
+  `bonus_points` bigint NOT NULL,
+  `is_active` boolean NOT NULL DEFAULT true,
+  `created_by` bigint unsigned NOT NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_streak_bonus_rules_template_id` (`template_id`),
+  INDEX `idx_streak_bonus_rules_created_by` (`created_by`)
+);
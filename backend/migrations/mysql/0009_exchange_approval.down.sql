@@ -0,0 +1,6 @@
+-- 回滚：需要家长确认的奖励和兑换的审核信息
+
+ALTER TABLE `exchange_records` DROP COLUMN `reviewed_at`;
+ALTER TABLE `exchange_records` DROP COLUMN `review_note`;
+ALTER TABLE `exchange_records` DROP COLUMN `reviewer_id`;
+ALTER TABLE `rewards` DROP COLUMN `requires_approval`;
@@ -0,0 +1,6 @@
+-- 需要家长确认的奖励和兑换的审核信息
+
+ALTER TABLE `rewards` ADD COLUMN `requires_approval` boolean NOT NULL DEFAULT false;
+ALTER TABLE `exchange_records` ADD COLUMN `reviewer_id` bigint unsigned;
+ALTER TABLE `exchange_records` ADD COLUMN `review_note` varchar(255);
+ALTER TABLE `exchange_records` ADD COLUMN `reviewed_at` datetime(3) NULL;
@@ -0,0 +1,5 @@
+-- 回滚：等级表、成就规则表和已解锁的成就表
+
+DROP TABLE IF EXISTS `child_achievements`;
+DROP TABLE IF EXISTS `achievements`;
+DROP TABLE IF EXISTS `levels`;
@@ -0,0 +1,40 @@
+-- 等级表、成就规则表和已解锁的成就表
+
+CREATE TABLE IF NOT EXISTS `levels` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `level` bigint NOT NULL,
+  `name` varchar(50) NOT NULL,
+  `min_points` bigint NOT NULL,
+  `created_by` bigint unsigned NOT NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_level_owner_level` (`level`,`created_by`)
+);
+
+CREATE TABLE IF NOT EXISTS `achievements` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `name` varchar(100) NOT NULL,
+  `description` varchar(255),
+  `icon` varchar(50),
+  `rule_type` varchar(20) NOT NULL,
+  `category` varchar(20),
+  `threshold` bigint NOT NULL,
+  `is_active` boolean NOT NULL DEFAULT true,
+  `created_by` bigint unsigned NOT NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_achievements_created_by` (`created_by`)
+);
+
+CREATE TABLE IF NOT EXISTS `child_achievements` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `child_id` bigint unsigned NOT NULL,
+  `achievement_id` bigint unsigned NOT NULL,
+  `unlocked_at` datetime(3) NOT NULL,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_child_achievement` (`child_id`,`achievement_id`),
+  CONSTRAINT `fk_child_achievements_achievement` FOREIGN KEY (`achievement_id`) REFERENCES `achievements`(`id`)
+);
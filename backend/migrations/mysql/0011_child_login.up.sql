@@ -0,0 +1,28 @@
+-- 儿童登录PIN、配对码表和已配对的设备表
+
+ALTER TABLE `users` ADD COLUMN `pin` varchar(255);
+
+CREATE TABLE IF NOT EXISTS `pairing_codes` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `code` varchar(16) NOT NULL,
+  `child_id` bigint unsigned NOT NULL,
+  `created_by` bigint unsigned NOT NULL,
+  `expires_at` datetime(3) NOT NULL,
+  `used_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_pairing_codes_code` (`code`),
+  INDEX `idx_pairing_codes_child_id` (`child_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `child_devices` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `child_id` bigint unsigned NOT NULL,
+  `device_id` varchar(64) NOT NULL,
+  `device_name` varchar(100),
+  `last_login_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_child_device` (`child_id`,`device_id`)
+);
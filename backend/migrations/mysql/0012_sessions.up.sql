@@ -0,0 +1,23 @@
+-- 登录会话表，修改密码或退出所有设备时递增用户的令牌版本
+
+ALTER TABLE `users` ADD COLUMN `token_version` bigint NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS `sessions` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `token_hash` varchar(64) NOT NULL,
+  `previous_token_hash` varchar(64),
+  `token_version` bigint NOT NULL,
+  `device_id` varchar(64),
+  `user_agent` varchar(255),
+  `ip_address` varchar(45),
+  `expires_at` datetime(3) NOT NULL,
+  `last_used_at` datetime(3) NULL,
+  `revoked_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_sessions_user_id` (`user_id`),
+  UNIQUE INDEX `idx_sessions_token_hash` (`token_hash`),
+  INDEX `idx_sessions_previous_token_hash` (`previous_token_hash`)
+);
@@ -0,0 +1,7 @@
+-- 回滚：家庭表、家庭成员表和邀请码表，用户关联所在的家庭
+
+DROP TABLE IF EXISTS `family_invites`;
+DROP TABLE IF EXISTS `family_members`;
+DROP TABLE IF EXISTS `families`;
+DROP INDEX `idx_users_family_id` ON `users`;
+ALTER TABLE `users` DROP COLUMN `family_id`;
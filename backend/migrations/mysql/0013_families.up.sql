@@ -0,0 +1,43 @@
+-- 家庭表、家庭成员表和邀请码表，用户关联所在的家庭
+
+ALTER TABLE `users` ADD COLUMN `family_id` bigint unsigned;
+CREATE INDEX `idx_users_family_id` ON `users`(`family_id`);
+
+CREATE TABLE IF NOT EXISTS `families` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `name` varchar(100) NOT NULL,
+  `owner_id` bigint unsigned NOT NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_families_owner_id` (`owner_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `family_members` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `family_id` bigint unsigned NOT NULL,
+  `user_id` bigint unsigned NOT NULL,
+  `role` varchar(20) NOT NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_family_members_family_id` (`family_id`),
+  UNIQUE INDEX `idx_family_members_user_id` (`user_id`),
+  CONSTRAINT `fk_family_members_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`),
+  CONSTRAINT `fk_families_members` FOREIGN KEY (`family_id`) REFERENCES `families`(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `family_invites` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `family_id` bigint unsigned NOT NULL,
+  `code` varchar(16) NOT NULL,
+  `role` varchar(20) NOT NULL,
+  `created_by` bigint unsigned NOT NULL,
+  `expires_at` datetime(3) NOT NULL,
+  `used_by` bigint unsigned,
+  `used_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_family_invites_family_id` (`family_id`),
+  UNIQUE INDEX `idx_family_invites_code` (`code`)
+);
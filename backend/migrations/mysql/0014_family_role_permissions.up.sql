@@ -0,0 +1,11 @@
+-- 家庭角色权限表
+
+CREATE TABLE IF NOT EXISTS `family_role_permissions` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `family_id` bigint unsigned NOT NULL,
+  `role` varchar(20) NOT NULL,
+  `permission` varchar(50) NOT NULL,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_family_role_permission` (`family_id`,`role`,`permission`)
+);
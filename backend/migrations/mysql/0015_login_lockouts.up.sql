@@ -0,0 +1,14 @@
+-- 登录失败锁定表
+
+CREATE TABLE IF NOT EXISTS `login_lockouts` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `scope` varchar(20) NOT NULL,
+  `subject` varchar(100) NOT NULL,
+  `failures` bigint NOT NULL DEFAULT 0,
+  `last_failed_at` datetime(3) NULL,
+  `locked_until` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_login_lockout_subject` (`scope`,`subject`)
+);
@@ -0,0 +1,3 @@
+-- 回滚：找回密码验证码表
+
+DROP TABLE IF EXISTS `password_reset_codes`;
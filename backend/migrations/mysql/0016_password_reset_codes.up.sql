@@ -0,0 +1,13 @@
+-- 找回密码验证码表
+
+CREATE TABLE IF NOT EXISTS `password_reset_codes` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `code_hash` varchar(64) NOT NULL,
+  `attempts` bigint NOT NULL DEFAULT 0,
+  `expires_at` datetime(3) NOT NULL,
+  `used_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_password_reset_codes_user_id` (`user_id`)
+);
@@ -0,0 +1,6 @@
+-- 回滚：两步验证密钥和恢复码表
+
+DROP TABLE IF EXISTS `recovery_codes`;
+ALTER TABLE `users` DROP COLUMN `totp_last_step`;
+ALTER TABLE `users` DROP COLUMN `totp_enabled`;
+ALTER TABLE `users` DROP COLUMN `totp_secret`;
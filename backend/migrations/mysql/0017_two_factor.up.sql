@@ -0,0 +1,15 @@
+-- 两步验证密钥和恢复码表
+
+ALTER TABLE `users` ADD COLUMN `totp_secret` varchar(64);
+ALTER TABLE `users` ADD COLUMN `totp_enabled` boolean NOT NULL DEFAULT false;
+ALTER TABLE `users` ADD COLUMN `totp_last_step` bigint NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS `recovery_codes` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `code_hash` varchar(64) NOT NULL,
+  `used_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_recovery_codes_user_id` (`user_id`)
+);
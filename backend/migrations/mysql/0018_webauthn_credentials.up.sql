@@ -0,0 +1,20 @@
+-- 通行密钥表
+
+CREATE TABLE IF NOT EXISTS `web_authn_credentials` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `credential_id` varchar(255) NOT NULL,
+  `public_key` longblob NOT NULL,
+  `attestation_type` varchar(32),
+  `aa_guid` varbinary(16),
+  `sign_count` int unsigned NOT NULL DEFAULT 0,
+  `transports` varchar(100),
+  `backup_eligible` boolean NOT NULL DEFAULT false,
+  `backup_state` boolean NOT NULL DEFAULT false,
+  `name` varchar(50),
+  `last_used_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_web_authn_credentials_user_id` (`user_id`),
+  UNIQUE INDEX `idx_web_authn_credentials_credential_id` (`credential_id`)
+);
@@ -0,0 +1,3 @@
+-- 回滚：审计日志表
+
+DROP TABLE IF EXISTS `audit_events`;
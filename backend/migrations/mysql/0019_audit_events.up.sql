@@ -0,0 +1,21 @@
+-- 审计日志表
+
+CREATE TABLE IF NOT EXISTS `audit_events` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `family_id` bigint unsigned,
+  `actor_id` bigint unsigned,
+  `actor_role` varchar(20),
+  `action` varchar(100) NOT NULL,
+  `target_type` varchar(50),
+  `target_id` varchar(50),
+  `status_code` bigint NOT NULL,
+  `ip` varchar(45),
+  `user_agent` varchar(255),
+  `before` text,
+  `after` text,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_audit_family_created` (`family_id`,`created_at`),
+  INDEX `idx_audit_events_actor_id` (`actor_id`),
+  INDEX `idx_audit_events_action` (`action`)
+);
@@ -0,0 +1,4 @@
+-- 恢复 enum 列，已有数据中不在取值范围内的值会导致回滚失败
+
+ALTER TABLE `users` MODIFY `role` enum('parent','child') NOT NULL;
+ALTER TABLE `exchange_records` MODIFY `status` enum('pending','completed','cancelled') NOT NULL DEFAULT 'completed';
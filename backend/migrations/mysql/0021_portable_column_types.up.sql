@@ -0,0 +1,4 @@
+-- enum 列改为 varchar，表结构不再依赖 MySQL 特有的类型，取值由程序校验
+
+ALTER TABLE `users` MODIFY `role` varchar(20) NOT NULL;
+ALTER TABLE `exchange_records` MODIFY `status` varchar(20) NOT NULL DEFAULT 'completed';
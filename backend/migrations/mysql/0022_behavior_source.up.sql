@@ -0,0 +1,13 @@
+-- 行为记录的来源：家长或儿童手动录入、任务完成、任务错过扣分和连续奖励，撤销只针对手动录入的记录
+-- 连续奖励记录关联触发它的行为记录
+
+ALTER TABLE `behavior_records` ADD COLUMN `source` varchar(20) NOT NULL DEFAULT 'manual';
+ALTER TABLE `behavior_records` ADD COLUMN `trigger_id` bigint unsigned;
+CREATE INDEX `idx_behavior_records_trigger_id` ON `behavior_records`(`trigger_id`);
+
+UPDATE `behavior_records` SET `source` = 'chore'
+  WHERE `id` IN (SELECT `behavior_record_id` FROM `chore_instances` WHERE `status` = 'done');
+UPDATE `behavior_records` SET `source` = 'chore_missed'
+  WHERE `id` IN (SELECT `behavior_record_id` FROM `chore_instances` WHERE `status` = 'missed');
+UPDATE `behavior_records` SET `source` = 'streak_bonus'
+  WHERE `source` = 'manual' AND `template_id` IS NULL AND `description` LIKE '连续%天%奖励';
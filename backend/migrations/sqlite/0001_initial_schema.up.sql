@@ -0,0 +1,77 @@
+-- 初始表结构（SQLite），与 MySQL 的初始版本一致
+
+CREATE TABLE IF NOT EXISTS `users` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `phone` text,
+  `password` text,
+  `nickname` text NOT NULL,
+  `email` text,
+  `avatar` text,
+  `age` integer DEFAULT 0,
+  `gender` text,
+  `role` text NOT NULL,
+  `parent_id` integer,
+  `created_at` datetime,
+  `updated_at` datetime,
+  CONSTRAINT `fk_users_children` FOREIGN KEY (`parent_id`) REFERENCES `users`(`id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_users_parent_id` ON `users`(`parent_id`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_users_phone` ON `users`(`phone`);
+
+CREATE TABLE IF NOT EXISTS `behavior_records` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `user_id` integer NOT NULL,
+  `recorder_id` integer NOT NULL,
+  `behavior_type` text NOT NULL,
+  `description` text NOT NULL,
+  `points` integer NOT NULL,
+  `image_url` text,
+  `recorded_at` datetime NOT NULL,
+  `created_at` datetime,
+  `updated_at` datetime,
+  CONSTRAINT `fk_behavior_records_child` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`),
+  CONSTRAINT `fk_behavior_records_recorder` FOREIGN KEY (`recorder_id`) REFERENCES `users`(`id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_behavior_records_recorder_id` ON `behavior_records`(`recorder_id`);
+CREATE INDEX IF NOT EXISTS `idx_behavior_records_child_id` ON `behavior_records`(`user_id`);
+
+CREATE TABLE IF NOT EXISTS `user_points` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `user_id` integer NOT NULL,
+  `total_points` integer NOT NULL DEFAULT 0,
+  `available_points` integer NOT NULL DEFAULT 0,
+  `created_at` datetime,
+  `updated_at` datetime,
+  CONSTRAINT `fk_user_points_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_user_points_user_id` ON `user_points`(`user_id`);
+
+CREATE TABLE IF NOT EXISTS `rewards` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `name` text NOT NULL,
+  `description` text,
+  `points` integer NOT NULL,
+  `image` text,
+  `stock` integer NOT NULL DEFAULT 1,
+  `is_active` numeric NOT NULL DEFAULT true,
+  `created_by` integer NOT NULL,
+  `created_at` datetime,
+  `updated_at` datetime,
+  CONSTRAINT `fk_rewards_creator` FOREIGN KEY (`created_by`) REFERENCES `users`(`id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_rewards_created_by` ON `rewards`(`created_by`);
+
+CREATE TABLE IF NOT EXISTS `exchange_records` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `user_id` integer NOT NULL,
+  `reward_id` integer NOT NULL,
+  `points_used` integer NOT NULL,
+  `exchanged_at` datetime NOT NULL,
+  `status` text NOT NULL DEFAULT "completed",
+  `created_at` datetime,
+  `updated_at` datetime,
+  CONSTRAINT `fk_exchange_records_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`),
+  CONSTRAINT `fk_exchange_records_reward` FOREIGN KEY (`reward_id`) REFERENCES `rewards`(`id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_exchange_records_reward_id` ON `exchange_records`(`reward_id`);
+CREATE INDEX IF NOT EXISTS `idx_exchange_records_user_id` ON `exchange_records`(`user_id`);
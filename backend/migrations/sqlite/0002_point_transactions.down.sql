@@ -0,0 +1,3 @@
+-- 回滚：积分流水表
+
+DROP TABLE IF EXISTS `point_transactions`;
@@ -0,0 +1,17 @@
+-- 积分流水表
+
+CREATE TABLE IF NOT EXISTS `point_transactions` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `user_id` integer NOT NULL,
+  `source_type` text NOT NULL,
+  `source_id` integer,
+  `delta` integer NOT NULL,
+  `balance_after` integer NOT NULL,
+  `total_after` integer NOT NULL,
+  `actor_id` integer NOT NULL,
+  `note` text,
+  `created_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_point_transactions_actor_id` ON `point_transactions`(`actor_id`);
+CREATE INDEX IF NOT EXISTS `idx_point_source` ON `point_transactions`(`source_type`,`source_id`);
+CREATE INDEX IF NOT EXISTS `idx_point_transactions_user_id` ON `point_transactions`(`user_id`);
@@ -0,0 +1,16 @@
+-- 积分写入接口的幂等键表
+
+CREATE TABLE IF NOT EXISTS `idempotency_keys` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `user_id` integer NOT NULL,
+  `key` text NOT NULL,
+  `method` text NOT NULL,
+  `path` text NOT NULL,
+  `request_hash` text NOT NULL,
+  `completed` numeric NOT NULL DEFAULT false,
+  `status_code` integer NOT NULL DEFAULT 0,
+  `response_body` text,
+  `created_at` datetime,
+  `updated_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_idempotency_user_key` ON `idempotency_keys`(`user_id`,`key`);
@@ -0,0 +1,4 @@
+-- 回滚：行为记录的分类
+
+DROP INDEX IF EXISTS `idx_behavior_records_category`;
+ALTER TABLE `behavior_records` DROP COLUMN `category`;
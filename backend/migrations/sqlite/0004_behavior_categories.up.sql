@@ -0,0 +1,4 @@
+-- 行为记录的分类
+
+ALTER TABLE `behavior_records` ADD COLUMN `category` text NOT NULL DEFAULT "";
+CREATE INDEX IF NOT EXISTS `idx_behavior_records_category` ON `behavior_records`(`category`);
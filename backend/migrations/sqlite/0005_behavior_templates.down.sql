@@ -0,0 +1,5 @@
+-- 回滚：家长自定义的行为模板表，行为记录关联使用的模板
+
+DROP TABLE IF EXISTS `behavior_templates`;
+DROP INDEX IF EXISTS `idx_behavior_records_template_id`;
+ALTER TABLE `behavior_records` DROP COLUMN `template_id`;
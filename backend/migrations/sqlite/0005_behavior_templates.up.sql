@@ -0,0 +1,18 @@
+-- 家长自定义的行为模板表，行为记录关联使用的模板
+
+ALTER TABLE `behavior_records` ADD COLUMN `template_id` integer;
+CREATE INDEX IF NOT EXISTS `idx_behavior_records_template_id` ON `behavior_records`(`template_id`);
+
+CREATE TABLE IF NOT EXISTS `behavior_templates` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `name` text NOT NULL,
+  `category` text NOT NULL,
+  `default_points` integer NOT NULL,
+  `icon` text,
+  `is_active` numeric NOT NULL DEFAULT true,
+  `created_by` integer NOT NULL,
+  `created_at` datetime,
+  `updated_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_behavior_templates_created_by` ON `behavior_templates`(`created_by`);
+CREATE INDEX IF NOT EXISTS `idx_behavior_templates_category` ON `behavior_templates`(`category`);
@@ -0,0 +1,7 @@
+-- 回滚：儿童申报的行为记录需要家长审核
+
+DROP INDEX IF EXISTS `idx_behavior_records_status`;
+ALTER TABLE `behavior_records` DROP COLUMN `reviewed_at`;
+ALTER TABLE `behavior_records` DROP COLUMN `review_note`;
+ALTER TABLE `behavior_records` DROP COLUMN `reviewer_id`;
+ALTER TABLE `behavior_records` DROP COLUMN `status`;
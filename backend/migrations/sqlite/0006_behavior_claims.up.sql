@@ -0,0 +1,7 @@
+-- 儿童申报的行为记录需要家长审核
+
+ALTER TABLE `behavior_records` ADD COLUMN `status` text NOT NULL DEFAULT "approved";
+ALTER TABLE `behavior_records` ADD COLUMN `reviewer_id` integer;
+ALTER TABLE `behavior_records` ADD COLUMN `review_note` text;
+ALTER TABLE `behavior_records` ADD COLUMN `reviewed_at` datetime;
+CREATE INDEX IF NOT EXISTS `idx_behavior_records_status` ON `behavior_records`(`status`);
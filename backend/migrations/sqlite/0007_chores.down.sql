@@ -0,0 +1,4 @@
+-- 回滚：周期任务表和每天的任务实例表
+
+DROP TABLE IF EXISTS `chore_instances`;
+DROP TABLE IF EXISTS `chores`;
@@ -0,0 +1,41 @@
+-- 周期任务表和每天的任务实例表
+
+CREATE TABLE IF NOT EXISTS `chores` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `child_id` integer NOT NULL,
+  `template_id` integer,
+  `name` text NOT NULL,
+  `category` text NOT NULL,
+  `points` integer NOT NULL,
+  `penalty_points` integer NOT NULL DEFAULT 0,
+  `recurrence` text NOT NULL,
+  `week_days` text,
+  `due_time` text NOT NULL,
+  `is_active` numeric NOT NULL DEFAULT true,
+  `created_by` integer NOT NULL,
+  `created_at` datetime,
+  `updated_at` datetime,
+  CONSTRAINT `fk_chores_child` FOREIGN KEY (`child_id`) REFERENCES `users`(`id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_chores_created_by` ON `chores`(`created_by`);
+CREATE INDEX IF NOT EXISTS `idx_chores_template_id` ON `chores`(`template_id`);
+CREATE INDEX IF NOT EXISTS `idx_chores_child_id` ON `chores`(`child_id`);
+
+CREATE TABLE IF NOT EXISTS `chore_instances` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `chore_id` integer NOT NULL,
+  `child_id` integer NOT NULL,
+  `due_date` text NOT NULL,
+  `due_at` datetime NOT NULL,
+  `status` text NOT NULL DEFAULT "pending",
+  `completed_at` datetime,
+  `completed_by` integer,
+  `behavior_record_id` integer,
+  `created_at` datetime,
+  `updated_at` datetime,
+  CONSTRAINT `fk_chore_instances_chore` FOREIGN KEY (`chore_id`) REFERENCES `chores`(`id`)
+);
+CREATE INDEX IF NOT EXISTS `idx_chore_instances_status` ON `chore_instances`(`status`);
+CREATE INDEX IF NOT EXISTS `idx_chore_instances_due_date` ON `chore_instances`(`due_date`);
+CREATE INDEX IF NOT EXISTS `idx_chore_instances_child_id` ON `chore_instances`(`child_id`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_chore_due_date` ON `chore_instances`(`chore_id`,`due_date`);
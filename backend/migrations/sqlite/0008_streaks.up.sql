@@ -0,0 +1,27 @@
+-- 连续打卡天数表和连续奖励规则表
+
+CREATE TABLE IF NOT EXISTS `child_streaks` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `child_id` integer NOT NULL,
+  `template_id` integer NOT NULL,
+  `current_streak` integer NOT NULL DEFAULT 0,
+  `best_streak` integer NOT NULL DEFAULT 0,
+  `last_date` text NOT NULL,
+  `created_at` datetime,
+  `updated_at` datetime,
+  CONSTRAINT `fk_child_streaks_template` FOREIGN KEY (`template_id`) REFERENCES `behavior_templates`(`id`)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_streak_child_template` ON `child_streaks`(`child_id`,`template_id`);
+
+CREATE TABLE IF NOT EXISTS `streak_bonus_rules` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `template_id` integer,
+  `days` integer NOT NULL,
+  `bonus_points` integer NOT NULL,
+  `is_active` numeric NOT NULL DEFAULT true,
+  `created_by` integer NOT NULL,
+  `created_at` datetime,
+  `updated_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_streak_bonus_rules_created_by` ON `streak_bonus_rules`(`created_by`);
+CREATE INDEX IF NOT EXISTS `idx_streak_bonus_rules_template_id` ON `streak_bonus_rules`(`template_id`);
@@ -0,0 +1,6 @@
+-- 需要家长确认的奖励和兑换的审核信息
+
+ALTER TABLE `rewards` ADD COLUMN `requires_approval` numeric NOT NULL DEFAULT false;
+ALTER TABLE `exchange_records` ADD COLUMN `reviewer_id` integer;
+ALTER TABLE `exchange_records` ADD COLUMN `review_note` text;
+ALTER TABLE `exchange_records` ADD COLUMN `reviewed_at` datetime;
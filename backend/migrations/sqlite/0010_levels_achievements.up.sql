@@ -0,0 +1,37 @@
+-- 等级表、成就规则表和已解锁的成就表
+
+CREATE TABLE IF NOT EXISTS `levels` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `level` integer NOT NULL,
+  `name` text NOT NULL,
+  `min_points` integer NOT NULL,
+  `created_by` integer NOT NULL,
+  `created_at` datetime,
+  `updated_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_level_owner_level` ON `levels`(`level`,`created_by`);
+
+CREATE TABLE IF NOT EXISTS `achievements` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `name` text NOT NULL,
+  `description` text,
+  `icon` text,
+  `rule_type` text NOT NULL,
+  `category` text,
+  `threshold` integer NOT NULL,
+  `is_active` numeric NOT NULL DEFAULT true,
+  `created_by` integer NOT NULL,
+  `created_at` datetime,
+  `updated_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_achievements_created_by` ON `achievements`(`created_by`);
+
+CREATE TABLE IF NOT EXISTS `child_achievements` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `child_id` integer NOT NULL,
+  `achievement_id` integer NOT NULL,
+  `unlocked_at` datetime NOT NULL,
+  `created_at` datetime,
+  CONSTRAINT `fk_child_achievements_achievement` FOREIGN KEY (`achievement_id`) REFERENCES `achievements`(`id`)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_child_achievement` ON `child_achievements`(`child_id`,`achievement_id`);
@@ -0,0 +1,5 @@
+-- 回滚：儿童登录PIN、配对码表和已配对的设备表
+
+DROP TABLE IF EXISTS `child_devices`;
+DROP TABLE IF EXISTS `pairing_codes`;
+ALTER TABLE `users` DROP COLUMN `pin`;
@@ -0,0 +1,26 @@
+-- 儿童登录PIN、配对码表和已配对的设备表
+
+ALTER TABLE `users` ADD COLUMN `pin` text;
+
+CREATE TABLE IF NOT EXISTS `pairing_codes` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `code` text NOT NULL,
+  `child_id` integer NOT NULL,
+  `created_by` integer NOT NULL,
+  `expires_at` datetime NOT NULL,
+  `used_at` datetime,
+  `created_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_pairing_codes_child_id` ON `pairing_codes`(`child_id`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_pairing_codes_code` ON `pairing_codes`(`code`);
+
+CREATE TABLE IF NOT EXISTS `child_devices` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `child_id` integer NOT NULL,
+  `device_id` text NOT NULL,
+  `device_name` text,
+  `last_login_at` datetime,
+  `created_at` datetime,
+  `updated_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_child_device` ON `child_devices`(`child_id`,`device_id`);
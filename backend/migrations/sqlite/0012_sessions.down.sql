@@ -0,0 +1,4 @@
+-- 回滚：登录会话表，修改密码或退出所有设备时递增用户的令牌版本
+
+DROP TABLE IF EXISTS `sessions`;
+ALTER TABLE `users` DROP COLUMN `token_version`;
@@ -0,0 +1,22 @@
+-- 登录会话表，修改密码或退出所有设备时递增用户的令牌版本
+
+ALTER TABLE `users` ADD COLUMN `token_version` integer NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS `sessions` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `user_id` integer NOT NULL,
+  `token_hash` text NOT NULL,
+  `previous_token_hash` text,
+  `token_version` integer NOT NULL,
+  `device_id` text,
+  `user_agent` text,
+  `ip_address` text,
+  `expires_at` datetime NOT NULL,
+  `last_used_at` datetime,
+  `revoked_at` datetime,
+  `created_at` datetime,
+  `updated_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_sessions_previous_token_hash` ON `sessions`(`previous_token_hash`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_sessions_token_hash` ON `sessions`(`token_hash`);
+CREATE INDEX IF NOT EXISTS `idx_sessions_user_id` ON `sessions`(`user_id`);
@@ -0,0 +1,40 @@
+-- 家庭表、家庭成员表和邀请码表，用户关联所在的家庭
+
+ALTER TABLE `users` ADD COLUMN `family_id` integer;
+CREATE INDEX IF NOT EXISTS `idx_users_family_id` ON `users`(`family_id`);
+
+CREATE TABLE IF NOT EXISTS `families` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `name` text NOT NULL,
+  `owner_id` integer NOT NULL,
+  `created_at` datetime,
+  `updated_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_families_owner_id` ON `families`(`owner_id`);
+
+CREATE TABLE IF NOT EXISTS `family_members` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `family_id` integer NOT NULL,
+  `user_id` integer NOT NULL,
+  `role` text NOT NULL,
+  `created_at` datetime,
+  `updated_at` datetime,
+  CONSTRAINT `fk_family_members_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`),
+  CONSTRAINT `fk_families_members` FOREIGN KEY (`family_id`) REFERENCES `families`(`id`)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_family_members_user_id` ON `family_members`(`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_family_members_family_id` ON `family_members`(`family_id`);
+
+CREATE TABLE IF NOT EXISTS `family_invites` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `family_id` integer NOT NULL,
+  `code` text NOT NULL,
+  `role` text NOT NULL,
+  `created_by` integer NOT NULL,
+  `expires_at` datetime NOT NULL,
+  `used_by` integer,
+  `used_at` datetime,
+  `created_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_family_invites_code` ON `family_invites`(`code`);
+CREATE INDEX IF NOT EXISTS `idx_family_invites_family_id` ON `family_invites`(`family_id`);
@@ -0,0 +1,3 @@
+-- 回滚：家庭角色权限表
+
+DROP TABLE IF EXISTS `family_role_permissions`;
@@ -0,0 +1,10 @@
+-- 家庭角色权限表
+
+CREATE TABLE IF NOT EXISTS `family_role_permissions` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `family_id` integer NOT NULL,
+  `role` text NOT NULL,
+  `permission` text NOT NULL,
+  `created_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_family_role_permission` ON `family_role_permissions`(`family_id`,`role`,`permission`);
@@ -0,0 +1,3 @@
+-- 回滚：登录失败锁定表
+
+DROP TABLE IF EXISTS `login_lockouts`;
@@ -0,0 +1,13 @@
+-- 登录失败锁定表
+
+CREATE TABLE IF NOT EXISTS `login_lockouts` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `scope` text NOT NULL,
+  `subject` text NOT NULL,
+  `failures` integer NOT NULL DEFAULT 0,
+  `last_failed_at` datetime,
+  `locked_until` datetime,
+  `created_at` datetime,
+  `updated_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_login_lockout_subject` ON `login_lockouts`(`scope`,`subject`);
@@ -0,0 +1,12 @@
+-- 找回密码验证码表
+
+CREATE TABLE IF NOT EXISTS `password_reset_codes` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `user_id` integer NOT NULL,
+  `code_hash` text NOT NULL,
+  `attempts` integer NOT NULL DEFAULT 0,
+  `expires_at` datetime NOT NULL,
+  `used_at` datetime,
+  `created_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_password_reset_codes_user_id` ON `password_reset_codes`(`user_id`);
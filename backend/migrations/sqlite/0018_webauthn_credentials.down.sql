@@ -0,0 +1,3 @@
+-- 回滚：通行密钥表
+
+DROP TABLE IF EXISTS `web_authn_credentials`;
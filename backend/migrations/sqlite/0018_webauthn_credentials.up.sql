@@ -0,0 +1,19 @@
+-- 通行密钥表
+
+CREATE TABLE IF NOT EXISTS `web_authn_credentials` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `user_id` integer NOT NULL,
+  `credential_id` text NOT NULL,
+  `public_key` blob NOT NULL,
+  `attestation_type` text,
+  `aa_guid` blob,
+  `sign_count` integer NOT NULL DEFAULT 0,
+  `transports` text,
+  `backup_eligible` numeric NOT NULL DEFAULT false,
+  `backup_state` numeric NOT NULL DEFAULT false,
+  `name` text,
+  `last_used_at` datetime,
+  `created_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_web_authn_credentials_credential_id` ON `web_authn_credentials`(`credential_id`);
+CREATE INDEX IF NOT EXISTS `idx_web_authn_credentials_user_id` ON `web_authn_credentials`(`user_id`);
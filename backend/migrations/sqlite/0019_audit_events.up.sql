@@ -0,0 +1,20 @@
+-- 审计日志表
+
+CREATE TABLE IF NOT EXISTS `audit_events` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `family_id` integer,
+  `actor_id` integer,
+  `actor_role` text,
+  `action` text NOT NULL,
+  `target_type` text,
+  `target_id` text,
+  `status_code` integer NOT NULL,
+  `ip` text,
+  `user_agent` text,
+  `before` text,
+  `after` text,
+  `created_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_audit_events_action` ON `audit_events`(`action`);
+CREATE INDEX IF NOT EXISTS `idx_audit_events_actor_id` ON `audit_events`(`actor_id`);
+CREATE INDEX IF NOT EXISTS `idx_audit_family_created` ON `audit_events`(`family_id`,`created_at`);
@@ -0,0 +1 @@
+-- SQLite 的初始表结构没有使用 enum，无需修改
@@ -0,0 +1,5 @@
+-- 回滚：行为记录的来源
+
+DROP INDEX `idx_behavior_records_trigger_id`;
+ALTER TABLE `behavior_records` DROP COLUMN `trigger_id`;
+ALTER TABLE `behavior_records` DROP COLUMN `source`;